@@ -0,0 +1,58 @@
+// gnu-reconcile は users.gnu_balance とヌー台帳（gnu_transactions）の合計を突き合わせる
+// 食い違うユーザーを出力し、1件でもあれば終了コード 1 で終了する
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/config"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/infrastructure/persistence"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/infrastructure/postgres"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/infrastructure/postgres/sqlc"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Printf("failed to load config: %v", err)
+		return 2
+	}
+
+	db, err := postgres.NewDB(cfg)
+	if err != nil {
+		log.Printf("failed to connect to db: %v", err)
+		return 2
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			log.Printf("failed to close db: %v", closeErr)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	ledger := persistence.NewGnuLedgerRepository(db, sqlc.New(db))
+	mismatches, err := ledger.ListMismatches(ctx)
+	if err != nil {
+		log.Printf("failed to reconcile gnu balances: %v", err)
+		return 2
+	}
+	for _, m := range mismatches {
+		log.Printf("mismatch: user=%s cached=%d ledger=%d diff=%d",
+			m.UserID, m.CachedBalance, m.LedgerBalance, m.CachedBalance-m.LedgerBalance)
+	}
+	if len(mismatches) > 0 {
+		log.Printf("%d user(s) have gnu_balance out of sync with the ledger", len(mismatches))
+		return 1
+	}
+	log.Println("all gnu balances match the ledger")
+	return 0
+}
@@ -0,0 +1,68 @@
+// leaderboard-rebuild は PostgreSQL の users と match_results から Redis のランキングを作り直す
+// Redis のデータを失った場合や、試合の終了時の更新に失敗した場合に使う
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/config"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/infrastructure/persistence"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/infrastructure/postgres"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/infrastructure/postgres/sqlc"
+	infra_redis "github.com/tobakuro/hackathon_nulabcup/backend/internal/infrastructure/redis"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/usecase"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Printf("failed to load config: %v", err)
+		return 1
+	}
+
+	db, err := postgres.NewDB(cfg)
+	if err != nil {
+		log.Printf("failed to connect to db: %v", err)
+		return 1
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			log.Printf("failed to close db: %v", closeErr)
+		}
+	}()
+
+	rdb, err := infra_redis.NewClient(cfg)
+	if err != nil {
+		log.Printf("failed to connect to redis: %v", err)
+		return 1
+	}
+	defer func() {
+		if closeErr := rdb.Close(); closeErr != nil {
+			log.Printf("failed to close redis: %v", closeErr)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	queries := sqlc.New(db)
+	uc := usecase.NewLeaderboardUsecase(
+		persistence.NewLeaderboardRepository(rdb),
+		persistence.NewUserRepository(db, queries),
+		persistence.NewMatchRepository(db, queries),
+	)
+	result, err := uc.Rebuild(ctx, time.Now())
+	if err != nil {
+		log.Printf("failed to rebuild leaderboards: %v", err)
+		return 1
+	}
+	log.Printf("rebuilt leaderboards: %d ranked user(s), %d winner(s) this week", result.Users, result.Winners)
+	return 0
+}
@@ -2,12 +2,20 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/tobakuro/hackathon_nulabcup/backend/internal/config"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
 	"github.com/tobakuro/hackathon_nulabcup/backend/internal/handler"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/infrastructure/llm"
 	"github.com/tobakuro/hackathon_nulabcup/backend/internal/infrastructure/persistence"
 	"github.com/tobakuro/hackathon_nulabcup/backend/internal/infrastructure/postgres"
 	"github.com/tobakuro/hackathon_nulabcup/backend/internal/infrastructure/postgres/sqlc"
@@ -47,33 +55,167 @@ func main() {
 
 	// DI
 	queries := sqlc.New(db)
-	userRepo := persistence.NewUserRepository(queries)
+	userRepo := persistence.NewUserRepository(db, queries)
 	userUsecase := usecase.NewUserUsecase(userRepo)
 
 	matchmakingRepo := persistence.NewMatchmakingRepository(rdb)
-	roomRepo := persistence.NewRoomRepository(queries, rdb)
-	matchmakingUsecase := usecase.NewMatchmakingUsecase(matchmakingRepo, roomRepo, userRepo)
+	roomRepo := persistence.NewRoomRepository(db, queries, rdb)
+	rateWindow := entity.RateWindow{
+		Base:        cfg.MatchRateWindowBase,
+		WidenPerSec: cfg.MatchRateWindowWidenPerSec,
+		Max:         cfg.MatchRateWindowMax,
+	}
+	matchmakingUsecase := usecase.NewMatchmakingUsecase(matchmakingRepo, roomRepo, userRepo, rateWindow)
+
+	authenticator := handler.NewGitHubAuthenticator(
+		cfg.GitHubAPIBaseURL, persistence.NewWSTicketRepository(rdb), cfg.WSTicketTTL,
+	)
 
-	hub := handler.NewHub(matchmakingUsecase)
+	// 同じ Redis を共有するインスタンス間でルームの担当とメッセージの中継を管理する
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	go hub.Run(ctx)
+	router := handler.NewInstanceRouter(persistence.NewRoutingRepository(rdb), cfg.InstanceID)
+
+	hub := handler.NewHub(matchmakingUsecase, router, cfg.MatchSweepInterval, cfg.MatchEventDriven)
+	hubCtx, stopHub := context.WithCancel(ctx)
+	defer stopHub()
+	hubDone := make(chan struct{}) // Run が戻ると閉じる
+	go func() {
+		defer close(hubDone)
+		hub.Run(hubCtx)
+	}()
 
-	userHandler := handler.NewUserHandler(userUsecase)
 	matchmakeHandler := handler.NewMatchmakeHandler(hub, userRepo)
-	roomManager := handler.NewRoomManager(userRepo)
+	ratingCalc, err := usecase.NewRatingCalculator(cfg.RatingAlgorithm, cfg.EloKFactor, cfg.Glicko2Tau)
+	if err != nil {
+		log.Fatalf("failed to create rating calculator: %v", err)
+	}
+	ratingUsecase := usecase.NewRatingUsecase(ratingCalc, userRepo)
+
+	matchRepo := persistence.NewMatchRepository(db, queries)
+	leaderboardUsecase := usecase.NewLeaderboardUsecase(persistence.NewLeaderboardRepository(rdb), userRepo, matchRepo)
+	userStatsUsecase := usecase.NewUserStatsUsecase(userRepo, matchRepo, persistence.NewUserStatsCacheRepository(rdb))
+	userHandler := handler.NewUserHandler(userUsecase, userStatsUsecase)
+	matchRules := entity.MatchRules{
+		TurnDuration:      cfg.MatchTurnDuration,
+		QuestionWaitLimit: cfg.MatchQuestionWaitLimit,
+		JoinWaitLimit:     cfg.MatchJoinWaitLimit,
+		RematchWaitLimit:  cfg.MatchRematchWaitLimit,
+		TotalTurns:        cfg.MatchTotalTurns,
+		TKOBonus:          cfg.MatchTKOBonus,
+		MinBet:            cfg.MatchMinBet,
+		DifficultyMultipliers: entity.DifficultyMultipliers{
+			Easy:   cfg.MatchDifficultyMultiplierEasy,
+			Normal: cfg.MatchDifficultyMultiplierNormal,
+			Hard:   cfg.MatchDifficultyMultiplierHard,
+		},
+	}
+	if err := matchRules.Validate(); err != nil {
+		log.Fatalf("invalid match rules: %v", err)
+	}
+	questionGen, err := llm.NewGenerator(cfg.QuestionGenerator, cfg.GeminiBaseURL, cfg.GeminiAPIKey, cfg.GeminiModel)
+	if err != nil {
+		log.Fatalf("failed to create question generator: %v", err)
+	}
+	questionUsecase := usecase.NewQuestionUsecase(questionGen, persistence.NewRepositoryFileRepository(db))
+
+	tournamentUsecase := usecase.NewTournamentUsecase(
+		persistence.NewTournamentRepository(db, queries), persistence.NewTournamentUpdateRepository(rdb),
+		roomRepo, persistence.NewRoutingRepository(rdb), cfg.TournamentNoShowLimit,
+	)
+	roomManager := handler.NewRoomManager(
+		userRepo, roomRepo, matchRepo, persistence.NewGnuLedgerRepository(db, queries),
+		ratingUsecase, leaderboardUsecase, userStatsUsecase, tournamentUsecase, questionUsecase,
+		matchRules, cfg.MaxSpectators, router,
+	)
 	roomHandler := handler.NewRoomHandler(roomManager)
+	battleRoyaleRules := entity.DefaultBattleRoyaleRules()
+	battleRoyaleRules.RoundDuration = cfg.BattleRoyaleRoundDuration
+	battleRoyaleRules.QuestionWaitLimit = cfg.MatchQuestionWaitLimit
+	battleRoyaleRules.LobbyWaitLimit = cfg.BattleRoyaleLobbyWaitLimit
+	battleRoyaleRules.EliminatePercent = cfg.BattleRoyaleEliminatePercent
+	if err := battleRoyaleRules.Validate(); err != nil {
+		log.Fatalf("invalid battle royale rules: %v", err)
+	}
+	battleRoyaleManager := handler.NewBattleRoyaleManager(questionUsecase, battleRoyaleRules)
+	battleRoyaleHandler := handler.NewBattleRoyaleHandler(battleRoyaleManager, userRepo)
+	replayHandler := handler.NewReplayHandler(usecase.NewReplayUsecase(roomRepo, matchRepo), userRepo)
+	inviteHandler := handler.NewRoomInviteHandler(
+		usecase.NewRoomInviteUsecase(persistence.NewRoomInviteRepository(rdb), roomRepo, cfg.InviteTTL), userRepo,
+	)
+	tournamentHandler := handler.NewTournamentHandler(tournamentUsecase, userRepo)
+	leaderboardHandler := handler.NewLeaderboardHandler(leaderboardUsecase, userRepo)
+
+	// ハンドラの登録後に購読を始める
+	if err := router.Start(ctx); err != nil {
+		log.Fatalf("failed to start instance router: %v", err)
+	}
+	log.Printf("instance id: %s", cfg.InstanceID)
+
+	// 前回の停止やクラッシュで中断した試合を復元し、プレイヤーの再接続を待つ
+	if err := roomManager.Recover(ctx); err != nil {
+		log.Printf("failed to recover rooms: %v", err)
+	}
+
+	// ルームに誰も接続しないトーナメントの試合を不参加として記録する
+	go tournamentUsecase.RunNoShowSweeper(ctx, cfg.TournamentSweepInterval)
 
 	var devHandler *handler.DevHandler
 	if os.Getenv("ENV") == "development" {
-		devHandler = handler.NewDevHandler(userRepo, matchmakingUsecase, hub)
+		devHandler = handler.NewDevHandler(userRepo, matchmakingUsecase, hub, authenticator)
 	}
 
 	// Router & Start
-	e := handler.NewRouter(userHandler, matchmakeHandler, roomHandler, devHandler)
+	e := handler.NewRouter(authenticator, userHandler, matchmakeHandler, roomHandler, replayHandler, inviteHandler, tournamentHandler, battleRoyaleHandler, leaderboardHandler, devHandler)
 	addr := fmt.Sprintf(":%d", cfg.ServerPort)
-	log.Printf("starting server on %s", addr)
-	if err := e.Start(addr); err != nil {
-		log.Fatalf("failed to start server: %v", err)
+	sigCtx, stopSignal := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stopSignal()
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("starting server on %s", addr)
+		if err := e.Start(addr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	select {
+	case err := <-serverErr:
+		log.Printf("failed to start server: %v", err)
+		return
+	case <-sigCtx.Done():
+	}
+	stopSignal()
+
+	// 停止の順序: マッチングの受け付けを止める → 試合の終了を待つ → HTTP を閉じる
+	// → インスタンス間の購読を止める → Redis → PostgreSQL（defer の逆順）
+	// 停止にかかる時間は最長で SHUTDOWN_TIMEOUT + 打ち切ったルームの精算 (15s) + HTTP の停止 (5s)。
+	// raspi/backend.service の TimeoutStopSec はこれより長くしておくこと
+	log.Printf("shutting down (timeout %s)", cfg.ShutdownTimeout)
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelDrain()
+	// 実行中のマッチングが終わってから Drain し、Drain の後に ev_match_found が届かないようにする
+	stopHub()
+	select {
+	case <-hubDone:
+	case <-drainCtx.Done():
+	}
+	hub.Drain(drainCtx)
+	// 1対1・チーム戦とバトルロイヤルは同じ期限で並行して終了を待ち、打ち切りも同時に行う
+	var drains sync.WaitGroup
+	for _, drain := range []func(context.Context){roomManager.Drain, battleRoyaleManager.Drain} {
+		drains.Add(1)
+		go func() {
+			defer drains.Done()
+			drain(drainCtx)
+		}()
+	}
+	drains.Wait()
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdown()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		log.Printf("failed to shut down server: %v", err)
 	}
+	cancel()
+	log.Println("server stopped")
 }
@@ -0,0 +1,43 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS match_results (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    room_id UUID NOT NULL UNIQUE REFERENCES rooms(id) ON DELETE RESTRICT,
+    player1_id UUID NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    player2_id UUID NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    winner_id UUID REFERENCES users(id) ON DELETE RESTRICT,
+    end_reason VARCHAR(50) NOT NULL CHECK (end_reason IN ('completed', 'tko')),
+    player1_correct_count INT NOT NULL DEFAULT 0,
+    player2_correct_count INT NOT NULL DEFAULT 0,
+    player1_gnu_earned INT NOT NULL DEFAULT 0,
+    player2_gnu_earned INT NOT NULL DEFAULT 0,
+    player1_final_gnu INT NOT NULL,
+    player2_final_gnu INT NOT NULL,
+    total_turns INT NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS match_results_player1_id_idx ON match_results (player1_id);
+CREATE INDEX IF NOT EXISTS match_results_player2_id_idx ON match_results (player2_id);
+
+CREATE TABLE IF NOT EXISTS match_turns (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    match_id UUID NOT NULL REFERENCES match_results(id) ON DELETE CASCADE,
+    turn INT NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    question JSONB NOT NULL,
+    choice_index INT NOT NULL DEFAULT -1,
+    is_correct BOOLEAN NOT NULL DEFAULT FALSE,
+    bet INT NOT NULL DEFAULT 0,
+    gnu_delta INT NOT NULL DEFAULT 0,
+    answer_time_ms INT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT match_turns_unique UNIQUE (match_id, turn, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS match_turns_user_id_idx ON match_turns (user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS match_turns;
+DROP TABLE IF EXISTS match_results;
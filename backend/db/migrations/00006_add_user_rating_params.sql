@@ -0,0 +1,10 @@
+-- +goose Up
+-- Glicko-2 用のレーティング偏差とボラティリティ（Elo では使用しない）
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS rating_deviation DOUBLE PRECISION NOT NULL DEFAULT 350,
+    ADD COLUMN IF NOT EXISTS rating_volatility DOUBLE PRECISION NOT NULL DEFAULT 0.06;
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN IF EXISTS rating_volatility,
+    DROP COLUMN IF EXISTS rating_deviation;
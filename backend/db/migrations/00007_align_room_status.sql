@@ -0,0 +1,21 @@
+-- +goose Up
+-- rooms.status を entity.RoomStatus と揃え、終了理由を記録する
+ALTER TABLE rooms DROP CONSTRAINT IF EXISTS rooms_status_check;
+UPDATE rooms SET status = 'in_progress' WHERE status = 'active';
+UPDATE rooms SET status = 'finished' WHERE status = 'closed';
+ALTER TABLE rooms
+    ADD CONSTRAINT rooms_status_check CHECK (status IN ('waiting', 'in_progress', 'finished', 'aborted')),
+    ADD COLUMN IF NOT EXISTS end_reason VARCHAR(50) CHECK (
+        end_reason IN ('completed', 'tko', 'no_show', 'disconnected', 'question_timeout', 'question_generation_failed', 'canceled')
+    );
+
+CREATE INDEX IF NOT EXISTS rooms_status_idx ON rooms (status);
+
+-- +goose Down
+DROP INDEX IF EXISTS rooms_status_idx;
+ALTER TABLE rooms DROP COLUMN IF EXISTS end_reason;
+ALTER TABLE rooms DROP CONSTRAINT IF EXISTS rooms_status_check;
+UPDATE rooms SET status = 'active' WHERE status = 'in_progress';
+UPDATE rooms SET status = 'closed' WHERE status IN ('finished', 'aborted');
+ALTER TABLE rooms
+    ADD CONSTRAINT rooms_status_check CHECK (status IN ('waiting', 'active', 'closed'));
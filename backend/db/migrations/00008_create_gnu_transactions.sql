@@ -0,0 +1,44 @@
+-- +goose Up
+-- ヌーの増減を追記のみの台帳に記録する。users.gnu_balance は台帳の合計のキャッシュ
+CREATE TABLE IF NOT EXISTS gnu_transactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    room_id UUID REFERENCES rooms(id) ON DELETE SET NULL,
+    turn INT,
+    reason VARCHAR(50) NOT NULL CHECK (reason IN ('opening', 'bet_win', 'bet_loss', 'tko_bonus', 'admin')),
+    delta INT NOT NULL,
+    balance_after INT NOT NULL CHECK (balance_after >= 0),
+    -- 同じ取引の二重反映を防ぐキー（試合の取引は room・turn・user から決まる）
+    idempotency_key VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS gnu_transactions_user_id_idx ON gnu_transactions (user_id, created_at);
+CREATE INDEX IF NOT EXISTS gnu_transactions_room_id_idx ON gnu_transactions (room_id);
+
+-- 既存ユーザーの残高を期首残高として記録する
+INSERT INTO gnu_transactions (user_id, reason, delta, balance_after, idempotency_key)
+SELECT id, 'opening', gnu_balance, gnu_balance, 'opening:' || id
+FROM users
+ON CONFLICT (idempotency_key) DO NOTHING;
+
+-- 新規ユーザーの初期残高も記録する（フロントエンドから作成されたユーザーを含む）
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_opening_gnu_balance() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO gnu_transactions (user_id, reason, delta, balance_after, idempotency_key)
+    VALUES (NEW.id, 'opening', NEW.gnu_balance, NEW.gnu_balance, 'opening:' || NEW.id)
+    ON CONFLICT (idempotency_key) DO NOTHING;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER users_record_opening_gnu_balance
+    AFTER INSERT ON users
+    FOR EACH ROW EXECUTE FUNCTION record_opening_gnu_balance();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS users_record_opening_gnu_balance ON users;
+DROP FUNCTION IF EXISTS record_opening_gnu_balance();
+DROP TABLE IF EXISTS gnu_transactions;
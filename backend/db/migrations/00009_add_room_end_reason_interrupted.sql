@@ -0,0 +1,15 @@
+-- +goose Up
+-- 再起動後に両プレイヤーが戻らず、完了したターンまでで精算したルームの終了理由
+ALTER TABLE rooms DROP CONSTRAINT IF EXISTS rooms_end_reason_check;
+ALTER TABLE rooms
+    ADD CONSTRAINT rooms_end_reason_check CHECK (
+        end_reason IN ('completed', 'tko', 'no_show', 'disconnected', 'question_timeout', 'question_generation_failed', 'canceled', 'interrupted')
+    );
+
+-- +goose Down
+UPDATE rooms SET end_reason = 'canceled' WHERE end_reason = 'interrupted';
+ALTER TABLE rooms DROP CONSTRAINT IF EXISTS rooms_end_reason_check;
+ALTER TABLE rooms
+    ADD CONSTRAINT rooms_end_reason_check CHECK (
+        end_reason IN ('completed', 'tko', 'no_show', 'disconnected', 'question_timeout', 'question_generation_failed', 'canceled')
+    );
@@ -0,0 +1,17 @@
+-- +goose Up
+-- ゲームルームが各プレイヤーに送ったイベントを記録する（リプレイ用）
+CREATE TABLE IF NOT EXISTS match_events (
+    -- 記録した順に並べるため連番にする（再起動をまたいでも後の記録ほど大きい）
+    id BIGSERIAL PRIMARY KEY,
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS match_events_room_id_idx ON match_events (room_id, id);
+
+-- +goose Down
+DROP TABLE IF EXISTS match_events;
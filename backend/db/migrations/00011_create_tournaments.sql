@@ -0,0 +1,49 @@
+-- +goose Up
+-- シングルエリミネーションのトーナメント
+CREATE TABLE IF NOT EXISTS tournaments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'registration' CHECK (status IN ('registration', 'in_progress', 'finished')),
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    -- 決勝が両者の不戦敗で終わった場合は NULL のまま終了する
+    winner_id UUID REFERENCES users(id) ON DELETE RESTRICT,
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS tournaments_created_at_idx ON tournaments (created_at DESC);
+
+CREATE TABLE IF NOT EXISTS tournament_participants (
+    tournament_id UUID NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    -- 開始時に users.rate の高い順に 1 から振る（登録中は NULL）
+    seed INT CHECK (seed >= 1),
+    registered_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tournament_id, user_id)
+);
+
+-- 開始時に全ラウンドの試合を作り、勝者が決まるたびに次のラウンドの枠を埋める
+CREATE TABLE IF NOT EXISTS tournament_matches (
+    id UUID PRIMARY KEY,
+    tournament_id UUID NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+    round INT NOT NULL CHECK (round >= 1),
+    position INT NOT NULL CHECK (position >= 0),
+    player1_id UUID REFERENCES users(id) ON DELETE RESTRICT,
+    player2_id UUID REFERENCES users(id) ON DELETE RESTRICT,
+    -- 対戦中のルーム（サーバー側の理由で中止した試合は新しいルームに差し替える）
+    room_id UUID UNIQUE REFERENCES rooms(id) ON DELETE SET NULL,
+    winner_id UUID REFERENCES users(id) ON DELETE RESTRICT,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'playing', 'finished')),
+    result VARCHAR(50) CHECK (result IN ('played', 'forfeit', 'bye', 'void', 'seed')),
+    -- 作成したルームの数
+    attempts INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tournament_id, round, position)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS tournament_matches;
+DROP TABLE IF EXISTS tournament_participants;
+DROP TABLE IF EXISTS tournaments;
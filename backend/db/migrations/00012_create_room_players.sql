@@ -0,0 +1,16 @@
+-- +goose Up
+-- チーム戦のルームの参加者と所属チーム（1対1のルームは rooms.player1_id / player2_id だけで表す）
+CREATE TABLE IF NOT EXISTS room_players (
+    room_id UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    team INT NOT NULL CHECK (team IN (0, 1)),
+    -- チーム内の並び順（rooms.player1_id / player2_id は各チームの position 0）
+    position INT NOT NULL CHECK (position >= 0),
+    PRIMARY KEY (room_id, user_id),
+    CONSTRAINT room_players_position_unique UNIQUE (room_id, team, position)
+);
+
+CREATE INDEX IF NOT EXISTS room_players_user_id_idx ON room_players (user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS room_players;
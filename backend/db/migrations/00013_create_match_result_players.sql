@@ -0,0 +1,31 @@
+-- +goose Up
+-- 試合に参加したプレイヤーごとの結果（1対1・チーム戦共通）
+-- match_results の player1_id / player2_id は各チームの position 0 だけを表すため、勝敗・成績はこのテーブルから集計する
+CREATE TABLE IF NOT EXISTS match_result_players (
+    match_id UUID NOT NULL REFERENCES match_results(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    team INT NOT NULL CHECK (team IN (0, 1)),
+    position INT NOT NULL CHECK (position >= 0),
+    outcome VARCHAR(10) NOT NULL CHECK (outcome IN ('win', 'lose', 'draw')),
+    correct_count INT NOT NULL DEFAULT 0,
+    gnu_earned INT NOT NULL DEFAULT 0,
+    final_gnu INT NOT NULL,
+    PRIMARY KEY (match_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS match_result_players_user_id_idx ON match_result_players (user_id);
+
+-- 既存の試合結果（1対1のみ）を移す
+INSERT INTO match_result_players (match_id, user_id, team, position, outcome, correct_count, gnu_earned, final_gnu)
+SELECT id, player1_id, 0, 0,
+    CASE WHEN winner_id IS NULL THEN 'draw' WHEN winner_id = player1_id THEN 'win' ELSE 'lose' END,
+    player1_correct_count, player1_gnu_earned, player1_final_gnu
+FROM match_results
+UNION ALL
+SELECT id, player2_id, 1, 0,
+    CASE WHEN winner_id IS NULL THEN 'draw' WHEN winner_id = player2_id THEN 'win' ELSE 'lose' END,
+    player2_correct_count, player2_gnu_earned, player2_final_gnu
+FROM match_results;
+
+-- +goose Down
+DROP TABLE IF EXISTS match_result_players;
@@ -0,0 +1,30 @@
+-- +goose Up
+-- 再起動後に戻らなかった試合（interrupted）とサーバーの停止で中止した試合（canceled）も、完了したターンまでで保存する
+-- 中止した試合の参加者は勝敗なし（no_contest）として、勝敗・連勝の集計から外す
+ALTER TABLE match_results DROP CONSTRAINT IF EXISTS match_results_end_reason_check;
+ALTER TABLE match_results
+    ADD CONSTRAINT match_results_end_reason_check CHECK (
+        end_reason IN ('completed', 'tko', 'interrupted', 'canceled')
+    );
+
+ALTER TABLE match_result_players DROP CONSTRAINT IF EXISTS match_result_players_outcome_check;
+ALTER TABLE match_result_players
+    ADD CONSTRAINT match_result_players_outcome_check CHECK (
+        outcome IN ('win', 'lose', 'draw', 'no_contest')
+    );
+
+-- +goose Down
+-- match_turns / match_result_players は ON DELETE CASCADE で一緒に消える
+DELETE FROM match_results WHERE end_reason IN ('interrupted', 'canceled');
+
+ALTER TABLE match_result_players DROP CONSTRAINT IF EXISTS match_result_players_outcome_check;
+ALTER TABLE match_result_players
+    ADD CONSTRAINT match_result_players_outcome_check CHECK (
+        outcome IN ('win', 'lose', 'draw')
+    );
+
+ALTER TABLE match_results DROP CONSTRAINT IF EXISTS match_results_end_reason_check;
+ALTER TABLE match_results
+    ADD CONSTRAINT match_results_end_reason_check CHECK (
+        end_reason IN ('completed', 'tko')
+    );
@@ -0,0 +1,7 @@
+-- +goose Up
+-- 問題の形式ごとの回答の内容（entity.Answer の JSON。未回答と形式の追加前の記録は NULL）
+-- choice_index は選択肢を1つ選ぶ形式の回答のみを表し、それ以外の形式では -1
+ALTER TABLE match_turns ADD COLUMN IF NOT EXISTS answer JSONB;
+
+-- +goose Down
+ALTER TABLE match_turns DROP COLUMN IF EXISTS answer;
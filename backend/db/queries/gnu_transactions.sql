@@ -0,0 +1,19 @@
+-- name: LockUserGnuBalance :one
+SELECT gnu_balance FROM users WHERE id = $1 FOR UPDATE;
+
+-- name: CreateGnuTransaction :one
+INSERT INTO gnu_transactions (user_id, room_id, turn, reason, delta, balance_after, idempotency_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (idempotency_key) DO NOTHING
+RETURNING *;
+
+-- name: SetUserGnuBalance :exec
+UPDATE users SET gnu_balance = $2, updated_at = NOW() WHERE id = $1;
+
+-- name: ListGnuBalanceMismatches :many
+SELECT u.id AS user_id, u.gnu_balance AS cached_balance, COALESCE(SUM(t.delta), 0)::INT AS ledger_balance
+FROM users u
+LEFT JOIN gnu_transactions t ON t.user_id = u.id
+GROUP BY u.id, u.gnu_balance
+HAVING u.gnu_balance <> COALESCE(SUM(t.delta), 0)
+ORDER BY u.id;
@@ -0,0 +1,108 @@
+-- name: CreateMatchResult :one
+INSERT INTO match_results (
+    room_id, player1_id, player2_id, winner_id, end_reason,
+    player1_correct_count, player2_correct_count,
+    player1_gnu_earned, player2_gnu_earned,
+    player1_final_gnu, player2_final_gnu,
+    total_turns, started_at, finished_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+RETURNING *;
+
+-- name: CreateMatchTurn :exec
+INSERT INTO match_turns (
+    match_id, turn, user_id, question, choice_index,
+    is_correct, bet, gnu_delta, answer_time_ms, answer
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+
+-- name: CreateMatchResultPlayer :exec
+INSERT INTO match_result_players (
+    match_id, user_id, team, position, outcome,
+    correct_count, gnu_earned, final_gnu
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: GetMatchResultByRoomID :one
+SELECT * FROM match_results WHERE room_id = $1;
+
+-- name: ListMatchTurnsByMatchID :many
+SELECT * FROM match_turns WHERE match_id = $1 ORDER BY turn, user_id;
+
+-- name: ListMatchResultPlayersByMatchID :many
+SELECT * FROM match_result_players WHERE match_id = $1 ORDER BY team, position;
+
+-- name: ListMatchResultsByUserID :many
+SELECT * FROM match_results
+WHERE id IN (SELECT match_id FROM match_result_players WHERE user_id = $1)
+ORDER BY finished_at DESC
+LIMIT $2;
+
+-- name: CreateMatchEvent :exec
+INSERT INTO match_events (room_id, user_id, event_type, payload, sent_at)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: ListMatchEventsByRoomID :many
+SELECT * FROM match_events WHERE room_id = $1 ORDER BY id;
+
+-- name: CountWinsSince :many
+SELECT p.user_id, COUNT(*) AS wins
+FROM match_result_players p
+JOIN match_results m ON m.id = p.match_id
+WHERE p.outcome = 'win' AND m.finished_at >= $1
+GROUP BY p.user_id;
+
+-- name: GetUserMatchSummary :one
+SELECT
+    COUNT(*) FILTER (WHERE p.outcome <> 'no_contest') AS matches_played,
+    COUNT(*) FILTER (WHERE p.outcome = 'win') AS wins,
+    COUNT(*) FILTER (WHERE p.outcome = 'lose') AS losses,
+    COUNT(*) FILTER (WHERE p.outcome = 'draw') AS draws,
+    COUNT(*) FILTER (WHERE m.end_reason = 'tko' AND p.outcome = 'win') AS tko_wins,
+    COUNT(*) FILTER (WHERE m.end_reason = 'tko' AND p.outcome = 'lose') AS tko_losses,
+    COALESCE(SUM(p.gnu_earned), 0)::INT AS gnu_earned
+FROM match_result_players p
+JOIN match_results m ON m.id = p.match_id
+WHERE p.user_id = sqlc.arg(user_id);
+
+-- name: GetUserBestWinStreak :one
+-- 勝った試合が連続する区間（gaps and islands）の最長を求める
+-- 中止した試合（no_contest）は数えず、連勝も途切れさせない
+WITH outcomes AS (
+    SELECT m.finished_at, p.outcome = 'win' AS won
+    FROM match_result_players p
+    JOIN match_results m ON m.id = p.match_id
+    WHERE p.user_id = sqlc.arg(user_id)
+        AND p.outcome <> 'no_contest'
+), runs AS (
+    SELECT won,
+        ROW_NUMBER() OVER (ORDER BY finished_at)
+            - ROW_NUMBER() OVER (PARTITION BY won ORDER BY finished_at) AS run_id
+    FROM outcomes
+)
+SELECT COALESCE(MAX(run_length), 0)::INT AS best_win_streak
+FROM (SELECT COUNT(*) AS run_length FROM runs WHERE won GROUP BY run_id) streaks;
+
+-- name: ListUserTurnStatsByDifficulty :many
+SELECT
+    COALESCE(question->>'difficulty', '')::TEXT AS difficulty,
+    COUNT(*) AS turns,
+    COUNT(*) FILTER (WHERE is_correct) AS correct,
+    COUNT(*) FILTER (WHERE bet > 0) AS bets,
+    COALESCE(SUM(bet), 0)::BIGINT AS bet_total
+FROM match_turns
+WHERE user_id = sqlc.arg(user_id)
+GROUP BY 1
+ORDER BY 1;
+
+-- name: ListUserDailyGnuEarned :many
+SELECT
+    (m.finished_at AT TIME ZONE 'UTC')::DATE AS day,
+    COUNT(*) AS matches,
+    SUM(p.gnu_earned)::INT AS gnu_earned
+FROM match_result_players p
+JOIN match_results m ON m.id = p.match_id
+WHERE p.user_id = sqlc.arg(user_id)
+    AND m.finished_at >= sqlc.arg(since)
+GROUP BY 1
+ORDER BY 1;
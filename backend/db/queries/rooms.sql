@@ -7,4 +7,11 @@ RETURNING *;
 SELECT * FROM rooms WHERE id = $1;
 
 -- name: UpdateRoomStatus :exec
-UPDATE rooms SET status = $2, updated_at = NOW() WHERE id = $1;
+UPDATE rooms SET status = $2, end_reason = $3, updated_at = NOW() WHERE id = $1;
+
+-- name: CreateRoomPlayer :exec
+INSERT INTO room_players (room_id, user_id, team, position)
+VALUES ($1, $2, $3, $4);
+
+-- name: ListRoomPlayers :many
+SELECT * FROM room_players WHERE room_id = $1 ORDER BY team, position;
@@ -0,0 +1,59 @@
+-- name: CreateTournament :one
+INSERT INTO tournaments (id, name, created_by)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetTournamentByID :one
+SELECT * FROM tournaments WHERE id = $1;
+
+-- name: LockTournament :one
+SELECT * FROM tournaments WHERE id = $1 FOR UPDATE;
+
+-- name: ListTournaments :many
+SELECT * FROM tournaments ORDER BY created_at DESC, id LIMIT $1;
+
+-- name: UpdateTournament :exec
+UPDATE tournaments
+SET status = $2, winner_id = $3, started_at = $4, finished_at = $5, updated_at = NOW()
+WHERE id = $1;
+
+-- name: ListTournamentParticipants :many
+SELECT p.tournament_id, p.user_id, p.seed, p.registered_at, u.github_login, u.rate
+FROM tournament_participants p
+JOIN users u ON u.id = p.user_id
+WHERE p.tournament_id = $1
+ORDER BY p.seed NULLS LAST, p.registered_at, p.user_id;
+
+-- name: UpsertTournamentParticipant :exec
+INSERT INTO tournament_participants (tournament_id, user_id, seed, registered_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (tournament_id, user_id) DO UPDATE SET seed = EXCLUDED.seed;
+
+-- name: ListTournamentMatches :many
+SELECT * FROM tournament_matches WHERE tournament_id = $1 ORDER BY round, position;
+
+-- name: GetTournamentMatchByRoomID :one
+SELECT * FROM tournament_matches WHERE room_id = $1;
+
+-- name: UpsertTournamentMatch :exec
+INSERT INTO tournament_matches (
+    id, tournament_id, round, position, player1_id, player2_id, room_id, winner_id, status, result, attempts
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (id) DO UPDATE SET
+    player1_id = EXCLUDED.player1_id,
+    player2_id = EXCLUDED.player2_id,
+    room_id = EXCLUDED.room_id,
+    winner_id = EXCLUDED.winner_id,
+    status = EXCLUDED.status,
+    result = EXCLUDED.result,
+    attempts = EXCLUDED.attempts,
+    updated_at = NOW();
+
+-- name: ListStaleTournamentMatches :many
+-- 対戦中のまま誰も接続していないルーム（rooms.status が waiting のまま作成から時間が経った）の試合
+SELECT m.*
+FROM tournament_matches m
+JOIN rooms r ON r.id = m.room_id
+WHERE m.status = 'playing' AND r.status = 'waiting' AND r.created_at < $1
+ORDER BY r.created_at;
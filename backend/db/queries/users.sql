@@ -1,6 +1,9 @@
 -- name: GetUserByID :one
 SELECT * FROM users WHERE id = $1;
 
+-- name: LockUserByID :one
+SELECT * FROM users WHERE id = $1 FOR UPDATE;
+
 -- name: GetUserByGitHubID :one
 SELECT * FROM users WHERE github_id = $1;
 
@@ -12,5 +15,14 @@ RETURNING *;
 -- name: GetUserByGitHubLogin :one
 SELECT * FROM users WHERE github_login = $1;
 
--- name: UpdateGnuBalance :exec
-UPDATE users SET gnu_balance = GREATEST(0, $2), updated_at = NOW() WHERE id = $1;
+-- name: UpdateUserRating :exec
+UPDATE users
+SET rate = $2, rating_deviation = $3, rating_volatility = $4, updated_at = NOW()
+WHERE id = $1;
+
+-- name: ListUsersWithMatches :many
+SELECT * FROM users u
+WHERE EXISTS (
+    SELECT 1 FROM match_result_players p WHERE p.user_id = u.id
+)
+ORDER BY u.id;
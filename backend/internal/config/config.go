@@ -4,25 +4,70 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/caarlos0/env/v11"
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	DatabaseURL string `env:"DATABASE_URL"`
-	DBHost      string `env:"DB_HOST"`
-	DBUser      string `env:"DB_USER"`
-	DBPassword  string `env:"DB_PASSWORD"`
-	DBName      string `env:"DB_NAME" envDefault:"hackathon"`
-	DBSSLMode   string `env:"DB_SSLMODE" envDefault:"disable"`
-	RedisURL    string `env:"REDIS_URL"`
-	RedisAddr   string `env:"REDIS_ADDR" envDefault:"localhost:6379"`
-	RedisPW     string `env:"REDIS_PASSWORD" envDefault:""`
-	RedisTLS    bool   `env:"REDIS_TLS" envDefault:"false"`
-	ServerPort  int    `env:"SERVER_PORT" envDefault:"8080"`
-	DBPort      int    `env:"DB_PORT" envDefault:"5432"`
-	RedisDB     int    `env:"REDIS_DB" envDefault:"0"`
+	DatabaseURL     string `env:"DATABASE_URL"`
+	DBHost          string `env:"DB_HOST"`
+	DBUser          string `env:"DB_USER"`
+	DBPassword      string `env:"DB_PASSWORD"`
+	DBName          string `env:"DB_NAME" envDefault:"hackathon"`
+	DBSSLMode       string `env:"DB_SSLMODE" envDefault:"disable"`
+	RedisURL        string `env:"REDIS_URL"`
+	RedisAddr       string `env:"REDIS_ADDR" envDefault:"localhost:6379"`
+	RedisPW         string `env:"REDIS_PASSWORD" envDefault:""`
+	RatingAlgorithm string `env:"RATING_ALGORITHM" envDefault:"elo"` // elo / glicko2
+	// トークン検証に使う GitHub API（テストではスタブサーバーに差し替える）
+	GitHubAPIBaseURL string `env:"GITHUB_API_BASE_URL" envDefault:"https://api.github.com"`
+	// 複数インスタンス構成でルームの担当と中継先を識別する ID（未設定の場合は起動ごとに生成する）
+	InstanceID string `env:"INSTANCE_ID"`
+	// 問題生成
+	QuestionGenerator string        `env:"QUESTION_GENERATOR" envDefault:"gemini"` // gemini / fake
+	GeminiAPIKey      string        `env:"GEMINI_API_KEY"`
+	GeminiBaseURL     string        `env:"GEMINI_BASE_URL"`
+	GeminiModel       string        `env:"GEMINI_MODEL" envDefault:"gemini-2.5-flash"`
+	RedisTLS          bool          `env:"REDIS_TLS" envDefault:"false"`
+	MatchEventDriven  bool          `env:"MATCH_EVENT_DRIVEN" envDefault:"true"` // キュー追加の通知でマッチングを試みる
+	ServerPort        int           `env:"SERVER_PORT" envDefault:"8080"`
+	DBPort            int           `env:"DB_PORT" envDefault:"5432"`
+	RedisDB           int           `env:"REDIS_DB" envDefault:"0"`
+	EloKFactor        int           `env:"ELO_K_FACTOR" envDefault:"32"`
+	MaxSpectators     int           `env:"MAX_SPECTATORS" envDefault:"20"` // ルームあたりの観戦者数の上限
+	WSTicketTTL       time.Duration `env:"WS_TICKET_TTL" envDefault:"30s"` // WebSocket 接続チケットの有効期限
+	InviteTTL         time.Duration `env:"INVITE_TTL" envDefault:"10m"`    // プライベートルームの招待の有効期限
+	// トーナメントで作成したルームに誰も接続しない試合を両者不参加とするまでの時間と、その見回り間隔
+	TournamentNoShowLimit   time.Duration `env:"TOURNAMENT_NO_SHOW_LIMIT" envDefault:"5m"`
+	TournamentSweepInterval time.Duration `env:"TOURNAMENT_SWEEP_INTERVAL" envDefault:"30s"`
+	// 試合ルール
+	MatchTotalTurns        int           `env:"MATCH_TOTAL_TURNS" envDefault:"10"`
+	MatchTKOBonus          int           `env:"MATCH_TKO_BONUS" envDefault:"300"`
+	MatchMinBet            int           `env:"MATCH_MIN_BET" envDefault:"0"`
+	MatchTurnDuration      time.Duration `env:"MATCH_TURN_DURATION" envDefault:"15s"`
+	MatchQuestionWaitLimit time.Duration `env:"MATCH_QUESTION_WAIT_LIMIT" envDefault:"180s"`
+	MatchJoinWaitLimit     time.Duration `env:"MATCH_JOIN_WAIT_LIMIT" envDefault:"60s"`
+	MatchRematchWaitLimit  time.Duration `env:"MATCH_REMATCH_WAIT_LIMIT" envDefault:"30s"` // 0 で再戦を無効にする
+	// 難易度ごとの倍率（正解時のベットの払い戻しと、勝敗判定の正解数に掛ける）
+	MatchDifficultyMultiplierEasy   float64 `env:"MATCH_DIFFICULTY_MULTIPLIER_EASY" envDefault:"1"`
+	MatchDifficultyMultiplierNormal float64 `env:"MATCH_DIFFICULTY_MULTIPLIER_NORMAL" envDefault:"1.5"`
+	MatchDifficultyMultiplierHard   float64 `env:"MATCH_DIFFICULTY_MULTIPLIER_HARD" envDefault:"2"`
+	// バトルロイヤルのルール（問題の生成待ちの上限は MATCH_QUESTION_WAIT_LIMIT を使う）
+	BattleRoyaleEliminatePercent int           `env:"BATTLE_ROYALE_ELIMINATE_PERCENT" envDefault:"25"`
+	BattleRoyaleRoundDuration    time.Duration `env:"BATTLE_ROYALE_ROUND_DURATION" envDefault:"20s"`
+	BattleRoyaleLobbyWaitLimit   time.Duration `env:"BATTLE_ROYALE_LOBBY_WAIT_LIMIT" envDefault:"30m"`
+	// マッチングで許容するレート差（待ち時間に応じて Base から Max まで広がる）
+	MatchRateWindowBase        int     `env:"MATCH_RATE_WINDOW_BASE" envDefault:"100"`
+	MatchRateWindowMax         int     `env:"MATCH_RATE_WINDOW_MAX" envDefault:"500"`
+	Glicko2Tau                 float64 `env:"GLICKO2_TAU" envDefault:"0.5"`
+	MatchRateWindowWidenPerSec float64 `env:"MATCH_RATE_WINDOW_WIDEN_PER_SEC" envDefault:"10"`
+	// マッチングループの見回り間隔（MATCH_EVENT_DRIVEN=false の場合はポーリング間隔）
+	MatchSweepInterval time.Duration `env:"MATCH_SWEEP_INTERVAL" envDefault:"2s"`
+	// SIGTERM を受けてから進行中の試合の終了を待つ上限。超えた試合は中止して完了したターンまで精算する
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"90s"`
 }
 
 // DSN returns the PostgreSQL connection string.
@@ -51,6 +96,9 @@ func Load() (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
+	if cfg.InstanceID == "" {
+		cfg.InstanceID = uuid.NewString()
+	}
 	return &cfg, nil
 }
 
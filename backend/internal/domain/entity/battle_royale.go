@@ -0,0 +1,149 @@
+package entity
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// BattleRoyaleMinPlayers はバトルロイヤルを開始できる最少人数
+	BattleRoyaleMinPlayers = 8
+	// BattleRoyaleMaxPlayers は1ルームに参加できる人数の上限
+	BattleRoyaleMaxPlayers = 32
+)
+
+// BattleRoyaleStatus はバトルロイヤルの状態を表す型
+type BattleRoyaleStatus string
+
+const (
+	BattleRoyaleStatusLobby      BattleRoyaleStatus = "lobby"      // 参加者の受け付け中
+	BattleRoyaleStatusGenerating BattleRoyaleStatus = "generating" // 開始済みで問題を生成している
+	BattleRoyaleStatusInProgress BattleRoyaleStatus = "in_progress"
+	BattleRoyaleStatusFinished   BattleRoyaleStatus = "finished"
+	BattleRoyaleStatusAborted    BattleRoyaleStatus = "aborted" // 開始前の期限切れ・問題生成の失敗・サーバーの停止
+)
+
+// BattleRoyaleRules はバトルロイヤルの進行ルール
+type BattleRoyaleRules struct {
+	RoundDuration     time.Duration // 1ラウンドの制限時間
+	QuestionWaitLimit time.Duration // 開始から問題が揃うまでの待ち時間の上限
+	LobbyWaitLimit    time.Duration // 作成から開始までの待ち時間の上限
+	CorrectGnu        int           // 正解で得るヌー
+	SpeedBonusGnu     int           // 回答の早さに応じて加算するヌーの上限（出題直後の回答で満額）
+	EliminatePercent  int           // ラウンドごとに脱落させる生存者の割合（%）。1人以上は必ず脱落する
+}
+
+// DefaultBattleRoyaleRules は標準ルール（20秒・毎ラウンド生存者の 25% が脱落）を返す
+func DefaultBattleRoyaleRules() BattleRoyaleRules {
+	return BattleRoyaleRules{
+		RoundDuration:     20 * time.Second,
+		QuestionWaitLimit: 180 * time.Second,
+		LobbyWaitLimit:    30 * time.Minute,
+		CorrectGnu:        100,
+		SpeedBonusGnu:     100,
+		EliminatePercent:  25,
+	}
+}
+
+// Validate は BattleRoyaleRules の整合性を検証する
+func (r BattleRoyaleRules) Validate() error {
+	if r.RoundDuration < time.Second {
+		return errors.New("round duration must be at least 1s")
+	}
+	if r.QuestionWaitLimit <= 0 {
+		return errors.New("question wait limit must be positive")
+	}
+	if r.LobbyWaitLimit <= 0 {
+		return errors.New("lobby wait limit must be positive")
+	}
+	if r.CorrectGnu < 0 || r.SpeedBonusGnu < 0 {
+		return errors.New("gnu rewards must not be negative")
+	}
+	if r.EliminatePercent < 1 || r.EliminatePercent > 50 {
+		return errors.New("eliminate percent must be between 1 and 50")
+	}
+	return nil
+}
+
+// EliminationCount は生存者 alive 人のラウンドで脱落させる人数を返す
+// 生存者の EliminatePercent %（切り捨て、最低1人）で、最後の1人は残す
+func (r BattleRoyaleRules) EliminationCount(alive int) int {
+	if alive <= 1 {
+		return 0
+	}
+	return min(max(alive*r.EliminatePercent/100, 1), alive-1)
+}
+
+// Rounds は players 人で始めたバトルロイヤルが1人になるまでのラウンド数を返す
+func (r BattleRoyaleRules) Rounds(players int) int {
+	rounds := 0
+	for alive := players; alive > 1; alive -= r.EliminationCount(alive) {
+		rounds++
+	}
+	return rounds
+}
+
+// RoundGnu は正解したときに得るヌーを返す
+// elapsed は出題から回答までの時間で、残り時間に比例した速さのボーナスを加える
+func (r BattleRoyaleRules) RoundGnu(elapsed time.Duration) int {
+	remaining := max(r.RoundDuration-elapsed, 0)
+	return r.CorrectGnu + int(int64(r.SpeedBonusGnu)*int64(remaining)/int64(r.RoundDuration))
+}
+
+// BattleRoyaleStanding はバトルロイヤルの参加者1人の成績
+type BattleRoyaleStanding struct {
+	GitHubLogin     string    `json:"github_login"`
+	UserID          uuid.UUID `json:"user_id"`
+	Gnu             int       `json:"gnu"` // 試合中に得たヌーの累計（残高には反映しない）
+	CorrectCount    int       `json:"correct_count"`
+	EliminatedRound int       `json:"eliminated_round"` // 脱落したラウンド（生存中は 0）
+	Rank            int       `json:"rank"`             // 確定した順位（脱落時と優勝時に決まる。未確定は 0）
+	Order           int       `json:"-"`                // 参加順（同じ成績の場合は先に参加した方が上位）
+}
+
+// compareStanding は生存者どうしの成績を比べ、a が上位なら負の値を返す
+// ヌーの累計、正解数、参加順の順に比べる
+func compareStanding(a, b BattleRoyaleStanding) int {
+	switch {
+	case a.Gnu != b.Gnu:
+		return b.Gnu - a.Gnu
+	case a.CorrectCount != b.CorrectCount:
+		return b.CorrectCount - a.CorrectCount
+	default:
+		return a.Order - b.Order
+	}
+}
+
+// SelectEliminated は生存者の成績 alive から下位 count 人を選び、最下位から順にインデックスを返す
+func SelectEliminated(alive []BattleRoyaleStanding, count int) []int {
+	idxs := make([]int, len(alive))
+	for i := range idxs {
+		idxs[i] = i
+	}
+	slices.SortStableFunc(idxs, func(a, b int) int {
+		return compareStanding(alive[b], alive[a])
+	})
+	return idxs[:min(count, len(idxs))]
+}
+
+// SortStandings は順位表の並びに整える
+// 生存者を成績順に先頭へ、脱落者をその後に確定した順位の順に並べる
+func SortStandings(standings []BattleRoyaleStanding) {
+	slices.SortStableFunc(standings, func(a, b BattleRoyaleStanding) int {
+		aOut, bOut := a.EliminatedRound > 0, b.EliminatedRound > 0
+		switch {
+		case aOut != bOut:
+			if aOut {
+				return 1
+			}
+			return -1
+		case aOut:
+			return a.Rank - b.Rank
+		default:
+			return compareStanding(a, b)
+		}
+	})
+}
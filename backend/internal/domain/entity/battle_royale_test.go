@@ -0,0 +1,72 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBattleRoyaleRules_EliminationCount(t *testing.T) {
+	rules := DefaultBattleRoyaleRules()
+
+	assert.Equal(t, 8, rules.EliminationCount(32))
+	assert.Equal(t, 2, rules.EliminationCount(8))
+	assert.Equal(t, 1, rules.EliminationCount(3), "at least one player is eliminated")
+	assert.Equal(t, 1, rules.EliminationCount(2))
+	assert.Equal(t, 0, rules.EliminationCount(1))
+
+	rules.EliminatePercent = 50
+	assert.Equal(t, 1, rules.EliminationCount(2), "the last player always remains")
+}
+
+func TestBattleRoyaleRules_Rounds(t *testing.T) {
+	rules := DefaultBattleRoyaleRules()
+
+	// 8 → 6 → 5 → 4 → 3 → 2 → 1
+	assert.Equal(t, 6, rules.Rounds(8))
+	assert.Equal(t, 12, rules.Rounds(32))
+	assert.Equal(t, 0, rules.Rounds(1))
+}
+
+func TestBattleRoyaleRules_RoundGnu(t *testing.T) {
+	rules := DefaultBattleRoyaleRules()
+
+	assert.Equal(t, 200, rules.RoundGnu(0))
+	assert.Equal(t, 150, rules.RoundGnu(rules.RoundDuration/2))
+	assert.Equal(t, 100, rules.RoundGnu(rules.RoundDuration+time.Second), "late answers get no bonus")
+}
+
+func TestSelectEliminated_LowestGnuFirst(t *testing.T) {
+	alive := []BattleRoyaleStanding{
+		{GitHubLogin: "alice", Gnu: 300, CorrectCount: 2, Order: 0},
+		{GitHubLogin: "bob", Gnu: 100, CorrectCount: 1, Order: 1},
+		{GitHubLogin: "carol", Gnu: 100, CorrectCount: 1, Order: 2},
+		{GitHubLogin: "dave", Gnu: 100, CorrectCount: 0, Order: 3},
+		{GitHubLogin: "erin", Gnu: 250, CorrectCount: 2, Order: 4},
+	}
+
+	got := SelectEliminated(alive, 2)
+
+	// 同じヌーなら正解数の少ない方、それも同じなら後から参加した方が下位
+	assert.Equal(t, []int{3, 2}, got)
+	assert.Len(t, SelectEliminated(alive, 10), len(alive))
+}
+
+func TestSortStandings(t *testing.T) {
+	standings := []BattleRoyaleStanding{
+		{GitHubLogin: "out-last", EliminatedRound: 1, Rank: 4},
+		{GitHubLogin: "alive-low", Gnu: 100, Order: 0},
+		{GitHubLogin: "out-later", EliminatedRound: 2, Rank: 3},
+		{GitHubLogin: "alive-high", Gnu: 200, Order: 1},
+	}
+
+	SortStandings(standings)
+
+	logins := make([]string, len(standings))
+	for i, s := range standings {
+		logins[i] = s.GitHubLogin
+	}
+	require.Equal(t, []string{"alive-high", "alive-low", "out-later", "out-last"}, logins)
+}
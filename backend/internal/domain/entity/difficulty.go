@@ -0,0 +1,80 @@
+package entity
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// maxDifficultyMultiplier は難易度の倍率の上限
+const maxDifficultyMultiplier = 10
+
+// Difficulty は問題の難易度を表す型
+type Difficulty string
+
+const (
+	DifficultyEasy   Difficulty = "easy"
+	DifficultyNormal Difficulty = "normal"
+	DifficultyHard   Difficulty = "hard"
+)
+
+// ParseDifficulty は難易度の表記を大文字・小文字を区別せずに解釈する（"Easy" / "easy" など）
+func ParseDifficulty(s string) (Difficulty, error) {
+	switch d := Difficulty(strings.ToLower(strings.TrimSpace(s))); d {
+	case DifficultyEasy, DifficultyNormal, DifficultyHard:
+		return d, nil
+	}
+	return "", fmt.Errorf("unknown difficulty %q", s)
+}
+
+// DifficultyMultipliers は難易度ごとの倍率
+// 正解したときのベットの払い戻しと、勝敗を決める正解数の重み付けに使う
+type DifficultyMultipliers struct {
+	Easy   float64 `json:"easy"`
+	Normal float64 `json:"normal"`
+	Hard   float64 `json:"hard"`
+}
+
+// DefaultDifficultyMultipliers は標準の倍率（easy 1 倍・normal 1.5 倍・hard 2 倍）を返す
+func DefaultDifficultyMultipliers() DifficultyMultipliers {
+	return DifficultyMultipliers{Easy: 1, Normal: 1.5, Hard: 2}
+}
+
+// Validate は倍率がすべて 0 より大きく上限以下であることを検証する
+func (m DifficultyMultipliers) Validate() error {
+	for d, v := range map[Difficulty]float64{DifficultyEasy: m.Easy, DifficultyNormal: m.Normal, DifficultyHard: m.Hard} {
+		if v <= 0 || v > maxDifficultyMultiplier {
+			return fmt.Errorf("%s multiplier must be greater than 0 and at most %d", d, maxDifficultyMultiplier)
+		}
+	}
+	return nil
+}
+
+// Of は難易度 d の倍率を返す
+// 未設定（0）の倍率と未知の難易度は 1 倍とする（倍率の導入前に保存したスナップショットのルールを含む）
+func (m DifficultyMultipliers) Of(d Difficulty) float64 {
+	var v float64
+	switch d {
+	case DifficultyEasy:
+		v = m.Easy
+	case DifficultyNormal:
+		v = m.Normal
+	case DifficultyHard:
+		v = m.Hard
+	}
+	if v <= 0 {
+		return 1
+	}
+	return v
+}
+
+// Scale は amount に難易度 d の倍率を掛けて四捨五入した値を返す
+func (m DifficultyMultipliers) Scale(d Difficulty, amount int) int {
+	return int(math.Round(float64(amount) * m.Of(d)))
+}
+
+// Points は難易度 d の問題に正解したときの重み付きの正解数を百分率で返す（1 倍で 100）
+// 浮動小数点の誤差なく合計・比較できるよう整数にする
+func (m DifficultyMultipliers) Points(d Difficulty) int {
+	return m.Scale(d, 100)
+}
@@ -0,0 +1,53 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDifficulty(t *testing.T) {
+	for in, want := range map[string]Difficulty{"easy": DifficultyEasy, "Normal": DifficultyNormal, " HARD ": DifficultyHard} {
+		got, err := ParseDifficulty(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	_, err := ParseDifficulty("Lv1")
+	assert.Error(t, err)
+}
+
+func TestDifficultyMultipliers_Validate(t *testing.T) {
+	require.NoError(t, DefaultDifficultyMultipliers().Validate())
+
+	assert.Error(t, DifficultyMultipliers{Easy: 0, Normal: 1, Hard: 1}.Validate())
+	assert.Error(t, DifficultyMultipliers{Easy: 1, Normal: -1, Hard: 1}.Validate())
+	assert.Error(t, DifficultyMultipliers{Easy: 1, Normal: 1, Hard: maxDifficultyMultiplier + 1}.Validate())
+}
+
+func TestDifficultyMultipliers_Scale(t *testing.T) {
+	m := DefaultDifficultyMultipliers()
+
+	assert.Equal(t, 10, m.Scale(DifficultyEasy, 10))
+	assert.Equal(t, 15, m.Scale(DifficultyNormal, 10))
+	assert.Equal(t, 20, m.Scale(DifficultyHard, 10))
+	assert.Equal(t, 2, m.Scale(DifficultyNormal, 1), "rounded to the nearest gnu")
+	assert.Equal(t, 200, m.Points(DifficultyHard))
+	assert.Equal(t, 100, m.Points("unknown"), "unknown difficulty counts as x1")
+	assert.Equal(t, 100, DifficultyMultipliers{}.Points(DifficultyHard), "unset multipliers count as x1")
+}
+
+func TestQuestion_Validate_NormalizesDifficulty(t *testing.T) {
+	q := Question{Difficulty: "Hard", Choices: []string{"a", "b", "c", "d"}, CorrectAnswer: "a"}
+	require.NoError(t, q.Validate())
+	assert.Equal(t, DifficultyHard, q.Difficulty)
+
+	q.Difficulty = ""
+	require.NoError(t, q.Validate())
+	assert.Equal(t, DifficultyNormal, q.Difficulty, "missing difficulty defaults to normal")
+
+	q.Difficulty = "extreme"
+	err := q.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown difficulty")
+}
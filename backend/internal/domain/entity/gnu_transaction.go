@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GnuTransactionReason はヌーが増減した理由を表す型
+type GnuTransactionReason string
+
+const (
+	GnuTransactionReasonOpening  GnuTransactionReason = "opening"   // 台帳導入時・ユーザー作成時の残高
+	GnuTransactionReasonBetWin   GnuTransactionReason = "bet_win"   // 正解によるベットの獲得
+	GnuTransactionReasonBetLoss  GnuTransactionReason = "bet_loss"  // 不正解・未回答によるベットの没収
+	GnuTransactionReasonTKOBonus GnuTransactionReason = "tko_bonus" // TKO 勝利ボーナス
+	GnuTransactionReasonAdmin    GnuTransactionReason = "admin"     // 運営による調整
+)
+
+// GnuTransaction はヌー台帳の1取引
+// 台帳は追記のみで、users.gnu_balance は台帳の Delta の合計と一致する
+type GnuTransaction struct {
+	CreatedAt time.Time `json:"created_at"`
+	// IdempotencyKey は同じ取引を二重に反映しないためのキー
+	IdempotencyKey string               `json:"idempotency_key"`
+	Reason         GnuTransactionReason `json:"reason"`
+	RoomID         uuid.NullUUID        `json:"room_id"`
+	ID             uuid.UUID            `json:"id"`
+	UserID         uuid.UUID            `json:"user_id"`
+	Delta          int                  `json:"delta"`
+	BalanceAfter   int                  `json:"balance_after"`
+	Turn           int                  `json:"turn,omitempty"` // 試合のターン以外の取引では 0
+}
+
+// TurnTransactionKey はターンのベット精算の IdempotencyKey を返す
+func TurnTransactionKey(roomID uuid.UUID, turn int, userID uuid.UUID) string {
+	return fmt.Sprintf("room:%s:turn:%d:user:%s", roomID, turn, userID)
+}
+
+// TKOBonusTransactionKey は TKO ボーナスの IdempotencyKey を返す
+func TKOBonusTransactionKey(roomID uuid.UUID, userID uuid.UUID) string {
+	return fmt.Sprintf("room:%s:tko_bonus:user:%s", roomID, userID)
+}
+
+// GnuBalanceMismatch は users.gnu_balance と台帳の合計が食い違うユーザー
+type GnuBalanceMismatch struct {
+	UserID        uuid.UUID `json:"user_id"`
+	CachedBalance int       `json:"cached_balance"`
+	LedgerBalance int       `json:"ledger_balance"`
+}
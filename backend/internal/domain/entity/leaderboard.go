@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LeaderboardKind はランキングの種類
+type LeaderboardKind string
+
+const (
+	LeaderboardKindRate       LeaderboardKind = "rate"        // レーティング
+	LeaderboardKindGnu        LeaderboardKind = "gnu"         // ヌー残高
+	LeaderboardKindWeeklyWins LeaderboardKind = "weekly_wins" // 今週の勝利数
+)
+
+// Valid は定義済みのランキングかを返す
+func (k LeaderboardKind) Valid() bool {
+	switch k {
+	case LeaderboardKindRate, LeaderboardKindGnu, LeaderboardKindWeeklyWins:
+		return true
+	}
+	return false
+}
+
+// Leaderboard は集計対象を含めたランキングの指定
+type Leaderboard struct {
+	Week time.Time // weekly_wins の対象の週の始まり（LeaderboardWeekStart の値）。他のランキングではゼロ値
+	Kind LeaderboardKind
+}
+
+// LeaderboardWeekStart は t を含む週の始まり（UTC の月曜 0 時）を返す
+func LeaderboardWeekStart(t time.Time) time.Time {
+	t = t.UTC()
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, time.UTC)
+}
+
+// LeaderboardEntry はランキングの1行
+type LeaderboardEntry struct {
+	GitHubLogin string    `json:"github_login"`
+	UserID      uuid.UUID `json:"user_id"`
+	Rank        int       `json:"rank"` // 1始まり。同点の並びはユーザー ID で決まる
+	Score       int       `json:"score"`
+}
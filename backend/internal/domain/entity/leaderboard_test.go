@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderboardWeekStart(t *testing.T) {
+	// 2026-10-17 は土曜日
+	sat := time.Date(2026, 10, 17, 23, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), LeaderboardWeekStart(sat))
+
+	mon := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, mon, LeaderboardWeekStart(mon))
+
+	sun := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, mon, LeaderboardWeekStart(sun))
+}
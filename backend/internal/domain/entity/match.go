@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MatchEndReason は試合の終了理由を表す型
+type MatchEndReason string
+
+const (
+	MatchEndReasonCompleted   MatchEndReason = "completed"   // 全ターン消化
+	MatchEndReasonTKO         MatchEndReason = "tko"         // 切断による TKO
+	MatchEndReasonInterrupted MatchEndReason = "interrupted" // 再起動後に全プレイヤーが戻らず、完了したターンまでで精算した
+	MatchEndReasonCanceled    MatchEndReason = "canceled"    // サーバーの停止で中止し、完了したターンまでで精算した
+)
+
+// IsDecided は勝敗（引き分けを含む）がついた終了理由かどうかを返す
+// 中止した試合はヌーだけ精算し、勝敗・成績には数えない
+func (r MatchEndReason) IsDecided() bool {
+	return r == MatchEndReasonCompleted || r == MatchEndReasonTKO
+}
+
+// MatchOutcome はプレイヤーから見た試合の勝敗
+type MatchOutcome string
+
+const (
+	MatchOutcomeWin  MatchOutcome = "win"
+	MatchOutcomeLose MatchOutcome = "lose"
+	MatchOutcomeDraw MatchOutcome = "draw"
+	// MatchOutcomeNoContest は中止した試合（勝敗なし）
+	MatchOutcomeNoContest MatchOutcome = "no_contest"
+)
+
+// MatchResult は1試合の最終結果
+// WinnerID が無効値の場合は引き分け（中止した試合では勝者なし）
+// Player1* / Player2* と WinnerID は各チームの先頭の参加者（ルームの Player1ID / Player2ID）を表す
+// チーム戦の全員分の結果は Players に入る
+type MatchResult struct {
+	StartedAt           time.Time           `json:"started_at"`
+	FinishedAt          time.Time           `json:"finished_at"`
+	EndReason           MatchEndReason      `json:"end_reason"`
+	Turns               []MatchTurn         `json:"turns,omitempty"`
+	Players             []MatchResultPlayer `json:"players,omitempty"`
+	WinnerID            uuid.NullUUID       `json:"winner_id"`
+	ID                  uuid.UUID           `json:"id"`
+	RoomID              uuid.UUID           `json:"room_id"`
+	Player1ID           uuid.UUID           `json:"player1_id"`
+	Player2ID           uuid.UUID           `json:"player2_id"`
+	Player1CorrectCount int                 `json:"player1_correct_count"`
+	Player2CorrectCount int                 `json:"player2_correct_count"`
+	Player1GnuEarned    int                 `json:"player1_gnu_earned"`
+	Player2GnuEarned    int                 `json:"player2_gnu_earned"`
+	Player1FinalGnu     int                 `json:"player1_final_gnu"`
+	Player2FinalGnu     int                 `json:"player2_final_gnu"`
+	TotalTurns          int                 `json:"total_turns"` // 消化したターン数（TKO・中止の場合は途中まで）
+}
+
+// MatchResultPlayer は試合に参加した1プレイヤー分の結果
+type MatchResultPlayer struct {
+	Outcome      MatchOutcome `json:"outcome"`
+	UserID       uuid.UUID    `json:"user_id"`
+	Team         int          `json:"team"`
+	Position     int          `json:"position"` // チーム内の並び順（0 が先頭）
+	CorrectCount int          `json:"correct_count"`
+	GnuEarned    int          `json:"gnu_earned"`
+	FinalGnu     int          `json:"final_gnu"`
+}
+
+// MatchTurn は1ターン・1プレイヤー分の回答記録
+type MatchTurn struct {
+	// AnswerTimeMs はターン開始から回答までのサーバー計測時間（未回答なら nil）
+	AnswerTimeMs *int `json:"answer_time_ms"`
+	// Answer は回答の内容（未回答なら nil）
+	Answer      *Answer   `json:"answer,omitempty"`
+	Question    Question  `json:"question"`
+	UserID      uuid.UUID `json:"user_id"`
+	Turn        int       `json:"turn"`
+	ChoiceIndex int       `json:"choice_index"` // -1 = 未回答、または選択肢を1つ選ぶ形式ではない
+	Bet         int       `json:"bet"`
+	GnuDelta    int       `json:"gnu_delta"`
+	IsCorrect   bool      `json:"is_correct"`
+}
@@ -0,0 +1,18 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MatchEvent はゲームルームがプレイヤーに送ったイベントの記録（リプレイに使う）
+// Payload は送信した WebSocket メッセージの payload そのもの
+type MatchEvent struct {
+	SentAt  time.Time       `json:"sent_at"` // サーバーが送信した時刻
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+	RoomID  uuid.UUID       `json:"-"`
+	UserID  uuid.UUID       `json:"user_id"`
+}
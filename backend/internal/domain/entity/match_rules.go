@@ -0,0 +1,73 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxTotalTurns は1試合あたりのターン数の上限
+const maxTotalTurns = 50
+
+// MatchRules は1試合の進行ルール
+type MatchRules struct {
+	TurnDuration      time.Duration // 1ターンの制限時間
+	QuestionWaitLimit time.Duration // 問題が揃うまでの待ち時間の上限
+	JoinWaitLimit     time.Duration // 最初のプレイヤーの接続から対戦相手の接続を待つ上限
+	RematchWaitLimit  time.Duration // 試合の終了後に再戦を受け付ける時間（0 = 再戦なし）
+	TotalTurns        int           // 1試合のターン数
+	TKOBonus          int           // 相手の切断で勝利したときのボーナスヌー
+	MinBet            int           // ベット額の最小値（0 = ノーリスク）
+	// DifficultyMultipliers は正解時のベットの払い戻しと勝敗判定の正解数に掛ける難易度ごとの倍率
+	DifficultyMultipliers DifficultyMultipliers
+}
+
+// DefaultMatchRules は標準ルール（10ターン・15秒）を返す
+func DefaultMatchRules() MatchRules {
+	return MatchRules{
+		TurnDuration:      15 * time.Second,
+		QuestionWaitLimit: 180 * time.Second, // 問題生成（Gemini×2回）に最大3分
+		JoinWaitLimit:     60 * time.Second,
+		RematchWaitLimit:  30 * time.Second,
+		TotalTurns:        10,
+		TKOBonus:          300,
+		MinBet:            0,
+
+		DifficultyMultipliers: DefaultDifficultyMultipliers(),
+	}
+}
+
+// QuestionsPerSide は各プレイヤーが my_questions / for_opponent にそれぞれ用意する問題数
+// ターンは for_opponent と my_questions を交互に使うため、ターン数の半分（切り上げ）になる
+func (r MatchRules) QuestionsPerSide() int {
+	return (r.TotalTurns + 1) / 2
+}
+
+// Validate は MatchRules の整合性を検証する
+func (r MatchRules) Validate() error {
+	if r.TurnDuration < time.Second {
+		return errors.New("turn duration must be at least 1s")
+	}
+	if r.QuestionWaitLimit <= 0 {
+		return errors.New("question wait limit must be positive")
+	}
+	if r.JoinWaitLimit <= 0 {
+		return errors.New("join wait limit must be positive")
+	}
+	if r.RematchWaitLimit < 0 {
+		return errors.New("rematch wait limit must not be negative")
+	}
+	if r.TotalTurns < 1 || r.TotalTurns > maxTotalTurns {
+		return fmt.Errorf("total turns must be between 1 and %d", maxTotalTurns)
+	}
+	if r.TKOBonus < 0 {
+		return errors.New("tko bonus must not be negative")
+	}
+	if r.MinBet < 0 {
+		return errors.New("min bet must not be negative")
+	}
+	if err := r.DifficultyMultipliers.Validate(); err != nil {
+		return fmt.Errorf("difficulty multipliers: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchRules_Validate(t *testing.T) {
+	require.NoError(t, DefaultMatchRules().Validate())
+
+	lightning := DefaultMatchRules()
+	lightning.TotalTurns = 3
+	lightning.TurnDuration = 5 * time.Second
+	require.NoError(t, lightning.Validate())
+
+	noRematch := DefaultMatchRules()
+	noRematch.RematchWaitLimit = 0
+	require.NoError(t, noRematch.Validate())
+
+	tests := map[string]func(r *MatchRules){
+		"zero turns":         func(r *MatchRules) { r.TotalTurns = 0 },
+		"too many turns":     func(r *MatchRules) { r.TotalTurns = maxTotalTurns + 1 },
+		"sub-second turn":    func(r *MatchRules) { r.TurnDuration = 500 * time.Millisecond },
+		"no question wait":   func(r *MatchRules) { r.QuestionWaitLimit = 0 },
+		"no join wait":       func(r *MatchRules) { r.JoinWaitLimit = 0 },
+		"negative rematch":   func(r *MatchRules) { r.RematchWaitLimit = -time.Second },
+		"negative tko bonus": func(r *MatchRules) { r.TKOBonus = -1 },
+		"negative min bet":   func(r *MatchRules) { r.MinBet = -1 },
+		"zero multiplier":    func(r *MatchRules) { r.DifficultyMultipliers.Hard = 0 },
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := DefaultMatchRules()
+			mutate(&r)
+			assert.Error(t, r.Validate())
+		})
+	}
+}
+
+func TestMatchRules_QuestionsPerSide(t *testing.T) {
+	for turns, want := range map[int]int{1: 1, 3: 2, 10: 5, 20: 10} {
+		r := MatchRules{TotalTurns: turns}
+		assert.Equal(t, want, r.QuestionsPerSide(), "turns=%d", turns)
+	}
+}
@@ -0,0 +1,68 @@
+package entity
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// QueueEntry はマッチングキューで待機中のユーザー
+type QueueEntry struct {
+	EnqueuedAt time.Time `json:"enqueued_at"`
+	UserID     uuid.UUID `json:"user_id"`
+	Rate       int       `json:"rate"`
+	// TeamSize は希望する対戦形式の1チームの人数（0 と 1 は1対1）。形式ごとに別のキューで待つ
+	TeamSize int `json:"team_size"`
+}
+
+// RateWindow はマッチング可能なレート差の許容幅
+// 待ち時間に応じて Base から毎秒 WidenPerSec ずつ広がり、Max で頭打ちになる
+type RateWindow struct {
+	WidenPerSec float64 `json:"widen_per_sec"`
+	Base        int     `json:"base"`
+	Max         int     `json:"max"`
+}
+
+// At は待ち時間 waited における許容レート差を返す
+func (w RateWindow) At(waited time.Duration) int {
+	if waited < 0 {
+		waited = 0
+	}
+	width := float64(w.Base) + w.WidenPerSec*waited.Seconds()
+	if width > float64(w.Max) {
+		return w.Max
+	}
+	return int(width)
+}
+
+// Accepts は2人の待機ユーザーが互いの許容幅に収まっているかを返す
+func (w RateWindow) Accepts(a, b QueueEntry, now time.Time) bool {
+	diff := a.Rate - b.Rate
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= w.At(now.Sub(a.EnqueuedAt)) && diff <= w.At(now.Sub(b.EnqueuedAt))
+}
+
+// BalanceTeams は待機ユーザーをレートの合計が近い2チームに分ける
+// レートの高い順に A, B, B, A, A, B, ... と振り分ける（同じレートは先に参加した方を先にする）
+func BalanceTeams(entries []*QueueEntry) [2][]*QueueEntry {
+	sorted := make([]*QueueEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Rate != sorted[j].Rate {
+			return sorted[i].Rate > sorted[j].Rate
+		}
+		return sorted[i].EnqueuedAt.Before(sorted[j].EnqueuedAt)
+	})
+	var teams [2][]*QueueEntry
+	for i, e := range sorted {
+		team := 1
+		if i%4 == 0 || i%4 == 3 {
+			team = 0
+		}
+		teams[team] = append(teams[team], e)
+	}
+	return teams
+}
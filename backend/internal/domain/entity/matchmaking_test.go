@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalanceTeams(t *testing.T) {
+	base := time.Now()
+	entry := func(rate int, waited time.Duration) *QueueEntry {
+		return &QueueEntry{UserID: uuid.New(), Rate: rate, EnqueuedAt: base.Add(-waited)}
+	}
+	e1600 := entry(1600, 0)
+	e1500old := entry(1500, time.Minute)
+	e1500 := entry(1500, 0)
+	e1400 := entry(1400, 0)
+
+	teams := BalanceTeams([]*QueueEntry{e1400, e1500, e1600, e1500old})
+
+	// 最上位と最下位、2位と3位が組む
+	assert.Equal(t, []*QueueEntry{e1600, e1400}, teams[0])
+	assert.Equal(t, []*QueueEntry{e1500old, e1500}, teams[1])
+}
@@ -2,33 +2,279 @@ package entity
 
 import (
 	"errors"
+	"fmt"
 	"slices"
+	"strings"
+	"unicode/utf8"
 )
 
-// NumChoices は4択問題の選択肢数
-const NumChoices = 4
+const (
+	// NumChoices は4択問題・複数選択問題の選択肢数
+	NumChoices = 4
+	// NumTrueFalseChoices は正誤問題の選択肢数
+	NumTrueFalseChoices = 2
+	// MinOrderingLines / MaxOrderingLines は並べ替え問題の行数の範囲
+	MinOrderingLines = 3
+	MaxOrderingLines = 8
+	// MaxFreeTextLength は記述問題の回答の最大文字数
+	MaxFreeTextLength = 100
+)
+
+// QuestionType は問題の形式を表す型
+type QuestionType string
 
-// Question は LLM が生成する4択問題
+const (
+	QuestionTypeSingleChoice QuestionType = "single_choice" // 4択（正解は1つ）
+	QuestionTypeMultiSelect  QuestionType = "multi_select"  // 4択から正解をすべて選ぶ
+	QuestionTypeTrueFalse    QuestionType = "true_false"    // 2択の正誤
+	QuestionTypeOrdering     QuestionType = "ordering"      // コードの行を正しい順に並べる
+	QuestionTypeFreeText     QuestionType = "free_text"     // 短い記述（正規化して照合する）
+)
+
+// Question は LLM が生成する問題
+// Type が空の問題は4択として扱う（形式の追加前に保存した問題を含む）
 type Question struct {
-	Difficulty    string   `json:"difficulty"`
-	QuestionText  string   `json:"question_text"`
-	CorrectAnswer string   `json:"correct_answer"`
-	Tips          string   `json:"tips"`
-	Choices       []string `json:"choices"`
+	Type          QuestionType `json:"type,omitempty"`
+	Difficulty    Difficulty   `json:"difficulty"`
+	QuestionText  string       `json:"question_text"`
+	CorrectAnswer string       `json:"correct_answer"` // single_choice / true_false / free_text の正解
+	Tips          string       `json:"tips"`
+	// Choices は選択肢。ordering では正しい順とは異なる並びの行、free_text では空
+	Choices []string `json:"choices"`
+	// CorrectAnswers は multi_select では正解の選択肢すべて、ordering では正しい順に並べた行
+	CorrectAnswers []string `json:"correct_answers,omitempty"`
+	// AcceptedAnswers は free_text で CorrectAnswer のほかに正解とする表記
+	AcceptedAnswers []string `json:"accepted_answers,omitempty"`
 }
 
-// Validate は Question の整合性を検証する
-func (q Question) Validate() error {
-	if len(q.Choices) != NumChoices {
-		return errors.New("question must have exactly 4 choices")
+// Answer はプレイヤーの回答。問題の形式に応じたフィールドのみを使う
+type Answer struct {
+	Text          string `json:"text,omitempty"`           // free_text
+	ChoiceIndexes []int  `json:"choice_indexes,omitempty"` // multi_select（順不同）
+	Order         []int  `json:"order,omitempty"`          // ordering（Choices のインデックスを並べた順）
+	ChoiceIndex   *int   `json:"choice_index,omitempty"`   // single_choice / true_false（未指定と 0 を区別する）
+}
+
+// ChoiceAnswer は選択肢を1つ選ぶ回答を返す
+func ChoiceAnswer(idx int) Answer {
+	return Answer{ChoiceIndex: &idx}
+}
+
+// Kind は問題の形式を返す。Type が空の場合は QuestionTypeSingleChoice
+func (q Question) Kind() QuestionType {
+	if q.Type == "" {
+		return QuestionTypeSingleChoice
 	}
-	if slices.Index(q.Choices, q.CorrectAnswer) == -1 {
-		return errors.New("correct_answer must be one of the choices")
+	return q.Type
+}
+
+// Validate は Question の整合性を形式ごとに検証する
+// 難易度は大文字・小文字を区別せずに解釈して小文字の表記に揃える。未指定は normal とする
+func (q *Question) Validate() error {
+	if q.Difficulty == "" {
+		q.Difficulty = DifficultyNormal
+	}
+	d, err := ParseDifficulty(string(q.Difficulty))
+	if err != nil {
+		return err
+	}
+	q.Difficulty = d
+
+	switch q.Kind() {
+	case QuestionTypeSingleChoice:
+		if len(q.Choices) != NumChoices {
+			return errors.New("question must have exactly 4 choices")
+		}
+		if slices.Index(q.Choices, q.CorrectAnswer) == -1 {
+			return errors.New("correct_answer must be one of the choices")
+		}
+	case QuestionTypeTrueFalse:
+		if len(q.Choices) != NumTrueFalseChoices {
+			return errors.New("true/false question must have exactly 2 choices")
+		}
+		if slices.Index(q.Choices, q.CorrectAnswer) == -1 {
+			return errors.New("correct_answer must be one of the choices")
+		}
+	case QuestionTypeMultiSelect:
+		if len(q.Choices) != NumChoices {
+			return errors.New("question must have exactly 4 choices")
+		}
+		if hasDuplicates(q.Choices) {
+			return errors.New("choices must be unique")
+		}
+		if len(q.CorrectAnswers) == 0 || hasDuplicates(q.CorrectAnswers) {
+			return errors.New("correct_answers must list at least one distinct choice")
+		}
+		for _, a := range q.CorrectAnswers {
+			if !slices.Contains(q.Choices, a) {
+				return errors.New("correct_answers must be among the choices")
+			}
+		}
+	case QuestionTypeOrdering:
+		if len(q.Choices) < MinOrderingLines || len(q.Choices) > MaxOrderingLines {
+			return fmt.Errorf("ordering question must have %d to %d lines", MinOrderingLines, MaxOrderingLines)
+		}
+		if hasDuplicates(q.Choices) {
+			return errors.New("lines must be unique")
+		}
+		if len(q.CorrectAnswers) != len(q.Choices) {
+			return errors.New("correct_answers must order all of the lines")
+		}
+		for _, line := range q.CorrectAnswers {
+			if !slices.Contains(q.Choices, line) {
+				return errors.New("correct_answers must order all of the lines")
+			}
+		}
+		if slices.Equal(q.Choices, q.CorrectAnswers) {
+			return errors.New("lines must not be presented in the correct order")
+		}
+	case QuestionTypeFreeText:
+		if len(q.Choices) != 0 {
+			return errors.New("free text question must not have choices")
+		}
+		if NormalizeFreeText(q.CorrectAnswer) == "" {
+			return errors.New("correct_answer must not be empty")
+		}
+		if utf8.RuneCountInString(q.CorrectAnswer) > MaxFreeTextLength {
+			return fmt.Errorf("correct_answer must be at most %d characters", MaxFreeTextLength)
+		}
+	default:
+		return fmt.Errorf("unknown question type %q", q.Type)
 	}
 	return nil
 }
 
-// CorrectIndex は正解選択肢のインデックスを返す（見つからない場合は -1）
+// CorrectIndex は正解選択肢のインデックスを返す
+// single_choice / true_false 以外の形式と、見つからない場合は -1
 func (q Question) CorrectIndex() int {
-	return slices.Index(q.Choices, q.CorrectAnswer)
+	switch q.Kind() {
+	case QuestionTypeSingleChoice, QuestionTypeTrueFalse:
+		return slices.Index(q.Choices, q.CorrectAnswer)
+	default:
+		return -1
+	}
+}
+
+// CorrectIndexes は正解を選択肢のインデックスで返す
+// multi_select は正解の選択肢を昇順に、ordering は正しい順に並べた行のインデックスを返す
+// single_choice / true_false は CorrectIndex の1要素、free_text は nil
+func (q Question) CorrectIndexes() []int {
+	switch q.Kind() {
+	case QuestionTypeMultiSelect:
+		idxs := make([]int, 0, len(q.CorrectAnswers))
+		for i, c := range q.Choices {
+			if slices.Contains(q.CorrectAnswers, c) {
+				idxs = append(idxs, i)
+			}
+		}
+		return idxs
+	case QuestionTypeOrdering:
+		idxs := make([]int, len(q.CorrectAnswers))
+		for i, line := range q.CorrectAnswers {
+			idxs[i] = slices.Index(q.Choices, line)
+		}
+		return idxs
+	case QuestionTypeFreeText:
+		return nil
+	default:
+		return []int{q.CorrectIndex()}
+	}
+}
+
+// CheckAnswer は回答が問題の形式に合っているかを検証する（正誤は問わない）
+// 形式ごとに必要なフィールドがない回答も形式に合わないものとして扱う
+func (q Question) CheckAnswer(a Answer) error {
+	switch q.Kind() {
+	case QuestionTypeMultiSelect:
+		if len(a.ChoiceIndexes) == 0 {
+			return errors.New("choice_indexes must not be empty")
+		}
+		if !validIndexes(a.ChoiceIndexes, len(q.Choices)) {
+			return errors.New("choice_indexes must be distinct choices")
+		}
+	case QuestionTypeOrdering:
+		if len(a.Order) != len(q.Choices) || !validIndexes(a.Order, len(q.Choices)) {
+			return errors.New("order must list every line exactly once")
+		}
+	case QuestionTypeFreeText:
+		if strings.TrimSpace(a.Text) == "" {
+			return errors.New("text is required")
+		}
+		if utf8.RuneCountInString(a.Text) > MaxFreeTextLength {
+			return fmt.Errorf("text must be at most %d characters", MaxFreeTextLength)
+		}
+	default:
+		if a.ChoiceIndex == nil {
+			return errors.New("choice_index is required")
+		}
+		if *a.ChoiceIndex < 0 || *a.ChoiceIndex >= len(q.Choices) {
+			return errors.New("choice_index is out of range")
+		}
+	}
+	return nil
+}
+
+// IsCorrect は回答が正解かを返す。形式に合わない回答は不正解
+// multi_select は正解の選択肢をすべて過不足なく選んだ場合、ordering は全行を正しい順に並べた場合のみ正解
+func (q Question) IsCorrect(a Answer) bool {
+	if q.CheckAnswer(a) != nil {
+		return false
+	}
+	switch q.Kind() {
+	case QuestionTypeMultiSelect:
+		selected := slices.Sorted(slices.Values(a.ChoiceIndexes))
+		return slices.Equal(selected, q.CorrectIndexes())
+	case QuestionTypeOrdering:
+		return slices.Equal(a.Order, q.CorrectIndexes())
+	case QuestionTypeFreeText:
+		text := NormalizeFreeText(a.Text)
+		if text == "" {
+			return false
+		}
+		if text == NormalizeFreeText(q.CorrectAnswer) {
+			return true
+		}
+		return slices.ContainsFunc(q.AcceptedAnswers, func(s string) bool {
+			return text == NormalizeFreeText(s)
+		})
+	default:
+		return *a.ChoiceIndex == q.CorrectIndex()
+	}
+}
+
+// NormalizeFreeText は記述問題の照合用に回答を正規化する
+// 全角英数記号を半角に、英字を小文字にし、前後の空白と引用符・バッククォートを除いて連続する空白を1つにまとめる
+func NormalizeFreeText(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= '！' && r <= '～':
+			return r - '！' + '!'
+		case r == '　':
+			return ' '
+		}
+		return r
+	}, s)
+	s = strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	return strings.TrimSpace(strings.Trim(s, "`\"'"))
+}
+
+func hasDuplicates(values []string) bool {
+	for i, v := range values {
+		if slices.Contains(values[:i], v) {
+			return true
+		}
+	}
+	return false
+}
+
+// validIndexes は idxs が重複のない [0, n) のインデックスかを返す
+func validIndexes(idxs []int, n int) bool {
+	seen := make([]bool, n)
+	for _, i := range idxs {
+		if i < 0 || i >= n || seen[i] {
+			return false
+		}
+		seen[i] = true
+	}
+	return true
 }
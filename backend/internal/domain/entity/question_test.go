@@ -0,0 +1,160 @@
+package entity
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuestion_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr string
+		q       Question
+	}{
+		{
+			name: "single choice without type",
+			q:    Question{Choices: []string{"a", "b", "c", "d"}, CorrectAnswer: "b"},
+		},
+		{
+			name:    "single choice with 3 choices",
+			q:       Question{Choices: []string{"a", "b", "c"}, CorrectAnswer: "b"},
+			wantErr: "exactly 4 choices",
+		},
+		{
+			name: "true/false",
+			q:    Question{Type: QuestionTypeTrueFalse, Choices: []string{"正しい", "誤り"}, CorrectAnswer: "誤り"},
+		},
+		{
+			name:    "true/false with 4 choices",
+			q:       Question{Type: QuestionTypeTrueFalse, Choices: []string{"a", "b", "c", "d"}, CorrectAnswer: "a"},
+			wantErr: "exactly 2 choices",
+		},
+		{
+			name: "multi select",
+			q:    Question{Type: QuestionTypeMultiSelect, Choices: []string{"a", "b", "c", "d"}, CorrectAnswers: []string{"a", "c"}},
+		},
+		{
+			name:    "multi select without answers",
+			q:       Question{Type: QuestionTypeMultiSelect, Choices: []string{"a", "b", "c", "d"}},
+			wantErr: "at least one distinct choice",
+		},
+		{
+			name:    "multi select with unknown answer",
+			q:       Question{Type: QuestionTypeMultiSelect, Choices: []string{"a", "b", "c", "d"}, CorrectAnswers: []string{"e"}},
+			wantErr: "among the choices",
+		},
+		{
+			name: "ordering",
+			q:    Question{Type: QuestionTypeOrdering, Choices: []string{"c", "a", "b"}, CorrectAnswers: []string{"a", "b", "c"}},
+		},
+		{
+			name:    "ordering already in order",
+			q:       Question{Type: QuestionTypeOrdering, Choices: []string{"a", "b", "c"}, CorrectAnswers: []string{"a", "b", "c"}},
+			wantErr: "must not be presented in the correct order",
+		},
+		{
+			name:    "ordering with duplicate lines",
+			q:       Question{Type: QuestionTypeOrdering, Choices: []string{"}", "a", "}"}, CorrectAnswers: []string{"a", "}", "}"}},
+			wantErr: "lines must be unique",
+		},
+		{
+			name:    "ordering missing a line",
+			q:       Question{Type: QuestionTypeOrdering, Choices: []string{"c", "a", "b"}, CorrectAnswers: []string{"a", "b", "d"}},
+			wantErr: "order all of the lines",
+		},
+		{
+			name: "free text",
+			q:    Question{Type: QuestionTypeFreeText, CorrectAnswer: "GetMe"},
+		},
+		{
+			name:    "free text with blank answer",
+			q:       Question{Type: QuestionTypeFreeText, CorrectAnswer: " `` "},
+			wantErr: "must not be empty",
+		},
+		{
+			name:    "unknown type",
+			q:       Question{Type: "essay"},
+			wantErr: "unknown question type",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.q.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestQuestion_IsCorrect_SingleChoice(t *testing.T) {
+	q := Question{Choices: []string{"a", "b", "c", "d"}, CorrectAnswer: "c"}
+
+	assert.True(t, q.IsCorrect(ChoiceAnswer(2)))
+	assert.False(t, q.IsCorrect(ChoiceAnswer(1)))
+	assert.False(t, q.IsCorrect(ChoiceAnswer(4)), "out of range answer is wrong")
+	assert.Equal(t, []int{2}, q.CorrectIndexes())
+}
+
+func TestQuestion_CheckAnswer_RequiresChoiceIndex(t *testing.T) {
+	q := Question{Choices: []string{"a", "b", "c", "d"}, CorrectAnswer: "a"}
+
+	var missing, first Answer
+	require.NoError(t, json.Unmarshal([]byte(`{"choice_indexes":[0]}`), &missing))
+	require.NoError(t, json.Unmarshal([]byte(`{"choice_index":0}`), &first))
+
+	assert.Error(t, q.CheckAnswer(missing), "an answer without choice_index is not the first choice")
+	assert.False(t, q.IsCorrect(missing))
+	require.NoError(t, q.CheckAnswer(first))
+	assert.True(t, q.IsCorrect(first))
+}
+
+func TestQuestion_IsCorrect_MultiSelect(t *testing.T) {
+	q := Question{Type: QuestionTypeMultiSelect, Choices: []string{"a", "b", "c", "d"}, CorrectAnswers: []string{"d", "b"}}
+
+	assert.Equal(t, []int{1, 3}, q.CorrectIndexes())
+	assert.Equal(t, -1, q.CorrectIndex())
+	assert.True(t, q.IsCorrect(Answer{ChoiceIndexes: []int{3, 1}}), "selection order does not matter")
+	assert.False(t, q.IsCorrect(Answer{ChoiceIndexes: []int{1}}), "missing a correct choice")
+	assert.False(t, q.IsCorrect(Answer{ChoiceIndexes: []int{0, 1, 3}}), "selecting a wrong choice")
+	assert.Error(t, q.CheckAnswer(Answer{ChoiceIndexes: []int{1, 1}}))
+	assert.Error(t, q.CheckAnswer(ChoiceAnswer(1)), "choice_index is not a multi select answer")
+}
+
+func TestQuestion_IsCorrect_Ordering(t *testing.T) {
+	q := Question{
+		Type:           QuestionTypeOrdering,
+		Choices:        []string{"return x", "x := 1", "func f() int {", "}"},
+		CorrectAnswers: []string{"func f() int {", "x := 1", "return x", "}"},
+	}
+
+	assert.Equal(t, []int{2, 1, 0, 3}, q.CorrectIndexes())
+	assert.True(t, q.IsCorrect(Answer{Order: []int{2, 1, 0, 3}}))
+	assert.False(t, q.IsCorrect(Answer{Order: []int{2, 0, 1, 3}}))
+	assert.Error(t, q.CheckAnswer(Answer{Order: []int{2, 1, 0}}), "every line must be placed")
+	assert.Error(t, q.CheckAnswer(Answer{Order: []int{2, 1, 0, 0}}))
+}
+
+func TestQuestion_IsCorrect_FreeText(t *testing.T) {
+	q := Question{Type: QuestionTypeFreeText, CorrectAnswer: "GetOrCreate", AcceptedAnswers: []string{"get or create"}}
+
+	assert.True(t, q.IsCorrect(Answer{Text: "  getorcreate "}))
+	assert.True(t, q.IsCorrect(Answer{Text: "`GetOrCreate`"}))
+	assert.True(t, q.IsCorrect(Answer{Text: "ＧｅｔＯｒＣｒｅａｔｅ"}), "full-width letters are normalized")
+	assert.True(t, q.IsCorrect(Answer{Text: "Get  Or\tCreate"}), "accepted answers are matched after collapsing spaces")
+	assert.False(t, q.IsCorrect(Answer{Text: "Create"}))
+	assert.False(t, q.IsCorrect(Answer{}), "empty answer is wrong")
+	assert.Error(t, q.CheckAnswer(Answer{Text: " \t"}), "blank text is not an answer")
+	assert.Nil(t, q.CorrectIndexes())
+}
+
+func TestNormalizeFreeText(t *testing.T) {
+	assert.Equal(t, "map[string]int", NormalizeFreeText(" 'map[string]int' "))
+	assert.Equal(t, "go run ./cmd/server", NormalizeFreeText("Go　Run ./cmd/server"))
+}
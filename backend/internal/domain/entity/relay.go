@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// RelayKind はインスタンス間で中継するメッセージの種類
+type RelayKind string
+
+const (
+	RelayKindJoin          RelayKind = "join"           // 中継 → 担当: プレイヤーが接続した
+	RelayKindPlayerMessage RelayKind = "player_message" // 中継 → 担当: プレイヤーからのメッセージ
+	RelayKindLeave         RelayKind = "leave"          // 中継 → 担当: プレイヤー・観戦者が切断した
+	RelayKindSpectate      RelayKind = "spectate"       // 中継 → 担当: 観戦者が接続した
+	RelayKindSend          RelayKind = "send"           // 担当 → 中継: プレイヤー・観戦者へのメッセージ
+	RelayKindClose         RelayKind = "close"          // 担当 → 中継: プレイヤー・観戦者の接続を閉じる
+	RelayKindDeliver       RelayKind = "deliver"        // マッチング接続を持つインスタンスへのメッセージ
+)
+
+// RelayMessage はインスタンス間で Redis pub/sub を通して送るメッセージ
+// ゲームルームを担当するインスタンスと、プレイヤーの WebSocket を受けたインスタンスが異なる場合に使う
+type RelayMessage struct {
+	User *User     `json:"user,omitempty"` // join のみ
+	Kind RelayKind `json:"kind"`
+	From string    `json:"from"` // 送信元インスタンス ID
+	// ConnID は中継側の接続 ID。再接続で同じユーザーの接続が入れ替わっても取り違えないために使う
+	ConnID string          `json:"conn_id,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"` // WebSocket のメッセージ本文
+	RoomID uuid.UUID       `json:"room_id"`
+	UserID uuid.UUID       `json:"user_id"`
+}
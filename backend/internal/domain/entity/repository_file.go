@@ -0,0 +1,7 @@
+package entity
+
+// RepositoryFile は解析済みリポジトリのソースファイル
+type RepositoryFile struct {
+	FilePath string `json:"file_path"`
+	Content  string `json:"content"`
+}
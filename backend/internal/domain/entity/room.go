@@ -13,13 +13,87 @@ const (
 	RoomStatusWaiting    RoomStatus = "waiting"
 	RoomStatusInProgress RoomStatus = "in_progress"
 	RoomStatusFinished   RoomStatus = "finished"
+	RoomStatusAborted    RoomStatus = "aborted" // 勝敗がつかずに中止された
 )
 
+// IsEnded は試合が終了（finished または aborted）しているかを返す
+func (s RoomStatus) IsEnded() bool {
+	return s == RoomStatusFinished || s == RoomStatusAborted
+}
+
+// RoomEndReason はルームが終了した理由を表す型
+type RoomEndReason string
+
+const (
+	RoomEndReasonNone                     RoomEndReason = ""
+	RoomEndReasonCompleted                RoomEndReason = "completed"                  // 全ターン消化
+	RoomEndReasonTKO                      RoomEndReason = "tko"                        // 対戦中の切断による TKO
+	RoomEndReasonNoShow                   RoomEndReason = "no_show"                    // 対戦相手が参加しなかった
+	RoomEndReasonDisconnected             RoomEndReason = "disconnected"               // 問題生成中に切断して戻らなかった
+	RoomEndReasonQuestionTimeout          RoomEndReason = "question_timeout"           // 問題生成がタイムアウトした
+	RoomEndReasonQuestionGenerationFailed RoomEndReason = "question_generation_failed" // 問題生成に失敗した
+	RoomEndReasonCanceled                 RoomEndReason = "canceled"                   // サーバー側で中断した
+	RoomEndReasonInterrupted              RoomEndReason = "interrupted"                // 再起動後に両プレイヤーが戻らなかった
+)
+
+// MaxTeamSize はチーム戦の1チームの人数の上限
+const MaxTeamSize = 2
+
+// RoomPlayer はルームの参加者と所属チーム（0 または 1）
+type RoomPlayer struct {
+	UserID uuid.UUID `json:"user_id"`
+	Team   int       `json:"team"`
+}
+
+// Room はルーム。ルームには常に2チームがあり、1対1ではチームごとに1人ずつになる
+// Player1ID・Player2ID はチーム 0・1 の先頭の参加者
 type Room struct {
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	Status    RoomStatus `json:"status"`
-	ID        uuid.UUID  `json:"id"`
-	Player1ID uuid.UUID  `json:"player1_id"`
-	Player2ID uuid.UUID  `json:"player2_id"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	Status    RoomStatus    `json:"status"`
+	EndReason RoomEndReason `json:"end_reason,omitempty"`
+	// Players はチーム戦の全参加者（1対1のルームでは空）。参加者の一覧には Members を使う
+	Players   []RoomPlayer `json:"players,omitempty"`
+	ID        uuid.UUID    `json:"id"`
+	Player1ID uuid.UUID    `json:"player1_id"`
+	Player2ID uuid.UUID    `json:"player2_id"`
+}
+
+// NewRoom は teams[0] と teams[1] が対戦する待機中のルームを作る
+// 1チーム2人以上の場合は Players に全参加者を記録する
+func NewRoom(id uuid.UUID, teams [2][]uuid.UUID, now time.Time) *Room {
+	room := &Room{
+		ID:        id,
+		Player1ID: teams[0][0],
+		Player2ID: teams[1][0],
+		Status:    RoomStatusWaiting,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if len(teams[0]) > 1 || len(teams[1]) > 1 {
+		for team, ids := range teams {
+			for _, id := range ids {
+				room.Players = append(room.Players, RoomPlayer{UserID: id, Team: team})
+			}
+		}
+	}
+	return room
+}
+
+// Members はルームの参加者を返す。1対1のルームでは Player1ID がチーム 0、Player2ID がチーム 1
+func (r *Room) Members() []RoomPlayer {
+	if len(r.Players) > 0 {
+		return r.Players
+	}
+	return []RoomPlayer{{UserID: r.Player1ID, Team: 0}, {UserID: r.Player2ID, Team: 1}}
+}
+
+// HasMember は userID がルームの参加者かどうかを返す
+func (r *Room) HasMember(userID uuid.UUID) bool {
+	for _, m := range r.Members() {
+		if m.UserID == userID {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RoomInvite はプライベートルームへの招待
+// 招待を使った時点で作成者と参加者のルームを作る（rooms は両プレイヤーが決まってから作成する）
+type RoomInvite struct {
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Code      string    `json:"code"`
+	CreatorID uuid.UUID `json:"creator_id"`
+	GuestID   uuid.UUID `json:"guest_id"` // 招待を使ったユーザー（未使用の場合は uuid.Nil）
+	RoomID    uuid.UUID `json:"room_id"`  // 招待を使ったときに作ったルーム（未使用の場合は uuid.Nil）
+}
+
+// Redeemed は招待が使用済みかを返す
+func (i *RoomInvite) Redeemed() bool {
+	return i.RoomID != uuid.Nil
+}
@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RoomSnapshot は完了したターンまでの試合の状態
+// ターンが終わるたびに Redis に保存し、サーバーの再起動後に試合を再開するか精算するために使う
+// 進行中のターンのベットは含めない（再開時はそのターンからやり直し、精算時は賭け金を戻す）
+type RoomSnapshot struct {
+	SavedAt   time.Time   `json:"saved_at"`
+	StartedAt time.Time   `json:"started_at"`
+	Turns     []MatchTurn `json:"turns"` // 完了したターンの記録（ヌーの精算に使う）
+	// Players はプレイヤーインデックス順（0 = room.player1_id とは限らない）。チームはルームの参加者から決まる
+	Players []RoomSnapshotPlayer `json:"players"`
+	Rules   MatchRules           `json:"rules"`
+	// CompletedTurns は完了したターン数。0 は問題の生成が終わった直後
+	CompletedTurns int       `json:"completed_turns"`
+	RoomID         uuid.UUID `json:"room_id"`
+}
+
+// RoomSnapshotPlayer はスナップショット時点のプレイヤーごとの状態
+type RoomSnapshotPlayer struct {
+	GitHubLogin  string     `json:"github_login"`
+	MyQuestions  []Question `json:"my_questions"`
+	ForOpponent  []Question `json:"for_opponent"`
+	GnuBalance   int        `json:"gnu_balance"` // 完了したターンまでを反映した試合中の残高
+	CorrectCount int        `json:"correct_count"`
+	GnuEarned    int        `json:"gnu_earned"`
+	Rate         int        `json:"rate"`
+	UserID       uuid.UUID  `json:"user_id"`
+}
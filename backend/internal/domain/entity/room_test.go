@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRoom_Members(t *testing.T) {
+	a, b, c, d := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	now := time.Now()
+
+	duel := NewRoom(uuid.New(), [2][]uuid.UUID{{a}, {b}}, now)
+	assert.Empty(t, duel.Players, "1v1 rooms are described by player1_id and player2_id")
+	assert.Equal(t, []RoomPlayer{{UserID: a, Team: 0}, {UserID: b, Team: 1}}, duel.Members())
+
+	team := NewRoom(uuid.New(), [2][]uuid.UUID{{a, d}, {b, c}}, now)
+	assert.Equal(t, a, team.Player1ID)
+	assert.Equal(t, b, team.Player2ID)
+	assert.Equal(t, []RoomPlayer{{UserID: a, Team: 0}, {UserID: d, Team: 0}, {UserID: b, Team: 1}, {UserID: c, Team: 1}}, team.Members())
+	assert.True(t, team.HasMember(c))
+	assert.False(t, team.HasMember(uuid.New()))
+	assert.Equal(t, RoomStatusWaiting, team.Status)
+}
@@ -0,0 +1,277 @@
+package entity
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxTournamentParticipants は1トーナメントに登録できる人数の上限
+const MaxTournamentParticipants = 64
+
+// TournamentStatus はトーナメントの状態を表す型
+type TournamentStatus string
+
+const (
+	TournamentStatusRegistration TournamentStatus = "registration" // 参加登録の受け付け中
+	TournamentStatusInProgress   TournamentStatus = "in_progress"
+	TournamentStatusFinished     TournamentStatus = "finished"
+)
+
+// TournamentMatchStatus はトーナメントの1試合の状態を表す型
+type TournamentMatchStatus string
+
+const (
+	TournamentMatchStatusPending  TournamentMatchStatus = "pending"  // 対戦者が決まるのを待っている
+	TournamentMatchStatusPlaying  TournamentMatchStatus = "playing"  // ルームを作成済み
+	TournamentMatchStatusFinished TournamentMatchStatus = "finished" // 勝ち上がりが確定した
+)
+
+// TournamentMatchResult は勝ち上がりが確定した理由を表す型
+type TournamentMatchResult string
+
+const (
+	TournamentMatchResultNone    TournamentMatchResult = ""
+	TournamentMatchResultPlayed  TournamentMatchResult = "played"  // 試合で勝敗がついた（引き分けは上位シードが勝ち上がる）
+	TournamentMatchResultForfeit TournamentMatchResult = "forfeit" // 相手が参加しなかった・切断した
+	TournamentMatchResultBye     TournamentMatchResult = "bye"     // 対戦相手がいなかった
+	TournamentMatchResultVoid    TournamentMatchResult = "void"    // 両者とも勝ち上がれなかった
+	TournamentMatchResultSeed    TournamentMatchResult = "seed"    // 試合が成立しなかったため上位シードが勝ち上がった
+)
+
+// Tournament はシングルエリミネーションのトーナメント
+// WinnerID が無効値のまま終了した場合は優勝者なし（決勝の両者が不参加）
+type Tournament struct {
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+	StartedAt  *time.Time       `json:"started_at,omitempty"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+	Name       string           `json:"name"`
+	Status     TournamentStatus `json:"status"`
+	WinnerID   uuid.NullUUID    `json:"winner_id"`
+	ID         uuid.UUID        `json:"id"`
+	CreatedBy  uuid.UUID        `json:"created_by"`
+}
+
+// TournamentParticipant はトーナメントの参加者
+type TournamentParticipant struct {
+	RegisteredAt time.Time `json:"registered_at"`
+	GitHubLogin  string    `json:"github_login"`
+	UserID       uuid.UUID `json:"user_id"`
+	Rate         int       `json:"rate"`
+	Seed         int       `json:"seed,omitempty"` // 開始時に 1 から振る（登録中は 0）
+}
+
+// TournamentMatch はトーナメント表の1試合
+// Round は 1 から数え、Position はラウンド内の 0 始まりの位置
+// 次のラウンドの Position/2 の試合へ、偶数位置の勝者は Player1、奇数位置の勝者は Player2 として進む
+type TournamentMatch struct {
+	UpdatedAt    time.Time             `json:"updated_at"`
+	Status       TournamentMatchStatus `json:"status"`
+	Result       TournamentMatchResult `json:"result,omitempty"`
+	Player1ID    uuid.NullUUID         `json:"player1_id"`
+	Player2ID    uuid.NullUUID         `json:"player2_id"`
+	RoomID       uuid.NullUUID         `json:"room_id"`
+	WinnerID     uuid.NullUUID         `json:"winner_id"`
+	Round        int                   `json:"round"`
+	Position     int                   `json:"position"`
+	Attempts     int                   `json:"attempts"` // 作成したルームの数
+	ID           uuid.UUID             `json:"id"`
+	TournamentID uuid.UUID             `json:"tournament_id"`
+}
+
+// Ready は両プレイヤーが決まり、ルームを作成できる状態かを返す
+func (m *TournamentMatch) Ready() bool {
+	return m.Status == TournamentMatchStatusPending && m.Player1ID.Valid && m.Player2ID.Valid
+}
+
+// HasPlayer は userID がこの試合の対戦者かを返す
+func (m *TournamentMatch) HasPlayer(userID uuid.UUID) bool {
+	return (m.Player1ID.Valid && m.Player1ID.UUID == userID) || (m.Player2ID.Valid && m.Player2ID.UUID == userID)
+}
+
+// TournamentBracket はトーナメントと参加者・全試合をまとめたもの
+// Participants はシード順（登録中は登録順）、Matches はラウンド・位置の順に並ぶ
+type TournamentBracket struct {
+	Participants []TournamentParticipant `json:"participants"`
+	Matches      []*TournamentMatch      `json:"matches"`
+	Tournament   Tournament              `json:"tournament"`
+}
+
+// Start は参加者を users.rate の高い順にシードし、全ラウンドの試合を作ってトーナメントを開始する
+// 参加者が2の累乗に満たない分は上位シードの不戦勝になる
+// 戻り値は両プレイヤーが決まった試合（呼び出し元がルームを作成する）
+func (b *TournamentBracket) Start(now time.Time) []*TournamentMatch {
+	sort.SliceStable(b.Participants, func(i, j int) bool {
+		pi, pj := b.Participants[i], b.Participants[j]
+		if pi.Rate != pj.Rate {
+			return pi.Rate > pj.Rate
+		}
+		return pi.RegisteredAt.Before(pj.RegisteredAt)
+	})
+	for i := range b.Participants {
+		b.Participants[i].Seed = i + 1
+	}
+
+	size := 2
+	for size < len(b.Participants) {
+		size *= 2
+	}
+	order := seedOrder(size)
+	b.Matches = nil
+	for round, count := 1, size/2; count >= 1; round, count = round+1, count/2 {
+		for pos := 0; pos < count; pos++ {
+			m := &TournamentMatch{
+				ID:           uuid.New(),
+				TournamentID: b.Tournament.ID,
+				Round:        round,
+				Position:     pos,
+				Status:       TournamentMatchStatusPending,
+				UpdatedAt:    now,
+			}
+			if round == 1 {
+				m.Player1ID = b.seedUser(order[2*pos])
+				m.Player2ID = b.seedUser(order[2*pos+1])
+			}
+			b.Matches = append(b.Matches, m)
+		}
+	}
+
+	b.Tournament.Status = TournamentStatusInProgress
+	b.Tournament.StartedAt = &now
+	return b.Advance(now)
+}
+
+// seedOrder は size 人のトーナメント表で、1回戦の枠に入るシードを上から順に返す
+// 上位シード同士が決勝まで当たらないよう、n 人の並びの各シード s を (s, 2n+1-s) に展開して作る
+func seedOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		n := len(order) * 2
+		next := make([]int, 0, n)
+		for _, s := range order {
+			next = append(next, s, n+1-s)
+		}
+		order = next
+	}
+	return order
+}
+
+func (b *TournamentBracket) seedUser(seed int) uuid.NullUUID {
+	if seed > len(b.Participants) {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: b.Participants[seed-1].UserID, Valid: true}
+}
+
+// Advance は確定した勝者を次のラウンドへ進め、対戦相手のいない試合を不戦勝・無効として確定させる
+// 決勝が確定するとトーナメントを終了する
+// 戻り値は両プレイヤーが決まり、まだルームを作成していない試合
+func (b *TournamentBracket) Advance(now time.Time) []*TournamentMatch {
+	var ready []*TournamentMatch
+	for _, m := range b.Matches {
+		if m.Status != TournamentMatchStatusPending {
+			continue
+		}
+		if m.Round > 1 {
+			feeder1 := b.Match(m.Round-1, m.Position*2)
+			feeder2 := b.Match(m.Round-1, m.Position*2+1)
+			if feeder1 == nil || feeder2 == nil {
+				continue
+			}
+			if feeder1.Status == TournamentMatchStatusFinished && m.Player1ID != feeder1.WinnerID {
+				m.Player1ID = feeder1.WinnerID
+				m.UpdatedAt = now
+			}
+			if feeder2.Status == TournamentMatchStatusFinished && m.Player2ID != feeder2.WinnerID {
+				m.Player2ID = feeder2.WinnerID
+				m.UpdatedAt = now
+			}
+			if feeder1.Status != TournamentMatchStatusFinished || feeder2.Status != TournamentMatchStatusFinished {
+				continue
+			}
+		}
+		switch {
+		case m.Player1ID.Valid && m.Player2ID.Valid:
+			ready = append(ready, m)
+		case m.Player1ID.Valid:
+			b.Decide(m, m.Player1ID, TournamentMatchResultBye, now)
+		case m.Player2ID.Valid:
+			b.Decide(m, m.Player2ID, TournamentMatchResultBye, now)
+		default:
+			b.Decide(m, uuid.NullUUID{}, TournamentMatchResultVoid, now)
+		}
+	}
+
+	if final := b.Final(); final != nil && final.Status == TournamentMatchStatusFinished &&
+		b.Tournament.Status != TournamentStatusFinished {
+		b.Tournament.Status = TournamentStatusFinished
+		b.Tournament.WinnerID = final.WinnerID
+		b.Tournament.FinishedAt = &now
+	}
+	return ready
+}
+
+// Decide は試合の勝ち上がりを確定させる。winner が無効値の場合は両者とも敗退する
+// 次のラウンドへの反映は Advance で行う
+func (b *TournamentBracket) Decide(m *TournamentMatch, winner uuid.NullUUID, result TournamentMatchResult, now time.Time) {
+	m.Status = TournamentMatchStatusFinished
+	m.WinnerID = winner
+	m.Result = result
+	m.UpdatedAt = now
+}
+
+// Match は round・position の試合を返す。存在しない場合は nil
+func (b *TournamentBracket) Match(round, position int) *TournamentMatch {
+	for _, m := range b.Matches {
+		if m.Round == round && m.Position == position {
+			return m
+		}
+	}
+	return nil
+}
+
+// MatchByRoomID は現在 roomID で対戦している試合を返す。存在しない場合は nil
+func (b *TournamentBracket) MatchByRoomID(roomID uuid.UUID) *TournamentMatch {
+	for _, m := range b.Matches {
+		if m.RoomID.Valid && m.RoomID.UUID == roomID {
+			return m
+		}
+	}
+	return nil
+}
+
+// Final は決勝の試合を返す。開始前は nil
+func (b *TournamentBracket) Final() *TournamentMatch {
+	if len(b.Matches) == 0 {
+		return nil
+	}
+	return b.Matches[len(b.Matches)-1]
+}
+
+// Participant は userID の参加者を返す。参加していない場合は nil
+func (b *TournamentBracket) Participant(userID uuid.UUID) *TournamentParticipant {
+	for i := range b.Participants {
+		if b.Participants[i].UserID == userID {
+			return &b.Participants[i]
+		}
+	}
+	return nil
+}
+
+// HigherSeed は試合の両プレイヤーのうちシード順位の高い方を返す
+func (b *TournamentBracket) HigherSeed(m *TournamentMatch) uuid.NullUUID {
+	if !m.Player1ID.Valid || !m.Player2ID.Valid {
+		if m.Player1ID.Valid {
+			return m.Player1ID
+		}
+		return m.Player2ID
+	}
+	p1 := b.Participant(m.Player1ID.UUID)
+	p2 := b.Participant(m.Player2ID.UUID)
+	if p1 != nil && p2 != nil && p2.Seed < p1.Seed {
+		return m.Player2ID
+	}
+	return m.Player1ID
+}
@@ -0,0 +1,120 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBracket(rates ...int) *TournamentBracket {
+	base := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	b := &TournamentBracket{Tournament: Tournament{ID: uuid.New(), Status: TournamentStatusRegistration}}
+	for i, rate := range rates {
+		b.Participants = append(b.Participants, TournamentParticipant{
+			UserID:       uuid.New(),
+			Rate:         rate,
+			RegisteredAt: base.Add(time.Duration(i) * time.Second),
+		})
+	}
+	return b
+}
+
+func TestSeedOrder(t *testing.T) {
+	assert.Equal(t, []int{1, 2}, seedOrder(2))
+	assert.Equal(t, []int{1, 4, 2, 3}, seedOrder(4))
+	assert.Equal(t, []int{1, 8, 4, 5, 2, 7, 3, 6}, seedOrder(8))
+}
+
+func TestTournamentBracket_StartSeedsByRate(t *testing.T) {
+	b := newTestBracket(1400, 1600, 1500, 1500)
+	first := b.Participants[2].UserID // 同じレートは先に登録した方が上位
+	now := time.Now()
+
+	ready := b.Start(now)
+
+	require.Len(t, b.Matches, 3)
+	assert.Equal(t, TournamentStatusInProgress, b.Tournament.Status)
+	assert.Equal(t, 1600, b.Participants[0].Rate)
+	assert.Equal(t, first, b.Participants[1].UserID)
+	for i, p := range b.Participants {
+		assert.Equal(t, i+1, p.Seed)
+	}
+	// 1回戦は 1 vs 4、2 vs 3
+	require.Len(t, ready, 2)
+	assert.Equal(t, b.Participants[0].UserID, ready[0].Player1ID.UUID)
+	assert.Equal(t, b.Participants[3].UserID, ready[0].Player2ID.UUID)
+	assert.Equal(t, b.Participants[1].UserID, ready[1].Player1ID.UUID)
+	assert.Equal(t, b.Participants[2].UserID, ready[1].Player2ID.UUID)
+	assert.False(t, b.Final().Player1ID.Valid)
+}
+
+func TestTournamentBracket_StartGivesByesToTopSeeds(t *testing.T) {
+	b := newTestBracket(1500, 1400, 1300, 1200, 1100)
+	ready := b.Start(time.Now())
+
+	require.Len(t, b.Matches, 7)
+	// 8 枠に 5 人なので、シード 1〜3 は不戦勝で2回戦へ進む
+	byes := 0
+	for _, m := range b.Matches {
+		if m.Round == 1 && m.Result == TournamentMatchResultBye {
+			byes++
+		}
+	}
+	assert.Equal(t, 3, byes)
+	require.Len(t, ready, 2)
+	// 1回戦で唯一成立するのはシード 4 vs 5
+	assert.Equal(t, 1, ready[0].Round)
+	assert.Equal(t, b.Participants[3].UserID, ready[0].Player1ID.UUID)
+	assert.Equal(t, b.Participants[4].UserID, ready[0].Player2ID.UUID)
+	// 2回戦でシード 2 vs 3 が成立する
+	assert.Equal(t, 2, ready[1].Round)
+	assert.Equal(t, b.Participants[1].UserID, ready[1].Player1ID.UUID)
+	assert.Equal(t, b.Participants[2].UserID, ready[1].Player2ID.UUID)
+	// シード 1 は 4 vs 5 の勝者を待つ
+	waiting := b.Match(2, 0)
+	assert.Equal(t, b.Participants[0].UserID, waiting.Player1ID.UUID)
+	assert.False(t, waiting.Player2ID.Valid)
+}
+
+func TestTournamentBracket_AdvanceToChampion(t *testing.T) {
+	b := newTestBracket(1500, 1400)
+	now := time.Now()
+	ready := b.Start(now)
+	require.Len(t, ready, 1)
+
+	b.Decide(ready[0], ready[0].Player2ID, TournamentMatchResultPlayed, now)
+	assert.Empty(t, b.Advance(now))
+
+	assert.Equal(t, TournamentStatusFinished, b.Tournament.Status)
+	assert.Equal(t, b.Participants[1].UserID, b.Tournament.WinnerID.UUID)
+	require.NotNil(t, b.Tournament.FinishedAt)
+}
+
+func TestTournamentBracket_VoidGivesByeInNextRound(t *testing.T) {
+	b := newTestBracket(1500, 1400, 1300, 1200)
+	now := time.Now()
+	ready := b.Start(now)
+	require.Len(t, ready, 2)
+
+	// 1 vs 4 は両者不参加、2 vs 3 はシード 3 の勝ち
+	b.Decide(ready[0], uuid.NullUUID{}, TournamentMatchResultVoid, now)
+	b.Decide(ready[1], ready[1].Player2ID, TournamentMatchResultPlayed, now)
+	assert.Empty(t, b.Advance(now))
+
+	final := b.Final()
+	assert.Equal(t, TournamentMatchResultBye, final.Result)
+	assert.Equal(t, b.Participants[2].UserID, b.Tournament.WinnerID.UUID)
+	assert.Equal(t, TournamentStatusFinished, b.Tournament.Status)
+}
+
+func TestTournamentBracket_HigherSeed(t *testing.T) {
+	b := newTestBracket(1500, 1400, 1300, 1200)
+	ready := b.Start(time.Now())
+
+	m := ready[1]
+	m.Player1ID, m.Player2ID = m.Player2ID, m.Player1ID
+	assert.Equal(t, b.Participants[1].UserID, b.HigherSeed(m).UUID)
+}
@@ -14,5 +14,14 @@ type User struct {
 	GitHubID       int64     `json:"github_id"`
 	GnuBalance     int       `json:"gnu_balance"`
 	Rate           int       `json:"rate"`
-	ID             uuid.UUID `json:"id"`
+	// Glicko-2 のレーティング偏差・ボラティリティ（Elo では未使用）
+	RatingDeviation  float64   `json:"-"`
+	RatingVolatility float64   `json:"-"`
+	ID               uuid.UUID `json:"id"`
+}
+
+// GitHubIdentity は GitHub のトークンまたは WebSocket チケットで認証されたユーザー
+type GitHubIdentity struct {
+	Login string `json:"github_login"`
+	ID    int64  `json:"github_id"`
 }
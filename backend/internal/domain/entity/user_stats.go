@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserStats はユーザーの通算成績（match_results と match_turns から集計する）
+type UserStats struct {
+	ComputedAt    time.Time            `json:"computed_at"` // 集計した時刻（キャッシュから返した場合も集計時のまま）
+	GitHubLogin   string               `json:"github_login"`
+	Accuracy      []DifficultyAccuracy `json:"accuracy_by_difficulty"`
+	GnuHistory    []DailyGnuEarned     `json:"gnu_history"` // 直近の日ごとの獲得ヌー（試合のない日は含まない）
+	UserID        uuid.UUID            `json:"user_id"`
+	AverageBet    float64              `json:"average_bet"` // ベットしたターンの平均ベット額
+	MatchesPlayed int                  `json:"matches_played"`
+	Wins          int                  `json:"wins"`
+	Losses        int                  `json:"losses"`
+	Draws         int                  `json:"draws"`
+	TKOWins       int                  `json:"tko_wins"`
+	TKOLosses     int                  `json:"tko_losses"`
+	BestWinStreak int                  `json:"best_win_streak"`
+	GnuEarned     int                  `json:"gnu_earned"` // 試合での獲得ヌーの通算（TKO ボーナスを除く）
+}
+
+// DifficultyAccuracy は問題の難易度ごとの正答率
+type DifficultyAccuracy struct {
+	Difficulty string  `json:"difficulty"`
+	Turns      int     `json:"turns"` // 出題されたターン数（未回答を含む）
+	Correct    int     `json:"correct"`
+	Accuracy   float64 `json:"accuracy"` // Correct / Turns
+}
+
+// DailyGnuEarned は1日（UTC）の試合での獲得ヌー
+type DailyGnuEarned struct {
+	Date      string `json:"date"` // YYYY-MM-DD
+	Matches   int    `json:"matches"`
+	GnuEarned int    `json:"gnu_earned"`
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+)
+
+// GnuLedgerRepository はヌー台帳を管理する
+type GnuLedgerRepository interface {
+	// Apply は取引を1トランザクションで台帳に記録し、各ユーザーの gnu_balance に差分を反映する
+	// IdempotencyKey が記録済みの取引は反映しない。残高が負になる減算は 0 で止める
+	// 戻り値は反映後の各ユーザーの残高
+	Apply(ctx context.Context, txs []entity.GnuTransaction) (map[uuid.UUID]int, error)
+	// ListMismatches は gnu_balance が台帳の合計と一致しないユーザーを返す
+	ListMismatches(ctx context.Context) ([]entity.GnuBalanceMismatch, error)
+}
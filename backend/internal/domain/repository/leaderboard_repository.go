@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+)
+
+// LeaderboardRepository はランキングを管理する
+type LeaderboardRepository interface {
+	// SetScores は各ユーザーのスコアをランキングに記録する（既存のスコアは上書きする）
+	SetScores(ctx context.Context, board entity.Leaderboard, entries []entity.LeaderboardEntry) error
+	// IncrementScore はユーザーのスコアに delta を加える
+	IncrementScore(ctx context.Context, board entity.Leaderboard, entry entity.LeaderboardEntry, delta int) error
+	// Top はスコアの高い順に limit 人を返す
+	Top(ctx context.Context, board entity.Leaderboard, limit int) ([]entity.LeaderboardEntry, error)
+	// Around は userID とスコアが前後 radius 人ずつのユーザーを順位の順に返す
+	// userID がランキングにいない場合は nil を返す
+	Around(ctx context.Context, board entity.Leaderboard, userID uuid.UUID, radius int) ([]entity.LeaderboardEntry, error)
+	// Replace はランキングを entries で丸ごと置き換える
+	Replace(ctx context.Context, board entity.Leaderboard, entries []entity.LeaderboardEntry) error
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+)
+
+type MatchRepository interface {
+	// Save は試合結果と全ターンの記録を1トランザクションで保存する
+	Save(ctx context.Context, result *entity.MatchResult) error
+	GetByRoomID(ctx context.Context, roomID uuid.UUID) (*entity.MatchResult, error)
+	ListByUserID(ctx context.Context, userID uuid.UUID, limit int) ([]*entity.MatchResult, error)
+	// CountWinsSince は since 以降に終了した試合の勝利数をユーザーごとに返す
+	CountWinsSince(ctx context.Context, since time.Time) (map[uuid.UUID]int, error)
+	// GetUserStats は userID の通算成績を集計する。GnuHistory は historySince 以降に終了した試合から求める
+	// UserID と GitHubLogin・ComputedAt は設定しない
+	GetUserStats(ctx context.Context, userID uuid.UUID, historySince time.Time) (*entity.UserStats, error)
+	// SaveEvents はプレイヤーに送ったイベントを1トランザクションで記録する
+	SaveEvents(ctx context.Context, events []entity.MatchEvent) error
+	// ListEventsByRoomID はルームで記録したイベントを記録した順に返す
+	ListEventsByRoomID(ctx context.Context, roomID uuid.UUID) ([]entity.MatchEvent, error)
+}
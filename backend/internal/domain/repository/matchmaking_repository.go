@@ -2,14 +2,29 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
 )
 
 type MatchmakingRepository interface {
-	Enqueue(ctx context.Context, userID uuid.UUID) error
-	Dequeue(ctx context.Context) (uuid.UUID, uuid.UUID, error)
+	// Enqueue はユーザーを entry.TeamSize の形式のキューに追加し、SubscribeQueueChanges の購読者に通知する
+	// EnqueuedAt がゼロ値の場合は現在時刻を使う
+	Enqueue(ctx context.Context, entry entity.QueueEntry) error
+	// Requeue は取り出したユーザーを元の参加時刻のままキューに戻す。Enqueue と異なり購読者には通知しない
+	// マッチの成立に失敗した直後にマッチングを再び起こすと、失敗が続く間ループし続けるため
+	Requeue(ctx context.Context, entry entity.QueueEntry) error
+	// Dequeue は1対1のキューから window に収まる2人を取り出す。該当ペアがいなければ nil を返す
+	Dequeue(ctx context.Context, window entity.RateWindow, now time.Time) (*entity.QueueEntry, *entity.QueueEntry, error)
+	// DequeueTeams は1チーム teamSize 人のキューから、レートの差が全員の window に収まる 2*teamSize 人を取り出す
+	// 該当する組み合わせがなければ nil を返す。チーム分けは呼び出し側で行う
+	DequeueTeams(ctx context.Context, teamSize int, window entity.RateWindow, now time.Time) ([]*entity.QueueEntry, error)
+	// Remove はユーザーを全ての形式のキューから外す
 	Remove(ctx context.Context, userID uuid.UUID) error
 	SetActive(ctx context.Context, userID uuid.UUID) (bool, error)
 	ClearActive(ctx context.Context, userID uuid.UUID) error
+	// SubscribeQueueChanges はキューへの追加を知らせるチャネルを返す。ctx が終わるとチャネルは閉じられる
+	// 処理中に届いた通知は1つにまとめられるため、受け取るたびにキュー全体を確認すること
+	SubscribeQueueChanges(ctx context.Context) (<-chan struct{}, error)
 }
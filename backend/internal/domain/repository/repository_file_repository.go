@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+)
+
+type RepositoryFileRepository interface {
+	// ListLatestByOwner は owner が最後に解析したリポジトリのファイルを返す
+	// 解析済みリポジトリがない場合は空スライスを返す
+	ListLatestByOwner(ctx context.Context, owner string) ([]entity.RepositoryFile, error)
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+)
+
+// RoomInviteRepository はプライベートルームへの招待を ExpiresAt まで保存する
+type RoomInviteRepository interface {
+	// Create は招待を保存する。同じコードの招待が既にある場合は false を返す
+	Create(ctx context.Context, invite *entity.RoomInvite) (bool, error)
+	// Get は招待を返す。存在しない・期限切れの場合は nil を返す
+	Get(ctx context.Context, code string) (*entity.RoomInvite, error)
+	// Claim は未使用の招待に参加者とルームを記録する。招待が存在しない・使用済みの場合は false を返す
+	Claim(ctx context.Context, code string, guestID, roomID uuid.UUID) (bool, error)
+	// Release は roomID での Claim を取り消し、招待を未使用に戻す
+	Release(ctx context.Context, code string, roomID uuid.UUID) error
+	// DeleteUnclaimed は未使用の招待を削除する。招待が存在しない・使用済みの場合は false を返す
+	DeleteUnclaimed(ctx context.Context, code string) (bool, error)
+}
@@ -10,4 +10,14 @@ import (
 type RoomRepository interface {
 	Create(ctx context.Context, room *entity.Room) error
 	GetByID(ctx context.Context, id uuid.UUID) (*entity.Room, error)
+	// UpdateStatus はルームの状態と終了理由を更新する。終了していない状態では reason に RoomEndReasonNone を渡す
+	UpdateStatus(ctx context.Context, id uuid.UUID, status entity.RoomStatus, reason entity.RoomEndReason) error
+	// SaveSnapshot は試合の状態を保存し、再起動後に復旧するルームとして登録する
+	SaveSnapshot(ctx context.Context, snapshot *entity.RoomSnapshot) error
+	// GetSnapshot は保存された試合の状態を返す。保存されていない場合は nil を返す
+	GetSnapshot(ctx context.Context, id uuid.UUID) (*entity.RoomSnapshot, error)
+	// DeleteSnapshot は試合の状態を削除し、復旧の対象から外す
+	DeleteSnapshot(ctx context.Context, id uuid.UUID) error
+	// ListSnapshotRoomIDs は試合の状態が保存されているルームの ID を返す
+	ListSnapshotRoomIDs(ctx context.Context) ([]uuid.UUID, error)
 }
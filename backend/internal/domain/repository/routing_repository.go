@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+)
+
+// RoutingRepository は複数インスタンス構成でのルームの担当とインスタンス間のメッセージ配送を管理する
+type RoutingRepository interface {
+	// ClaimOwner は担当がいなければ instanceID をルームの担当として登録し、担当インスタンスの ID を返す
+	ClaimOwner(ctx context.Context, roomID uuid.UUID, instanceID string, ttl time.Duration) (string, error)
+	// GetOwner はルームの担当インスタンスの ID を返す。担当がいなければ空文字を返す
+	GetOwner(ctx context.Context, roomID uuid.UUID) (string, error)
+	// RefreshOwner は担当が instanceID の場合に限り有効期限を延長する
+	RefreshOwner(ctx context.Context, roomID uuid.UUID, instanceID string, ttl time.Duration) error
+	// ReleaseOwner は担当が instanceID の場合に限り登録を削除する
+	ReleaseOwner(ctx context.Context, roomID uuid.UUID, instanceID string) error
+
+	// SetUserInstance はユーザーのマッチング接続を持つインスタンスを登録する
+	SetUserInstance(ctx context.Context, userID uuid.UUID, instanceID string, ttl time.Duration) error
+	// GetUserInstance はユーザーのマッチング接続を持つインスタンスの ID を返す。なければ空文字を返す
+	GetUserInstance(ctx context.Context, userID uuid.UUID) (string, error)
+	// ClearUserInstance は登録が instanceID の場合に限り削除する
+	ClearUserInstance(ctx context.Context, userID uuid.UUID, instanceID string) error
+
+	// Publish は instanceID 宛てにメッセージを送る
+	Publish(ctx context.Context, instanceID string, msg entity.RelayMessage) error
+	// Subscribe は instanceID 宛てのメッセージの購読を開始する。ctx が終わるとチャネルは閉じられる
+	Subscribe(ctx context.Context, instanceID string) (<-chan entity.RelayMessage, error)
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+)
+
+type TournamentRepository interface {
+	Create(ctx context.Context, tournament *entity.Tournament) error
+	List(ctx context.Context, limit int) ([]*entity.Tournament, error)
+	// GetBracket はトーナメントと参加者・全試合を返す。存在しない場合は sql.ErrNoRows をラップして返す
+	GetBracket(ctx context.Context, id uuid.UUID) (*entity.TournamentBracket, error)
+	// Update はトーナメントを行ロックした状態で fn を呼び、fn が変更した内容を1トランザクションで保存する
+	// fn がエラーを返した場合は何も保存せずにそのエラーを返す
+	Update(ctx context.Context, id uuid.UUID, fn func(*entity.TournamentBracket) error) (*entity.TournamentBracket, error)
+	// GetMatchByRoomID は roomID で対戦している試合を返す。トーナメントの試合でない場合は nil を返す
+	GetMatchByRoomID(ctx context.Context, roomID uuid.UUID) (*entity.TournamentMatch, error)
+	// ListStaleMatches は createdBefore より前に作成したルームに誰も参加していない試合を返す
+	ListStaleMatches(ctx context.Context, createdBefore time.Time) ([]*entity.TournamentMatch, error)
+}
+
+// TournamentUpdateRepository はトーナメント表の更新をインスタンス間で通知する
+type TournamentUpdateRepository interface {
+	Publish(ctx context.Context, tournamentID uuid.UUID) error
+	// Subscribe は tournamentID の更新を知らせるチャネルを返す。ctx が終わるとチャネルは閉じられる
+	// 処理中に届いた通知は1つにまとめられるため、受け取るたびにトーナメント表全体を読み直すこと
+	Subscribe(ctx context.Context, tournamentID uuid.UUID) (<-chan struct{}, error)
+}
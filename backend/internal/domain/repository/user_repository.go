@@ -12,5 +12,10 @@ type UserRepository interface {
 	GetByGitHubID(ctx context.Context, githubID int64) (*entity.User, error)
 	GetByGitHubLogin(ctx context.Context, login string) (*entity.User, error)
 	Create(ctx context.Context, user *entity.User) error
-	UpdateGnuBalance(ctx context.Context, id uuid.UUID, balance int) error
+	// UpdateRatings は ids のユーザーを行ロックした状態で fn を呼び、fn が変更したレーティング
+	// （Rate・RatingDeviation・RatingVolatility）を1トランザクションで保存する
+	// fn がエラーを返した場合は何も保存せずにそのエラーを返す
+	UpdateRatings(ctx context.Context, ids []uuid.UUID, fn func(map[uuid.UUID]*entity.User) error) error
+	// ListWithMatches は1試合以上の結果が記録されているユーザーを返す
+	ListWithMatches(ctx context.Context) ([]*entity.User, error)
 }
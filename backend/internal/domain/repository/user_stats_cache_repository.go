@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+)
+
+// UserStatsCacheRepository は集計した通算成績をキャッシュする
+type UserStatsCacheRepository interface {
+	// Get はキャッシュした成績を返す。キャッシュがない場合は nil を返す
+	Get(ctx context.Context, userID uuid.UUID) (*entity.UserStats, error)
+	Set(ctx context.Context, stats *entity.UserStats, ttl time.Duration) error
+	// Delete は各ユーザーのキャッシュを削除する
+	Delete(ctx context.Context, userIDs ...uuid.UUID) error
+}
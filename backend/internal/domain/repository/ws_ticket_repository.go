@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+)
+
+// WSTicketRepository は WebSocket 接続用の使い捨てチケットを保存する
+type WSTicketRepository interface {
+	// Save はチケットを ttl の間だけ保存する
+	Save(ctx context.Context, ticket string, identity entity.GitHubIdentity, ttl time.Duration) error
+	// Consume はチケットを取り出して削除する。存在しない・期限切れの場合は nil を返す
+	Consume(ctx context.Context, ticket string) (*entity.GitHubIdentity, error)
+}
@@ -2,6 +2,8 @@ package handler
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -9,19 +11,49 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
 )
 
-const githubUserAPI = "https://api.github.com/user"
+const (
+	// wsAuthSubprotocol は WebSocket でトークンを送るときのサブプロトコル名
+	// ブラウザは WebSocket に Authorization ヘッダーを付けられないため、
+	// new WebSocket(url, ["bearer", token]) の形でトークンを受け取る
+	wsAuthSubprotocol = "bearer"
+	githubAPITimeout  = 5 * time.Second
+	wsTicketBytes     = 32
+)
 
 // githubUser は GitHub API /user のレスポンスの必要フィールドのみ定義する
 type githubUser struct {
 	Login string `json:"login"`
+	ID    int64  `json:"id"`
+}
+
+// GitHubAuthenticator は GitHub のアクセストークンと WebSocket チケットを検証する
+type GitHubAuthenticator struct {
+	client    *http.Client
+	tickets   repository.WSTicketRepository
+	baseURL   string
+	ticketTTL time.Duration
+}
+
+// NewGitHubAuthenticator は baseURL の GitHub API でトークンを検証する GitHubAuthenticator を返す
+// baseURL はテストでスタブサーバーに差し替えられるよう設定から渡す
+func NewGitHubAuthenticator(baseURL string, tickets repository.WSTicketRepository, ticketTTL time.Duration) *GitHubAuthenticator {
+	return &GitHubAuthenticator{
+		client:    &http.Client{Timeout: githubAPITimeout},
+		tickets:   tickets,
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		ticketTTL: ticketTTL,
+	}
 }
 
-// GitHubAuthMiddleware は Authorization: Bearer <token> ヘッダーを検証し、
-// GitHub API でトークンを検証したうえで github_login をコンテキストにセットする
-func GitHubAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+// Middleware は Authorization: Bearer <token> ヘッダーを検証し、
+// GitHub API でトークンを検証したうえで github_login / github_id をコンテキストにセットする
+func (a *GitHubAuthenticator) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		authHeader := c.Request().Header.Get("Authorization")
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
@@ -29,31 +61,92 @@ func GitHubAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 		}
 		token := strings.TrimPrefix(authHeader, "Bearer ")
 
-		login, err := resolveGitHubLogin(c.Request().Context(), token)
+		identity, err := a.resolveGitHubUser(c.Request().Context(), token)
 		if err != nil {
 			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
 		}
 
-		c.Set("github_login", login)
+		setIdentity(c, identity)
+		return next(c)
+	}
+}
+
+// WSMiddleware は WebSocket のアップグレード前に資格情報を検証し、
+// 認証済みユーザーの github_login / github_id をコンテキストにセットする
+// 資格情報はサブプロトコル ("bearer", <token>) またはクエリパラメータ ticket で受け取る
+func (a *GitHubAuthenticator) WSMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		var identity *entity.GitHubIdentity
+		if token := subprotocolToken(c.Request()); token != "" {
+			resolved, err := a.resolveGitHubUser(ctx, token)
+			if err != nil {
+				log.Printf("ws auth: token rejected: %v", err)
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			}
+			identity = resolved
+		} else if ticket := c.QueryParam("ticket"); ticket != "" {
+			consumed, err := a.tickets.Consume(ctx, ticket)
+			if err != nil {
+				log.Printf("ws auth: failed to consume ticket: %v", err)
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			}
+			if consumed == nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			}
+			identity = consumed
+		} else {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		}
+
+		setIdentity(c, identity)
 		return next(c)
 	}
 }
 
-func resolveGitHubLogin(ctx context.Context, token string) (string, error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
+// IssueWSTicket は認証済みユーザーに WebSocket 接続用の使い捨てチケットを発行する
+// Middleware の後ろで使う
+func (a *GitHubAuthenticator) IssueWSTicket(c echo.Context) error {
+	identity, ok := authenticatedIdentity(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+	}
+	ticket, err := a.issueTicket(c.Request().Context(), identity)
+	if err != nil {
+		log.Printf("auth: failed to issue ws ticket for %s: %v", identity.Login, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+	return c.JSON(http.StatusOK, map[string]any{
+		"ticket":     ticket,
+		"expires_in": int(a.ticketTTL / time.Second),
+	})
+}
+
+// issueTicket はランダムなチケットを生成し、identity に紐づけて保存する
+func (a *GitHubAuthenticator) issueTicket(ctx context.Context, identity entity.GitHubIdentity) (string, error) {
+	buf := make([]byte, wsTicketBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate ticket: %w", err)
+	}
+	ticket := hex.EncodeToString(buf)
+	if err := a.tickets.Save(ctx, ticket, identity, a.ticketTTL); err != nil {
+		return "", err
+	}
+	return ticket, nil
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, githubUserAPI, nil)
+func (a *GitHubAuthenticator) resolveGitHubUser(ctx context.Context, token string) (*entity.GitHubIdentity, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.baseURL+"/user", nil)
 	if err != nil {
-		return "", fmt.Errorf("build request: %w", err)
+		return nil, fmt.Errorf("build request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := a.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("github api: %w", err)
+		return nil, fmt.Errorf("github api: %w", err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -62,15 +155,41 @@ func resolveGitHubLogin(ctx context.Context, token string) (string, error) {
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("github api returned %d", resp.StatusCode)
+		return nil, fmt.Errorf("github api returned %d", resp.StatusCode)
 	}
 
 	var u githubUser
 	if err := json.NewDecoder(resp.Body).Decode(&u); err != nil {
-		return "", fmt.Errorf("decode response: %w", err)
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if u.Login == "" || u.ID == 0 {
+		return nil, fmt.Errorf("incomplete github user")
 	}
-	if u.Login == "" {
-		return "", fmt.Errorf("empty login")
+	return &entity.GitHubIdentity{Login: u.Login, ID: u.ID}, nil
+}
+
+// subprotocolToken は Sec-WebSocket-Protocol の "bearer" の次の要素をトークンとして返す
+func subprotocolToken(r *http.Request) string {
+	protocols := websocket.Subprotocols(r)
+	for i, p := range protocols {
+		if p == wsAuthSubprotocol && i+1 < len(protocols) {
+			return protocols[i+1]
+		}
+	}
+	return ""
+}
+
+func setIdentity(c echo.Context, identity *entity.GitHubIdentity) {
+	c.Set("github_login", identity.Login)
+	c.Set("github_id", identity.ID)
+}
+
+// authenticatedIdentity は Middleware / WSMiddleware がセットした認証済みユーザーを返す
+func authenticatedIdentity(c echo.Context) (entity.GitHubIdentity, bool) {
+	login, _ := c.Get("github_login").(string)
+	id, _ := c.Get("github_id").(int64)
+	if login == "" || id == 0 {
+		return entity.GitHubIdentity{}, false
 	}
-	return u.Login, nil
+	return entity.GitHubIdentity{Login: login, ID: id}, true
 }
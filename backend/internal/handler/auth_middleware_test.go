@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/testutil"
+)
+
+const testGitHubToken = "gho_valid"
+
+// newGitHubStub は testGitHubToken のみを受け付ける GitHub API /user のスタブ
+func newGitHubStub(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" || r.Header.Get("Authorization") != "Bearer "+testGitHubToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"login": "alice", "id": 42})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newTicketStore はメモリ上で動く使い捨てチケットのモック
+func newTicketStore() *testutil.MockWSTicketRepository {
+	tickets := make(map[string]entity.GitHubIdentity)
+	return &testutil.MockWSTicketRepository{
+		SaveFunc: func(_ context.Context, ticket string, identity entity.GitHubIdentity, _ time.Duration) error {
+			tickets[ticket] = identity
+			return nil
+		},
+		ConsumeFunc: func(_ context.Context, ticket string) (*entity.GitHubIdentity, error) {
+			identity, ok := tickets[ticket]
+			if !ok {
+				return nil, nil
+			}
+			delete(tickets, ticket)
+			return &identity, nil
+		},
+	}
+}
+
+// serveWithAuth は mw を通して req を処理し、認証済みユーザーとレスポンスを返す
+func serveWithAuth(mw echo.MiddlewareFunc, req *http.Request) (*entity.GitHubIdentity, *httptest.ResponseRecorder) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	var got *entity.GitHubIdentity
+	_ = mw(func(c echo.Context) error {
+		identity, ok := authenticatedIdentity(c)
+		if ok {
+			got = &identity
+		}
+		return c.NoContent(http.StatusOK)
+	})(c)
+	return got, rec
+}
+
+func TestGitHubAuthenticator_Middleware(t *testing.T) {
+	auth := NewGitHubAuthenticator(newGitHubStub(t).URL, newTicketStore(), time.Minute)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+	req.Header.Set("Authorization", "Bearer "+testGitHubToken)
+	got, rec := serveWithAuth(auth.Middleware, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, got)
+	assert.Equal(t, entity.GitHubIdentity{Login: "alice", ID: 42}, *got)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+	req.Header.Set("Authorization", "Bearer gho_invalid")
+	got, rec = serveWithAuth(auth.Middleware, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Nil(t, got)
+}
+
+func TestGitHubAuthenticator_WSMiddleware_Subprotocol(t *testing.T) {
+	auth := NewGitHubAuthenticator(newGitHubStub(t).URL, newTicketStore(), time.Minute)
+
+	req := httptest.NewRequest(http.MethodGet, "/ws/matchmake", nil)
+	req.Header.Set("Sec-WebSocket-Protocol", "bearer, "+testGitHubToken)
+	got, rec := serveWithAuth(auth.WSMiddleware, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, got)
+	assert.Equal(t, "alice", got.Login)
+	assert.Equal(t, int64(42), got.ID)
+
+	req = httptest.NewRequest(http.MethodGet, "/ws/matchmake", nil)
+	req.Header.Set("Sec-WebSocket-Protocol", "bearer, gho_invalid")
+	got, rec = serveWithAuth(auth.WSMiddleware, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Nil(t, got)
+}
+
+func TestGitHubAuthenticator_WSMiddleware_Ticket(t *testing.T) {
+	auth := NewGitHubAuthenticator(newGitHubStub(t).URL, newTicketStore(), time.Minute)
+
+	// チケットは Middleware で認証したユーザーに発行される
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ws-tickets", nil)
+	req.Header.Set("Authorization", "Bearer "+testGitHubToken)
+	rec := httptest.NewRecorder()
+	require.NoError(t, auth.Middleware(auth.IssueWSTicket)(echo.New().NewContext(req, rec)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body struct {
+		Ticket    string `json:"ticket"`
+		ExpiresIn int    `json:"expires_in"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.NotEmpty(t, body.Ticket)
+	assert.Equal(t, 60, body.ExpiresIn)
+
+	req = httptest.NewRequest(http.MethodGet, "/ws/matchmake?ticket="+body.Ticket, nil)
+	got, rec := serveWithAuth(auth.WSMiddleware, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, got)
+	assert.Equal(t, entity.GitHubIdentity{Login: "alice", ID: 42}, *got)
+
+	// 同じチケットは2回使えない
+	req = httptest.NewRequest(http.MethodGet, "/ws/matchmake?ticket="+body.Ticket, nil)
+	got, rec = serveWithAuth(auth.WSMiddleware, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Nil(t, got)
+}
+
+func TestGitHubAuthenticator_WSMiddleware_NoCredential(t *testing.T) {
+	auth := NewGitHubAuthenticator(newGitHubStub(t).URL, newTicketStore(), time.Minute)
+
+	// 旧方式のクエリパラメータでは認証されない
+	req := httptest.NewRequest(http.MethodGet, "/ws/matchmake?github_login=alice&github_id=42", nil)
+	got, rec := serveWithAuth(auth.WSMiddleware, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Nil(t, got)
+}
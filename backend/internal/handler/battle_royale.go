@@ -0,0 +1,770 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/usecase"
+)
+
+const (
+	brSendBuffer = 32               // 参加者の接続ごとの送信キュー長
+	brWriteWait  = 10 * time.Second // 参加者への1メッセージあたりの書き込み期限
+)
+
+var (
+	// ErrBattleRoyaleNotFound は稼働していないバトルロイヤルへの操作を示す
+	ErrBattleRoyaleNotFound = errors.New("battle royale not found")
+	// ErrBattleRoyaleForbidden は作成者以外による開始を示す
+	ErrBattleRoyaleForbidden = errors.New("not the host of the battle royale")
+	// ErrBattleRoyaleStarted は開始済みのバトルロイヤルへの新規参加・再度の開始を示す
+	ErrBattleRoyaleStarted = errors.New("battle royale already started")
+	// ErrBattleRoyaleFull は参加者が上限に達したロビーへの参加を示す
+	ErrBattleRoyaleFull = errors.New("battle royale is full")
+	// ErrBattleRoyaleTooFew は最少人数に満たないロビーの開始を示す
+	ErrBattleRoyaleTooFew = errors.New("not enough players to start the battle royale")
+)
+
+// brConn は参加者の WebSocket 接続
+// 多数の接続へ同じメッセージを送るため、送信は準備済みメッセージをキュー経由で writeLoop が書き出す
+// キューが詰まった接続は閉じ、再接続時に現在の状態を送り直す
+type brConn struct {
+	conn   *websocket.Conn
+	sendCh chan *websocket.PreparedMessage
+}
+
+func newBRConn(conn *websocket.Conn) *brConn {
+	return &brConn{conn: conn, sendCh: make(chan *websocket.PreparedMessage, brSendBuffer)}
+}
+
+// enqueue は送信キューにメッセージを積む。キューが詰まっていれば false を返す
+func (c *brConn) enqueue(pm *websocket.PreparedMessage) bool {
+	select {
+	case c.sendCh <- pm:
+		return true
+	default:
+		return false
+	}
+}
+
+// interrupt は読み取りを打ち切り、接続を終了させる
+func (c *brConn) interrupt() {
+	if err := c.conn.SetReadDeadline(time.Now()); err != nil {
+		log.Printf("battle royale: failed to interrupt conn: %v", err)
+	}
+}
+
+// writeLoop は送信キューを WebSocket に書き出す
+// 読み取りの終了（readerDone）かルームの終了まで戻らない。ルームの終了時は残りを送り切ってから読み取りを打ち切る
+func (c *brConn) writeLoop(readerDone, roomClosed <-chan struct{}) {
+	for {
+		select {
+		case pm := <-c.sendCh:
+			if !c.write(pm) {
+				c.interrupt()
+				return
+			}
+		case <-readerDone:
+			return
+		case <-roomClosed:
+			for {
+				select {
+				case pm := <-c.sendCh:
+					if !c.write(pm) {
+						c.interrupt()
+						return
+					}
+				default:
+					c.interrupt()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *brConn) write(pm *websocket.PreparedMessage) bool {
+	if err := c.conn.SetWriteDeadline(time.Now().Add(brWriteWait)); err != nil {
+		log.Printf("battle royale: set write deadline error: %v", err)
+	}
+	if err := c.conn.WritePreparedMessage(pm); err != nil {
+		log.Printf("battle royale: write error: %v", err)
+		return false
+	}
+	return true
+}
+
+// brPlayer はバトルロイヤルの参加者
+type brPlayer struct {
+	conn     *brConn // BattleRoyaleRoom.mu で保護される（未接続は nil）
+	user     *entity.User
+	standing entity.BattleRoyaleStanding // BattleRoyaleRoom.mu で保護される（書き込みは run goroutine のみ）
+}
+
+// brEventKind は run goroutine に送る接続イベントの種類
+type brEventKind int
+
+const (
+	brEventJoin    brEventKind = iota // 接続した（再接続を含む）
+	brEventLeave                      // 接続が閉じた
+	brEventMessage                    // 参加者からのメッセージ
+)
+
+// brEvent は接続から run goroutine に送るイベント
+type brEvent struct {
+	conn    *brConn
+	player  *brPlayer
+	msgType string
+	payload json.RawMessage
+	kind    brEventKind
+}
+
+// brRound は進行中のラウンドの状態（run goroutine のみが操作する）
+type brRound struct {
+	startedAt time.Time
+	deadline  time.Time
+	answers   map[*brPlayer]entity.Answer
+	elapsed   map[*brPlayer]time.Duration
+	question  entity.Question
+	number    int
+}
+
+// BattleRoyaleSummary は REST API で返すバトルロイヤルの状態
+type BattleRoyaleSummary struct {
+	CreatedAt  time.Time                     `json:"created_at"`
+	Status     entity.BattleRoyaleStatus     `json:"status"`
+	Standings  []entity.BattleRoyaleStanding `json:"standings"`
+	ID         uuid.UUID                     `json:"id"`
+	HostID     uuid.UUID                     `json:"host_id"`
+	Round      int                           `json:"round"`       // 進行中・最後に終えたラウンド（開始前は 0）
+	Alive      int                           `json:"alive"`       // 生存者数（開始前は参加者数）
+	MinPlayers int                           `json:"min_players"` // 開始に必要な人数
+	MaxPlayers int                           `json:"max_players"`
+}
+
+// BattleRoyaleRoom は多人数が毎ラウンド同じ問題に答え、累計ヌーの下位から脱落していくルーム
+// 脱落した参加者も接続したまま観戦者として進行を受け取る
+// 試合中のヌーは順位を決めるためだけに使い、残高・レーティング・ランキングには反映しない
+type BattleRoyaleRoom struct {
+	createdAt time.Time
+	questions *usecase.QuestionUsecase
+	players   []*brPlayer // 参加順。開始後は増減しない（mu で保護される）
+	eventCh   chan brEvent
+	startCh   chan struct{}   // start で close される
+	closedCh  chan struct{}   // run 終了時に close される
+	draining  <-chan struct{} // サーバーの停止の開始時に close される（開始前のロビーを中止する）
+	onClose   func()
+	status    entity.BattleRoyaleStatus // mu で保護される
+	rules     entity.BattleRoyaleRules
+	round     int // mu で保護される（書き込みは run goroutine のみ）
+	id        uuid.UUID
+	hostID    uuid.UUID
+	mu        sync.Mutex
+	closeOnce sync.Once
+}
+
+func newBattleRoyaleRoom(
+	id, hostID uuid.UUID,
+	rules entity.BattleRoyaleRules,
+	questions *usecase.QuestionUsecase,
+	draining <-chan struct{},
+	onClose func(),
+) *BattleRoyaleRoom {
+	return &BattleRoyaleRoom{
+		createdAt: time.Now(),
+		questions: questions,
+		eventCh:   make(chan brEvent, 64),
+		startCh:   make(chan struct{}),
+		closedCh:  make(chan struct{}),
+		draining:  draining,
+		onClose:   onClose,
+		status:    entity.BattleRoyaleStatusLobby,
+		rules:     rules,
+		id:        id,
+		hostID:    hostID,
+	}
+}
+
+// close はルームを終了状態にし、onClose を一度だけ呼び出す
+func (r *BattleRoyaleRoom) close() {
+	r.closeOnce.Do(func() {
+		close(r.closedCh)
+		r.onClose()
+	})
+}
+
+// connect は参加者の接続をルームに登録する
+// ロビーでは新しい参加者を受け付け、開始後は参加者の再接続のみ受け付ける
+// 同じユーザーが接続し直した場合は古い接続を打ち切って差し替える
+func (r *BattleRoyaleRoom) connect(conn *brConn, user *entity.User) (*brPlayer, error) {
+	select {
+	case <-r.closedCh:
+		return nil, errRoomClosed
+	default:
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range r.players {
+		if p.user.ID != user.ID {
+			continue
+		}
+		if p.conn != nil {
+			p.conn.interrupt()
+		}
+		p.conn = conn
+		return p, nil
+	}
+	if r.status != entity.BattleRoyaleStatusLobby {
+		return nil, ErrBattleRoyaleStarted
+	}
+	if len(r.players) >= entity.BattleRoyaleMaxPlayers {
+		return nil, ErrBattleRoyaleFull
+	}
+	p := &brPlayer{
+		conn: conn,
+		user: user,
+		standing: entity.BattleRoyaleStanding{
+			UserID:      user.ID,
+			GitHubLogin: user.GitHubLogin,
+			Order:       len(r.players),
+		},
+	}
+	r.players = append(r.players, p)
+	return p, nil
+}
+
+// serve は参加者の接続を読み取り、接続が閉じるまでブロックする
+func (r *BattleRoyaleRoom) serve(conn *brConn, p *brPlayer) {
+	readerDone := make(chan struct{})
+	go conn.writeLoop(readerDone, r.closedCh)
+	defer func() {
+		close(readerDone)
+		r.post(brEvent{kind: brEventLeave, conn: conn, player: p})
+	}()
+	r.post(brEvent{kind: brEventJoin, conn: conn, player: p})
+
+	for {
+		_, data, err := conn.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("battle royale %s: %s unexpected close: %v", r.id, p.user.GitHubLogin, err)
+			}
+			return
+		}
+		var raw struct {
+			Type    string          `json:"type"`
+			Payload json.RawMessage `json:"payload"`
+		}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			log.Printf("battle royale %s: %s invalid json: %v", r.id, p.user.GitHubLogin, err)
+			continue
+		}
+		r.post(brEvent{kind: brEventMessage, conn: conn, player: p, msgType: raw.Type, payload: raw.Payload})
+	}
+}
+
+// post は run goroutine にイベントを送る。ルームが終了していれば捨てる
+func (r *BattleRoyaleRoom) post(ev brEvent) {
+	select {
+	case r.eventCh <- ev:
+	case <-r.closedCh:
+	}
+}
+
+// start は作成者の操作でロビーを締め切り、ゲームを開始する
+func (r *BattleRoyaleRoom) start(userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if userID != r.hostID {
+		return ErrBattleRoyaleForbidden
+	}
+	if r.status != entity.BattleRoyaleStatusLobby {
+		return ErrBattleRoyaleStarted
+	}
+	if len(r.players) < entity.BattleRoyaleMinPlayers {
+		return ErrBattleRoyaleTooFew
+	}
+	r.status = entity.BattleRoyaleStatusGenerating
+	close(r.startCh)
+	return nil
+}
+
+// summary は現在の状態を返す
+func (r *BattleRoyaleRoom) summary() BattleRoyaleSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return BattleRoyaleSummary{
+		CreatedAt:  r.createdAt,
+		Status:     r.status,
+		Standings:  r.standingsLocked(),
+		ID:         r.id,
+		HostID:     r.hostID,
+		Round:      r.round,
+		Alive:      len(r.aliveLocked()),
+		MinPlayers: entity.BattleRoyaleMinPlayers,
+		MaxPlayers: entity.BattleRoyaleMaxPlayers,
+	}
+}
+
+// standingsLocked は順位表の並びの成績を返す（mu を保持して呼ぶ）
+func (r *BattleRoyaleRoom) standingsLocked() []entity.BattleRoyaleStanding {
+	standings := make([]entity.BattleRoyaleStanding, len(r.players))
+	for i, p := range r.players {
+		standings[i] = p.standing
+	}
+	entity.SortStandings(standings)
+	return standings
+}
+
+// aliveLocked は生存している参加者を参加順に返す（mu を保持して呼ぶ）
+func (r *BattleRoyaleRoom) aliveLocked() []*brPlayer {
+	alive := make([]*brPlayer, 0, len(r.players))
+	for _, p := range r.players {
+		if p.standing.EliminatedRound == 0 {
+			alive = append(alive, p)
+		}
+	}
+	return alive
+}
+
+func (r *BattleRoyaleRoom) alive() []*brPlayer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.aliveLocked()
+}
+
+func (r *BattleRoyaleRoom) setStatus(status entity.BattleRoyaleStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+// prepareWSMessage は送信用に msg を一度だけエンコードする
+func prepareWSMessage(msg WSMessage) *websocket.PreparedMessage {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("battle royale: marshal error: %v", err)
+		return nil
+	}
+	pm, err := websocket.NewPreparedMessage(websocket.TextMessage, data)
+	if err != nil {
+		log.Printf("battle royale: prepare error: %v", err)
+		return nil
+	}
+	return pm
+}
+
+// broadcast は接続中の全参加者（脱落者を含む）に同じメッセージを送る
+// エンコードは1回だけ行い、送信キューが詰まっている接続は打ち切る
+func (r *BattleRoyaleRoom) broadcast(msg WSMessage) {
+	pm := prepareWSMessage(msg)
+	if pm == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range r.players {
+		if p.conn == nil {
+			continue
+		}
+		if !p.conn.enqueue(pm) {
+			log.Printf("battle royale %s: send queue of %s is full, closing conn", r.id, p.user.GitHubLogin)
+			p.conn.interrupt()
+		}
+	}
+}
+
+// sendTo は1つの接続にメッセージを送る（再接続時の状態の送り直しに使う）
+func (r *BattleRoyaleRoom) sendTo(conn *brConn, msg WSMessage) {
+	if pm := prepareWSMessage(msg); pm != nil && !conn.enqueue(pm) {
+		conn.interrupt()
+	}
+}
+
+// lobbyMessage はロビーの参加者一覧を ev_br_lobby にする
+func (r *BattleRoyaleRoom) lobbyMessage() WSMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	logins := make([]string, len(r.players))
+	for i, p := range r.players {
+		logins[i] = p.user.GitHubLogin
+	}
+	return WSMessage{
+		Type: "ev_br_lobby",
+		Payload: map[string]any{
+			"battle_royale_id": r.id.String(),
+			"host_id":          r.hostID.String(),
+			"players":          logins,
+			"min_players":      entity.BattleRoyaleMinPlayers,
+			"max_players":      entity.BattleRoyaleMaxPlayers,
+		},
+	}
+}
+
+// run はロビーから最後の1人が決まるまでを進行する（goroutine で呼び出す）
+func (r *BattleRoyaleRoom) run(ctx context.Context) {
+	defer r.close()
+	if !r.waitStart(ctx) {
+		return
+	}
+	pool, ok := r.generatePool(ctx)
+	if !ok {
+		return
+	}
+	r.setStatus(entity.BattleRoyaleStatusInProgress)
+
+	for number := 1; len(r.alive()) > 1 && number <= len(pool); number++ {
+		if !r.playRound(ctx, number, pool[number-1]) {
+			return
+		}
+	}
+	r.finish()
+}
+
+// waitStart は作成者が開始するまでロビーの参加者の出入りを通知する
+// LobbyWaitLimit を過ぎた場合とサーバーの停止の開始時はルームを中止して false を返す
+func (r *BattleRoyaleRoom) waitStart(ctx context.Context) bool {
+	timer := time.NewTimer(r.rules.LobbyWaitLimit)
+	defer timer.Stop()
+	for {
+		select {
+		case <-r.startCh:
+			return true
+		case ev := <-r.eventCh:
+			switch ev.kind {
+			case brEventJoin:
+				r.broadcast(r.lobbyMessage())
+			case brEventLeave:
+				if r.removeFromLobby(ev) {
+					r.broadcast(r.lobbyMessage())
+				}
+			}
+		case <-timer.C:
+			log.Printf("battle royale %s: not started within %s", r.id, r.rules.LobbyWaitLimit)
+			r.abort("lobby_expired", "開始されないまま受付時間が過ぎました")
+			return false
+		case <-r.draining:
+			r.abort("server_shutdown", "サーバーの再起動のためバトルロイヤルを中止しました")
+			return false
+		case <-ctx.Done():
+			r.abort("server_shutdown", "サーバーの再起動のためバトルロイヤルを中止しました")
+			return false
+		}
+	}
+}
+
+// removeFromLobby は開始前に接続が閉じた参加者をロビーから外す
+// 開始済み、または接続が差し替え済みの場合は false を返す
+func (r *BattleRoyaleRoom) removeFromLobby(ev brEvent) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.status != entity.BattleRoyaleStatusLobby || ev.player.conn != ev.conn {
+		return false
+	}
+	for i, p := range r.players {
+		if p == ev.player {
+			r.players = append(r.players[:i], r.players[i+1:]...)
+			break
+		}
+	}
+	for i, p := range r.players {
+		p.standing.Order = i
+	}
+	return true
+}
+
+// handleConnEvent は開始後の接続・切断を処理する
+// 切断した参加者は生存したまま残り、回答しなかったラウンドは得点なしになる
+func (r *BattleRoyaleRoom) handleConnEvent(ev brEvent, round *brRound) {
+	switch ev.kind {
+	case brEventJoin:
+		log.Printf("battle royale %s: %s connected", r.id, ev.player.user.GitHubLogin)
+		r.sendTo(ev.conn, r.stateMessage(ev.player, round))
+	case brEventLeave:
+		r.mu.Lock()
+		if ev.player.conn == ev.conn {
+			ev.player.conn = nil
+		}
+		r.mu.Unlock()
+	}
+}
+
+// generatePool は全参加者のリポジトリから1人になるまでのラウンド数の問題を生成する
+// 失敗した場合はルームを中止して false を返す
+func (r *BattleRoyaleRoom) generatePool(ctx context.Context) ([]entity.Question, bool) {
+	r.mu.Lock()
+	logins := make([]string, len(r.players))
+	for i, p := range r.players {
+		logins[i] = p.user.GitHubLogin
+	}
+	r.mu.Unlock()
+	// 出題元の順番が参加順に偏らないよう混ぜる
+	rand.Shuffle(len(logins), func(i, j int) { logins[i], logins[j] = logins[j], logins[i] })
+	rounds := r.rules.Rounds(len(logins))
+	log.Printf("battle royale %s: started with %d players, generating %d questions", r.id, len(logins), rounds)
+	r.broadcast(WSMessage{
+		Type: "ev_br_starting",
+		Payload: map[string]any{
+			"players":        len(logins),
+			"max_rounds":     rounds,
+			"round_time_sec": int(r.rules.RoundDuration / time.Second),
+		},
+	})
+
+	genCtx, cancel := context.WithTimeout(ctx, r.rules.QuestionWaitLimit)
+	defer cancel()
+	type poolResult struct {
+		err  error
+		pool []entity.Question
+	}
+	resCh := make(chan poolResult, 1)
+	go func() {
+		if r.questions == nil {
+			resCh <- poolResult{err: errors.New("question generator is not configured")}
+			return
+		}
+		pool, err := r.questions.GeneratePool(genCtx, logins, rounds)
+		resCh <- poolResult{pool: pool, err: err}
+	}()
+
+	for {
+		select {
+		case res := <-resCh:
+			if res.err == nil {
+				return res.pool, true
+			}
+			log.Printf("battle royale %s: failed to generate questions: %v", r.id, res.err)
+			if errors.Is(res.err, context.DeadlineExceeded) {
+				r.abort("question_timeout", "問題の生成がタイムアウトしました")
+			} else {
+				r.abort("question_generation_failed", "問題の生成に失敗しました")
+			}
+			return nil, false
+		case ev := <-r.eventCh:
+			r.handleConnEvent(ev, nil)
+		case <-ctx.Done():
+			r.abort("server_shutdown", "サーバーの再起動のためバトルロイヤルを中止しました")
+			return nil, false
+		}
+	}
+}
+
+// playRound は1ラウンドを出題し、回答を集計して下位の参加者を脱落させる
+// サーバーの停止で打ち切った場合は false を返す
+func (r *BattleRoyaleRoom) playRound(ctx context.Context, number int, q entity.Question) bool {
+	now := time.Now()
+	round := &brRound{
+		startedAt: now,
+		deadline:  now.Add(r.rules.RoundDuration),
+		answers:   make(map[*brPlayer]entity.Answer),
+		elapsed:   make(map[*brPlayer]time.Duration),
+		question:  q,
+		number:    number,
+	}
+	r.mu.Lock()
+	r.round = number
+	r.mu.Unlock()
+	alive := r.alive()
+	r.broadcast(r.roundStartMessage(round, len(alive)))
+
+	timer := time.NewTimer(r.rules.RoundDuration)
+	defer timer.Stop()
+	for len(round.answers) < len(alive) {
+		select {
+		case <-timer.C:
+			log.Printf("battle royale %s: round %d timeout (%d/%d answered)", r.id, number, len(round.answers), len(alive))
+			return r.closeRound(round, alive)
+		case ev := <-r.eventCh:
+			if ev.kind != brEventMessage {
+				r.handleConnEvent(ev, round)
+				continue
+			}
+			if ev.msgType != "act_submit_answer" {
+				continue
+			}
+			if ev.player.standing.EliminatedRound > 0 {
+				continue // 脱落した参加者は観戦のみ
+			}
+			if _, ok := round.answers[ev.player]; ok {
+				continue // 二重回答は無視
+			}
+			var ap submitAnswerPayload
+			if err := json.Unmarshal(ev.payload, &ap); err != nil {
+				continue
+			}
+			if err := q.CheckAnswer(ap.Answer); err != nil {
+				r.sendTo(ev.conn, WSMessage{
+					Type: "ev_error",
+					Payload: map[string]any{
+						"code":          "invalid_answer",
+						"message":       "回答の形式が問題と一致しません",
+						"question_type": q.Kind(),
+					},
+				})
+				continue
+			}
+			round.answers[ev.player] = ap.Answer
+			round.elapsed[ev.player] = time.Since(round.startedAt)
+			r.sendTo(ev.conn, WSMessage{
+				Type:    "ev_br_answer_accepted",
+				Payload: map[string]any{"round": number, "answer": ap.Answer},
+			})
+		case <-ctx.Done():
+			r.abort("server_shutdown", "サーバーの再起動のためバトルロイヤルを中止しました")
+			return false
+		}
+	}
+	return r.closeRound(round, alive)
+}
+
+// closeRound はラウンドの回答を採点し、累計ヌーの下位の参加者を脱落させて結果を送る
+func (r *BattleRoyaleRoom) closeRound(round *brRound, alive []*brPlayer) bool {
+	q := round.question
+	results := make([]map[string]any, len(alive))
+	standings := make([]entity.BattleRoyaleStanding, len(alive))
+
+	r.mu.Lock()
+	for i, p := range alive {
+		answer, answered := round.answers[p]
+		isCorrect := answered && q.IsCorrect(answer)
+		var detail *entity.Answer
+		if answered {
+			detail = &answer
+		}
+		delta := 0
+		if isCorrect {
+			delta = r.rules.RoundGnu(round.elapsed[p])
+			p.standing.Gnu += delta
+			p.standing.CorrectCount++
+		}
+		results[i] = map[string]any{
+			"github_login": p.user.GitHubLogin,
+			"answer":       detail,
+			"is_correct":   isCorrect,
+			"gnu_delta":    delta,
+		}
+		standings[i] = p.standing
+	}
+
+	// 最下位から順に、脱落時点の生存者数を順位にする
+	eliminated := make([]string, 0, len(alive))
+	for k, i := range entity.SelectEliminated(standings, r.rules.EliminationCount(len(alive))) {
+		p := alive[i]
+		p.standing.EliminatedRound = round.number
+		p.standing.Rank = len(alive) - k
+		eliminated = append(eliminated, p.user.GitHubLogin)
+	}
+	remaining := len(alive) - len(eliminated)
+	ranking := r.standingsLocked()
+	r.mu.Unlock()
+
+	log.Printf("battle royale %s: round %d done, eliminated %v, %d remaining", r.id, round.number, eliminated, remaining)
+	r.broadcast(WSMessage{
+		Type: "ev_br_round_result",
+		Payload: map[string]any{
+			"round":           round.number,
+			"question_type":   q.Kind(),
+			"correct_answer":  q.CorrectAnswer,
+			"correct_answers": q.CorrectAnswers,
+			"correct_index":   q.CorrectIndex(),
+			"correct_indexes": q.CorrectIndexes(),
+			"tips":            q.Tips,
+			"results":         results,
+			"eliminated":      eliminated,
+			"alive":           remaining,
+			"standings":       ranking,
+		},
+	})
+	return true
+}
+
+// finish は最後に残った参加者を優勝者として結果を送る
+// 問題が尽きて複数人が残った場合は、その時点の成績の順に順位を確定する
+func (r *BattleRoyaleRoom) finish() {
+	r.mu.Lock()
+	alive := r.aliveLocked()
+	standings := make([]entity.BattleRoyaleStanding, len(alive))
+	for i, p := range alive {
+		standings[i] = p.standing
+	}
+	order := entity.SelectEliminated(standings, len(standings))
+	for k, i := range order {
+		alive[i].standing.Rank = len(alive) - k
+	}
+	var winner *brPlayer
+	if len(order) > 0 {
+		winner = alive[order[len(order)-1]]
+	}
+	ranking := r.standingsLocked()
+	r.status = entity.BattleRoyaleStatusFinished
+	r.mu.Unlock()
+
+	payload := map[string]any{
+		"rounds":    r.round,
+		"standings": ranking,
+	}
+	if winner != nil {
+		payload["winner"] = winner.standing
+		log.Printf("battle royale %s: finished after %d rounds, winner %s", r.id, r.round, winner.user.GitHubLogin)
+	}
+	r.broadcast(WSMessage{Type: "ev_br_end", Payload: payload})
+}
+
+// abort はルームを中止し、接続中の全参加者にエラーを送る
+func (r *BattleRoyaleRoom) abort(code, message string) {
+	r.setStatus(entity.BattleRoyaleStatusAborted)
+	r.broadcast(WSMessage{
+		Type: "ev_error",
+		Payload: map[string]any{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
+
+// roundStartMessage はラウンドの出題を ev_br_round_start にする（正解は含まない）
+func (r *BattleRoyaleRoom) roundStartMessage(round *brRound, alive int) WSMessage {
+	remaining := max(time.Until(round.deadline), 0)
+	return WSMessage{
+		Type: "ev_br_round_start",
+		Payload: map[string]any{
+			"round":          round.number,
+			"alive":          alive,
+			"question_type":  round.question.Kind(),
+			"difficulty":     round.question.Difficulty,
+			"question_text":  round.question.QuestionText,
+			"choices":        round.question.Choices,
+			"time_limit_sec": int((remaining + time.Second - 1) / time.Second),
+		},
+	}
+}
+
+// stateMessage は再接続した参加者に送る現在の状態を ev_br_state にする
+// ラウンドの進行中であれば出題と自分の回答の有無を添える
+func (r *BattleRoyaleRoom) stateMessage(p *brPlayer, round *brRound) WSMessage {
+	r.mu.Lock()
+	alive := len(r.aliveLocked())
+	payload := map[string]any{
+		"status":    r.status,
+		"round":     r.round,
+		"alive":     alive,
+		"you":       p.standing,
+		"standings": r.standingsLocked(),
+	}
+	r.mu.Unlock()
+	if round != nil {
+		_, answered := round.answers[p]
+		payload["current_round"] = r.roundStartMessage(round, alive).Payload
+		payload["answered"] = answered
+	}
+	return WSMessage{Type: "ev_br_state", Payload: payload}
+}
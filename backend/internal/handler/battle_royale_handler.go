@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+)
+
+// BattleRoyaleHandler はバトルロイヤルの REST API と WebSocket エンドポイントのハンドラ
+type BattleRoyaleHandler struct {
+	manager  *BattleRoyaleManager
+	userRepo repository.UserRepository
+}
+
+func NewBattleRoyaleHandler(manager *BattleRoyaleManager, userRepo repository.UserRepository) *BattleRoyaleHandler {
+	return &BattleRoyaleHandler{manager: manager, userRepo: userRepo}
+}
+
+// CreateBattleRoyale は POST /api/v1/battle-royales を処理する
+// ログインユーザーが主催するロビーを作成する。作成者も参加する場合は WebSocket で接続する
+func (h *BattleRoyaleHandler) CreateBattleRoyale(c echo.Context) error {
+	identity, ok := authenticatedIdentity(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+	}
+	user, err := getOrCreateUser(c.Request().Context(), h.userRepo, identity)
+	if err != nil {
+		log.Printf("battle royale: failed to get or create user %s: %v", identity.Login, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+
+	room, err := h.manager.Create(user.ID)
+	if err != nil {
+		return h.battleRoyaleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, room.summary())
+}
+
+// GetBattleRoyale は GET /api/v1/battle-royales/:id を処理する
+// 状態と順位表を返す。終了したバトルロイヤルは参照できない
+func (h *BattleRoyaleHandler) GetBattleRoyale(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid battle royale id"})
+	}
+	room := h.manager.Get(id)
+	if room == nil {
+		return h.battleRoyaleError(c, ErrBattleRoyaleNotFound)
+	}
+	return c.JSON(http.StatusOK, room.summary())
+}
+
+// StartBattleRoyale は POST /api/v1/battle-royales/:id/start を処理する
+// ロビーを締め切って問題の生成を始める。開始できるのは作成者のみ
+func (h *BattleRoyaleHandler) StartBattleRoyale(c echo.Context) error {
+	identity, ok := authenticatedIdentity(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid battle royale id"})
+	}
+	user, err := getOrCreateUser(c.Request().Context(), h.userRepo, identity)
+	if err != nil {
+		log.Printf("battle royale: failed to get or create user %s: %v", identity.Login, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+	room := h.manager.Get(id)
+	if room == nil {
+		return h.battleRoyaleError(c, ErrBattleRoyaleNotFound)
+	}
+	if err := room.start(user.ID); err != nil {
+		return h.battleRoyaleError(c, err)
+	}
+	return c.JSON(http.StatusOK, room.summary())
+}
+
+// HandleBattleRoyale は ws://{host}/ws/battle-royale/:battle_royale_id を処理する
+// 開始前はロビーに参加し、開始後は参加者の再接続のみ受け付ける
+// 脱落した参加者も接続したまま最後まで進行を受け取る
+func (h *BattleRoyaleHandler) HandleBattleRoyale(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("battle_royale_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid battle_royale_id")
+	}
+	identity, ok := authenticatedIdentity(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	room := h.manager.Get(id)
+	if room == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "battle royale not found")
+	}
+	user, err := getOrCreateUser(c.Request().Context(), h.userRepo, identity)
+	if err != nil {
+		log.Printf("battle royale %s: failed to get or create user %s: %v", id, identity.Login, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get or create user")
+	}
+
+	ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := ws.Close(); closeErr != nil {
+			log.Printf("battle royale %s: ws close error: %v", id, closeErr)
+		}
+	}()
+
+	conn := newBRConn(ws)
+	p, err := room.connect(conn, user)
+	if err != nil {
+		code, message := "join_failed", "バトルロイヤルへの参加に失敗しました"
+		switch {
+		case errors.Is(err, ErrBattleRoyaleStarted):
+			code, message = "battle_royale_started", "バトルロイヤルは開始済みです"
+		case errors.Is(err, ErrBattleRoyaleFull):
+			code, message = "battle_royale_full", "参加者が上限に達しています"
+		case errors.Is(err, errRoomClosed):
+			code, message = "room_closed", "バトルロイヤルは終了しました"
+		}
+		sendWSMessage(ws, WSMessage{
+			Type: "ev_error",
+			Payload: map[string]any{
+				"code":    code,
+				"message": message,
+			},
+		})
+		return nil
+	}
+
+	log.Printf("battle royale %s: %s connected", id, user.GitHubLogin)
+	room.serve(conn, p)
+	log.Printf("battle royale %s: %s disconnected", id, user.GitHubLogin)
+	return nil
+}
+
+// battleRoyaleError はバトルロイヤルの操作の失敗をレスポンスに変換する
+func (h *BattleRoyaleHandler) battleRoyaleError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, ErrBattleRoyaleNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "battle royale not found"})
+	case errors.Is(err, ErrBattleRoyaleForbidden):
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "only the host can start the battle royale"})
+	case errors.Is(err, ErrBattleRoyaleStarted):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "battle royale already started"})
+	case errors.Is(err, ErrBattleRoyaleTooFew):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "battle royale needs at least 8 players"})
+	case errors.Is(err, ErrServerDraining):
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "server is draining"})
+	}
+	log.Printf("battle royale: failed to handle battle royale %q: %v", c.Param("id"), err)
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/usecase"
+)
+
+// BattleRoyaleManager は稼働中のバトルロイヤルのレジストリ
+// バトルロイヤルは作成したインスタンスのメモリ上で進行し、インスタンス間の中継には対応しない
+type BattleRoyaleManager struct {
+	rooms     map[uuid.UUID]*BattleRoyaleRoom
+	questions *usecase.QuestionUsecase
+	// runCtx はルームに渡すコンテキスト。Drain の期限切れで cancelRun が呼ばれる
+	runCtx    context.Context
+	cancelRun context.CancelFunc
+	drainCh   chan struct{} // Drain の開始時に close される
+	rules     entity.BattleRoyaleRules
+	mu        sync.RWMutex
+	drainOnce sync.Once
+	draining  atomic.Bool // true の間は新しいルームを作らない
+}
+
+func NewBattleRoyaleManager(questions *usecase.QuestionUsecase, rules entity.BattleRoyaleRules) *BattleRoyaleManager {
+	runCtx, cancelRun := context.WithCancel(context.Background())
+	return &BattleRoyaleManager{
+		rooms:     make(map[uuid.UUID]*BattleRoyaleRoom),
+		questions: questions,
+		runCtx:    runCtx,
+		cancelRun: cancelRun,
+		drainCh:   make(chan struct{}),
+		rules:     rules,
+	}
+}
+
+// Create は hostID が主催するロビーを作成し、進行を始める
+// Drain 中は ErrServerDraining で拒否する
+func (m *BattleRoyaleManager) Create(hostID uuid.UUID) (*BattleRoyaleRoom, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.draining.Load() {
+		return nil, ErrServerDraining
+	}
+	id := uuid.New()
+	room := newBattleRoyaleRoom(id, hostID, m.rules, m.questions, m.drainCh, func() {
+		m.remove(id)
+		log.Printf("battle royale manager: removed %s", id)
+	})
+	m.rooms[id] = room
+	go room.run(m.runCtx)
+	log.Printf("battle royale manager: created %s", id)
+	return room, nil
+}
+
+// Get は稼働中のバトルロイヤルを返す。存在しない場合は nil
+func (m *BattleRoyaleManager) Get(id uuid.UUID) *BattleRoyaleRoom {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.rooms[id]
+}
+
+func (m *BattleRoyaleManager) remove(id uuid.UUID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.rooms, id)
+}
+
+func (m *BattleRoyaleManager) count() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.rooms)
+}
+
+// Drain は新しいバトルロイヤルの作成を止めて開始前のロビーを中止し、進行中のものが終わるのを ctx が終わるまで待つ
+// 期限までに終わらなかったものは中止する（試合中のヌーは残高に反映しないため精算はない）
+func (m *BattleRoyaleManager) Drain(ctx context.Context) {
+	m.draining.Store(true)
+	m.drainOnce.Do(func() { close(m.drainCh) })
+	log.Printf("battle royale manager: draining %d rooms", m.count())
+
+	if !m.waitRooms(ctx) {
+		log.Printf("battle royale manager: drain deadline exceeded, stopping %d rooms", m.count())
+		m.cancelRun()
+		abortCtx, cancel := context.WithTimeout(context.Background(), drainAbortGrace)
+		defer cancel()
+		if !m.waitRooms(abortCtx) {
+			log.Printf("battle royale manager: %d rooms did not stop in time", m.count())
+		}
+	}
+	log.Println("battle royale manager: drained")
+}
+
+// waitRooms は稼働中のルームがなくなるまで待つ。ctx が先に終わった場合は false を返す
+func (m *BattleRoyaleManager) waitRooms(ctx context.Context) bool {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for m.count() > 0 {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+)
+
+func newTestBattleRoyaleRoom(hostID uuid.UUID) *BattleRoyaleRoom {
+	return newBattleRoyaleRoom(uuid.New(), hostID, entity.DefaultBattleRoyaleRules(), nil, make(chan struct{}), func() {})
+}
+
+// fillBattleRoyaleLobby は n 人をロビーに参加させ、参加したユーザーを返す
+func fillBattleRoyaleLobby(t *testing.T, room *BattleRoyaleRoom, n int) []*entity.User {
+	t.Helper()
+	users := make([]*entity.User, n)
+	for i := range users {
+		users[i] = &entity.User{ID: uuid.New()}
+		_, err := room.connect(newBRConn(&websocket.Conn{}), users[i])
+		require.NoError(t, err)
+	}
+	return users
+}
+
+func TestBattleRoyaleRoom_Start(t *testing.T) {
+	hostID := uuid.New()
+	room := newTestBattleRoyaleRoom(hostID)
+	fillBattleRoyaleLobby(t, room, entity.BattleRoyaleMinPlayers-1)
+
+	assert.ErrorIs(t, room.start(hostID), ErrBattleRoyaleTooFew)
+
+	fillBattleRoyaleLobby(t, room, 1)
+	assert.ErrorIs(t, room.start(uuid.New()), ErrBattleRoyaleForbidden)
+	require.NoError(t, room.start(hostID))
+	assert.Equal(t, entity.BattleRoyaleStatusGenerating, room.summary().Status)
+	assert.ErrorIs(t, room.start(hostID), ErrBattleRoyaleStarted)
+}
+
+func TestBattleRoyaleRoom_Connect_RejectsWhenFull(t *testing.T) {
+	room := newTestBattleRoyaleRoom(uuid.New())
+	fillBattleRoyaleLobby(t, room, entity.BattleRoyaleMaxPlayers)
+
+	_, err := room.connect(newBRConn(&websocket.Conn{}), &entity.User{ID: uuid.New()})
+
+	assert.ErrorIs(t, err, ErrBattleRoyaleFull)
+}
+
+func TestBattleRoyaleRoom_Connect_OnlyReconnectsAfterStart(t *testing.T) {
+	hostID := uuid.New()
+	room := newTestBattleRoyaleRoom(hostID)
+	users := fillBattleRoyaleLobby(t, room, entity.BattleRoyaleMinPlayers)
+	require.NoError(t, room.start(hostID))
+
+	_, err := room.connect(newBRConn(&websocket.Conn{}), &entity.User{ID: uuid.New()})
+	assert.ErrorIs(t, err, ErrBattleRoyaleStarted)
+
+	// 切断した参加者は開始後も接続し直せる
+	room.mu.Lock()
+	room.players[3].conn = nil
+	room.mu.Unlock()
+	conn := newBRConn(&websocket.Conn{})
+	p, err := room.connect(conn, users[3])
+	require.NoError(t, err)
+	assert.Same(t, conn, p.conn)
+	assert.Equal(t, 3, p.standing.Order)
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"math/rand/v2"
 	"net/http"
+	"net/url"
 	"os"
 	"time"
 
@@ -13,53 +14,12 @@ import (
 	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
 )
 
-// botQuestions は Bot が送信するダミー問題セット（5問 × 2セット分）
-var botQuestions = []entity.Question{
-	{
-		Difficulty:    "easy",
-		QuestionText:  "Go 言語で変数を宣言するキーワードはどれ？",
-		CorrectAnswer: "var",
-		Tips:          "var キーワードを使うと型推論なしで変数宣言できます。",
-		Choices:       []string{"var", "let", "val", "dim"},
-	},
-	{
-		Difficulty:    "hard",
-		QuestionText:  "Go の goroutine 間でデータを安全に共有するための推奨手段はどれ？",
-		CorrectAnswer: "channel",
-		Tips:          "\"Do not communicate by sharing memory; instead, share memory by communicating.\"",
-		Choices:       []string{"channel", "mutex のみ", "グローバル変数", "atomic だけ"},
-	},
-	{
-		Difficulty:    "easy",
-		QuestionText:  "HTTP ステータスコード 404 が示すものは？",
-		CorrectAnswer: "Not Found",
-		Tips:          "404 はリソースが見つからないことを示します。",
-		Choices:       []string{"Not Found", "Internal Server Error", "Unauthorized", "Bad Request"},
-	},
-	{
-		Difficulty:    "normal",
-		QuestionText:  "Git で直前のコミットメッセージを修正するコマンドはどれ？",
-		CorrectAnswer: "git commit --amend",
-		Tips:          "--amend は直前のコミットを上書き修正します。push 済みの場合は force push が必要です。",
-		Choices:       []string{"git commit --amend", "git rebase -i", "git reset HEAD~1", "git revert HEAD"},
-	},
-	{
-		Difficulty:    "normal",
-		QuestionText:  "RESTful API でリソースの一部更新に使う HTTP メソッドはどれ？",
-		CorrectAnswer: "PATCH",
-		Tips:          "PATCH はリソースの部分更新、PUT はリソース全体の置換に使います。",
-		Choices:       []string{"PATCH", "PUT", "POST", "UPDATE"},
-	},
-}
-
 // RunBotPlayer は指定ルームに Bot として接続し、自動でゲームをプレイする
 // serverAddr: "localhost:8080" 形式
-func RunBotPlayer(serverAddr string, roomID uuid.UUID, botUser *entity.User) {
-	wsURL := "ws://" + serverAddr + "/ws/room/" + roomID.String() +
-		"?github_login=" + botUser.GitHubLogin +
-		"&github_id=999999999"
+func RunBotPlayer(serverAddr string, roomID uuid.UUID, ticket string) {
+	wsURL := "ws://" + serverAddr + "/ws/room/" + roomID.String() + "?ticket=" + url.QueryEscape(ticket)
 
-	log.Printf("bot: connecting to %s", wsURL)
+	log.Printf("bot: connecting to room %s", roomID)
 
 	// 少し待ってから接続（人間プレイヤーが先に接続するための猶予）
 	time.Sleep(500 * time.Millisecond)
@@ -106,23 +66,12 @@ func RunBotPlayer(serverAddr string, roomID uuid.UUID, botUser *entity.User) {
 		log.Printf("bot: received %s", msg.Type)
 
 		switch msg.Type {
-		case "ev_room_ready":
-			// 問題を送信
-			time.Sleep(300 * time.Millisecond)
-			sendBotMessage(conn, map[string]any{
-				"type": "act_submit_questions",
-				"payload": map[string]any{
-					"my_questions": botQuestions[:5],
-					"for_opponent": botQuestions[:5],
-				},
-			})
-			log.Printf("bot: submitted questions")
-
 		case "ev_turn_start":
 			var payload struct {
-				Choices      []string `json:"choices"`
-				MaxBet       int      `json:"max_bet"`
-				TimeLimitSec int      `json:"time_limit_sec"`
+				QuestionType entity.QuestionType `json:"question_type"`
+				Choices      []string            `json:"choices"`
+				MaxBet       int                 `json:"max_bet"`
+				TimeLimitSec int                 `json:"time_limit_sec"`
 			}
 			if err := json.Unmarshal(msg.Payload, &payload); err != nil {
 				continue
@@ -143,16 +92,13 @@ func RunBotPlayer(serverAddr string, roomID uuid.UUID, botUser *entity.User) {
 			thinkMs := 2000 + rand.IntN(8000)
 			time.Sleep(time.Duration(thinkMs) * time.Millisecond)
 
-			// ランダムに回答（約50%の正解率）
-			choiceIdx := rand.IntN(len(payload.Choices))
+			// 問題の形式に合わせてランダムに回答する
+			answer := randomBotAnswer(payload.QuestionType, len(payload.Choices))
 			sendBotMessage(conn, map[string]any{
-				"type": "act_submit_answer",
-				"payload": map[string]any{
-					"choice_index": choiceIdx,
-					"time_ms":      thinkMs,
-				},
+				"type":    "act_submit_answer",
+				"payload": submitAnswerPayload{Answer: answer, TimeMs: thinkMs},
 			})
-			log.Printf("bot: answered choice %d", choiceIdx)
+			log.Printf("bot: answered %s question", payload.QuestionType)
 
 		case "ev_game_end", "ev_tko":
 			log.Printf("bot: game finished")
@@ -181,3 +127,18 @@ func sendBotMessage(conn *websocket.Conn, v any) {
 		log.Printf("bot: write error: %v", err)
 	}
 }
+
+// randomBotAnswer は問題の形式に合ったランダムな回答を返す
+func randomBotAnswer(questionType entity.QuestionType, numChoices int) entity.Answer {
+	switch questionType {
+	case entity.QuestionTypeMultiSelect:
+		idxs := rand.Perm(numChoices)
+		return entity.Answer{ChoiceIndexes: idxs[:1+rand.IntN(numChoices)]}
+	case entity.QuestionTypeOrdering:
+		return entity.Answer{Order: rand.Perm(numChoices)}
+	case entity.QuestionTypeFreeText:
+		return entity.Answer{Text: "わかりません"}
+	default:
+		return entity.ChoiceAnswer(rand.IntN(numChoices))
+	}
+}
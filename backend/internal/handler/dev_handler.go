@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"log"
@@ -17,10 +18,16 @@ type DevHandler struct {
 	userRepo      repository.UserRepository
 	matchmakingUC *usecase.MatchmakingUsecase
 	hub           *Hub
+	auth          *GitHubAuthenticator
 	serverAddr    string
 }
 
-func NewDevHandler(userRepo repository.UserRepository, matchmakingUC *usecase.MatchmakingUsecase, hub *Hub) *DevHandler {
+func NewDevHandler(
+	userRepo repository.UserRepository,
+	matchmakingUC *usecase.MatchmakingUsecase,
+	hub *Hub,
+	auth *GitHubAuthenticator,
+) *DevHandler {
 	addr := os.Getenv("BOT_SERVER_ADDR")
 	if addr == "" {
 		addr = "localhost:8080"
@@ -29,6 +36,7 @@ func NewDevHandler(userRepo repository.UserRepository, matchmakingUC *usecase.Ma
 		userRepo:      userRepo,
 		matchmakingUC: matchmakingUC,
 		hub:           hub,
+		auth:          auth,
 		serverAddr:    addr,
 	}
 }
@@ -68,7 +76,7 @@ func (h *DevHandler) EnqueueTestUser(c echo.Context) error {
 	}
 
 	ctx := c.Request().Context()
-	if err := h.matchmakingUC.JoinQueue(ctx, user.ID); err != nil {
+	if err := h.matchmakingUC.JoinQueue(ctx, user, 1); err != nil {
 		if errors.Is(err, usecase.ErrAlreadyInQueue) {
 			return c.JSON(http.StatusConflict, map[string]string{
 				"message": "test-bot is already in queue",
@@ -100,7 +108,7 @@ func (h *DevHandler) StartBotMatch(c echo.Context) error {
 	matchCh := make(chan *usecase.MatchmakingResult, 1)
 	h.hub.SubscribeMatch(user.ID, matchCh)
 
-	if err := h.matchmakingUC.JoinQueue(ctx, user.ID); err != nil {
+	if err := h.matchmakingUC.JoinQueue(ctx, user, 1); err != nil {
 		h.hub.UnsubscribeMatch(user.ID)
 		if errors.Is(err, usecase.ErrAlreadyInQueue) {
 			return c.JSON(http.StatusConflict, map[string]string{
@@ -123,7 +131,16 @@ func (h *DevHandler) StartBotMatch(c echo.Context) error {
 			return
 		}
 		log.Printf("dev: bot matched! room=%s", result.Room.ID)
-		go RunBotPlayer(serverAddr, result.Room.ID, user)
+		// Bot は GitHub トークンを持たないため、サーバー側でチケットを発行して接続させる
+		ticket, err := h.auth.issueTicket(context.Background(), entity.GitHubIdentity{
+			Login: user.GitHubLogin,
+			ID:    user.GitHubID,
+		})
+		if err != nil {
+			log.Printf("dev: failed to issue ws ticket for bot: %v", err)
+			return
+		}
+		go RunBotPlayer(serverAddr, result.Room.ID, ticket)
 	}()
 
 	return c.JSON(http.StatusOK, map[string]string{
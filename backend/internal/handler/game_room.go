@@ -3,8 +3,10 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"slices"
 	"sync"
 	"time"
 
@@ -12,18 +14,21 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
 	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/usecase"
 )
 
-const (
-	turnDuration      = 15 * time.Second
-	questionWaitLimit = 180 * time.Second // 問題生成（Gemini×2回）に最大3分
-	tkoBonus          = 300
-	minBet            = 0 // ベット額の最小値（0 = ノーリスク）
+const reconnectGrace = 20 * time.Second // 切断から TKO 判定までの再接続猶予
+
+var (
+	// errRoomClosed は終了済みのルームへの参加を示す
+	errRoomClosed = errors.New("room is closed")
+	// errRoomFull は全員揃ったルームへの新規参加を示す
+	errRoomFull = errors.New("room is full")
 )
 
-// QuestionSet はフロントエンドが送信する問題セット
-// MyQuestions[0-4]: 相手のリポジトリから生成 (自分が解く 5問)
-// ForOpponent[0-4]: 自分のリポジトリから生成 (相手が解く 5問)
+// QuestionSet はプレイヤーごとの問題セット（サーバーが生成する）
+// MyQuestions: 相手チームのプレイヤーのリポジトリから生成 (自分が解く MatchRules.QuestionsPerSide 問)
+// ForOpponent: 自分のリポジトリから生成 (相手チームのプレイヤーが解く MatchRules.QuestionsPerSide 問)
 type QuestionSet struct {
 	MyQuestions []entity.Question `json:"my_questions"`
 	ForOpponent []entity.Question `json:"for_opponent"`
@@ -42,98 +47,449 @@ type betPayload struct {
 }
 
 // submitAnswerPayload は act_submit_answer のペイロード
+// 回答は問題の形式に応じて choice_index / choice_indexes / order / text のいずれかで送る
 type submitAnswerPayload struct {
-	ChoiceIndex int `json:"choice_index"`
-	TimeMs      int `json:"time_ms"`
+	entity.Answer
+	TimeMs int `json:"time_ms"`
 }
 
-// submitQuestionsPayload は act_submit_questions のペイロード
-type submitQuestionsPayload struct {
-	MyQuestions []entity.Question `json:"my_questions"`
-	ForOpponent []entity.Question `json:"for_opponent"`
+// questionsResult は問題生成 goroutine の結果
+type questionsResult struct {
+	err  error
+	sets []*QuestionSet
+}
+
+// playerConn はプレイヤーとの接続
+// 同じインスタンスで受けた *websocket.Conn と、他のインスタンスから中継される relayConn がある
+type playerConn interface {
+	ReadMessage() (int, []byte, error)
+	WriteMessage(messageType int, data []byte) error
+	// SetReadDeadline に現在時刻を渡すと読み取りを打ち切る（再接続で差し替えた古い接続に使う）
+	SetReadDeadline(t time.Time) error
+	// SetWriteDeadline は遅い接続への書き込みを打ち切る（送信キューを持つ観戦者・中継の接続に使う）
+	SetWriteDeadline(t time.Time) error
+}
+
+// playerConnEvent は読み取りループ終了時に送られる切断イベント
+// conn が現在の接続と異なる場合は、再接続で差し替え済みの古い接続からのイベント
+type playerConnEvent struct {
+	conn playerConn
+	idx  int
+}
+
+// turnState は進行中ターンの状態（再接続時のリプレイに使う）
+// スライスはプレイヤーインデックス順
+type turnState struct {
+	startedAt  time.Time
+	deadline   time.Time
+	answeredAt []time.Time
+	questions  []entity.Question
+	bets       []int
+	answers    []entity.Answer
+	answered   []bool
+	turn       int
+}
+
+// newTurnState は全員が未回答の turnState を作る
+func newTurnState(turn int, questions []entity.Question, now time.Time, duration time.Duration) *turnState {
+	n := len(questions)
+	return &turnState{
+		turn:       turn,
+		questions:  questions,
+		answers:    make([]entity.Answer, n),
+		bets:       make([]int, n),
+		answered:   make([]bool, n),
+		answeredAt: make([]time.Time, n),
+		startedAt:  now,
+		deadline:   now.Add(duration),
+	}
+}
+
+// answerIndex は i 番目のプレイヤーが選んだ選択肢のインデックスを返す
+// 未回答（タイムアウト）と、選択肢を1つ選ぶ形式ではない問題は -1
+func (ts *turnState) answerIndex(i int) int {
+	switch ts.questions[i].Kind() {
+	case entity.QuestionTypeSingleChoice, entity.QuestionTypeTrueFalse:
+		if ts.answered[i] && ts.answers[i].ChoiceIndex != nil {
+			return *ts.answers[i].ChoiceIndex
+		}
+	}
+	return -1
+}
+
+// answerDetail は i 番目のプレイヤーの回答を返す（未回答なら nil）
+func (ts *turnState) answerDetail(i int) *entity.Answer {
+	if !ts.answered[i] {
+		return nil
+	}
+	a := ts.answers[i]
+	return &a
 }
 
 // gamePlayerState はプレイヤーごとのゲーム状態
 type gamePlayerState struct {
 	user       *entity.User
-	conn       *websocket.Conn
+	conn       playerConn // writeMu と GameRoom.mu の両方で保護される
 	questions  *QuestionSet
-	doneCh     chan struct{} // 読み取りループ終了時に close される
+	events     *matchEventLog // 送ったイベントの記録先（ルームと共有する）
+	doneCh     chan struct{}  // 読み取りループ終了時に close される
 	writeMu    sync.Mutex
 	gnuBalance int
+	team       int  // 所属チーム（0 または 1）
+	connected  bool // GameRoom.mu で保護される
+	left       bool // 再接続猶予が切れて試合から抜けた（run goroutine のみが操作する）
 }
 
 func (p *gamePlayerState) send(msg WSMessage) {
 	p.writeMu.Lock()
 	defer p.writeMu.Unlock()
+	if p.conn == nil {
+		return // 復旧したルームでまだ再接続していない
+	}
 	data, err := json.Marshal(msg)
 	if err != nil {
 		log.Printf("game: marshal error: %v", err)
 		return
 	}
+	p.events.record(p.user.ID, msg)
 	if err := p.conn.WriteMessage(websocket.TextMessage, data); err != nil {
 		log.Printf("game: write error to %s: %v", p.user.GitHubLogin, err)
 	}
 }
 
+// gameRoomDeps は GameRoom が利用する外部依存
+type gameRoomDeps struct {
+	roomRepo    repository.RoomRepository      // nil の場合ルームの状態を記録しない
+	gnuLedger   repository.GnuLedgerRepository // nil の場合ヌーを精算しない
+	matchRepo   repository.MatchRepository
+	rating      *usecase.RatingUsecase      // nil の場合レーティングを更新しない
+	leaderboard *usecase.LeaderboardUsecase // nil の場合ランキングを更新しない
+	stats       *usecase.UserStatsUsecase   // nil の場合成績のキャッシュを削除しない
+	tournaments *usecase.TournamentUsecase  // nil の場合トーナメントに結果を反映しない
+	questions   *usecase.QuestionUsecase
+	// maxSpectators はルームあたりの観戦者数の上限（0 の場合は観戦不可）
+	maxSpectators int
+}
+
 // GameRoom は1試合のゲームルーム
+// プレイヤーは2チームに分かれて対戦する（1対1では1チーム1人）。players は参加順で、チームは members から決まる
 type GameRoom struct {
-	userRepo  repository.UserRepository
-	players   [2]*gamePlayerState
-	startCh   chan struct{} // 両プレイヤーが揃った時に close される
-	msgCh     chan playerMsg
-	disconnCh chan int // 切断したプレイヤーのインデックス
-	onClose   func()   // ルーム終了時に一度だけ呼ばれるコールバック
-	id        uuid.UUID
-	mu        sync.Mutex
-	closeOnce sync.Once
-	joined    int
-}
-
-func newGameRoom(id uuid.UUID, userRepo repository.UserRepository, onClose func()) *GameRoom {
+	gameRoomDeps
+	startedAt   time.Time
+	specTurnEnd time.Time     // specTurn のターンの回答期限（specMu で保護される）
+	graceTimers []*time.Timer // 再接続待ちのタイマー（run goroutine のみが操作する）
+	players     []*gamePlayerState
+	members     []entity.RoomPlayer  // ルームの参加者と所属チーム（空の場合は参加順に交互に振り分ける）
+	resume      *entity.RoomSnapshot // 復旧したルームの元になったスナップショット（新規のルームでは nil）
+	events      *matchEventLog       // プレイヤーに送ったイベントの記録（リプレイ用）
+	startCh     chan struct{}        // 全プレイヤーが揃った時に close される
+	closedCh    chan struct{}        // run 終了時に close される
+	msgCh       chan playerMsg
+	disconnCh   chan playerConnEvent
+	reconnCh    chan int                // 再接続したプレイヤーのインデックス
+	graceCh     chan int                // 再接続猶予が切れたプレイヤーのインデックス
+	onClose     func()                  // ルーム終了時に一度だけ呼ばれるコールバック
+	spectators  map[*spectator]struct{} // specMu で保護される
+	specTurn    *WSMessage              // 進行中のターンの観戦者向け ev_turn_start（ターンの合間は nil。specMu で保護される）
+	turnRecords []entity.MatchTurn      // 完了したターンの記録（run goroutine のみが操作する）
+	// 試合中の集計（run goroutine のみが操作する）
+	correctCounts []int
+	correctPoints []int // 難易度の倍率で重み付けした正解数（1 倍の正解で 100）
+	gnuEarned     []int
+	rules         entity.MatchRules // 作成時に確定するルール（試合中は変更しない）
+	id            uuid.UUID
+	mu            sync.Mutex
+	specMu        sync.Mutex
+	closeOnce     sync.Once
+	checkpointed  bool // 最新の状態を保存できているか（run goroutine のみが操作する）
+	joined        int
+	winnerTeam    int // 勝利チーム（引き分け・勝敗がついていない場合は -1。run goroutine のみが操作する）
+}
+
+// newGameRoom は members が参加するルームを作る
+// members が空の場合は2人のルームとし、参加順に交互にチームを振り分ける
+func newGameRoom(
+	id uuid.UUID,
+	members []entity.RoomPlayer,
+	rules entity.MatchRules,
+	deps gameRoomDeps,
+	onClose func(),
+) *GameRoom {
+	n := max(len(members), 2)
 	return &GameRoom{
-		id:        id,
-		userRepo:  userRepo,
-		startCh:   make(chan struct{}),
-		msgCh:     make(chan playerMsg, 32),
-		disconnCh: make(chan int, 2),
-		onClose:   onClose,
+		gameRoomDeps:  deps,
+		rules:         rules,
+		id:            id,
+		members:       members,
+		players:       make([]*gamePlayerState, n),
+		graceTimers:   make([]*time.Timer, n),
+		correctCounts: make([]int, n),
+		correctPoints: make([]int, n),
+		gnuEarned:     make([]int, n),
+		startCh:       make(chan struct{}),
+		closedCh:      make(chan struct{}),
+		msgCh:         make(chan playerMsg, 32),
+		disconnCh:     make(chan playerConnEvent, n),
+		reconnCh:      make(chan int, n),
+		graceCh:       make(chan int, n),
+		onClose:       onClose,
+		spectators:    make(map[*spectator]struct{}),
+		events:        &matchEventLog{roomID: id},
+		winnerTeam:    -1,
 	}
 }
 
+// newRecoveredGameRoom はスナップショットから試合の途中のルームを復元する
+// プレイヤーは未接続の状態で、全員が再接続すると保存した次のターンから再開する
+func newRecoveredGameRoom(
+	snapshot *entity.RoomSnapshot,
+	members []entity.RoomPlayer,
+	deps gameRoomDeps,
+	onClose func(),
+) *GameRoom {
+	r := newGameRoom(snapshot.RoomID, members, snapshot.Rules, deps, onClose)
+	r.resume = snapshot
+	r.startedAt = snapshot.StartedAt
+	r.turnRecords = snapshot.Turns
+	for i, sp := range snapshot.Players {
+		r.players[i] = &gamePlayerState{
+			user:       &entity.User{ID: sp.UserID, GitHubLogin: sp.GitHubLogin, Rate: sp.Rate},
+			questions:  &QuestionSet{MyQuestions: sp.MyQuestions, ForOpponent: sp.ForOpponent},
+			events:     r.events,
+			doneCh:     make(chan struct{}),
+			gnuBalance: sp.GnuBalance,
+			team:       r.teamOf(sp.UserID, i),
+		}
+		r.correctCounts[i] = sp.CorrectCount
+		r.gnuEarned[i] = sp.GnuEarned
+	}
+	// 重み付けした正解数はスナップショットに持たないため、完了したターンの記録から数え直す
+	for _, t := range r.turnRecords {
+		if !t.IsCorrect {
+			continue
+		}
+		for i, p := range r.players {
+			if p.user.ID == t.UserID {
+				r.correctPoints[i] += r.rules.DifficultyMultipliers.Points(t.Question.Difficulty)
+			}
+		}
+	}
+	r.joined = len(r.players)
+	close(r.startCh)
+	r.checkpointed = true
+	return r
+}
+
+// close はルームを終了状態にし、onClose を一度だけ呼び出す
+func (r *GameRoom) close() {
+	r.closeOnce.Do(func() {
+		close(r.closedCh)
+		for _, t := range r.graceTimers {
+			if t != nil {
+				t.Stop()
+			}
+		}
+		r.onClose()
+	})
+}
+
 // join はプレイヤーをルームに参加させ、プレイヤーインデックスと doneCh を返す
-func (r *GameRoom) join(conn *websocket.Conn, user *entity.User) (int, <-chan struct{}, error) {
+// 既に参加済みのユーザーが再度接続した場合は接続を差し替え、reconnected=true を返す
+func (r *GameRoom) join(conn playerConn, user *entity.User) (int, <-chan struct{}, bool, error) {
+	select {
+	case <-r.closedCh:
+		return -1, nil, false, errRoomClosed
+	default:
+	}
+
 	r.mu.Lock()
+	for i, p := range r.players {
+		if p == nil || p.user.ID != user.ID {
+			continue
+		}
+		// 既存の接続を差し替える。古い接続がまだ生きていれば読み取りを打ち切る
+		p.writeMu.Lock()
+		old := p.conn
+		p.conn = conn
+		p.writeMu.Unlock()
+		if p.connected {
+			if err := old.SetReadDeadline(time.Now()); err != nil {
+				log.Printf("game room %s: player[%d] failed to interrupt old conn: %v", r.id, i, err)
+			}
+		}
+		doneCh := make(chan struct{})
+		p.doneCh = doneCh
+		p.connected = true
+		r.mu.Unlock()
+
+		// 状態のリプレイは run goroutine が行う
+		select {
+		case r.reconnCh <- i:
+		case <-r.closedCh:
+			return -1, nil, false, errRoomClosed
+		}
+		return i, doneCh, true, nil
+	}
 	defer r.mu.Unlock()
-	if r.joined >= 2 {
-		return -1, nil, fmt.Errorf("room is full")
+
+	if r.joined >= len(r.players) {
+		return -1, nil, false, errRoomFull
 	}
 	idx := r.joined
 	doneCh := make(chan struct{})
 	r.players[idx] = &gamePlayerState{
 		user:       user,
 		conn:       conn,
+		events:     r.events,
 		gnuBalance: user.GnuBalance,
+		team:       r.teamOf(user.ID, idx),
 		doneCh:     doneCh,
+		connected:  true,
 	}
 	r.joined++
-	if r.joined == 2 {
+	if r.joined == len(r.players) {
 		close(r.startCh)
 	}
-	return idx, doneCh, nil
+	return idx, doneCh, false, nil
+}
+
+// teamOf はユーザーの所属チームを返す
+// members にいないユーザーは参加順に交互に振り分ける
+func (r *GameRoom) teamOf(userID uuid.UUID, idx int) int {
+	for _, m := range r.members {
+		if m.UserID == userID {
+			return m.Team
+		}
+	}
+	return idx % 2
+}
+
+// isTeamRoom は1チーム2人以上のルームかどうかを返す
+func (r *GameRoom) isTeamRoom() bool {
+	return len(r.players) > 2
+}
+
+// teamMembers は team に所属するプレイヤーのインデックスを参加順に返す
+func (r *GameRoom) teamMembers(team int) []int {
+	idxs := make([]int, 0, len(r.players))
+	for i, p := range r.players {
+		if p != nil && p.team == team {
+			idxs = append(idxs, i)
+		}
+	}
+	return idxs
+}
+
+// positionOf はプレイヤー idx のチーム内の並び順を返す（room_players.position と同じく members の順に数える）
+// members にいない場合は参加順で数える
+func (r *GameRoom) positionOf(idx int) int {
+	p := r.players[idx]
+	pos := 0
+	for _, m := range r.members {
+		if m.UserID == p.user.ID {
+			return pos
+		}
+		if m.Team == p.team {
+			pos++
+		}
+	}
+	return slices.Index(r.teamMembers(p.team), idx)
+}
+
+// opponentOf はプレイヤー idx と組になる相手チームのプレイヤーを返す
+// チーム内で同じ順番のプレイヤーを組にし、shift だけずらした相手を返す（1対1では常に相手）
+func (r *GameRoom) opponentOf(idx, shift int) int {
+	team := r.players[idx].team
+	opps := r.teamMembers(1 - team)
+	pos := slices.Index(r.teamMembers(team), idx)
+	return opps[(pos+shift)%len(opps)]
+}
+
+// teamTotals はチームごとの正解数・重み付けした正解数・獲得ヌーの合計を返す
+func (r *GameRoom) teamTotals() (correct, points, gnu [2]int) {
+	for i, p := range r.players {
+		correct[p.team] += r.correctCounts[i]
+		points[p.team] += r.correctPoints[i]
+		gnu[p.team] += r.gnuEarned[i]
+	}
+	return correct, points, gnu
+}
+
+// correctScore は重み付けした正解数をクライアント向けの値（1 倍の正解で 1）に変換する
+func correctScore(points int) float64 {
+	return float64(points) / 100
+}
+
+// teamLeft はチームの全員が試合から抜けたかどうかを返す
+func (r *GameRoom) teamLeft(team int) bool {
+	for _, i := range r.teamMembers(team) {
+		if !r.players[i].left {
+			return false
+		}
+	}
+	return true
+}
+
+// leaveBeforeStart は開始前に再接続猶予を過ぎたプレイヤー idx を離脱扱いにし、チームの全員が離脱したかを返す
+// 猶予の間に再接続していれば何もせず left=false を返す
+// まだ参加していない枠はこれから参加しうるため、離脱したとはみなさない
+// 開始前は join が r.players を書き換えるため、r.mu の中で読む
+func (r *GameRoom) leaveBeforeStart(idx int) (left, teamLeft bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p := r.players[idx]
+	if p.connected {
+		return false, false
+	}
+	p.left = true
+	gone := 0
+	for _, q := range r.players {
+		if q != nil && q.team == p.team && q.left {
+			gone++
+		}
+	}
+	return true, gone == len(r.players)/2
+}
+
+// teamSummaries はチームごとの集計とメンバーを返す（チーム戦のペイロードに使う）
+func (r *GameRoom) teamSummaries() []map[string]any {
+	correct, points, gnu := r.teamTotals()
+	teams := make([]map[string]any, 2)
+	for t := range teams {
+		logins := make([]string, 0, len(r.players))
+		for _, i := range r.teamMembers(t) {
+			logins = append(logins, r.players[i].user.GitHubLogin)
+		}
+		teams[t] = map[string]any{
+			"team":          t,
+			"members":       logins,
+			"correct_count": correct[t],
+			"correct_score": correctScore(points[t]),
+			"gnu_earned":    gnu[t],
+		}
+	}
+	return teams
 }
 
 // startReaderLoop はプレイヤーの WebSocket を読み取り msgCh に転送する
-// 切断時に doneCh を close して disconnCh にインデックスを送る
+// 切断時に doneCh を close して disconnCh に切断イベントを送る
 func (r *GameRoom) startReaderLoop(idx int) {
 	p := r.players[idx]
+	r.mu.Lock()
+	conn := p.conn
+	doneCh := p.doneCh
+	r.mu.Unlock()
+
 	defer func() {
-		close(p.doneCh)
-		r.disconnCh <- idx
+		close(doneCh)
+		select {
+		case r.disconnCh <- playerConnEvent{idx: idx, conn: conn}:
+		case <-r.closedCh:
+		}
 	}()
 
 	for {
-		_, data, err := p.conn.ReadMessage()
+		_, data, err := conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
 				log.Printf("game room %s: player[%d] unexpected close: %v", r.id, idx, err)
@@ -165,190 +521,222 @@ func (r *GameRoom) startReaderLoop(idx int) {
 	}
 }
 
-// run はゲームループを実行する（goroutine で呼び出す）
-func (r *GameRoom) run(ctx context.Context) {
-	defer r.closeOnce.Do(r.onClose)
-	log.Printf("game room %s: waiting for both players", r.id)
+// startFresh は全プレイヤーの接続を待って問題を生成する
+// 試合を続けられない場合はルームを終了状態にして false を返す
+func (r *GameRoom) startFresh(ctx context.Context) bool {
+	log.Printf("game room %s: waiting for %d players", r.id, len(r.players))
 
-	// 両プレイヤーが揃うまで待つ
-	select {
-	case <-r.startCh:
-	case idx := <-r.disconnCh:
-		log.Printf("game room %s: player[%d] disconnected before game started", r.id, idx)
-		r.notifyOpponentDisconnect(idx)
-		return
-	case <-ctx.Done():
-		return
+	// 全プレイヤーが揃うまで待つ
+	joinTimer := time.NewTimer(r.rules.JoinWaitLimit)
+	defer joinTimer.Stop()
+waitLoop:
+	for {
+		select {
+		case <-r.startCh:
+			break waitLoop
+		case ev := <-r.disconnCh:
+			// 開始前の切断も再接続猶予の間は待つ
+			if r.markDisconnected(ev) {
+				log.Printf("game room %s: player[%d] disconnected before game started", r.id, ev.idx)
+			}
+		case idx := <-r.graceCh:
+			left, teamLeft := r.leaveBeforeStart(idx)
+			if !left {
+				continue
+			}
+			log.Printf("game room %s: player[%d] did not reconnect before game started", r.id, idx)
+			if !teamLeft {
+				continue
+			}
+			r.notifyOpponentDisconnect(idx)
+			r.updateStatus(entity.RoomStatusAborted, entity.RoomEndReasonNoShow)
+			return false
+		case <-joinTimer.C:
+			log.Printf("game room %s: opponent did not join within %s", r.id, r.rules.JoinWaitLimit)
+			r.sendAllError("opponent_no_show", "対戦相手が参加しませんでした")
+			r.updateStatus(entity.RoomStatusAborted, entity.RoomEndReasonNoShow)
+			return false
+		case idx := <-r.reconnCh:
+			// 開始前の接続差し替えはリプレイ不要
+			r.mu.Lock()
+			r.players[idx].left = false
+			r.mu.Unlock()
+			r.stopGrace(idx)
+		case <-ctx.Done():
+			r.abortForShutdown()
+			return false
+		}
 	}
 
-	p0 := r.players[0]
-	p1 := r.players[1]
-
-	log.Printf("game room %s: both players joined, starting game", r.id)
+	log.Printf("game room %s: all players joined, starting game", r.id)
+	r.startedAt = time.Now()
+	r.updateStatus(entity.RoomStatusInProgress, entity.RoomEndReasonNone)
 
-	// ev_room_ready を両プレイヤーに送信
-	for i, p := range r.players {
-		opp := r.players[1-i]
-		p.send(WSMessage{
-			Type: "ev_room_ready",
-			Payload: map[string]any{
-				"your_gnu_balance": p.gnuBalance,
-				"opponent": map[string]any{
-					"id":           opp.user.ID.String(),
-					"github_login": opp.user.GitHubLogin,
-					"rate":         opp.user.Rate,
-					"gnu_balance":  opp.gnuBalance,
-				},
-			},
-		})
+	// ev_room_ready を全プレイヤーに送信
+	for i := range r.players {
+		r.sendRoomReady(i, false)
 	}
 
-	// ―― 問題受取フェーズ ――
-	questionTimer := time.After(questionWaitLimit)
-	questionsDone := [2]bool{}
+	// ―― 問題生成フェーズ ――
+	// 問題はサーバーが各プレイヤーのリポジトリから生成する（クライアントからの問題は受け付けない）
+	genCtx, cancelGen := context.WithTimeout(ctx, r.rules.QuestionWaitLimit)
+	defer cancelGen()
+	genCh := make(chan questionsResult, 1)
+	go func() {
+		sets, err := r.generateQuestions(genCtx)
+		genCh <- questionsResult{sets: sets, err: err}
+	}()
 
-	for !questionsDone[0] || !questionsDone[1] {
+questionLoop:
+	for {
 		select {
-		case <-questionTimer:
-			log.Printf("game room %s: timeout waiting for questions", r.id)
-			r.sendBothError("question_timeout", "問題の送信がタイムアウトしました")
-			return
-		case idx := <-r.disconnCh:
-			log.Printf("game room %s: player[%d] disconnected during question phase", r.id, idx)
-			r.notifyOpponentDisconnect(idx)
-			return
-		case <-ctx.Done():
-			return
-		case msg := <-r.msgCh:
-			if msg.msgType != "act_submit_questions" {
-				continue
+		case res := <-genCh:
+			if res.err != nil {
+				log.Printf("game room %s: failed to generate questions: %v", r.id, res.err)
+				if errors.Is(res.err, context.DeadlineExceeded) {
+					r.sendAllError("question_timeout", "問題の生成がタイムアウトしました")
+					r.updateStatus(entity.RoomStatusAborted, entity.RoomEndReasonQuestionTimeout)
+				} else {
+					r.sendAllError("question_generation_failed", "問題の生成に失敗しました")
+					r.updateStatus(entity.RoomStatusAborted, entity.RoomEndReasonQuestionGenerationFailed)
+				}
+				return false
 			}
-			if questionsDone[msg.idx] {
-				continue
+			for i, p := range r.players {
+				p.questions = res.sets[i]
 			}
-			var qs submitQuestionsPayload
-			if err := json.Unmarshal(msg.payload, &qs); err != nil {
-				log.Printf("game room %s: player[%d] invalid questions payload: %v", r.id, msg.idx, err)
-				continue
+			break questionLoop
+		case ev := <-r.disconnCh:
+			if r.markDisconnected(ev) {
+				log.Printf("game room %s: player[%d] disconnected during question phase", r.id, ev.idx)
 			}
-			if len(qs.MyQuestions) < 5 || len(qs.ForOpponent) < 5 {
-				log.Printf("game room %s: player[%d] insufficient questions (my=%d, for_opp=%d)", r.id, msg.idx, len(qs.MyQuestions), len(qs.ForOpponent))
-				r.players[msg.idx].send(WSMessage{
-					Type: "ev_error",
-					Payload: map[string]any{
-						"code":    "invalid_questions",
-						"message": "my_questions と for_opponent はそれぞれ5問必要です",
-					},
-				})
+		case idx := <-r.reconnCh:
+			r.handleReconnect(idx, nil)
+			r.players[idx].send(WSMessage{
+				Type: "ev_questions_status",
+				Payload: map[string]any{
+					"generating": true,
+				},
+			})
+		case idx := <-r.graceCh:
+			if r.isConnected(idx) {
 				continue
 			}
-			allQs := append(qs.MyQuestions[:5:5], qs.ForOpponent[:5]...)
-			valid := true
-			for _, q := range allQs {
-				if err := q.Validate(); err != nil {
-					log.Printf("game room %s: player[%d] invalid question: %v", r.id, msg.idx, err)
-					r.players[msg.idx].send(WSMessage{
-						Type: "ev_error",
-						Payload: map[string]any{
-							"code":    "invalid_questions",
-							"message": err.Error(),
-						},
-					})
-					valid = false
-					break
-				}
-			}
-			if !valid {
+			log.Printf("game room %s: player[%d] did not reconnect during question phase", r.id, idx)
+			// チームの誰かが残っていれば試合を続ける
+			r.players[idx].left = true
+			if !r.teamLeft(r.players[idx].team) {
 				continue
 			}
-			r.players[msg.idx].questions = &QuestionSet{
-				MyQuestions: qs.MyQuestions,
-				ForOpponent: qs.ForOpponent,
+			r.notifyOpponentDisconnect(idx)
+			r.updateStatus(entity.RoomStatusAborted, entity.RoomEndReasonDisconnected)
+			return false
+		case <-ctx.Done():
+			r.abortForShutdown()
+			return false
+		case msg := <-r.msgCh:
+			if msg.msgType == "act_submit_questions" {
+				log.Printf("game room %s: player[%d] ignoring client-submitted questions", r.id, msg.idx)
 			}
-			questionsDone[msg.idx] = true
-			log.Printf("game room %s: player[%d] submitted questions", r.id, msg.idx)
 		}
 	}
+	return true
+}
 
-	log.Printf("game room %s: all questions received, starting turns", r.id)
-
-	// ―― ターン定義（計10ターン） ――
-	// 奇数ターン(0,2,4,6,8): 相手のfor_opponent[i] = 相手のリポジトリから生成された問題を解く
-	// 偶数ターン(1,3,5,7,9): 自分のmy_questions[i] = 自分のリポジトリから生成された問題を解く
-	type turnDef struct {
-		qForP0 entity.Question
-		qForP1 entity.Question
-	}
-	turns := []turnDef{
-		{qForP0: p1.questions.ForOpponent[0], qForP1: p0.questions.ForOpponent[0]},
-		{qForP0: p0.questions.MyQuestions[0], qForP1: p1.questions.MyQuestions[0]},
-		{qForP0: p1.questions.ForOpponent[1], qForP1: p0.questions.ForOpponent[1]},
-		{qForP0: p0.questions.MyQuestions[1], qForP1: p1.questions.MyQuestions[1]},
-		{qForP0: p1.questions.ForOpponent[2], qForP1: p0.questions.ForOpponent[2]},
-		{qForP0: p0.questions.MyQuestions[2], qForP1: p1.questions.MyQuestions[2]},
-		{qForP0: p1.questions.ForOpponent[3], qForP1: p0.questions.ForOpponent[3]},
-		{qForP0: p0.questions.MyQuestions[3], qForP1: p1.questions.MyQuestions[3]},
-		{qForP0: p1.questions.ForOpponent[4], qForP1: p0.questions.ForOpponent[4]},
-		{qForP0: p0.questions.MyQuestions[4], qForP1: p1.questions.MyQuestions[4]},
+// run はゲームループを実行する（goroutine で呼び出す）
+// 復旧したルームでは全プレイヤーの再接続を待ち、保存した次のターンから再開する
+func (r *GameRoom) run(ctx context.Context) {
+	defer r.close()
+	firstTurn := 0
+	if r.resume != nil {
+		if !r.waitResume(ctx) {
+			return
+		}
+		firstTurn = r.resume.CompletedTurns
+	} else {
+		if !r.startFresh(ctx) {
+			return
+		}
+		log.Printf("game room %s: questions generated, starting turns", r.id)
+		r.checkpoint(0)
 	}
 
-	totalGnuEarned := [2]int{}
-	correctCounts := [2]int{}
+	sets := make([]*QuestionSet, len(r.players))
+	counterparts := make([]int, len(r.players))
+	for i, p := range r.players {
+		sets[i] = p.questions
+		counterparts[i] = r.opponentOf(i, 0)
+	}
+	turns := buildTurnSchedule(r.rules.TotalTurns, sets, counterparts)
 
 	// ―― ターンループ ――
-	for turnIdx, turn := range turns {
-		questions := [2]entity.Question{turn.qForP0, turn.qForP1}
-		bets := [2]int{}
-		answers := [2]int{-1, -1} // -1 = 未回答（タイムアウト）
-		answered := [2]bool{}
+	for turnIdx := firstTurn; turnIdx < len(turns); turnIdx++ {
+		ts := newTurnState(turnIdx+1, turns[turnIdx], time.Now(), r.rules.TurnDuration)
+		// ベットしなかったプレイヤーも最小額を賭けたものとする（抜けたプレイヤーは賭けない）
+		for i, p := range r.players {
+			if !p.left {
+				ts.bets[i], _ = r.betRange(i)
+			}
+		}
 
 		// ev_turn_start 送信
-		for i, p := range r.players {
-			q := questions[i]
-			p.send(WSMessage{
-				Type: "ev_turn_start",
-				Payload: map[string]any{
-					"turn":             turnIdx + 1,
-					"total_turns":      10,
-					"difficulty":       q.Difficulty,
-					"question_text":    q.QuestionText,
-					"choices":          q.Choices,
-					"time_limit_sec":   15,
-					"your_gnu_balance": p.gnuBalance,
-					"min_bet":          minBet,
-					"max_bet":          p.gnuBalance,
-				},
-			})
+		for i := range r.players {
+			r.sendTurnStart(i, ts)
 		}
+		r.broadcastSpectatorTurnStart(ts)
 
-		turnTimer := time.After(turnDuration)
+		turnTimer := time.After(r.rules.TurnDuration)
 		turnDone := false
 
 		for !turnDone {
 			select {
 			case <-turnTimer:
-				log.Printf("game room %s: turn %d timeout", r.id, turnIdx+1)
+				log.Printf("game room %s: turn %d timeout", r.id, ts.turn)
 				turnDone = true
 
-			case idx := <-r.disconnCh:
-				log.Printf("game room %s: player[%d] disconnected during turn %d", r.id, idx, turnIdx+1)
-				r.handleTKO(idx)
-				return
+			case ev := <-r.disconnCh:
+				// 再接続猶予の間もターンタイマーは進める
+				if r.markDisconnected(ev) {
+					log.Printf("game room %s: player[%d] disconnected during turn %d", r.id, ev.idx, ts.turn)
+				}
+
+			case idx := <-r.reconnCh:
+				r.handleReconnect(idx, ts)
+
+			case idx := <-r.graceCh:
+				if r.isConnected(idx) {
+					continue
+				}
+				log.Printf("game room %s: player[%d] did not reconnect during turn %d", r.id, idx, ts.turn)
+				// チームの全員が抜けた場合に TKO とし、誰かが残っていれば抜けたプレイヤーを除いて続ける
+				r.players[idx].left = true
+				if team := r.players[idx].team; r.teamLeft(team) {
+					r.handleTKO(team)
+					r.updateStatus(entity.RoomStatusFinished, entity.RoomEndReasonTKO)
+					return
+				}
+				turnDone = r.allAnswered(ts)
 
 			case <-ctx.Done():
+				// 保存した状態があれば再起動後に再開できるよう残し、なければ中止して精算する
+				if r.checkpointed {
+					r.suspendForShutdown(ts.turn)
+				} else {
+					r.abortForShutdown()
+				}
 				return
 
 			case msg := <-r.msgCh:
 				switch msg.msgType {
 				case "act_bet_gnu":
-					if answered[msg.idx] {
+					if ts.answered[msg.idx] {
 						continue // 回答後のベット変更は禁止
 					}
 					var bp betPayload
 					if err := json.Unmarshal(msg.payload, &bp); err != nil {
 						continue
 					}
-					maxBet := r.players[msg.idx].gnuBalance
+					minBet, maxBet := r.betRange(msg.idx)
 					if bp.Amount < minBet || bp.Amount > maxBet {
 						r.players[msg.idx].send(WSMessage{
 							Type: "ev_error",
@@ -361,7 +749,7 @@ func (r *GameRoom) run(ctx context.Context) {
 						})
 						continue
 					}
-					bets[msg.idx] = bp.Amount
+					ts.bets[msg.idx] = bp.Amount
 					r.players[msg.idx].send(WSMessage{
 						Type: "ev_bet_confirmed",
 						Payload: map[string]any{
@@ -373,172 +761,918 @@ func (r *GameRoom) run(ctx context.Context) {
 					log.Printf("game room %s: player[%d] bet %d gnu", r.id, msg.idx, bp.Amount)
 
 				case "act_submit_answer":
-					if answered[msg.idx] {
+					if ts.answered[msg.idx] {
 						continue // 二重回答は無視
 					}
 					var ap submitAnswerPayload
 					if err := json.Unmarshal(msg.payload, &ap); err != nil {
 						continue
 					}
-					answers[msg.idx] = ap.ChoiceIndex
-					answered[msg.idx] = true
-					log.Printf("game room %s: player[%d] answered %d", r.id, msg.idx, ap.ChoiceIndex)
-					if answered[0] && answered[1] {
-						turnDone = true
+					q := ts.questions[msg.idx]
+					if err := q.CheckAnswer(ap.Answer); err != nil {
+						r.players[msg.idx].send(WSMessage{
+							Type: "ev_error",
+							Payload: map[string]any{
+								"code":          "invalid_answer",
+								"message":       "回答の形式が問題と一致しません",
+								"question_type": q.Kind(),
+							},
+						})
+						continue
 					}
+					ts.answers[msg.idx] = ap.Answer
+					ts.answered[msg.idx] = true
+					ts.answeredAt[msg.idx] = time.Now()
+					log.Printf("game room %s: player[%d] answered %s question", r.id, msg.idx, q.Kind())
+					turnDone = r.allAnswered(ts)
 				}
 			}
 		}
 
 		// ―― ターン結果計算 ――
-		gnuDeltas := [2]int{}
-		corrects := [2]bool{}
+		gnuDeltas := make([]int, len(r.players))
+		corrects := make([]bool, len(r.players))
+		// 正解はベットに問題の難易度の倍率を掛けた額を得て、不正解はベットをそのまま失う
+		multipliers := r.rules.DifficultyMultipliers
 		for i, p := range r.players {
-			q := questions[i]
-			correctIdx := q.CorrectIndex()
-			isCorrect := answers[i] >= 0 && answers[i] == correctIdx
+			q := ts.questions[i]
+			isCorrect := ts.answered[i] && q.IsCorrect(ts.answers[i])
 			corrects[i] = isCorrect
 			if isCorrect {
-				gnuDeltas[i] = bets[i]
-				p.gnuBalance += bets[i]
-				totalGnuEarned[i] += bets[i]
-				correctCounts[i]++
+				payout := multipliers.Scale(q.Difficulty, ts.bets[i])
+				gnuDeltas[i] = payout
+				p.gnuBalance += payout
+				r.gnuEarned[i] += payout
+				r.correctCounts[i]++
+				r.correctPoints[i] += multipliers.Points(q.Difficulty)
 			} else {
-				gnuDeltas[i] = -bets[i]
-				p.gnuBalance -= bets[i]
+				gnuDeltas[i] = -ts.bets[i]
+				p.gnuBalance -= ts.bets[i]
 				if p.gnuBalance < 0 {
 					p.gnuBalance = 0
 				}
-				totalGnuEarned[i] -= bets[i]
+				r.gnuEarned[i] -= ts.bets[i]
 			}
 		}
 
-		// ev_turn_result 送信
+		// ev_turn_result 送信（opponent は組になる相手チームのプレイヤー）
+		var teams []map[string]any
+		if r.isTeamRoom() {
+			teams = r.teamSummaries()
+		}
 		for i, p := range r.players {
-			q := questions[i]
-			p.send(WSMessage{
-				Type: "ev_turn_result",
-				Payload: map[string]any{
-					"turn":                turnIdx + 1,
-					"correct_answer":      q.CorrectAnswer,
-					"correct_index":       q.CorrectIndex(),
-					"your_answer":         answers[i],
-					"is_correct":          corrects[i],
-					"tips":                q.Tips,
-					"gnu_delta":           gnuDeltas[i],
-					"your_gnu_balance":    p.gnuBalance,
-					"opponent_is_correct": corrects[1-i],
-					"opponent_gnu_delta":  gnuDeltas[1-i],
-				},
-			})
+			q := ts.questions[i]
+			opp := r.opponentOf(i, 0)
+			payload := map[string]any{
+				"turn":                ts.turn,
+				"question_type":       q.Kind(),
+				"correct_answer":      q.CorrectAnswer,
+				"correct_answers":     q.CorrectAnswers,
+				"correct_index":       q.CorrectIndex(),
+				"correct_indexes":     q.CorrectIndexes(),
+				"your_answer":         ts.answerIndex(i),
+				"your_answer_detail":  ts.answerDetail(i),
+				"is_correct":          corrects[i],
+				"tips":                q.Tips,
+				"gnu_delta":           gnuDeltas[i],
+				"your_gnu_balance":    p.gnuBalance,
+				"opponent_is_correct": corrects[opp],
+				"opponent_gnu_delta":  gnuDeltas[opp],
+			}
+			if teams != nil {
+				payload["your_team"] = p.team
+				payload["teams"] = teams
+			}
+			p.send(WSMessage{Type: "ev_turn_result", Payload: payload})
 		}
 
-		log.Printf("game room %s: turn %d done | p0: correct=%v delta=%d | p1: correct=%v delta=%d",
-			r.id, turnIdx+1, corrects[0], gnuDeltas[0], corrects[1], gnuDeltas[1])
+		r.broadcastSpectators(r.spectatorTurnResult(ts, corrects, gnuDeltas))
+		r.recordTurn(ts, corrects, gnuDeltas)
+		r.checkpoint(ts.turn)
+		r.flushEvents()
+
+		log.Printf("game room %s: turn %d done | correct=%v delta=%v", r.id, ts.turn, corrects, gnuDeltas)
 	}
 
 	// ―― 試合終了処理 ――
-	winnerIdx := -1
+	// 難易度の倍率で重み付けしたチームの正解数の合計、同じならチームの獲得ヌーの合計で勝敗を決める
+	teamCorrect, teamPoints, teamGnu := r.teamTotals()
+	winnerTeam := -1
 	switch {
-	case correctCounts[0] > correctCounts[1]:
-		winnerIdx = 0
-	case correctCounts[1] > correctCounts[0]:
-		winnerIdx = 1
-	case totalGnuEarned[0] > totalGnuEarned[1]:
-		winnerIdx = 0
-	case totalGnuEarned[1] > totalGnuEarned[0]:
-		winnerIdx = 1
+	case teamPoints[0] > teamPoints[1]:
+		winnerTeam = 0
+	case teamPoints[1] > teamPoints[0]:
+		winnerTeam = 1
+	case teamGnu[0] > teamGnu[1]:
+		winnerTeam = 0
+	case teamGnu[1] > teamGnu[0]:
+		winnerTeam = 1
 	}
 
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// レーティング更新とヌーの精算（結果を ev_game_end に含めるため送信前に行う）
+	rateChanges := r.applyRating(dbCtx, winnerTeam)
+	r.settleGnu(dbCtx, r.gnuTransactions())
+
+	rematchWaitSec := 0
+	if r.rematchAvailable() {
+		rematchWaitSec = int(r.rules.RematchWaitLimit / time.Second)
+	}
+	var teams []map[string]any
+	if r.isTeamRoom() {
+		teams = r.teamSummaries()
+	}
 	for i, p := range r.players {
 		result := "draw"
-		if winnerIdx == i {
+		if winnerTeam == p.team {
 			result = "win"
-		} else if winnerIdx != -1 {
+		} else if winnerTeam != -1 {
 			result = "lose"
 		}
-		opp := r.players[1-i]
-		p.send(WSMessage{
-			Type: "ev_game_end",
-			Payload: map[string]any{
-				"result":                 result,
-				"your_correct_count":     correctCounts[i],
-				"opponent_correct_count": correctCounts[1-i],
-				"your_final_gnu":         p.gnuBalance,
-				"opponent_final_gnu":     opp.gnuBalance,
-				"gnu_earned_this_game":   totalGnuEarned[i],
-				"total_turns":            10,
-			},
+		opp := r.opponentOf(i, 0)
+		payload := map[string]any{
+			"result":                 result,
+			"your_correct_count":     r.correctCounts[i],
+			"opponent_correct_count": r.correctCounts[opp],
+			"your_correct_score":     correctScore(r.correctPoints[i]),
+			"opponent_correct_score": correctScore(r.correctPoints[opp]),
+			"your_final_gnu":         p.gnuBalance,
+			"opponent_final_gnu":     r.players[opp].gnuBalance,
+			"gnu_earned_this_game":   r.gnuEarned[i],
+			"total_turns":            r.rules.TotalTurns,
+			"rate_before":            rateChanges[i].Before,
+			"rate_after":             rateChanges[i].After,
+			"rate_delta":             rateChanges[i].Delta,
+			"rematch_wait_sec":       rematchWaitSec, // 0 の場合は再戦できない
+		}
+		if teams != nil {
+			payload["your_team"] = p.team
+			payload["your_team_correct_count"] = teamCorrect[p.team]
+			payload["opponent_team_correct_count"] = teamCorrect[1-p.team]
+			payload["your_team_correct_score"] = correctScore(teamPoints[p.team])
+			payload["opponent_team_correct_score"] = correctScore(teamPoints[1-p.team])
+			payload["teams"] = teams
+		}
+		p.send(WSMessage{Type: "ev_game_end", Payload: payload})
+	}
+
+	r.broadcastSpectators(r.spectatorGameEnd(entity.MatchEndReasonCompleted, winnerTeam, rateChanges))
+
+	log.Printf("game room %s: game finished. winner team=%d | correct=%v | points=%v | gnu earned=%v",
+		r.id, winnerTeam, teamCorrect, teamPoints, teamGnu)
+
+	r.saveMatchResult(dbCtx, entity.MatchEndReasonCompleted, winnerTeam)
+	r.updateLeaderboard(dbCtx, winnerTeam)
+	r.winnerTeam = winnerTeam
+	r.updateStatus(entity.RoomStatusFinished, entity.RoomEndReasonCompleted)
+
+	// ―― 再戦の受付 ――
+	r.waitRematch(ctx)
+}
+
+// allAnswered は試合から抜けたプレイヤーを除く全員が回答したかどうかを返す
+func (r *GameRoom) allAnswered(ts *turnState) bool {
+	for i, p := range r.players {
+		if !p.left && !ts.answered[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// generateQuestions は全プレイヤーのリポジトリから問題を並行して生成し、プレイヤーごとの QuestionSet を組み立てる
+// プレイヤー i のリポジトリから 2n 問を生成し、前半 n 問を i の for_opponent、後半 n 問を相手チームの
+// プレイヤーの my_questions とする（チーム戦では for_opponent と別の相手に割り当てる）
+func (r *GameRoom) generateQuestions(ctx context.Context) ([]*QuestionSet, error) {
+	if r.questions == nil {
+		return nil, errors.New("question generator is not configured")
+	}
+
+	n := r.rules.QuestionsPerSide()
+	fromRepo := make([][]entity.Question, len(r.players))
+	errs := make([]error, len(r.players))
+	var wg sync.WaitGroup
+	for i, p := range r.players {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fromRepo[i], errs[i] = r.questions.GenerateForOwner(ctx, p.user.GitHubLogin, 2*n)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("player[%d] %s: %w", i, p.user.GitHubLogin, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	sets := make([]*QuestionSet, len(r.players))
+	for i := range sets {
+		sets[i] = &QuestionSet{
+			ForOpponent: fromRepo[i][:n],
+			MyQuestions: fromRepo[r.opponentOf(i, 1)][n:],
+		}
+	}
+	return sets, nil
+}
+
+// buildTurnSchedule は totalTurns 分のターンごとの出題（プレイヤーインデックス順）を組み立てる
+// counterparts[i] はプレイヤー i と組になる相手チームのプレイヤー
+// 奇数ターン(1,3,5,...): 相手の for_opponent = 相手のリポジトリから生成された問題を解く
+// 偶数ターン(2,4,6,...): 自分の my_questions = 相手チームのリポジトリから生成された問題を解く
+func buildTurnSchedule(totalTurns int, sets []*QuestionSet, counterparts []int) [][]entity.Question {
+	turns := make([][]entity.Question, totalTurns)
+	for t := range turns {
+		k := t / 2
+		turns[t] = make([]entity.Question, len(sets))
+		for i, set := range sets {
+			if t%2 == 0 {
+				turns[t][i] = sets[counterparts[i]].ForOpponent[k]
+			} else {
+				turns[t][i] = set.MyQuestions[k]
+			}
+		}
+	}
+	return turns
+}
+
+// applyRating は勝利チームから全プレイヤーのレーティングを更新し、プレイヤーインデックス順に変動を返す
+// winnerTeam が -1 の場合は引き分け。更新に失敗した場合は変動なしとして扱う
+func (r *GameRoom) applyRating(ctx context.Context, winnerTeam int) []usecase.RatingChange {
+	changes := make([]usecase.RatingChange, len(r.players))
+	for i, p := range r.players {
+		changes[i] = usecase.RatingChange{Before: p.user.Rate, After: p.user.Rate}
+	}
+	if r.rating == nil {
+		return changes
+	}
+
+	if r.isTeamRoom() {
+		var teams [2][]uuid.UUID
+		for _, p := range r.players {
+			teams[p.team] = append(teams[p.team], p.user.ID)
+		}
+		updated, err := r.rating.ApplyTeamMatchResult(ctx, teams, winnerTeam)
+		if err != nil {
+			log.Printf("game room %s: failed to update team rating: %v", r.id, err)
+			return changes
+		}
+		for i, p := range r.players {
+			if c, ok := updated[p.user.ID]; ok {
+				changes[i] = c
+			}
+		}
+		log.Printf("game room %s: team rating updated | %v", r.id, changes)
+		return changes
+	}
+
+	winnerID := r.winnerID(winnerTeam)
+	c0, c1, err := r.rating.ApplyMatchResult(ctx, r.players[0].user.ID, r.players[1].user.ID, winnerID)
+	if err != nil {
+		log.Printf("game room %s: failed to update rating: %v", r.id, err)
+		return changes
+	}
+	log.Printf("game room %s: rating updated | p0: %d -> %d | p1: %d -> %d",
+		r.id, c0.Before, c0.After, c1.Before, c1.After)
+	return []usecase.RatingChange{c0, c1}
+}
+
+// winnerID は1対1のルームで winnerTeam のプレイヤーを返す
+// 引き分けの場合とチーム戦のルームでは無効な値を返す
+func (r *GameRoom) winnerID(winnerTeam int) uuid.NullUUID {
+	if winnerTeam < 0 || r.isTeamRoom() {
+		return uuid.NullUUID{}
+	}
+	for _, p := range r.players {
+		if p != nil && p.team == winnerTeam {
+			return uuid.NullUUID{UUID: p.user.ID, Valid: true}
+		}
+	}
+	return uuid.NullUUID{}
+}
+
+// winnerIDs は winnerTeam に所属する全プレイヤーを返す。引き分けの場合は空を返す
+func (r *GameRoom) winnerIDs(winnerTeam int) []uuid.UUID {
+	if winnerTeam < 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(r.players))
+	for _, p := range r.players {
+		if p != nil && p.team == winnerTeam {
+			ids = append(ids, p.user.ID)
+		}
+	}
+	return ids
+}
+
+// recordTurn は完了したターンの全プレイヤー分の記録を追加する
+func (r *GameRoom) recordTurn(ts *turnState, corrects []bool, gnuDeltas []int) {
+	for i, p := range r.players {
+		rec := entity.MatchTurn{
+			Turn:        ts.turn,
+			UserID:      p.user.ID,
+			Question:    ts.questions[i],
+			ChoiceIndex: ts.answerIndex(i),
+			Answer:      ts.answerDetail(i),
+			IsCorrect:   corrects[i],
+			Bet:         ts.bets[i],
+			GnuDelta:    gnuDeltas[i],
+		}
+		if ts.answered[i] {
+			ms := int(ts.answeredAt[i].Sub(ts.startedAt).Milliseconds())
+			rec.AnswerTimeMs = &ms
+		}
+		r.turnRecords = append(r.turnRecords, rec)
+	}
+}
+
+// gnuTransactions は完了したターンのベット精算を台帳の取引に変換する（増減のないターンは含めない）
+func (r *GameRoom) gnuTransactions() []entity.GnuTransaction {
+	roomID := uuid.NullUUID{UUID: r.id, Valid: true}
+	txs := make([]entity.GnuTransaction, 0, len(r.turnRecords))
+	for _, t := range r.turnRecords {
+		if t.GnuDelta == 0 {
+			continue
+		}
+		reason := entity.GnuTransactionReasonBetWin
+		if t.GnuDelta < 0 {
+			reason = entity.GnuTransactionReasonBetLoss
+		}
+		txs = append(txs, entity.GnuTransaction{
+			UserID:         t.UserID,
+			RoomID:         roomID,
+			Turn:           t.Turn,
+			Reason:         reason,
+			Delta:          t.GnuDelta,
+			IdempotencyKey: entity.TurnTransactionKey(r.id, t.Turn, t.UserID),
 		})
 	}
+	return txs
+}
 
-	log.Printf("game room %s: game finished. winner idx=%d | p0 balance=%d | p1 balance=%d",
-		r.id, winnerIdx, p0.gnuBalance, p1.gnuBalance)
+// settleGnu は試合中のヌーの増減を台帳に差分として反映し、各プレイヤーの残高を DB の値に合わせる
+// 参加後に別の試合や運営の調整で残高が変わっていても上書きしない
+// 反映に失敗した場合は試合中に計算した残高のままにする
+func (r *GameRoom) settleGnu(ctx context.Context, txs []entity.GnuTransaction) {
+	if r.gnuLedger == nil || len(txs) == 0 {
+		return
+	}
+	balances, err := r.gnuLedger.Apply(ctx, txs)
+	if err != nil {
+		log.Printf("game room %s: failed to settle gnu: %v", r.id, err)
+		return
+	}
+	for _, p := range r.players {
+		if p == nil {
+			continue
+		}
+		if b, ok := balances[p.user.ID]; ok {
+			p.gnuBalance = b
+		}
+	}
+}
 
-	// DB 更新: gnu_balance
-	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// saveMatchResult は試合結果とターン記録、プレイヤーごとの結果を永続化する
+// winnerTeam が -1 の場合は引き分け。勝敗のつかない終了理由（中止）では全員を no_contest にする
+// match_results の player1 / player2 と勝者は各チームの先頭の参加者（ルームの Player1ID / Player2ID）で表す
+func (r *GameRoom) saveMatchResult(ctx context.Context, reason entity.MatchEndReason, winnerTeam int) {
+	if r.matchRepo == nil {
+		return
+	}
+	result := &entity.MatchResult{
+		RoomID:     r.id,
+		EndReason:  reason,
+		TotalTurns: len(r.turnRecords) / len(r.players),
+		StartedAt:  r.startedAt,
+		FinishedAt: time.Now(),
+		Turns:      r.turnRecords,
+		Players:    make([]entity.MatchResultPlayer, 0, len(r.players)),
+	}
+	ids := make([]uuid.UUID, 0, len(r.players))
+	for i, p := range r.players {
+		rp := entity.MatchResultPlayer{
+			UserID:       p.user.ID,
+			Team:         p.team,
+			Position:     r.positionOf(i),
+			Outcome:      entity.MatchOutcomeDraw,
+			CorrectCount: r.correctCounts[i],
+			GnuEarned:    r.gnuEarned[i],
+			FinalGnu:     p.gnuBalance,
+		}
+		switch {
+		case !reason.IsDecided():
+			rp.Outcome = entity.MatchOutcomeNoContest
+		case winnerTeam < 0:
+		case p.team == winnerTeam:
+			rp.Outcome = entity.MatchOutcomeWin
+		default:
+			rp.Outcome = entity.MatchOutcomeLose
+		}
+		result.Players = append(result.Players, rp)
+		ids = append(ids, p.user.ID)
+
+		if rp.Position != 0 {
+			continue
+		}
+		if p.team == 0 {
+			result.Player1ID, result.Player1CorrectCount, result.Player1GnuEarned, result.Player1FinalGnu = rp.UserID, rp.CorrectCount, rp.GnuEarned, rp.FinalGnu
+		} else {
+			result.Player2ID, result.Player2CorrectCount, result.Player2GnuEarned, result.Player2FinalGnu = rp.UserID, rp.CorrectCount, rp.GnuEarned, rp.FinalGnu
+		}
+		if p.team == winnerTeam {
+			result.WinnerID = uuid.NullUUID{UUID: p.user.ID, Valid: true}
+		}
+	}
+	if err := r.matchRepo.Save(ctx, result); err != nil {
+		log.Printf("game room %s: failed to save match result: %v", r.id, err)
+		return
+	}
+	// 保存した結果が成績に反映されるよう、全プレイヤーのキャッシュを削除する
+	if r.stats != nil {
+		if err := r.stats.Invalidate(ctx, ids...); err != nil {
+			log.Printf("game room %s: failed to invalidate user stats: %v", r.id, err)
+		}
+	}
+}
+
+// saveAbortedMatch は中止した試合を、完了したターンまでの記録と勝者なしの結果で保存する
+// 1ターンも完了していない場合（開始前や問題生成中の中止）はヌーが動いていないため保存しない
+func (r *GameRoom) saveAbortedMatch(ctx context.Context, reason entity.MatchEndReason) {
+	if len(r.turnRecords) == 0 {
+		return
+	}
+	r.saveMatchResult(ctx, reason, -1)
+}
+
+// updateLeaderboard は精算後のレートとヌー残高、勝者の勝利数をランキングに反映する
+// winnerTeam が -1 の場合は勝者なし（中止した試合もヌーは精算するため反映する）
+// チーム戦では勝利チームの全員に勝利数を加算する
+// 反映に失敗しても試合の結果には影響しない（make leaderboard-rebuild で作り直せる）
+func (r *GameRoom) updateLeaderboard(ctx context.Context, winnerTeam int) {
+	if r.leaderboard == nil {
+		return
+	}
+	ids := make([]uuid.UUID, 0, len(r.players))
+	for _, p := range r.players {
+		ids = append(ids, p.user.ID)
+	}
+	if err := r.leaderboard.RecordMatch(ctx, ids, r.winnerIDs(winnerTeam), time.Now()); err != nil {
+		log.Printf("game room %s: failed to update leaderboard: %v", r.id, err)
+	}
+}
+
+// updateStatus はルームの状態を記録する
+// 終了状態にした場合は保存した試合の状態を削除し、再起動後の復旧の対象から外す
+// トーナメントの試合であれば結果をトーナメント表に反映する
+// 記録に失敗しても試合の進行は止めない
+func (r *GameRoom) updateStatus(status entity.RoomStatus, reason entity.RoomEndReason) {
+	// 終了したルームのリプレイが揃っているよう、状態より先にイベントを書き出す
+	r.flushEvents()
+	if r.roomRepo == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+	if err := r.roomRepo.UpdateStatus(ctx, r.id, status, reason); err != nil {
+		log.Printf("game room %s: failed to update status to %s: %v", r.id, status, err)
+	}
+	if status.IsEnded() {
+		if err := r.roomRepo.DeleteSnapshot(ctx, r.id); err != nil {
+			log.Printf("game room %s: failed to delete snapshot: %v", r.id, err)
+		}
+		r.reportTournament(ctx, reason)
+	}
+}
+
+// reportTournament はルームの終了結果をトーナメントに報告する
+// 不参加・切断の判定のため、終了時に接続していたプレイヤーを添える
+func (r *GameRoom) reportTournament(ctx context.Context, reason entity.RoomEndReason) {
+	if r.tournaments == nil {
+		return
+	}
+	res := usecase.TournamentRoomResult{RoomID: r.id, Reason: reason}
+	r.mu.Lock()
 	for _, p := range r.players {
-		if err := r.userRepo.UpdateGnuBalance(dbCtx, p.user.ID, p.gnuBalance); err != nil {
-			log.Printf("game room %s: failed to update gnu_balance for %s: %v",
-				r.id, p.user.GitHubLogin, err)
+		if p != nil && p.connected {
+			res.Present = append(res.Present, p.user.ID)
 		}
 	}
+	res.WinnerID = r.winnerID(r.winnerTeam)
+	r.mu.Unlock()
+	if err := r.tournaments.RecordRoomResult(ctx, res); err != nil {
+		log.Printf("game room %s: failed to report tournament result: %v", r.id, err)
+	}
 }
 
-// handleTKO は切断プレイヤーの TKO 処理を行う
-func (r *GameRoom) handleTKO(disconnIdx int) {
-	remainingIdx := 1 - disconnIdx
-	winner := r.players[remainingIdx]
-	if winner == nil {
+// flushEvents は記録したイベントを永続化する
+// 失敗した場合はイベントを戻し、次の書き出しでやり直す
+func (r *GameRoom) flushEvents() {
+	if r.matchRepo == nil {
 		return
 	}
+	events := r.events.take()
+	if len(events) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.matchRepo.SaveEvents(ctx, events); err != nil {
+		log.Printf("game room %s: failed to save %d match events: %v", r.id, len(events), err)
+		r.events.putBack(events)
+	}
+}
 
-	winner.gnuBalance += tkoBonus
+// checkpoint は completedTurns ターン目までの試合の状態を保存する
+// 保存に失敗した場合、停止時は保存済みの古い状態で再開せず、中止して精算する
+func (r *GameRoom) checkpoint(completedTurns int) {
+	if r.roomRepo == nil {
+		return
+	}
+	snapshot := &entity.RoomSnapshot{
+		RoomID:         r.id,
+		Rules:          r.rules,
+		CompletedTurns: completedTurns,
+		StartedAt:      r.startedAt,
+		SavedAt:        time.Now(),
+		Turns:          r.turnRecords,
+	}
+	for i, p := range r.players {
+		snapshot.Players = append(snapshot.Players, entity.RoomSnapshotPlayer{
+			UserID:       p.user.ID,
+			GitHubLogin:  p.user.GitHubLogin,
+			Rate:         p.user.Rate,
+			GnuBalance:   p.gnuBalance,
+			CorrectCount: r.correctCounts[i],
+			GnuEarned:    r.gnuEarned[i],
+			MyQuestions:  p.questions.MyQuestions,
+			ForOpponent:  p.questions.ForOpponent,
+		})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.roomRepo.SaveSnapshot(ctx, snapshot); err != nil {
+		log.Printf("game room %s: failed to save snapshot after turn %d: %v", r.id, completedTurns, err)
+		r.checkpointed = false
+		return
+	}
+	r.checkpointed = true
+}
 
-	winner.send(WSMessage{
-		Type: "ev_tko",
-		Payload: map[string]any{
-			"message":        "対戦相手が切断しました。TKO勝利です！",
-			"tko_bonus":      tkoBonus,
-			"your_final_gnu": winner.gnuBalance,
-		},
-	})
+// waitResume は復旧したルームで全プレイヤーの再接続を待つ
+// JoinWaitLimit 以内に揃わなければ完了したターンまでで精算して中止し、false を返す
+func (r *GameRoom) waitResume(ctx context.Context) bool {
+	completed := r.resume.CompletedTurns
+	log.Printf("game room %s: recovered after turn %d, waiting for %d players", r.id, completed, len(r.players))
+	timer := time.NewTimer(r.rules.JoinWaitLimit)
+	defer timer.Stop()
+	for !r.allConnected() {
+		select {
+		case idx := <-r.reconnCh:
+			log.Printf("game room %s: player[%d] reconnected to recovered room", r.id, idx)
+			r.players[idx].send(WSMessage{
+				Type: "ev_match_resuming",
+				Payload: map[string]any{
+					"completed_turns": completed,
+					"total_turns":     r.rules.TotalTurns,
+				},
+			})
+		case ev := <-r.disconnCh:
+			// 再開前の切断は猶予タイマーを使わず、JoinWaitLimit まで待ち続ける
+			r.mu.Lock()
+			if p := r.players[ev.idx]; p.conn == ev.conn {
+				p.connected = false
+			}
+			r.mu.Unlock()
+		case <-r.msgCh:
+			// 再開前の操作は受け付けない
+		case <-timer.C:
+			r.settleInterrupted()
+			return false
+		case <-ctx.Done():
+			r.suspendForShutdown(completed + 1)
+			return false
+		}
+	}
+
+	// 揃った時点で残っている再接続の通知は、続く ev_room_ready で足りるため捨てる
+	for len(r.reconnCh) > 0 {
+		<-r.reconnCh
+	}
+	log.Printf("game room %s: all players reconnected, resuming from turn %d", r.id, completed+1)
+	for i := range r.players {
+		r.sendRoomReady(i, true)
+	}
+	return true
+}
 
-	// 両プレイヤーの gnu_balance を DB 更新
+// settleInterrupted は再起動後に全プレイヤーが揃わなかった試合を、完了したターンまでで精算して中止する
+// 進行中だったターンのベットは精算しない（賭け金は戻る）
+func (r *GameRoom) settleInterrupted() {
+	log.Printf("game room %s: players did not return after restart, settling %d turns", r.id, len(r.turnRecords)/len(r.players))
 	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	for _, p := range r.players {
-		if p == nil {
-			continue
+	r.settleGnu(dbCtx, r.gnuTransactions())
+	r.saveAbortedMatch(dbCtx, entity.MatchEndReasonInterrupted)
+	r.updateLeaderboard(dbCtx, -1)
+	r.sendAllError("match_interrupted", "対戦相手が戻らなかったため、完了したターンまでで精算しました")
+	r.updateStatus(entity.RoomStatusAborted, entity.RoomEndReasonInterrupted)
+}
+
+// sendRoomReady は ev_room_ready を送信する（再接続時のリプレイにも使う）
+// opponent は組になる相手チームのプレイヤー。チーム戦では両チームのメンバーを添える
+func (r *GameRoom) sendRoomReady(idx int, reconnected bool) {
+	p := r.players[idx]
+	payload := map[string]any{
+		"your_gnu_balance": p.gnuBalance,
+		"reconnected":      reconnected,
+		"rules": map[string]any{
+			"total_turns":        r.rules.TotalTurns,
+			"questions_per_side": r.rules.QuestionsPerSide(),
+			"turn_duration_sec":  int(r.rules.TurnDuration / time.Second),
+			"min_bet":            r.rules.MinBet,
+			"tko_bonus":          r.rules.TKOBonus,
+		},
+		"opponent": r.playerSummary(r.opponentOf(idx, 0)),
+	}
+	if r.isTeamRoom() {
+		teammates := make([]map[string]any, 0, len(r.players))
+		for _, i := range r.teamMembers(p.team) {
+			if i != idx {
+				teammates = append(teammates, r.playerSummary(i))
+			}
 		}
-		if err := r.userRepo.UpdateGnuBalance(dbCtx, p.user.ID, p.gnuBalance); err != nil {
-			log.Printf("game room %s: TKO: failed to update gnu_balance for %s: %v",
-				r.id, p.user.GitHubLogin, err)
+		opponents := make([]map[string]any, 0, len(r.players))
+		for _, i := range r.teamMembers(1 - p.team) {
+			opponents = append(opponents, r.playerSummary(i))
 		}
+		payload["your_team"] = p.team
+		payload["teammates"] = teammates
+		payload["opponents"] = opponents
 	}
+	p.send(WSMessage{Type: "ev_room_ready", Payload: payload})
+}
 
-	log.Printf("game room %s: TKO. winner=%s (+%d gnu)", r.id, winner.user.GitHubLogin, tkoBonus)
+// playerSummary は ev_room_ready に載せるプレイヤーの情報を返す
+func (r *GameRoom) playerSummary(idx int) map[string]any {
+	p := r.players[idx]
+	return map[string]any{
+		"id":           p.user.ID.String(),
+		"github_login": p.user.GitHubLogin,
+		"rate":         p.user.Rate,
+		"gnu_balance":  p.gnuBalance,
+	}
 }
 
-// notifyOpponentDisconnect は相手プレイヤーに切断を通知する（ゲーム開始前）
-func (r *GameRoom) notifyOpponentDisconnect(disconnIdx int) {
-	opp := r.players[1-disconnIdx]
-	if opp == nil {
-		return
+// sendTurnStart は ev_turn_start を送信する
+// 再接続時のリプレイでは残り時間とベット・回答済み状態を反映する
+func (r *GameRoom) sendTurnStart(idx int, ts *turnState) {
+	p := r.players[idx]
+	q := ts.questions[idx]
+	minBet, maxBet := r.betRange(idx)
+	remaining := time.Until(ts.deadline)
+	if remaining < 0 {
+		remaining = 0
 	}
-	opp.send(WSMessage{
-		Type: "ev_error",
+	p.send(WSMessage{
+		Type: "ev_turn_start",
 		Payload: map[string]any{
-			"code":    "opponent_disconnected",
-			"message": "対戦相手が切断しました",
+			"turn":             ts.turn,
+			"total_turns":      r.rules.TotalTurns,
+			"question_type":    q.Kind(),
+			"difficulty":       q.Difficulty,
+			"question_text":    q.QuestionText,
+			"choices":          q.Choices,
+			"time_limit_sec":   int((remaining + time.Second - 1) / time.Second),
+			"your_gnu_balance": p.gnuBalance,
+			"min_bet":          minBet,
+			"max_bet":          maxBet,
+			"your_bet":         ts.bets[idx],
+			"answered":         ts.answered[idx],
 		},
 	})
 }
 
-// sendBothError は両プレイヤーにエラーを送信する
-func (r *GameRoom) sendBothError(code, message string) {
+// betRange は idx のプレイヤーがこのターンに賭けられる範囲を返す
+// 残高が MinBet に届かない場合は残高を最小額とし、どの額も賭けられないターンを作らない
+func (r *GameRoom) betRange(idx int) (minBet, maxBet int) {
+	maxBet = r.players[idx].gnuBalance
+	return min(r.rules.MinBet, maxBet), maxBet
+}
+
+// markDisconnected は切断イベントを処理し、再接続猶予タイマーを開始する
+// 既に差し替え済みの古い接続からのイベントであれば false を返す
+func (r *GameRoom) markDisconnected(ev playerConnEvent) bool {
+	r.mu.Lock()
+	p := r.players[ev.idx]
+	if p.conn != ev.conn {
+		r.mu.Unlock()
+		return false
+	}
+	p.connected = false
+	r.mu.Unlock()
+
+	if t := r.graceTimers[ev.idx]; t != nil {
+		t.Stop()
+	}
+	idx := ev.idx
+	r.graceTimers[idx] = time.AfterFunc(reconnectGrace, func() {
+		select {
+		case r.graceCh <- idx:
+		case <-r.closedCh:
+		}
+	})
+
+	r.notifyConnection(idx, "reconnecting", map[string]any{
+		"grace_sec": int(reconnectGrace / time.Second),
+	})
+	return true
+}
+
+// notifyConnection はプレイヤー idx の接続状態の変化を他のプレイヤーに通知する
+// 相手チームには ev_opponent_{state}、味方には ev_teammate_{state} を送る
+// チーム戦では誰の変化かが分かるよう github_login を添える
+func (r *GameRoom) notifyConnection(idx int, state string, payload map[string]any) {
+	players := r.joinedPlayers()
+	p := players[idx]
+	if r.isTeamRoom() {
+		payload["github_login"] = p.user.GitHubLogin
+	}
+	for i, o := range players {
+		if i == idx || o == nil {
+			continue
+		}
+		evType := "ev_opponent_" + state
+		if o.team == p.team {
+			evType = "ev_teammate_" + state
+		}
+		o.send(WSMessage{Type: evType, Payload: payload})
+	}
+}
+
+// joinedPlayers は players の写しを返す（未参加の位置は nil）
+// 開始前は join が並行して players に書き込むため、ロックを取って写す
+func (r *GameRoom) joinedPlayers() []*gamePlayerState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.players)
+}
+
+// isConnected はプレイヤーが接続中かどうかを返す
+func (r *GameRoom) isConnected(idx int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.players[idx].connected
+}
+
+// allConnected は全プレイヤーが接続中かどうかを返す
+func (r *GameRoom) allConnected() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	for _, p := range r.players {
+		if p == nil || !p.connected {
+			return false
+		}
+	}
+	return true
+}
+
+// handleReconnect は再接続したプレイヤーに現在の状態をリプレイし、他のプレイヤーに復帰を通知する
+// ts が nil の場合は問題受取フェーズ中
+func (r *GameRoom) handleReconnect(idx int, ts *turnState) {
+	log.Printf("game room %s: player[%d] reconnected", r.id, idx)
+	r.players[idx].left = false
+	r.stopGrace(idx)
+
+	r.sendRoomReady(idx, true)
+	if ts != nil {
+		r.sendTurnStart(idx, ts)
+	}
+}
+
+// stopGrace は再接続したプレイヤーの猶予タイマーを止め、他のプレイヤーに復帰を通知する
+func (r *GameRoom) stopGrace(idx int) {
+	if t := r.graceTimers[idx]; t != nil {
+		t.Stop()
+		r.graceTimers[idx] = nil
+		r.notifyConnection(idx, "reconnected", map[string]any{})
+	}
+}
+
+// handleTKO は全員が切断したチームの TKO 処理を行う
+// 相手チームの試合に残っているプレイヤーに TKO ボーナスを与える
+func (r *GameRoom) handleTKO(disconnTeam int) {
+	winnerTeam := 1 - disconnTeam
+	winners := make([]int, 0, len(r.players))
+	for _, i := range r.teamMembers(winnerTeam) {
+		if !r.players[i].left {
+			winners = append(winners, i)
+		}
+	}
+	if len(winners) == 0 {
+		return
+	}
+
+	tkoBonus := r.rules.TKOBonus
+	for _, i := range winners {
+		r.players[i].gnuBalance += tkoBonus
+	}
+
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rateChanges := r.applyRating(dbCtx, winnerTeam)
+	txs := r.gnuTransactions()
+	if tkoBonus > 0 {
+		for _, i := range winners {
+			winner := r.players[i]
+			txs = append(txs, entity.GnuTransaction{
+				UserID:         winner.user.ID,
+				RoomID:         uuid.NullUUID{UUID: r.id, Valid: true},
+				Reason:         entity.GnuTransactionReasonTKOBonus,
+				Delta:          tkoBonus,
+				IdempotencyKey: entity.TKOBonusTransactionKey(r.id, winner.user.ID),
+			})
+		}
+	}
+	r.settleGnu(dbCtx, txs)
+
+	message := "対戦相手が切断しました。TKO勝利です！"
+	if r.isTeamRoom() {
+		message = "相手チームが全員切断しました。TKO勝利です！"
+	}
+	for _, i := range winners {
+		winner := r.players[i]
+		winner.send(WSMessage{
+			Type: "ev_tko",
+			Payload: map[string]any{
+				"message":        message,
+				"tko_bonus":      tkoBonus,
+				"your_final_gnu": winner.gnuBalance,
+				"rate_before":    rateChanges[i].Before,
+				"rate_after":     rateChanges[i].After,
+				"rate_delta":     rateChanges[i].Delta,
+			},
+		})
+	}
+
+	r.broadcastSpectators(r.spectatorGameEnd(entity.MatchEndReasonTKO, winnerTeam, rateChanges))
+
+	r.saveMatchResult(dbCtx, entity.MatchEndReasonTKO, winnerTeam)
+	r.updateLeaderboard(dbCtx, winnerTeam)
+	r.winnerTeam = winnerTeam
+
+	log.Printf("game room %s: TKO. winner team=%d (+%d gnu each)", r.id, winnerTeam, tkoBonus)
+}
+
+// suspendForShutdown はサーバーの停止でゲームループを打ち切り、保存した状態から再開できるよう残す
+// ルームは in_progress のままにし、ヌーは精算しない。turn は再開時にやり直すターン
+func (r *GameRoom) suspendForShutdown(turn int) {
+	log.Printf("game room %s: suspended by server shutdown, will resume from turn %d", r.id, turn)
+	r.sendAllError("server_restarting", "サーバーを再起動しています。再接続すると試合を再開します")
+	r.flushEvents()
+}
+
+// abortForShutdown はサーバーの停止でゲームループを打ち切る（保存した状態がない場合）
+// 完了したターンまでのヌーは精算し、進行中のターンのベットは精算しない（賭け金は戻る）
+func (r *GameRoom) abortForShutdown() {
+	log.Printf("game room %s: aborted by server shutdown after %d turns", r.id, len(r.turnRecords)/len(r.players))
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	r.settleGnu(dbCtx, r.gnuTransactions())
+	r.saveAbortedMatch(dbCtx, entity.MatchEndReasonCanceled)
+	r.updateLeaderboard(dbCtx, -1)
+	r.sendAllError("server_shutdown", "サーバーの再起動のため試合を中止しました")
+	r.updateStatus(entity.RoomStatusAborted, entity.RoomEndReasonCanceled)
+}
+
+// notifyOpponentDisconnect は他のプレイヤーに切断を通知する（試合の中止時）
+func (r *GameRoom) notifyOpponentDisconnect(disconnIdx int) {
+	players := r.joinedPlayers()
+	team := players[disconnIdx].team
+	for i, o := range players {
+		if i == disconnIdx || o == nil {
+			continue
+		}
+		code, message := "opponent_disconnected", "対戦相手が切断しました"
+		if o.team == team {
+			code, message = "teammate_disconnected", "味方のプレイヤーが切断しました"
+		}
+		o.send(WSMessage{
+			Type: "ev_error",
+			Payload: map[string]any{
+				"code":    code,
+				"message": message,
+			},
+		})
+	}
+}
+
+// sendAllError は全プレイヤーにエラーを送信する
+func (r *GameRoom) sendAllError(code, message string) {
+	for _, p := range r.joinedPlayers() {
 		if p != nil {
 			p.send(WSMessage{
 				Type: "ev_error",
@@ -0,0 +1,1107 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/infrastructure/llm"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/testutil"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/usecase"
+)
+
+func TestGameRoom_Join_RejectsThirdPlayer(t *testing.T) {
+	room := newGameRoom(uuid.New(), nil, entity.DefaultMatchRules(), gameRoomDeps{}, func() {})
+
+	_, _, _, err := room.join(&websocket.Conn{}, &entity.User{ID: uuid.New()})
+	require.NoError(t, err)
+	_, _, _, err = room.join(&websocket.Conn{}, &entity.User{ID: uuid.New()})
+	require.NoError(t, err)
+
+	_, _, _, err = room.join(&websocket.Conn{}, &entity.User{ID: uuid.New()})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "room is full")
+}
+
+func TestGameRoom_Join_ReconnectSwapsConn(t *testing.T) {
+	room := newGameRoom(uuid.New(), nil, entity.DefaultMatchRules(), gameRoomDeps{}, func() {})
+	u1 := &entity.User{ID: uuid.New()}
+	u2 := &entity.User{ID: uuid.New()}
+
+	_, _, _, err := room.join(&websocket.Conn{}, u1)
+	require.NoError(t, err)
+	idx, _, _, err := room.join(&websocket.Conn{}, u2)
+	require.NoError(t, err)
+	require.Equal(t, 1, idx)
+
+	// 切断状態を再現する
+	room.mu.Lock()
+	room.players[1].connected = false
+	room.mu.Unlock()
+
+	newConn := &websocket.Conn{}
+	idx, doneCh, reconnected, err := room.join(newConn, u2)
+	require.NoError(t, err)
+	assert.True(t, reconnected)
+	assert.Equal(t, 1, idx)
+	assert.NotNil(t, doneCh)
+	assert.Same(t, newConn, room.players[1].conn)
+	assert.True(t, room.players[1].connected)
+
+	select {
+	case got := <-room.reconnCh:
+		assert.Equal(t, 1, got)
+	default:
+		t.Fatal("reconnect should be notified to the game loop")
+	}
+}
+
+func TestGameRoom_Join_ClosedRoom(t *testing.T) {
+	room := newGameRoom(uuid.New(), nil, entity.DefaultMatchRules(), gameRoomDeps{}, func() {})
+	room.close()
+
+	_, _, _, err := room.join(&websocket.Conn{}, &entity.User{ID: uuid.New()})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "room is closed")
+}
+
+func TestGameRoom_SaveMatchResult_TKO(t *testing.T) {
+	var saved *entity.MatchResult
+	matchRepo := &testutil.MockMatchRepository{
+		SaveFunc: func(_ context.Context, result *entity.MatchResult) error {
+			saved = result
+			return nil
+		},
+	}
+	room := newGameRoom(uuid.New(), nil, entity.DefaultMatchRules(), gameRoomDeps{matchRepo: matchRepo}, func() {})
+	u1 := &entity.User{ID: uuid.New(), GnuBalance: 1000}
+	u2 := &entity.User{ID: uuid.New(), GnuBalance: 1000}
+	_, _, _, err := room.join(&websocket.Conn{}, u1)
+	require.NoError(t, err)
+	_, _, _, err = room.join(&websocket.Conn{}, u2)
+	require.NoError(t, err)
+
+	start := time.Now()
+	ts := &turnState{
+		turn:       1,
+		startedAt:  start,
+		answers:    []entity.Answer{entity.ChoiceAnswer(2), {}},
+		answered:   []bool{true, false},
+		answeredAt: []time.Time{start.Add(1500 * time.Millisecond), {}},
+		bets:       []int{100, 50},
+		questions:  make([]entity.Question, 2),
+	}
+	room.recordTurn(ts, []bool{true, false}, []int{100, -50})
+	room.correctCounts = []int{1, 0}
+
+	room.saveMatchResult(context.Background(), entity.MatchEndReasonTKO, 0)
+
+	require.NotNil(t, saved)
+	assert.Equal(t, entity.MatchEndReasonTKO, saved.EndReason)
+	assert.Equal(t, uuid.NullUUID{UUID: u1.ID, Valid: true}, saved.WinnerID)
+	assert.Equal(t, 1, saved.TotalTurns)
+	assert.Equal(t, 1, saved.Player1CorrectCount)
+	require.Len(t, saved.Turns, 2)
+	require.NotNil(t, saved.Turns[0].AnswerTimeMs)
+	assert.Equal(t, 1500, *saved.Turns[0].AnswerTimeMs)
+	assert.Nil(t, saved.Turns[1].AnswerTimeMs, "unanswered turn should have no answer time")
+	assert.Equal(t, 2, saved.Turns[0].ChoiceIndex)
+	assert.Equal(t, -1, saved.Turns[1].ChoiceIndex)
+	assert.Nil(t, saved.Turns[1].Answer, "unanswered turn should have no answer")
+}
+
+func TestGameRoom_SaveMatchResult_InvalidatesStats(t *testing.T) {
+	var invalidated []uuid.UUID
+	cache := &testutil.MockUserStatsCacheRepository{
+		DeleteFunc: func(_ context.Context, userIDs ...uuid.UUID) error {
+			invalidated = append(invalidated, userIDs...)
+			return nil
+		},
+	}
+	saveErr := errors.New("db down")
+	matchRepo := &testutil.MockMatchRepository{
+		SaveFunc: func(context.Context, *entity.MatchResult) error { return saveErr },
+	}
+	deps := gameRoomDeps{matchRepo: matchRepo, stats: usecase.NewUserStatsUsecase(nil, matchRepo, cache)}
+	room := newGameRoom(uuid.New(), nil, entity.DefaultMatchRules(), deps, func() {})
+	u1 := &entity.User{ID: uuid.New()}
+	u2 := &entity.User{ID: uuid.New()}
+	_, _, _, err := room.join(&websocket.Conn{}, u1)
+	require.NoError(t, err)
+	_, _, _, err = room.join(&websocket.Conn{}, u2)
+	require.NoError(t, err)
+
+	room.saveMatchResult(context.Background(), entity.MatchEndReasonCompleted, -1)
+	assert.Empty(t, invalidated, "stats are unchanged when the result was not saved")
+
+	saveErr = nil
+	room.saveMatchResult(context.Background(), entity.MatchEndReasonCompleted, -1)
+	assert.Equal(t, []uuid.UUID{u1.ID, u2.ID}, invalidated)
+}
+
+func TestGameRoom_SaveMatchResult_Team(t *testing.T) {
+	var saved *entity.MatchResult
+	matchRepo := &testutil.MockMatchRepository{
+		SaveFunc: func(_ context.Context, result *entity.MatchResult) error {
+			saved = result
+			return nil
+		},
+	}
+	var invalidated []uuid.UUID
+	cache := &testutil.MockUserStatsCacheRepository{
+		DeleteFunc: func(_ context.Context, userIDs ...uuid.UUID) error {
+			invalidated = append(invalidated, userIDs...)
+			return nil
+		},
+	}
+	users := map[string]*entity.User{}
+	var members []entity.RoomPlayer
+	for i, login := range []string{"alice", "bob", "carol", "dave"} {
+		users[login] = &entity.User{ID: uuid.New(), GitHubLogin: login, GnuBalance: 100 + i}
+		members = append(members, entity.RoomPlayer{UserID: users[login].ID, Team: i % 2})
+	}
+	deps := gameRoomDeps{matchRepo: matchRepo, stats: usecase.NewUserStatsUsecase(nil, matchRepo, cache)}
+	room := newGameRoom(uuid.New(), members, entity.DefaultMatchRules(), deps, func() {})
+	// チーム内の並び順は参加順ではなく members の順
+	for _, login := range []string{"carol", "bob", "alice", "dave"} {
+		_, _, _, err := room.join(&websocket.Conn{}, users[login])
+		require.NoError(t, err)
+	}
+	room.correctCounts = []int{3, 2, 1, 0}
+	room.gnuEarned = []int{30, 20, 10, 0}
+
+	room.saveMatchResult(context.Background(), entity.MatchEndReasonCompleted, 1)
+
+	require.NotNil(t, saved)
+	assert.Equal(t, users["alice"].ID, saved.Player1ID)
+	assert.Equal(t, users["bob"].ID, saved.Player2ID)
+	assert.Equal(t, 1, saved.Player1CorrectCount)
+	assert.Equal(t, 2, saved.Player2CorrectCount)
+	assert.Equal(t, uuid.NullUUID{UUID: users["bob"].ID, Valid: true}, saved.WinnerID)
+	assert.Equal(t, []entity.MatchResultPlayer{
+		{UserID: users["carol"].ID, Team: 0, Position: 1, Outcome: entity.MatchOutcomeLose, CorrectCount: 3, GnuEarned: 30, FinalGnu: 102},
+		{UserID: users["bob"].ID, Team: 1, Position: 0, Outcome: entity.MatchOutcomeWin, CorrectCount: 2, GnuEarned: 20, FinalGnu: 101},
+		{UserID: users["alice"].ID, Team: 0, Position: 0, Outcome: entity.MatchOutcomeLose, CorrectCount: 1, GnuEarned: 10, FinalGnu: 100},
+		{UserID: users["dave"].ID, Team: 1, Position: 1, Outcome: entity.MatchOutcomeWin, CorrectCount: 0, GnuEarned: 0, FinalGnu: 103},
+	}, saved.Players)
+	assert.Len(t, invalidated, 4, "stats of every player are invalidated")
+}
+
+func TestGameRoom_AddSpectator_Limit(t *testing.T) {
+	room := newGameRoom(uuid.New(), nil, entity.DefaultMatchRules(), gameRoomDeps{maxSpectators: 1}, func() {})
+
+	s, err := room.addSpectator(&websocket.Conn{})
+	require.NoError(t, err)
+	require.NotNil(t, s)
+
+	_, err = room.addSpectator(&websocket.Conn{})
+	require.ErrorIs(t, err, ErrSpectatorLimit)
+
+	room.removeSpectator(s)
+	_, err = room.addSpectator(&websocket.Conn{})
+	require.NoError(t, err, "slot should be freed after a spectator leaves")
+}
+
+func TestGameRoom_SpectatorTurnStart_HidesAnswer(t *testing.T) {
+	room := newGameRoom(uuid.New(), nil, entity.DefaultMatchRules(), gameRoomDeps{maxSpectators: 1}, func() {})
+	_, _, _, err := room.join(&websocket.Conn{}, &entity.User{ID: uuid.New(), GitHubLogin: "alice"})
+	require.NoError(t, err)
+	_, _, _, err = room.join(&websocket.Conn{}, &entity.User{ID: uuid.New(), GitHubLogin: "bob"})
+	require.NoError(t, err)
+	s, err := room.addSpectator(&websocket.Conn{})
+	require.NoError(t, err)
+
+	q := entity.Question{
+		QuestionText:  "What does main.go do?",
+		Choices:       []string{"a", "b", "c", "secret"},
+		CorrectAnswer: "secret",
+	}
+	ts := newTurnState(1, []entity.Question{q, q}, time.Now(), time.Minute)
+	room.broadcastSpectators(room.spectatorTurnStart(ts))
+
+	msg := <-s.sendCh
+	data, err := json.Marshal(msg)
+	require.NoError(t, err)
+	assert.Equal(t, "ev_turn_start", msg.Type)
+	assert.Contains(t, string(data), "What does main.go do?")
+	assert.NotContains(t, string(data), "correct_answer")
+	assert.NotContains(t, string(data), "correct_index")
+
+	room.broadcastSpectators(room.spectatorTurnResult(ts, make([]bool, 2), make([]int, 2)))
+	msg = <-s.sendCh
+	data, err = json.Marshal(msg)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"correct_answer":"secret"`, "answer is revealed once the turn ends")
+}
+
+func TestGameRoom_AddSpectator_MidTurnGetsCurrentTurn(t *testing.T) {
+	room := newGameRoom(uuid.New(), nil, entity.DefaultMatchRules(), gameRoomDeps{maxSpectators: 2}, func() {})
+	_, _, _, err := room.join(&websocket.Conn{}, &entity.User{ID: uuid.New(), GitHubLogin: "alice"})
+	require.NoError(t, err)
+	_, _, _, err = room.join(&websocket.Conn{}, &entity.User{ID: uuid.New(), GitHubLogin: "bob"})
+	require.NoError(t, err)
+
+	q := entity.Question{
+		QuestionText:  "What does main.go do?",
+		Choices:       []string{"a", "b", "c", "secret"},
+		CorrectAnswer: "secret",
+	}
+	ts := newTurnState(3, []entity.Question{q, q}, time.Now().Add(-20*time.Second), time.Minute)
+	room.broadcastSpectatorTurnStart(ts)
+
+	// ターンの途中から観戦を始めても、進行中のターンが残り時間とともに届く
+	s, err := room.addSpectator(&websocket.Conn{})
+	require.NoError(t, err)
+	msg := <-s.sendCh
+	assert.Equal(t, "ev_turn_start", msg.Type)
+	payload := msg.Payload.(map[string]any)
+	assert.Equal(t, 3, payload["turn"])
+	assert.InDelta(t, 40, payload["time_limit_sec"], 1)
+	data, err := json.Marshal(msg)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "What does main.go do?")
+	assert.NotContains(t, string(data), "correct_answer")
+
+	// ターンが終わった後に観戦を始めた場合は、次のターンを待つ
+	room.broadcastSpectators(room.spectatorTurnResult(ts, make([]bool, 2), make([]int, 2)))
+	s, err = room.addSpectator(&websocket.Conn{})
+	require.NoError(t, err)
+	assert.Empty(t, s.sendCh)
+}
+
+func TestGameRoom_BroadcastSpectators_DoesNotBlock(t *testing.T) {
+	room := newGameRoom(uuid.New(), nil, entity.DefaultMatchRules(), gameRoomDeps{maxSpectators: 1}, func() {})
+	_, err := room.addSpectator(&websocket.Conn{})
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range spectatorSendBuffer + 5 {
+			room.broadcastSpectators(WSMessage{Type: "ev_turn_start"})
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("broadcast should drop messages instead of blocking on a slow spectator")
+	}
+}
+
+func TestBuildTurnSchedule_FollowsTotalTurns(t *testing.T) {
+	q := func(text string) entity.Question { return entity.Question{QuestionText: text} }
+	q0 := &QuestionSet{
+		MyQuestions: []entity.Question{q("p0-my-0"), q("p0-my-1")},
+		ForOpponent: []entity.Question{q("p0-for-0"), q("p0-for-1")},
+	}
+	q1 := &QuestionSet{
+		MyQuestions: []entity.Question{q("p1-my-0"), q("p1-my-1")},
+		ForOpponent: []entity.Question{q("p1-for-0"), q("p1-for-1")},
+	}
+
+	turns := buildTurnSchedule(3, []*QuestionSet{q0, q1}, []int{1, 0})
+
+	require.Len(t, turns, 3)
+	assert.Equal(t, "p1-for-0", turns[0][0].QuestionText, "player0 answers opponent's for_opponent first")
+	assert.Equal(t, "p0-for-0", turns[0][1].QuestionText)
+	assert.Equal(t, "p0-my-0", turns[1][0].QuestionText)
+	assert.Equal(t, "p1-my-0", turns[1][1].QuestionText)
+	assert.Equal(t, "p1-for-1", turns[2][0].QuestionText)
+	assert.Equal(t, "p0-for-1", turns[2][1].QuestionText)
+}
+
+func TestGameRoom_GenerateQuestions_SplitsByRepository(t *testing.T) {
+	fileRepo := &testutil.MockRepositoryFileRepository{
+		ListLatestByOwnerFunc: func(_ context.Context, owner string) ([]entity.RepositoryFile, error) {
+			return []entity.RepositoryFile{{FilePath: owner + ".go", Content: "package " + owner}}, nil
+		},
+	}
+	questionUC := usecase.NewQuestionUsecase(llm.NewFakeGenerator(), fileRepo)
+	rules := entity.DefaultMatchRules()
+	rules.TotalTurns = 3
+	room := newGameRoom(uuid.New(), nil, rules, gameRoomDeps{questions: questionUC}, func() {})
+	_, _, _, err := room.join(&websocket.Conn{}, &entity.User{ID: uuid.New(), GitHubLogin: "alice"})
+	require.NoError(t, err)
+	_, _, _, err = room.join(&websocket.Conn{}, &entity.User{ID: uuid.New(), GitHubLogin: "bob"})
+	require.NoError(t, err)
+
+	sets, err := room.generateQuestions(context.Background())
+
+	require.NoError(t, err)
+	for i, owner := range []string{"alice", "bob"} {
+		opp := []string{"bob", "alice"}[i]
+		require.Len(t, sets[i].ForOpponent, 2)
+		require.Len(t, sets[i].MyQuestions, 2)
+		for j := range 2 {
+			assert.Contains(t, sets[i].ForOpponent[j].QuestionText, owner+".go", "for_opponent comes from own repository")
+			assert.Contains(t, sets[i].MyQuestions[j].QuestionText, opp+".go", "my_questions come from opponent's repository")
+		}
+	}
+	assert.NotEqual(t, sets[0].ForOpponent[0], sets[1].MyQuestions[0], "each player solves distinct questions")
+}
+
+// newTestConn は httptest サーバー越しに接続した WebSocket のサーバー側とクライアント側を返す
+func newTestConn(t *testing.T) (*websocket.Conn, *websocket.Conn) {
+	t.Helper()
+	serverConnCh := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		serverConnCh <- conn
+	}))
+	t.Cleanup(srv.Close)
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	server := <-serverConnCh
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	return server, client
+}
+
+// readUntil は client から msgType のメッセージを受け取るまで読み進める
+func readUntil(t *testing.T, client *websocket.Conn, msgType string) WSMessage {
+	t.Helper()
+	require.NoError(t, client.SetReadDeadline(time.Now().Add(2*time.Second)))
+	for {
+		var msg WSMessage
+		require.NoError(t, client.ReadJSON(&msg))
+		if msg.Type == msgType {
+			return msg
+		}
+	}
+}
+
+type roomStatusChange struct {
+	status entity.RoomStatus
+	reason entity.RoomEndReason
+}
+
+// newStatusRecorder はルームの状態遷移を記録する RoomRepository を返す
+func newStatusRecorder() (*testutil.MockRoomRepository, func() []roomStatusChange) {
+	var mu sync.Mutex
+	var changes []roomStatusChange
+	repo := &testutil.MockRoomRepository{
+		UpdateStatusFunc: func(_ context.Context, _ uuid.UUID, status entity.RoomStatus, reason entity.RoomEndReason) error {
+			mu.Lock()
+			defer mu.Unlock()
+			changes = append(changes, roomStatusChange{status: status, reason: reason})
+			return nil
+		},
+	}
+	return repo, func() []roomStatusChange {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]roomStatusChange(nil), changes...)
+	}
+}
+
+func TestGameRoom_Run_NoShowAbortsRoom(t *testing.T) {
+	roomRepo, changes := newStatusRecorder()
+	rules := entity.DefaultMatchRules()
+	rules.JoinWaitLimit = 50 * time.Millisecond
+	room := newGameRoom(uuid.New(), nil, rules, gameRoomDeps{roomRepo: roomRepo}, func() {})
+	server, client := newTestConn(t)
+	_, _, _, err := room.join(server, &entity.User{ID: uuid.New(), GitHubLogin: "alice"})
+	require.NoError(t, err)
+
+	room.run(context.Background())
+
+	msg := readUntil(t, client, "ev_error")
+	assert.Equal(t, "opponent_no_show", msg.Payload.(map[string]any)["code"])
+	assert.Equal(t, []roomStatusChange{
+		{status: entity.RoomStatusAborted, reason: entity.RoomEndReasonNoShow},
+	}, changes())
+}
+
+func TestGameRoom_Run_DisconnectBeforeStartWaitsForReconnect(t *testing.T) {
+	roomRepo, changes := newStatusRecorder()
+	room := newGameRoom(uuid.New(), nil, entity.DefaultMatchRules(), gameRoomDeps{roomRepo: roomRepo}, func() {})
+	alice := &entity.User{ID: uuid.New(), GitHubLogin: "alice"}
+	server, client := newTestConn(t)
+	idx, _, _, err := room.join(server, alice)
+	require.NoError(t, err)
+	go room.startReaderLoop(idx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		room.run(context.Background())
+	}()
+
+	// 相手の参加前に切断しても、再接続猶予の間はルームを閉じない
+	require.NoError(t, client.Close())
+	require.Eventually(t, func() bool { return !room.isConnected(idx) }, time.Second, 10*time.Millisecond)
+	server, _ = newTestConn(t)
+	_, _, reconnected, err := room.join(server, alice)
+	require.NoError(t, err)
+	assert.True(t, reconnected)
+	server, _ = newTestConn(t)
+	_, _, _, err = room.join(server, &entity.User{ID: uuid.New(), GitHubLogin: "bob"})
+	require.NoError(t, err)
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("room did not finish")
+	}
+	// 問題の生成器がないため、開始した後に中止される
+	assert.Equal(t, []roomStatusChange{
+		{status: entity.RoomStatusInProgress, reason: entity.RoomEndReasonNone},
+		{status: entity.RoomStatusAborted, reason: entity.RoomEndReasonQuestionGenerationFailed},
+	}, changes())
+}
+
+func TestGameRoom_Run_DisconnectWhileTeamRoomFills(t *testing.T) {
+	roomRepo, changes := newStatusRecorder()
+	users := map[string]*entity.User{}
+	var members []entity.RoomPlayer
+	for i, login := range []string{"alice", "bob", "carol", "dave"} {
+		users[login] = &entity.User{ID: uuid.New(), GitHubLogin: login}
+		members = append(members, entity.RoomPlayer{UserID: users[login].ID, Team: i % 2})
+	}
+	room := newGameRoom(uuid.New(), members, entity.DefaultMatchRules(), gameRoomDeps{roomRepo: roomRepo}, func() {})
+	server, client := newTestConn(t)
+	idx, _, _, err := room.join(server, users["alice"])
+	require.NoError(t, err)
+	go room.startReaderLoop(idx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		room.run(context.Background())
+	}()
+
+	// alice の再接続猶予が切れる処理と、他のプレイヤーの参加が並行する
+	require.NoError(t, client.Close())
+	require.Eventually(t, func() bool { return !room.isConnected(idx) }, time.Second, 10*time.Millisecond)
+	room.graceCh <- idx
+	server, _ = newTestConn(t)
+	_, _, _, err = room.join(server, users["bob"])
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		room.mu.Lock()
+		defer room.mu.Unlock()
+		return room.players[idx].left
+	}, time.Second, 10*time.Millisecond)
+
+	// チームメイトの carol がまだ参加していないため、alice のチームは全員離脱したことにならない
+	for _, login := range []string{"carol", "dave"} {
+		server, _ = newTestConn(t)
+		_, _, _, err = room.join(server, users[login])
+		require.NoError(t, err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("room did not finish")
+	}
+	// 問題の生成器がないため、開始した後に中止される
+	assert.Equal(t, []roomStatusChange{
+		{status: entity.RoomStatusInProgress, reason: entity.RoomEndReasonNone},
+		{status: entity.RoomStatusAborted, reason: entity.RoomEndReasonQuestionGenerationFailed},
+	}, changes())
+}
+
+func TestGameRoom_Run_NoShowReportsTournamentForfeit(t *testing.T) {
+	roomRepo, _ := newStatusRecorder()
+	rules := entity.DefaultMatchRules()
+	rules.JoinWaitLimit = 50 * time.Millisecond
+	roomID := uuid.New()
+	alice := &entity.User{ID: uuid.New(), GitHubLogin: "alice"}
+	bob := &entity.User{ID: uuid.New(), GitHubLogin: "bob"}
+	match := &entity.TournamentMatch{
+		ID:           uuid.New(),
+		TournamentID: uuid.New(),
+		Round:        1,
+		Status:       entity.TournamentMatchStatusPlaying,
+		Player1ID:    uuid.NullUUID{UUID: alice.ID, Valid: true},
+		Player2ID:    uuid.NullUUID{UUID: bob.ID, Valid: true},
+		RoomID:       uuid.NullUUID{UUID: roomID, Valid: true},
+		Attempts:     1,
+	}
+	var saved *entity.TournamentBracket
+	tournamentRepo := &testutil.MockTournamentRepository{
+		GetMatchByRoomIDFunc: func(_ context.Context, id uuid.UUID) (*entity.TournamentMatch, error) {
+			assert.Equal(t, roomID, id)
+			return match, nil
+		},
+		UpdateFunc: func(_ context.Context, _ uuid.UUID, fn func(*entity.TournamentBracket) error) (*entity.TournamentBracket, error) {
+			b := &entity.TournamentBracket{
+				Tournament: entity.Tournament{ID: match.TournamentID, Status: entity.TournamentStatusInProgress},
+				Participants: []entity.TournamentParticipant{
+					{UserID: alice.ID, Seed: 1},
+					{UserID: bob.ID, Seed: 2},
+				},
+				Matches: []*entity.TournamentMatch{match},
+			}
+			if err := fn(b); err != nil {
+				return nil, err
+			}
+			saved = b
+			return b, nil
+		},
+	}
+	tournaments := usecase.NewTournamentUsecase(tournamentRepo, nil, roomRepo, nil, time.Minute)
+	room := newGameRoom(roomID, nil, rules, gameRoomDeps{roomRepo: roomRepo, tournaments: tournaments}, func() {})
+	server, client := newTestConn(t)
+	_, _, _, err := room.join(server, bob)
+	require.NoError(t, err)
+
+	room.run(context.Background())
+
+	readUntil(t, client, "ev_error")
+	require.NotNil(t, saved)
+	// 参加した bob が不戦勝で決勝（1試合のみ）を制する
+	assert.Equal(t, entity.TournamentMatchResultForfeit, match.Result)
+	assert.Equal(t, bob.ID, match.WinnerID.UUID)
+	assert.Equal(t, entity.TournamentStatusFinished, saved.Tournament.Status)
+	assert.Equal(t, bob.ID, saved.Tournament.WinnerID.UUID)
+}
+
+func TestGameRoom_Run_QuestionFailureAbortsRoom(t *testing.T) {
+	roomRepo, changes := newStatusRecorder()
+	fileRepo := &testutil.MockRepositoryFileRepository{
+		ListLatestByOwnerFunc: func(context.Context, string) ([]entity.RepositoryFile, error) {
+			return nil, errors.New("db down")
+		},
+	}
+	questionUC := usecase.NewQuestionUsecase(llm.NewFakeGenerator(), fileRepo)
+	room := newGameRoom(uuid.New(), nil, entity.DefaultMatchRules(), gameRoomDeps{roomRepo: roomRepo, questions: questionUC}, func() {})
+	clients := make([]*websocket.Conn, 2)
+	for i, login := range []string{"alice", "bob"} {
+		server, client := newTestConn(t)
+		clients[i] = client
+		_, _, _, err := room.join(server, &entity.User{ID: uuid.New(), GitHubLogin: login})
+		require.NoError(t, err)
+	}
+
+	room.run(context.Background())
+
+	for _, client := range clients {
+		msg := readUntil(t, client, "ev_error")
+		assert.Equal(t, "question_generation_failed", msg.Payload.(map[string]any)["code"])
+	}
+	assert.Equal(t, []roomStatusChange{
+		{status: entity.RoomStatusInProgress, reason: entity.RoomEndReasonNone},
+		{status: entity.RoomStatusAborted, reason: entity.RoomEndReasonQuestionGenerationFailed},
+	}, changes())
+}
+
+func TestGameRoom_SettleGnu_AppliesTurnDeltas(t *testing.T) {
+	var applied []entity.GnuTransaction
+	alice := &entity.User{ID: uuid.New(), GitHubLogin: "alice", GnuBalance: 1000}
+	bob := &entity.User{ID: uuid.New(), GitHubLogin: "bob", GnuBalance: 1000}
+	ledger := &testutil.MockGnuLedgerRepository{
+		ApplyFunc: func(_ context.Context, txs []entity.GnuTransaction) (map[uuid.UUID]int, error) {
+			applied = txs
+			// 試合中に別の取引があった想定で、参加時とは異なる残高を返す
+			return map[uuid.UUID]int{alice.ID: 1600, bob.ID: 470}, nil
+		},
+	}
+	room := newGameRoom(uuid.New(), nil, entity.DefaultMatchRules(), gameRoomDeps{gnuLedger: ledger}, func() {})
+	_, _, _, err := room.join(&websocket.Conn{}, alice)
+	require.NoError(t, err)
+	_, _, _, err = room.join(&websocket.Conn{}, bob)
+	require.NoError(t, err)
+	room.turnRecords = []entity.MatchTurn{
+		{UserID: alice.ID, Turn: 1, GnuDelta: 100},
+		{UserID: bob.ID, Turn: 1, GnuDelta: -50},
+		{UserID: alice.ID, Turn: 2, GnuDelta: 0},
+		{UserID: bob.ID, Turn: 2, GnuDelta: 20},
+	}
+
+	room.settleGnu(context.Background(), room.gnuTransactions())
+
+	require.Len(t, applied, 3, "turns without a gnu change are not recorded")
+	assert.Equal(t, entity.GnuTransactionReasonBetWin, applied[0].Reason)
+	assert.Equal(t, entity.GnuTransactionReasonBetLoss, applied[1].Reason)
+	assert.Equal(t, -50, applied[1].Delta)
+	assert.Equal(t, entity.TurnTransactionKey(room.id, 2, bob.ID), applied[2].IdempotencyKey)
+	for _, tx := range applied {
+		assert.Equal(t, uuid.NullUUID{UUID: room.id, Valid: true}, tx.RoomID)
+	}
+	assert.Equal(t, 1600, room.players[0].gnuBalance)
+	assert.Equal(t, 470, room.players[1].gnuBalance)
+}
+
+func TestGameRoom_SettleGnu_KeepsBalanceOnFailure(t *testing.T) {
+	ledger := &testutil.MockGnuLedgerRepository{
+		ApplyFunc: func(context.Context, []entity.GnuTransaction) (map[uuid.UUID]int, error) {
+			return nil, errors.New("db down")
+		},
+	}
+	room := newGameRoom(uuid.New(), nil, entity.DefaultMatchRules(), gameRoomDeps{gnuLedger: ledger}, func() {})
+	user := &entity.User{ID: uuid.New(), GitHubLogin: "alice", GnuBalance: 1000}
+	_, _, _, err := room.join(&websocket.Conn{}, user)
+	require.NoError(t, err)
+	room.players[0].gnuBalance = 1100
+	room.turnRecords = []entity.MatchTurn{{UserID: user.ID, Turn: 1, GnuDelta: 100}}
+
+	room.settleGnu(context.Background(), room.gnuTransactions())
+
+	assert.Equal(t, 1100, room.players[0].gnuBalance)
+}
+
+// startTestMatch は alice と bob が参加したルームのゲームループを起動し、最初のターンが始まるまで進める
+// 返り値の cancel でゲームループの ctx を終わらせ、done でゲームループの終了を待つ
+func startTestMatch(t *testing.T, deps gameRoomDeps) (*GameRoom, []*websocket.Conn, context.CancelFunc, <-chan struct{}) {
+	t.Helper()
+	return startTestMatchWithRules(t, entity.DefaultMatchRules(), deps)
+}
+
+func startTestMatchWithRules(t *testing.T, rules entity.MatchRules, deps gameRoomDeps) (*GameRoom, []*websocket.Conn, context.CancelFunc, <-chan struct{}) {
+	t.Helper()
+	fileRepo := &testutil.MockRepositoryFileRepository{
+		ListLatestByOwnerFunc: func(_ context.Context, owner string) ([]entity.RepositoryFile, error) {
+			return []entity.RepositoryFile{{FilePath: owner + ".go", Content: "package " + owner}}, nil
+		},
+	}
+	deps.questions = usecase.NewQuestionUsecase(llm.NewFakeGenerator(), fileRepo)
+	room := newGameRoom(uuid.New(), nil, rules, deps, func() {})
+	clients := make([]*websocket.Conn, 2)
+	for i, login := range []string{"alice", "bob"} {
+		server, client := newTestConn(t)
+		clients[i] = client
+		idx, _, _, err := room.join(server, &entity.User{ID: uuid.New(), GitHubLogin: login, GnuBalance: 100})
+		require.NoError(t, err)
+		go room.startReaderLoop(idx)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		room.run(ctx)
+	}()
+	for _, client := range clients {
+		readUntil(t, client, "ev_turn_start")
+	}
+	return room, clients, cancel, done
+}
+
+func TestGameRoom_Run_ShutdownAbortsRoomWithoutSnapshot(t *testing.T) {
+	roomRepo, changes := newStatusRecorder()
+	roomRepo.SaveSnapshotFunc = func(context.Context, *entity.RoomSnapshot) error {
+		return errors.New("redis down")
+	}
+	var applied []entity.GnuTransaction
+	ledger := &testutil.MockGnuLedgerRepository{
+		ApplyFunc: func(_ context.Context, txs []entity.GnuTransaction) (map[uuid.UUID]int, error) {
+			applied = append(applied, txs...)
+			return map[uuid.UUID]int{}, nil
+		},
+	}
+	matchSaved := false
+	matchRepo := &testutil.MockMatchRepository{
+		SaveFunc: func(context.Context, *entity.MatchResult) error {
+			matchSaved = true
+			return nil
+		},
+	}
+	_, clients, cancel, done := startTestMatch(t, gameRoomDeps{roomRepo: roomRepo, gnuLedger: ledger, matchRepo: matchRepo})
+
+	require.NoError(t, clients[0].WriteJSON(WSMessage{Type: "act_bet_gnu", Payload: map[string]any{"amount": 10}}))
+	readUntil(t, clients[0], "ev_bet_confirmed")
+	cancel()
+	<-done
+
+	for _, client := range clients {
+		msg := readUntil(t, client, "ev_error")
+		assert.Equal(t, "server_shutdown", msg.Payload.(map[string]any)["code"])
+	}
+	assert.Empty(t, applied, "bets of the unfinished turn are not settled")
+	assert.False(t, matchSaved, "a match without a completed turn is not saved")
+	assert.Equal(t, []roomStatusChange{
+		{status: entity.RoomStatusInProgress, reason: entity.RoomEndReasonNone},
+		{status: entity.RoomStatusAborted, reason: entity.RoomEndReasonCanceled},
+	}, changes())
+}
+
+func TestGameRoom_Run_ShutdownSavesCanceledMatch(t *testing.T) {
+	roomRepo, _ := newStatusRecorder()
+	roomRepo.SaveSnapshotFunc = func(context.Context, *entity.RoomSnapshot) error {
+		return errors.New("redis down")
+	}
+	var saved *entity.MatchResult
+	matchRepo := &testutil.MockMatchRepository{
+		SaveFunc: func(_ context.Context, result *entity.MatchResult) error {
+			saved = result
+			return nil
+		},
+	}
+	room, clients, cancel, done := startTestMatch(t, gameRoomDeps{roomRepo: roomRepo, matchRepo: matchRepo})
+
+	for _, client := range clients {
+		require.NoError(t, client.WriteJSON(WSMessage{Type: "act_submit_answer", Payload: map[string]any{"choice_index": 0}}))
+	}
+	for _, client := range clients {
+		readUntil(t, client, "ev_turn_start")
+	}
+	cancel()
+	<-done
+
+	require.NotNil(t, saved)
+	assert.Equal(t, entity.MatchEndReasonCanceled, saved.EndReason)
+	assert.Equal(t, 1, saved.TotalTurns, "only the completed turn is recorded")
+	assert.Len(t, saved.Turns, 2)
+	assert.False(t, saved.WinnerID.Valid)
+	require.Len(t, saved.Players, 2)
+	for i, p := range saved.Players {
+		assert.Equal(t, room.players[i].user.ID, p.UserID)
+		assert.Equal(t, entity.MatchOutcomeNoContest, p.Outcome, "a canceled match counts as neither a win nor a draw")
+	}
+}
+
+func TestGameRoom_Run_ShutdownSuspendsCheckpointedRoom(t *testing.T) {
+	roomRepo, changes := newStatusRecorder()
+	var mu sync.Mutex
+	var snapshots []entity.RoomSnapshot
+	roomRepo.SaveSnapshotFunc = func(_ context.Context, snapshot *entity.RoomSnapshot) error {
+		mu.Lock()
+		defer mu.Unlock()
+		snapshots = append(snapshots, *snapshot)
+		return nil
+	}
+	ledger := &testutil.MockGnuLedgerRepository{
+		ApplyFunc: func(context.Context, []entity.GnuTransaction) (map[uuid.UUID]int, error) {
+			t.Error("suspended room must not settle gnu")
+			return nil, nil
+		},
+	}
+	_, clients, cancel, done := startTestMatch(t, gameRoomDeps{roomRepo: roomRepo, gnuLedger: ledger})
+
+	// 1ターン目を終えてから停止する
+	for _, client := range clients {
+		require.NoError(t, client.WriteJSON(WSMessage{Type: "act_bet_gnu", Payload: map[string]any{"amount": 10}}))
+		require.NoError(t, client.WriteJSON(WSMessage{Type: "act_submit_answer", Payload: map[string]any{"choice_index": 0}}))
+	}
+	for _, client := range clients {
+		readUntil(t, client, "ev_turn_result")
+		readUntil(t, client, "ev_turn_start")
+	}
+	cancel()
+	<-done
+
+	for _, client := range clients {
+		msg := readUntil(t, client, "ev_error")
+		assert.Equal(t, "server_restarting", msg.Payload.(map[string]any)["code"])
+	}
+	assert.Equal(t, []roomStatusChange{
+		{status: entity.RoomStatusInProgress, reason: entity.RoomEndReasonNone},
+	}, changes(), "suspended room stays in progress")
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, snapshots, 2, "checkpoint after question generation and after turn 1")
+	last := snapshots[1]
+	assert.Equal(t, 1, last.CompletedTurns)
+	assert.Len(t, last.Turns, 2)
+	assert.Len(t, last.Players[0].MyQuestions, entity.DefaultMatchRules().QuestionsPerSide())
+	for i, p := range last.Players {
+		assert.Equal(t, 100+last.Turns[i].GnuDelta, p.GnuBalance)
+	}
+}
+
+func TestGameRoom_Run_RecordsEventsForReplay(t *testing.T) {
+	var mu sync.Mutex
+	var saved []entity.MatchEvent
+	calls := 0
+	matchRepo := &testutil.MockMatchRepository{
+		SaveEventsFunc: func(_ context.Context, events []entity.MatchEvent) error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			if calls == 1 {
+				return errors.New("db down") // 最初の書き出しの失敗は次の書き出しでやり直す
+			}
+			saved = append(saved, events...)
+			return nil
+		},
+	}
+	room, clients, cancel, done := startTestMatch(t, gameRoomDeps{matchRepo: matchRepo})
+
+	require.NoError(t, clients[0].WriteJSON(WSMessage{Type: "act_bet_gnu", Payload: map[string]any{"amount": 10}}))
+	readUntil(t, clients[0], "ev_bet_confirmed")
+	for _, client := range clients {
+		require.NoError(t, client.WriteJSON(WSMessage{Type: "act_submit_answer", Payload: map[string]any{"choice_index": 0}}))
+	}
+	for _, client := range clients {
+		readUntil(t, client, "ev_turn_result")
+		readUntil(t, client, "ev_turn_start")
+	}
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	byUser := map[uuid.UUID][]string{}
+	for _, ev := range saved {
+		assert.Equal(t, room.id, ev.RoomID)
+		assert.False(t, ev.SentAt.IsZero())
+		byUser[ev.UserID] = append(byUser[ev.UserID], ev.Type)
+	}
+	assert.Equal(t, []string{"ev_room_ready", "ev_turn_start", "ev_bet_confirmed", "ev_turn_result", "ev_turn_start"},
+		byUser[room.players[0].user.ID], "ev_error is not part of the replay")
+	assert.Equal(t, []string{"ev_room_ready", "ev_turn_start", "ev_turn_result", "ev_turn_start"},
+		byUser[room.players[1].user.ID])
+}
+
+func TestGameRoom_Run_WeightsCorrectAnswersByDifficulty(t *testing.T) {
+	rules := entity.DefaultMatchRules()
+	rules.TotalTurns = 1
+	rules.DifficultyMultipliers.Easy = 3 // FakeGenerator の最初の問題は easy
+	_, clients, cancel, done := startTestMatchWithRules(t, rules, gameRoomDeps{})
+
+	require.NoError(t, clients[0].WriteJSON(WSMessage{Type: "act_bet_gnu", Payload: map[string]any{"amount": 10}}))
+	readUntil(t, clients[0], "ev_bet_confirmed")
+	require.NoError(t, clients[1].WriteJSON(WSMessage{Type: "act_bet_gnu", Payload: map[string]any{"amount": 10}}))
+	readUntil(t, clients[1], "ev_bet_confirmed")
+	for i, client := range clients {
+		require.NoError(t, client.WriteJSON(WSMessage{Type: "act_submit_answer", Payload: map[string]any{"choice_index": i}}))
+	}
+
+	result := readUntil(t, clients[0], "ev_turn_result").Payload.(map[string]any)
+	assert.Equal(t, true, result["is_correct"])
+	assert.EqualValues(t, 30, result["gnu_delta"], "payout is scaled by the difficulty multiplier")
+	assert.EqualValues(t, -10, result["opponent_gnu_delta"], "a wrong answer loses only the bet")
+
+	end := readUntil(t, clients[0], "ev_game_end").Payload.(map[string]any)
+	assert.Equal(t, "win", end["result"])
+	assert.EqualValues(t, 3, end["your_correct_score"])
+	assert.EqualValues(t, 0, end["opponent_correct_score"])
+	assert.EqualValues(t, 1, end["your_correct_count"])
+	cancel()
+	<-done
+}
+
+func TestGameRoom_Run_UnbetTurnsStakeMinBetClampedToBalance(t *testing.T) {
+	rules := entity.DefaultMatchRules()
+	rules.TotalTurns = 1
+	rules.MinBet = 150 // 残高 (100) を超える
+	rules.DifficultyMultipliers.Easy = 2
+	_, clients, cancel, done := startTestMatchWithRules(t, rules, gameRoomDeps{})
+
+	require.NoError(t, clients[0].WriteJSON(WSMessage{Type: "act_bet_gnu", Payload: map[string]any{"amount": 10}}))
+	errMsg := readUntil(t, clients[0], "ev_error").Payload.(map[string]any)
+	assert.Equal(t, "invalid_bet", errMsg["code"])
+	assert.EqualValues(t, 100, errMsg["min_bet"], "min bet is clamped to the balance")
+	require.NoError(t, clients[1].WriteJSON(WSMessage{Type: "act_bet_gnu", Payload: map[string]any{"amount": 100}}))
+	readUntil(t, clients[1], "ev_bet_confirmed")
+
+	// どちらもベットを変えずに回答すると、最小額を賭けたものとして精算する
+	for i, client := range clients {
+		require.NoError(t, client.WriteJSON(WSMessage{Type: "act_submit_answer", Payload: map[string]any{"choice_index": i}}))
+	}
+	result := readUntil(t, clients[0], "ev_turn_result").Payload.(map[string]any)
+	assert.EqualValues(t, 200, result["gnu_delta"])
+	assert.EqualValues(t, -100, result["opponent_gnu_delta"])
+	cancel()
+	<-done
+}
+
+func TestNewRecoveredGameRoom_RestoresCorrectPoints(t *testing.T) {
+	p1 := &entity.User{ID: uuid.New(), GitHubLogin: "alice"}
+	p2 := &entity.User{ID: uuid.New(), GitHubLogin: "bob"}
+	snapshot := newTestSnapshot(uuid.New(), p1, p2, 2)
+	snapshot.Turns[0].Question.Difficulty = entity.DifficultyHard
+	snapshot.Turns[2].Question.Difficulty = entity.DifficultyEasy
+
+	room := newRecoveredGameRoom(snapshot, nil, gameRoomDeps{}, func() {})
+
+	assert.Equal(t, []int{300, 0}, room.correctPoints, "hard (x2) + easy (x1)")
+}
+
+// finishOneTurnMatch は1ターンの試合を両者の回答で終わらせ、ev_game_end を読むまで進める
+func finishOneTurnMatch(t *testing.T, clients []*websocket.Conn) []WSMessage {
+	t.Helper()
+	for _, client := range clients {
+		require.NoError(t, client.WriteJSON(WSMessage{Type: "act_submit_answer", Payload: map[string]any{"choice_index": 0}}))
+	}
+	ends := make([]WSMessage, len(clients))
+	for i, client := range clients {
+		ends[i] = readUntil(t, client, "ev_game_end")
+	}
+	return ends
+}
+
+func TestGameRoom_Run_RematchCreatesRoomForSamePair(t *testing.T) {
+	roomRepo, _ := newStatusRecorder()
+	created := make(chan *entity.Room, 1)
+	roomRepo.CreateFunc = func(_ context.Context, room *entity.Room) error {
+		created <- room
+		return nil
+	}
+	rules := entity.DefaultMatchRules()
+	rules.TotalTurns = 1
+	rules.RematchWaitLimit = 5 * time.Second
+	room, clients, _, done := startTestMatchWithRules(t, rules, gameRoomDeps{roomRepo: roomRepo})
+
+	for _, end := range finishOneTurnMatch(t, clients) {
+		assert.EqualValues(t, 5, end.Payload.(map[string]any)["rematch_wait_sec"])
+	}
+
+	// 申し込まれていない再戦は受けられない
+	require.NoError(t, clients[0].WriteJSON(WSMessage{Type: "act_accept_rematch"}))
+	msg := readUntil(t, clients[0], "ev_error")
+	assert.Equal(t, "rematch_not_offered", msg.Payload.(map[string]any)["code"])
+
+	require.NoError(t, clients[0].WriteJSON(WSMessage{Type: "act_request_rematch"}))
+	offer := readUntil(t, clients[1], "ev_rematch_offered")
+	assert.NotZero(t, offer.Payload.(map[string]any)["expires_in_sec"])
+	require.NoError(t, clients[1].WriteJSON(WSMessage{Type: "act_accept_rematch"}))
+
+	var rematch *entity.Room
+	select {
+	case rematch = <-created:
+	case <-time.After(2 * time.Second):
+		t.Fatal("rematch room was not created")
+	}
+	assert.NotEqual(t, room.id, rematch.ID)
+	assert.Equal(t, room.players[0].user.ID, rematch.Player1ID)
+	assert.Equal(t, room.players[1].user.ID, rematch.Player2ID)
+	assert.Equal(t, entity.RoomStatusWaiting, rematch.Status)
+	for _, client := range clients {
+		ready := readUntil(t, client, "ev_rematch_ready")
+		assert.Equal(t, rematch.ID.String(), ready.Payload.(map[string]any)["room_id"])
+	}
+	<-done
+}
+
+func TestGameRoom_Run_RematchExpires(t *testing.T) {
+	roomRepo, changes := newStatusRecorder()
+	roomRepo.CreateFunc = func(context.Context, *entity.Room) error {
+		t.Error("no rematch room is created")
+		return nil
+	}
+	rules := entity.DefaultMatchRules()
+	rules.TotalTurns = 1
+	rules.RematchWaitLimit = time.Second
+	_, clients, _, done := startTestMatchWithRules(t, rules, gameRoomDeps{roomRepo: roomRepo})
+	finishOneTurnMatch(t, clients)
+
+	require.NoError(t, clients[0].WriteJSON(WSMessage{Type: "act_request_rematch"}))
+	readUntil(t, clients[1], "ev_rematch_offered")
+	for _, client := range clients {
+		msg := readUntil(t, client, "ev_rematch_canceled")
+		assert.Equal(t, rematchCanceledExpired, msg.Payload.(map[string]any)["reason"])
+	}
+	<-done
+	// 受付の前にルームは終了している
+	assert.Equal(t, roomStatusChange{status: entity.RoomStatusFinished, reason: entity.RoomEndReasonCompleted}, changes()[len(changes())-1])
+}
+
+func TestGameRoom_Run_RematchCanceledWhenOpponentLeaves(t *testing.T) {
+	roomRepo, _ := newStatusRecorder()
+	rules := entity.DefaultMatchRules()
+	rules.TotalTurns = 1
+	rules.RematchWaitLimit = 5 * time.Second
+	_, clients, _, done := startTestMatchWithRules(t, rules, gameRoomDeps{roomRepo: roomRepo})
+	finishOneTurnMatch(t, clients)
+
+	require.NoError(t, clients[1].Close())
+	msg := readUntil(t, clients[0], "ev_rematch_canceled")
+	assert.Equal(t, rematchCanceledOpponentLeft, msg.Payload.(map[string]any)["reason"])
+	<-done
+}
+
+func TestGameRoom_Run_NoRematchWithoutWaitLimit(t *testing.T) {
+	rules := entity.DefaultMatchRules()
+	rules.TotalTurns = 1
+	rules.RematchWaitLimit = 0
+	_, clients, _, done := startTestMatchWithRules(t, rules, gameRoomDeps{})
+
+	for _, end := range finishOneTurnMatch(t, clients) {
+		assert.EqualValues(t, 0, end.Payload.(map[string]any)["rematch_wait_sec"])
+	}
+	<-done
+}
+
+func TestGameRoom_Run_TeamMatchAggregatesByTeam(t *testing.T) {
+	fileRepo := &testutil.MockRepositoryFileRepository{
+		ListLatestByOwnerFunc: func(_ context.Context, owner string) ([]entity.RepositoryFile, error) {
+			return []entity.RepositoryFile{{FilePath: owner + ".go", Content: "package " + owner}}, nil
+		},
+	}
+	rules := entity.DefaultMatchRules()
+	rules.TotalTurns = 1
+	users := map[string]*entity.User{}
+	var members []entity.RoomPlayer
+	for i, login := range []string{"alice", "bob", "carol", "dave"} {
+		users[login] = &entity.User{ID: uuid.New(), GitHubLogin: login, GnuBalance: 100}
+		members = append(members, entity.RoomPlayer{UserID: users[login].ID, Team: i % 2})
+	}
+	deps := gameRoomDeps{questions: usecase.NewQuestionUsecase(llm.NewFakeGenerator(), fileRepo)}
+	room := newGameRoom(uuid.New(), members, rules, deps, func() {})
+
+	// 参加順とチームは関係しない
+	order := []string{"alice", "carol", "bob", "dave"}
+	clients := make(map[string]*websocket.Conn, len(order))
+	for _, login := range order {
+		server, client := newTestConn(t)
+		clients[login] = client
+		idx, _, _, err := room.join(server, users[login])
+		require.NoError(t, err)
+		go room.startReaderLoop(idx)
+	}
+	_, _, _, err := room.join(&websocket.Conn{}, &entity.User{ID: uuid.New()})
+	require.ErrorIs(t, err, errRoomFull)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		room.run(context.Background())
+	}()
+
+	ready := readUntil(t, clients["alice"], "ev_room_ready").Payload.(map[string]any)
+	assert.EqualValues(t, 0, ready["your_team"])
+	teammates := ready["teammates"].([]any)
+	require.Len(t, teammates, 1)
+	assert.Equal(t, "carol", teammates[0].(map[string]any)["github_login"])
+	assert.Len(t, ready["opponents"], 2)
+
+	// 相手チームのリポジトリから出題され、チーム 0 だけが正解する
+	for _, login := range order {
+		start := readUntil(t, clients[login], "ev_turn_start").Payload.(map[string]any)
+		team := map[string]int{"alice": 0, "carol": 0, "bob": 1, "dave": 1}[login]
+		text := start["question_text"].(string)
+		if team == 0 {
+			assert.True(t, strings.Contains(text, "bob.go") || strings.Contains(text, "dave.go"), text)
+		} else {
+			assert.True(t, strings.Contains(text, "alice.go") || strings.Contains(text, "carol.go"), text)
+		}
+		require.NoError(t, clients[login].WriteJSON(WSMessage{
+			Type:    "act_submit_answer",
+			Payload: map[string]any{"choice_index": team},
+		}))
+	}
+
+	for _, login := range order {
+		end := readUntil(t, clients[login], "ev_game_end").Payload.(map[string]any)
+		if login == "alice" || login == "carol" {
+			assert.Equal(t, "win", end["result"], login)
+			assert.EqualValues(t, 2, end["your_team_correct_count"], login)
+			assert.EqualValues(t, 0, end["opponent_team_correct_count"], login)
+		} else {
+			assert.Equal(t, "lose", end["result"], login)
+			assert.EqualValues(t, 0, end["your_team_correct_count"], login)
+			assert.EqualValues(t, 2, end["opponent_team_correct_count"], login)
+		}
+		assert.Len(t, end["teams"], 2)
+	}
+	<-done
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+)
+
+const (
+	// roomOwnerTTL はルーム担当の登録の有効期限。担当インスタンスが落ちた場合はこの時間で失効する
+	roomOwnerTTL = 30 * time.Second
+	// roomOwnerRefreshInterval は担当の登録を延長し、中継側が担当の生存を確認する間隔
+	roomOwnerRefreshInterval = roomOwnerTTL / 3
+	// userInstanceTTL はマッチング接続を持つインスタンスの登録の有効期限
+	userInstanceTTL     = 10 * time.Minute
+	relayPublishTimeout = 5 * time.Second
+)
+
+// InstanceRouter は同じ Redis を共有するバックエンドインスタンス間でメッセージを中継する
+// インスタンスごとのチャネルを購読し、届いたメッセージを種類ごとのハンドラに渡す
+type InstanceRouter struct {
+	routing  repository.RoutingRepository
+	handlers map[entity.RelayKind]func(entity.RelayMessage)
+	id       string
+	mu       sync.RWMutex
+}
+
+func NewInstanceRouter(routing repository.RoutingRepository, instanceID string) *InstanceRouter {
+	return &InstanceRouter{
+		routing:  routing,
+		handlers: make(map[entity.RelayKind]func(entity.RelayMessage)),
+		id:       instanceID,
+	}
+}
+
+// ID はこのインスタンスの ID を返す
+func (r *InstanceRouter) ID() string {
+	return r.id
+}
+
+// Handle は kind のメッセージを受け取るハンドラを登録する
+// ハンドラは購読 goroutine から順番に呼ばれるため、ブロックしないこと
+func (r *InstanceRouter) Handle(kind entity.RelayKind, fn func(entity.RelayMessage)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[kind] = fn
+}
+
+// Publish は instanceID 宛てに msg を送る。From にはこのインスタンスの ID をセットする
+func (r *InstanceRouter) Publish(instanceID string, msg entity.RelayMessage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), relayPublishTimeout)
+	defer cancel()
+	msg.From = r.id
+	return r.routing.Publish(ctx, instanceID, msg)
+}
+
+// Start はこのインスタンス宛てのチャネルを購読し、ctx が終わるまでメッセージを配送する
+// 購読が確立してから戻るため、戻った時点で他のインスタンスからのメッセージを受け取れる
+func (r *InstanceRouter) Start(ctx context.Context) error {
+	ch, err := r.routing.Subscribe(ctx, r.id)
+	if err != nil {
+		return fmt.Errorf("start instance router: %w", err)
+	}
+	log.Printf("instance router: %s subscribed", r.id)
+	go func() {
+		for msg := range ch {
+			r.mu.RLock()
+			fn, ok := r.handlers[msg.Kind]
+			r.mu.RUnlock()
+			if !ok {
+				log.Printf("instance router: no handler for %q from %s", msg.Kind, msg.From)
+				continue
+			}
+			fn(msg)
+		}
+		log.Printf("instance router: %s unsubscribed", r.id)
+	}()
+	return nil
+}
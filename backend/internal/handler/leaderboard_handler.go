@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/usecase"
+)
+
+const (
+	defaultLeaderboardLimit  = 20
+	maxLeaderboardLimit      = 100
+	defaultLeaderboardRadius = 5
+	maxLeaderboardRadius     = 25
+)
+
+// leaderboardResponse はランキング API のレスポンス
+type leaderboardResponse struct {
+	Me        *entity.LeaderboardEntry  `json:"me,omitempty"`
+	Kind      entity.LeaderboardKind    `json:"kind"`
+	WeekStart string                    `json:"week_start,omitempty"` // weekly_wins のみ（YYYY-MM-DD, UTC）
+	Entries   []entity.LeaderboardEntry `json:"entries"`
+}
+
+type LeaderboardHandler struct {
+	leaderboardUsecase *usecase.LeaderboardUsecase
+	userRepo           repository.UserRepository
+}
+
+func NewLeaderboardHandler(uc *usecase.LeaderboardUsecase, userRepo repository.UserRepository) *LeaderboardHandler {
+	return &LeaderboardHandler{leaderboardUsecase: uc, userRepo: userRepo}
+}
+
+// GetTop は GET /api/v1/leaderboards/:kind を処理する
+// ?limit で人数を指定する（既定 20、最大 100）
+func (h *LeaderboardHandler) GetTop(c echo.Context) error {
+	board, ok := h.board(c)
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "unknown leaderboard"})
+	}
+	limit, ok := queryInt(c, "limit", defaultLeaderboardLimit, maxLeaderboardLimit)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "limit must be a positive integer"})
+	}
+
+	entries, err := h.leaderboardUsecase.Top(c.Request().Context(), board, limit)
+	if err != nil {
+		log.Printf("leaderboard: failed to get top of %s: %v", board.Kind, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+	return c.JSON(http.StatusOK, newLeaderboardResponse(board, entries))
+}
+
+// GetMine は GET /api/v1/leaderboards/:kind/me を処理する
+// ログインユーザーの順位と前後 ?radius 人（既定 5、最大 25）を返す
+func (h *LeaderboardHandler) GetMine(c echo.Context) error {
+	identity, ok := authenticatedIdentity(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+	}
+	board, ok := h.board(c)
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "unknown leaderboard"})
+	}
+	radius, ok := queryInt(c, "radius", defaultLeaderboardRadius, maxLeaderboardRadius)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "radius must be a positive integer"})
+	}
+
+	ctx := c.Request().Context()
+	user, err := h.userRepo.GetByGitHubID(ctx, identity.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "not ranked"})
+		}
+		log.Printf("leaderboard: failed to get user %s: %v", identity.Login, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+
+	entries, err := h.leaderboardUsecase.Around(ctx, board, user.ID, radius)
+	if err != nil {
+		log.Printf("leaderboard: failed to get rank of %s in %s: %v", user.ID, board.Kind, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+	me := findLeaderboardEntry(entries, user.ID)
+	if me == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "not ranked"})
+	}
+	res := newLeaderboardResponse(board, entries)
+	res.Me = me
+	return c.JSON(http.StatusOK, res)
+}
+
+// board はパスの :kind から集計中のランキングを返す
+func (h *LeaderboardHandler) board(c echo.Context) (entity.Leaderboard, bool) {
+	kind := entity.LeaderboardKind(c.Param("kind"))
+	if !kind.Valid() {
+		return entity.Leaderboard{}, false
+	}
+	return h.leaderboardUsecase.Board(kind, time.Now()), true
+}
+
+func newLeaderboardResponse(board entity.Leaderboard, entries []entity.LeaderboardEntry) leaderboardResponse {
+	res := leaderboardResponse{Kind: board.Kind, Entries: entries}
+	if board.Kind == entity.LeaderboardKindWeeklyWins {
+		res.WeekStart = board.Week.Format("2006-01-02")
+	}
+	return res
+}
+
+func findLeaderboardEntry(entries []entity.LeaderboardEntry, userID uuid.UUID) *entity.LeaderboardEntry {
+	for i := range entries {
+		if entries[i].UserID == userID {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// queryInt はクエリパラメータ name を正の整数として読み取り、upper で抑える
+// 指定がない場合は def を返す。正の整数でない場合は false を返す
+func queryInt(c echo.Context, name string, def, upper int) (int, bool) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return min(n, upper), true
+}
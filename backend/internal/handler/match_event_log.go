@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+)
+
+// replayEventTypes はリプレイのために記録するイベント
+// ev_error や ev_match_resuming などの進行の通知は試合の内容ではないため記録しない
+var replayEventTypes = map[string]bool{
+	"ev_room_ready":    true,
+	"ev_turn_start":    true,
+	"ev_bet_confirmed": true,
+	"ev_turn_result":   true,
+	"ev_game_end":      true,
+	"ev_tko":           true,
+}
+
+// matchEventLog はプレイヤーに送ったイベントを、永続化するまでルームごとにためておく
+type matchEventLog struct {
+	pending []entity.MatchEvent
+	mu      sync.Mutex
+	roomID  uuid.UUID
+}
+
+// record は userID に送ったイベントを送信時刻とともに記録する
+// リプレイの対象でないイベントは記録しない
+func (l *matchEventLog) record(userID uuid.UUID, msg WSMessage) {
+	if !replayEventTypes[msg.Type] {
+		return
+	}
+	payload, err := json.Marshal(msg.Payload)
+	if err != nil {
+		log.Printf("game room %s: failed to marshal %s for replay: %v", l.roomID, msg.Type, err)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending = append(l.pending, entity.MatchEvent{
+		RoomID:  l.roomID,
+		UserID:  userID,
+		Type:    msg.Type,
+		Payload: payload,
+		SentAt:  time.Now(),
+	})
+}
+
+// take はためているイベントを取り出す
+func (l *matchEventLog) take() []entity.MatchEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	events := l.pending
+	l.pending = nil
+	return events
+}
+
+// putBack は永続化に失敗したイベントを、順序を保ったまま先頭に戻す
+func (l *matchEventLog) putBack(events []entity.MatchEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending = append(events, l.pending...)
+}
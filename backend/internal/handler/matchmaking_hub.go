@@ -3,12 +3,15 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
 	"github.com/tobakuro/hackathon_nulabcup/backend/internal/usecase"
 )
 
@@ -17,40 +20,177 @@ type WSMessage struct {
 	Type    string `json:"type"`
 }
 
+const (
+	// drainCloseGrace は Drain で close フレームを送ってから、応答のない接続の読み取りを打ち切るまでの時間
+	drainCloseGrace = 5 * time.Second
+	hubWriteWait    = 10 * time.Second // マッチング接続への1メッセージあたりの書き込み期限
+)
+
+// hubConn はマッチング用の WebSocket 接続
+// 書き込みはハンドラ・マッチングループ・中継の受信・Drain から行われるため、mu で直列化する
+type hubConn struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+}
+
+func (c *hubConn) write(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.conn.SetWriteDeadline(time.Now().Add(hubWriteWait)); err != nil {
+		return err
+	}
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// send は msg を書き込む。失敗はログのみ
+func (c *hubConn) send(msg WSMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("hub: marshal error: %v", err)
+		return
+	}
+	if err := c.write(data); err != nil {
+		log.Printf("hub: write error: %v", err)
+	}
+}
+
+// Hub はマッチング用 WebSocket 接続のレジストリ
+// router を設定すると、接続を持つインスタンスを Redis に登録し、
+// 他のインスタンスで成立したマッチの通知を受け取れるようにする
 type Hub struct {
-	connections map[uuid.UUID]*websocket.Conn
+	connections map[uuid.UUID]*hubConn
 	usecase     *usecase.MatchmakingUsecase
+	router      *InstanceRouter // nil の場合は単一インスタンスで動作する
 	// Bot 向けマッチ通知サブスクライバ (userID → channel)
-	matchSubs map[uuid.UUID]chan<- *usecase.MatchmakingResult
-	mu        sync.RWMutex
+	matchSubs     map[uuid.UUID]chan<- *usecase.MatchmakingResult
+	sweepInterval time.Duration // マッチングループの見回り間隔（eventDriven でなければポーリング間隔）
+	// active は Register から Unregister までの接続を数える。Drain はこれが 0 になるのを待つ
+	active      sync.WaitGroup
+	mu          sync.RWMutex
+	draining    atomic.Bool // true の間は新しい接続を受け付けない
+	eventDriven bool        // キューへの追加の通知でマッチングを試みるかどうか
 }
 
-func NewHub(uc *usecase.MatchmakingUsecase) *Hub {
-	return &Hub{
-		connections: make(map[uuid.UUID]*websocket.Conn),
-		matchSubs:   make(map[uuid.UUID]chan<- *usecase.MatchmakingResult),
-		usecase:     uc,
+func NewHub(uc *usecase.MatchmakingUsecase, router *InstanceRouter, sweepInterval time.Duration, eventDriven bool) *Hub {
+	h := &Hub{
+		connections:   make(map[uuid.UUID]*hubConn),
+		matchSubs:     make(map[uuid.UUID]chan<- *usecase.MatchmakingResult),
+		usecase:       uc,
+		router:        router,
+		sweepInterval: sweepInterval,
+		eventDriven:   eventDriven,
+	}
+	if router != nil {
+		router.Handle(entity.RelayKindDeliver, h.handleDeliver)
 	}
+	return h
 }
 
-func (h *Hub) Register(userID uuid.UUID, conn *websocket.Conn) {
+// Register は userID の接続を登録し、以降の書き込みに使う hubConn を返す。Drain 中は ErrServerDraining を返し、登録しない
+// 登録に成功した場合、呼び出し元は接続への書き込みを hubConn で行い、接続が閉じたときに必ず Unregister を呼ぶこと
+func (h *Hub) Register(userID uuid.UUID, conn *websocket.Conn) (*hubConn, error) {
+	hc := &hubConn{conn: conn}
+	// draining の確認と active への追加を mu の中で行い、Drain の待機開始後に追加されないようにする
 	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.connections[userID] = conn
+	if h.draining.Load() {
+		h.mu.Unlock()
+		return nil, ErrServerDraining
+	}
+	h.connections[userID] = hc
+	h.active.Add(1)
+	h.mu.Unlock()
+
+	if h.router == nil {
+		return hc, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.router.routing.SetUserInstance(ctx, userID, h.router.ID(), userInstanceTTL); err != nil {
+		log.Printf("hub: failed to register instance for %s: %v", userID, err)
+	}
+	return hc, nil
 }
 
+// Unregister は userID の接続の登録を解除し、キューから外す
 func (h *Hub) Unregister(userID uuid.UUID) {
+	defer h.active.Done()
 	h.mu.Lock()
 	delete(h.connections, userID)
 	h.mu.Unlock()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+	if h.router != nil {
+		if err := h.router.routing.ClearUserInstance(ctx, userID, h.router.ID()); err != nil {
+			log.Printf("hub: failed to clear instance for %s: %v", userID, err)
+		}
+	}
 	if err := h.usecase.LeaveQueue(ctx, userID); err != nil {
 		log.Printf("hub: failed to leave queue for %s: %v", userID, err)
 	}
 }
 
+// Draining は Drain が始まっているかどうかを返す
+func (h *Hub) Draining() bool {
+	return h.draining.Load()
+}
+
+// Drain は新しい接続の受け付けを止め、キューで待機中のユーザーに ev_server_draining を送って接続を閉じる
+// 各接続の Unregister でキューから外れるのを ctx が終わるまで待つ
+// マッチングループ（Run）は呼び出し前に止めておくこと
+func (h *Hub) Drain(ctx context.Context) {
+	h.mu.Lock()
+	h.draining.Store(true)
+	conns := make(map[uuid.UUID]*hubConn, len(h.connections))
+	for id, conn := range h.connections {
+		conns[id] = conn
+	}
+	h.mu.Unlock()
+	log.Printf("hub: draining %d connections", len(conns))
+
+	data, err := json.Marshal(serverDrainingMessage())
+	if err != nil {
+		log.Printf("hub: failed to marshal message: %v", err)
+	}
+	for userID, conn := range conns {
+		if data != nil {
+			if err := conn.write(data); err != nil {
+				log.Printf("hub: failed to send to %s: %v", userID, err)
+			}
+		}
+		closeMsg := websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server draining")
+		if err := conn.conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second)); err != nil {
+			log.Printf("hub: failed to close %s: %v", userID, err)
+		}
+		// クライアントが close に応答しなくても読み取りループを抜けて Unregister されるようにする
+		if err := conn.conn.SetReadDeadline(time.Now().Add(drainCloseGrace)); err != nil {
+			log.Printf("hub: failed to set read deadline for %s: %v", userID, err)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.active.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		log.Println("hub: drained")
+	case <-ctx.Done():
+		log.Println("hub: drain deadline exceeded")
+	}
+}
+
+// serverDrainingMessage はサーバーの停止をマッチング接続に知らせるメッセージ
+func serverDrainingMessage() WSMessage {
+	return WSMessage{
+		Type: "ev_server_draining",
+		Payload: map[string]any{
+			"message": "サーバーの再起動のためマッチングを中断しました。しばらくしてから再度お試しください",
+		},
+	}
+}
+
 // SubscribeMatch は userID のマッチ成立を待つチャネルを登録する
 // 呼び出し元は UnsubscribeMatch で必ずクリーンアップすること
 func (h *Hub) SubscribeMatch(userID uuid.UUID, ch chan<- *usecase.MatchmakingResult) {
@@ -66,107 +206,190 @@ func (h *Hub) UnsubscribeMatch(userID uuid.UUID) {
 	delete(h.matchSubs, userID)
 }
 
+// SendToUser は userID のマッチング接続に msg を送る
+// このインスタンスに接続がなければ、接続を持つインスタンスへ中継する
 func (h *Hub) SendToUser(userID uuid.UUID, msg WSMessage) {
-	h.mu.RLock()
-	conn, ok := h.connections[userID]
-	h.mu.RUnlock()
-	if !ok {
-		log.Printf("hub: no connection found for user %s (skipping send)", userID)
-		return
-	}
-
 	data, err := json.Marshal(msg)
 	if err != nil {
 		log.Printf("hub: failed to marshal message: %v", err)
 		return
 	}
+	if h.writeLocal(userID, data) {
+		log.Printf("hub: sent %s to user %s", msg.Type, userID)
+		return
+	}
 
-	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+	if h.router != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		instanceID, err := h.router.routing.GetUserInstance(ctx, userID)
+		cancel()
+		if err != nil {
+			log.Printf("hub: failed to look up instance for %s: %v", userID, err)
+			return
+		}
+		if instanceID != "" && instanceID != h.router.ID() {
+			if err := h.router.Publish(instanceID, entity.RelayMessage{
+				Kind:   entity.RelayKindDeliver,
+				UserID: userID,
+				Data:   data,
+			}); err != nil {
+				log.Printf("hub: failed to relay %s to %s: %v", msg.Type, userID, err)
+				return
+			}
+			log.Printf("hub: relayed %s to user %s via %s", msg.Type, userID, instanceID)
+			return
+		}
+	}
+	log.Printf("hub: no connection found for user %s (skipping send)", userID)
+}
+
+// writeLocal はこのインスタンスの接続に data を書き込み、接続があったかどうかを返す
+func (h *Hub) writeLocal(userID uuid.UUID, data []byte) bool {
+	h.mu.RLock()
+	conn, ok := h.connections[userID]
+	h.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	if err := conn.write(data); err != nil {
 		log.Printf("hub: failed to send to %s: %v", userID, err)
-	} else {
-		log.Printf("hub: sent %s to user %s", msg.Type, userID)
 	}
+	return true
 }
 
+// handleDeliver は他のインスタンスから中継されたメッセージをこのインスタンスの接続に書き込む
+// ルーターの購読 goroutine を遅い接続で止めないよう、書き込みは別の goroutine で行う
+func (h *Hub) handleDeliver(msg entity.RelayMessage) {
+	go func() {
+		if !h.writeLocal(msg.UserID, msg.Data) {
+			log.Printf("hub: no connection found for relayed message to %s", msg.UserID)
+		}
+	}()
+}
+
+// Run はマッチングループを動かす
+// eventDriven の場合はキューへの追加の通知でマッチングを試み、sweepInterval ごとの見回りは
+// 待ち時間による許容幅の拡大と通知の取りこぼしを拾うためだけに行う
+// eventDriven でない場合は sweepInterval ごとのポーリングのみで動く
 func (h *Hub) Run(ctx context.Context) {
-	ticker := time.NewTicker(500 * time.Millisecond)
+	var changes <-chan struct{}
+	if h.eventDriven {
+		ch, err := h.usecase.WatchQueue(ctx)
+		if err != nil {
+			log.Printf("hub: failed to watch queue, falling back to sweep only: %v", err)
+		} else {
+			changes = ch
+		}
+	}
+	ticker := time.NewTicker(h.sweepInterval)
 	defer ticker.Stop()
 
-	log.Println("hub: matchmaking loop started")
+	log.Printf("hub: matchmaking loop started (event_driven=%t, sweep=%s)", changes != nil, h.sweepInterval)
 	for {
 		select {
 		case <-ctx.Done():
 			log.Println("hub: matchmaking loop stopped")
 			return
+		case _, ok := <-changes:
+			if !ok {
+				log.Println("hub: queue watch closed, falling back to sweep only")
+				changes = nil
+				continue
+			}
+			h.matchAll(ctx, usecase.MatchTriggerQueueChange)
 		case <-ticker.C:
-			result, err := h.usecase.TryMatch(ctx)
+			h.matchAll(ctx, usecase.MatchTriggerSweep)
+		}
+	}
+}
+
+// matchAll は形式ごとにマッチが見つからなくなるまでマッチングを試み、成立したマッチに通知する
+func (h *Hub) matchAll(ctx context.Context, trigger usecase.MatchTrigger) {
+	for teamSize := 1; teamSize <= entity.MaxTeamSize; teamSize++ {
+		for {
+			result, err := h.usecase.TryMatch(ctx, teamSize, trigger)
 			if err != nil {
-				log.Printf("hub: try match error: %v", err)
-				continue
+				log.Printf("hub: try match error (team size %d): %v", teamSize, err)
+				break
 			}
 			if result == nil {
-				continue
+				break
 			}
+			h.notifyMatch(result)
+		}
+	}
+}
 
-			log.Printf("hub: match found! room=%s, p1=%s (%s), p2=%s (%s)",
-				result.Room.ID,
-				result.Player1.GitHubLogin, result.Room.Player1ID,
-				result.Player2.GitHubLogin, result.Room.Player2ID)
+// notifyMatch はマッチ成立を Bot サブスクライバと全プレイヤーの接続に通知する
+// opponent は組になる相手チームのプレイヤー。チーム戦では所属チームと両チームのメンバーを添える
+func (h *Hub) notifyMatch(result *usecase.MatchmakingResult) {
+	members := result.Room.Members()
+	logins := make([]string, len(result.Players))
+	for i, p := range result.Players {
+		logins[i] = fmt.Sprintf("%s (team %d)", p.GitHubLogin, members[i].Team)
+	}
+	log.Printf("hub: match found! room=%s, players=%v", result.Room.ID, logins)
 
-			// 接続マップの状態をログ
-			h.mu.RLock()
-			connIDs := make([]string, 0, len(h.connections))
-			for id := range h.connections {
-				connIDs = append(connIDs, id.String())
-			}
-			h.mu.RUnlock()
-			log.Printf("hub: current connections: %v", connIDs)
-
-			// Bot サブスクライバに通知
-			h.mu.RLock()
-			sub1, ok1 := h.matchSubs[result.Room.Player1ID]
-			sub2, ok2 := h.matchSubs[result.Room.Player2ID]
-			h.mu.RUnlock()
-			if ok1 {
-				select {
-				case sub1 <- result:
-				case <-time.After(200 * time.Millisecond):
-					log.Printf("hub: dropped match notification for subscriber %s", result.Room.Player1ID)
+	// 接続マップの状態をログ
+	h.mu.RLock()
+	connIDs := make([]string, 0, len(h.connections))
+	for id := range h.connections {
+		connIDs = append(connIDs, id.String())
+	}
+	h.mu.RUnlock()
+	log.Printf("hub: current connections: %v", connIDs)
+
+	// Bot サブスクライバに通知
+	for _, m := range members {
+		h.mu.RLock()
+		sub, ok := h.matchSubs[m.UserID]
+		h.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		select {
+		case sub <- result:
+		case <-time.After(200 * time.Millisecond):
+			log.Printf("hub: dropped match notification for subscriber %s", m.UserID)
+		}
+	}
+
+	// チームごとのプレイヤー（参加者と同じ順）
+	var teams [2][]*entity.User
+	for i, m := range members {
+		teams[m.Team] = append(teams[m.Team], result.Players[i])
+	}
+	summary := func(u *entity.User) map[string]any {
+		return map[string]any{
+			"id":           u.ID.String(),
+			"github_login": u.GitHubLogin,
+			"rate":         u.Rate,
+		}
+	}
+	positions := [2]int{}
+	for i, m := range members {
+		own, opps := teams[m.Team], teams[1-m.Team]
+		pos := positions[m.Team]
+		positions[m.Team]++
+		payload := map[string]any{
+			"room_id":  result.Room.ID.String(),
+			"opponent": summary(opps[pos%len(opps)]),
+		}
+		if len(result.Players) > 2 {
+			teammates := make([]map[string]any, 0, len(own))
+			for _, u := range own {
+				if u.ID != m.UserID {
+					teammates = append(teammates, summary(u))
 				}
 			}
-			if ok2 {
-				select {
-				case sub2 <- result:
-				case <-time.After(200 * time.Millisecond):
-					log.Printf("hub: dropped match notification for subscriber %s", result.Room.Player2ID)
-				}
+			opponents := make([]map[string]any, 0, len(opps))
+			for _, u := range opps {
+				opponents = append(opponents, summary(u))
 			}
-
-			// Player1 に通知
-			h.SendToUser(result.Room.Player1ID, WSMessage{
-				Type: "ev_match_found",
-				Payload: map[string]any{
-					"room_id": result.Room.ID.String(),
-					"opponent": map[string]any{
-						"id":           result.Player2.ID.String(),
-						"github_login": result.Player2.GitHubLogin,
-						"rate":         result.Player2.Rate,
-					},
-				},
-			})
-
-			// Player2 に通知
-			h.SendToUser(result.Room.Player2ID, WSMessage{
-				Type: "ev_match_found",
-				Payload: map[string]any{
-					"room_id": result.Room.ID.String(),
-					"opponent": map[string]any{
-						"id":           result.Player1.ID.String(),
-						"github_login": result.Player1.GitHubLogin,
-						"rate":         result.Player1.Rate,
-					},
-				},
-			})
+			payload["team"] = m.Team
+			payload["teammates"] = teammates
+			payload["opponents"] = opponents
 		}
+		h.SendToUser(result.Players[i].ID, WSMessage{Type: "ev_match_found", Payload: payload})
 	}
 }
@@ -1,28 +1,36 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/testutil"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/usecase"
 )
 
 func TestHub_RegisterAndUnregister(t *testing.T) {
 	hub := &Hub{
-		connections: make(map[uuid.UUID]*websocket.Conn),
+		connections: make(map[uuid.UUID]*hubConn),
 	}
 
 	userID := uuid.New()
 	conn := &websocket.Conn{} // dummy, not used for map ops
 
-	hub.Register(userID, conn)
+	_, err := hub.Register(userID, conn)
+	require.NoError(t, err)
 	hub.mu.RLock()
 	_, exists := hub.connections[userID]
 	hub.mu.RUnlock()
@@ -43,7 +51,7 @@ func TestHub_RegisterAndUnregister(t *testing.T) {
 
 func TestHub_SendToUser_WithConnection(t *testing.T) {
 	hub := &Hub{
-		connections: make(map[uuid.UUID]*websocket.Conn),
+		connections: make(map[uuid.UUID]*hubConn),
 	}
 
 	userID := uuid.New()
@@ -60,7 +68,9 @@ func TestHub_SendToUser_WithConnection(t *testing.T) {
 			wg.Done()
 			return
 		}
-		hub.Register(userID, conn)
+		if _, err := hub.Register(userID, conn); err != nil {
+			upgradeErr = err
+		}
 		wg.Done()
 	}))
 	defer server.Close()
@@ -95,7 +105,7 @@ func TestHub_SendToUser_WithConnection(t *testing.T) {
 
 func TestHub_SendToUser_NoConnection(t *testing.T) {
 	hub := &Hub{
-		connections: make(map[uuid.UUID]*websocket.Conn),
+		connections: make(map[uuid.UUID]*hubConn),
 	}
 
 	// Should not panic when sending to a non-existent user
@@ -103,3 +113,290 @@ func TestHub_SendToUser_NoConnection(t *testing.T) {
 		hub.SendToUser(uuid.New(), WSMessage{Type: "ev_test"})
 	})
 }
+
+func TestHub_SendToUser_ConcurrentWrites(t *testing.T) {
+	hub := &Hub{
+		connections: make(map[uuid.UUID]*hubConn),
+	}
+	userID := uuid.New()
+	server, client := newTestConn(t)
+	conn, err := hub.Register(userID, server)
+	require.NoError(t, err)
+
+	// マッチングループ・中継の受信・ハンドラが同じ接続に同時に書き込む
+	const writers, perWriter = 4, 20
+	var wg sync.WaitGroup
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perWriter {
+				hub.SendToUser(userID, WSMessage{Type: "ev_test"})
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range perWriter {
+			conn.send(WSMessage{Type: "ev_test"})
+		}
+	}()
+
+	for range (writers + 1) * perWriter {
+		readUntil(t, client, "ev_test")
+	}
+	wg.Wait()
+}
+
+// newDequeueCounter は Dequeue の呼び出しを数える MatchmakingRepository を返す
+// キューは常に空で、changes にキューの変更を送ると購読者に通知される
+func newDequeueCounter() (*testutil.MockMatchmakingRepository, chan struct{}, chan struct{}) {
+	changes := make(chan struct{}, 1)
+	dequeued := make(chan struct{}, 16)
+	repo := &testutil.MockMatchmakingRepository{
+		DequeueFunc: func(context.Context, entity.RateWindow, time.Time) (*entity.QueueEntry, *entity.QueueEntry, error) {
+			dequeued <- struct{}{}
+			return nil, nil, nil
+		},
+		SubscribeQueueChangesFunc: func(context.Context) (<-chan struct{}, error) {
+			return changes, nil
+		},
+	}
+	return repo, changes, dequeued
+}
+
+func TestHub_Run_MatchesOnQueueChange(t *testing.T) {
+	repo, changes, dequeued := newDequeueCounter()
+	uc := usecase.NewMatchmakingUsecase(repo, nil, nil, entity.RateWindow{Base: 100, Max: 100})
+	// 見回りは起きない長さにして、通知だけで試行されることを確かめる
+	hub := NewHub(uc, nil, time.Hour, true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	changes <- struct{}{}
+
+	select {
+	case <-dequeued:
+	case <-time.After(2 * time.Second):
+		t.Fatal("queue change did not trigger matchmaking")
+	}
+	// 1回の通知で形式ごとのキューを1回ずつ試す
+	require.Eventually(t, func() bool {
+		return uc.Metrics().Attempts[usecase.MatchTriggerQueueChange] == entity.MaxTeamSize
+	}, time.Second, 10*time.Millisecond)
+	assert.Zero(t, uc.Metrics().Attempts[usecase.MatchTriggerSweep])
+}
+
+func TestHub_Run_PollingIgnoresQueueChanges(t *testing.T) {
+	repo, _, dequeued := newDequeueCounter()
+	uc := usecase.NewMatchmakingUsecase(repo, nil, nil, entity.RateWindow{Base: 100, Max: 100})
+	hub := NewHub(uc, nil, 10*time.Millisecond, false)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	select {
+	case <-dequeued:
+	case <-time.After(2 * time.Second):
+		t.Fatal("sweep did not trigger matchmaking")
+	}
+	cancel()
+	snapshot := uc.Metrics()
+	assert.Zero(t, snapshot.Attempts[usecase.MatchTriggerQueueChange])
+	assert.Positive(t, snapshot.Attempts[usecase.MatchTriggerSweep])
+	// 購読しないため、Redis 呼び出しは Dequeue・DequeueTeams のみ
+	assert.Equal(t, snapshot.Attempts[usecase.MatchTriggerSweep], snapshot.RedisCalls)
+}
+
+func TestHub_Run_FailedMatchDoesNotRetrigger(t *testing.T) {
+	changes := make(chan struct{}, 1)
+	var mu sync.Mutex
+	dequeues, requeues := 0, 0
+	repo := &testutil.MockMatchmakingRepository{
+		DequeueFunc: func(context.Context, entity.RateWindow, time.Time) (*entity.QueueEntry, *entity.QueueEntry, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			dequeues++
+			return &entity.QueueEntry{UserID: uuid.New()}, &entity.QueueEntry{UserID: uuid.New()}, nil
+		},
+		// Enqueue はキューの変更を通知するため、戻し先に使われるとマッチングが連鎖する
+		EnqueueFunc: func(context.Context, entity.QueueEntry) error {
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+			return nil
+		},
+		RequeueFunc: func(context.Context, entity.QueueEntry) error {
+			mu.Lock()
+			defer mu.Unlock()
+			requeues++
+			return nil
+		},
+		SubscribeQueueChangesFunc: func(context.Context) (<-chan struct{}, error) {
+			return changes, nil
+		},
+	}
+	userRepo := &testutil.MockUserRepository{
+		GetByIDFunc: func(context.Context, uuid.UUID) (*entity.User, error) {
+			return nil, errors.New("db down")
+		},
+	}
+	uc := usecase.NewMatchmakingUsecase(repo, nil, userRepo, entity.RateWindow{Base: 100, Max: 100})
+	hub := NewHub(uc, nil, time.Hour, true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	changes <- struct{}{}
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return requeues == 2
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, dequeues, "a failed match must wait for the next sweep instead of retrying immediately")
+	assert.Equal(t, 2, requeues)
+}
+
+func TestHub_Drain_ClosesQueuedConnections(t *testing.T) {
+	var mu sync.Mutex
+	var removed []uuid.UUID
+	repo := &testutil.MockMatchmakingRepository{
+		RemoveFunc: func(_ context.Context, userID uuid.UUID) error {
+			mu.Lock()
+			defer mu.Unlock()
+			removed = append(removed, userID)
+			return nil
+		},
+	}
+	uc := usecase.NewMatchmakingUsecase(repo, nil, nil, entity.RateWindow{Base: 100, Max: 100})
+	hub := NewHub(uc, nil, time.Hour, false)
+
+	// HandleMatchmake と同じく、読み取りループを抜けたら Unregister する
+	userID := uuid.New()
+	server, client := newTestConn(t)
+	_, err := hub.Register(userID, server)
+	require.NoError(t, err)
+	go func() {
+		defer hub.Unregister(userID)
+		for {
+			if _, _, err := server.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		hub.Drain(context.Background())
+	}()
+
+	readUntil(t, client, "ev_server_draining")
+	_, _, err = client.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseServiceRestart), "got %v", err)
+	select {
+	case <-drained:
+	case <-time.After(2 * time.Second):
+		t.Fatal("drain did not wait for the connection to unregister")
+	}
+	mu.Lock()
+	assert.Equal(t, []uuid.UUID{userID}, removed, "drained users leave the queue")
+	mu.Unlock()
+
+	// Drain の後は新しい接続を受け付けない
+	_, err = hub.Register(uuid.New(), &websocket.Conn{})
+	assert.ErrorIs(t, err, ErrServerDraining)
+}
+
+func TestHub_MatchFoundWhenPartnerAlreadyQueued(t *testing.T) {
+	t.Setenv("ALLOWED_ORIGINS", "http://localhost:3000")
+	partner := &entity.User{ID: uuid.New(), GitHubLogin: "bob", Rate: 1500}
+	me := &entity.User{ID: uuid.New(), GitHubID: 1, GitHubLogin: "alice", Rate: 1500}
+
+	// 相手は既にキューにいるため、自分が追加された通知で即座にマッチが成立する
+	// Enqueue はマッチングの1巡（2対2のキューの試行まで）が終わるのを待ってから戻り、
+	// 通知を送る時点で接続が登録されていることを確かめる
+	changes := make(chan struct{}, 1)
+	matched := make(chan struct{}, 1)
+	var mu sync.Mutex
+	queued := false
+	repo := &testutil.MockMatchmakingRepository{
+		SetActiveFunc: func(context.Context, uuid.UUID) (bool, error) { return true, nil },
+		EnqueueFunc: func(context.Context, entity.QueueEntry) error {
+			mu.Lock()
+			queued = true
+			mu.Unlock()
+			changes <- struct{}{}
+			select {
+			case <-matched:
+			case <-time.After(2 * time.Second):
+				t.Error("queue change did not trigger matchmaking")
+			}
+			return nil
+		},
+		DequeueTeamsFunc: func(context.Context, int, entity.RateWindow, time.Time) ([]*entity.QueueEntry, error) {
+			select {
+			case matched <- struct{}{}:
+			default:
+			}
+			return nil, nil
+		},
+		DequeueFunc: func(context.Context, entity.RateWindow, time.Time) (*entity.QueueEntry, *entity.QueueEntry, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if !queued {
+				return nil, nil, nil
+			}
+			queued = false
+			return &entity.QueueEntry{UserID: partner.ID}, &entity.QueueEntry{UserID: me.ID}, nil
+		},
+		SubscribeQueueChangesFunc: func(context.Context) (<-chan struct{}, error) { return changes, nil },
+	}
+	userRepo := &testutil.MockUserRepository{
+		GetByGitHubIDFunc: func(context.Context, int64) (*entity.User, error) { return me, nil },
+		GetByIDFunc: func(_ context.Context, id uuid.UUID) (*entity.User, error) {
+			if id == partner.ID {
+				return partner, nil
+			}
+			return me, nil
+		},
+	}
+	roomRepo := &testutil.MockRoomRepository{
+		CreateFunc: func(context.Context, *entity.Room) error { return nil },
+	}
+	uc := usecase.NewMatchmakingUsecase(repo, roomRepo, userRepo, entity.RateWindow{Base: 100, Max: 100})
+	hub := NewHub(uc, nil, time.Hour, true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	e := echo.New()
+	authenticated := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("github_login", me.GitHubLogin)
+			c.Set("github_id", me.GitHubID)
+			return next(c)
+		}
+	}
+	e.GET("/ws/matchmake", NewMatchmakeHandler(hub, userRepo).HandleMatchmake, authenticated)
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	client, _, err := websocket.DefaultDialer.Dial(
+		"ws"+strings.TrimPrefix(srv.URL, "http")+"/ws/matchmake",
+		http.Header{"Origin": []string{"http://localhost:3000"}},
+	)
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	readUntil(t, client, "ev_queue_joined")
+	msg := readUntil(t, client, "ev_match_found")
+	opponent := msg.Payload.(map[string]any)["opponent"].(map[string]any)
+	assert.Equal(t, partner.ID.String(), opponent["id"])
+}
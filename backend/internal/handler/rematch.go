@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+)
+
+// 再戦の受付が終わった理由（ev_rematch_canceled の reason）
+const (
+	rematchCanceledExpired      = "expired"       // 受付時間内に全員がそろわなかった
+	rematchCanceledOpponentLeft = "opponent_left" // 他のプレイヤーが切断した
+	rematchCanceledFailed       = "failed"        // ルームの作成に失敗した
+)
+
+// rematchAvailable は試合の終了後に再戦を受け付けるかを返す
+// 再戦のルームを作れない場合と、誰かが切断している場合は受け付けない
+func (r *GameRoom) rematchAvailable() bool {
+	return r.rules.RematchWaitLimit > 0 && r.roomRepo != nil && r.allConnected()
+}
+
+// waitRematch は試合の終了後に再戦の申し込みを受け付ける
+// act_request_rematch で申し込むと他のプレイヤーに ev_rematch_offered を送り、全員が act_accept_rematch で受けると
+// 同じメンバー・同じチーム分けの新しいルームを作成して全員に ev_rematch_ready で room_id を送る
+// ルームは終了済みのため、受付中の切断からは再接続できない
+func (r *GameRoom) waitRematch(ctx context.Context) {
+	if !r.rematchAvailable() {
+		return
+	}
+	deadline := time.Now().Add(r.rules.RematchWaitLimit)
+	timer := time.NewTimer(r.rules.RematchWaitLimit)
+	defer timer.Stop()
+
+	requested := make([]bool, len(r.players))
+	for {
+		select {
+		case msg := <-r.msgCh:
+			if msg.msgType != "act_request_rematch" && msg.msgType != "act_accept_rematch" {
+				continue
+			}
+			if msg.msgType == "act_accept_rematch" && !slices.Contains(requested, true) {
+				r.players[msg.idx].send(WSMessage{
+					Type: "ev_error",
+					Payload: map[string]any{
+						"code":    "rematch_not_offered",
+						"message": "対戦相手は再戦を申し込んでいません",
+					},
+				})
+				continue
+			}
+			if requested[msg.idx] {
+				continue
+			}
+			requested[msg.idx] = true
+			// 他のプレイヤーの申し込みと行き違いになった場合も承諾として扱う
+			if !slices.Contains(requested, false) {
+				r.startRematch()
+				return
+			}
+			log.Printf("game room %s: player[%d] requested a rematch", r.id, msg.idx)
+			for i, p := range r.players {
+				if requested[i] {
+					continue
+				}
+				p.send(WSMessage{
+					Type: "ev_rematch_offered",
+					Payload: map[string]any{
+						"expires_in_sec": int(time.Until(deadline).Round(time.Second) / time.Second),
+					},
+				})
+			}
+
+		case ev := <-r.disconnCh:
+			r.mu.Lock()
+			p := r.players[ev.idx]
+			current := p.conn == ev.conn
+			if current {
+				p.connected = false
+			}
+			r.mu.Unlock()
+			if !current {
+				continue
+			}
+			for i := range r.players {
+				if i != ev.idx {
+					r.sendRematchCanceled(i, rematchCanceledOpponentLeft)
+				}
+			}
+			return
+
+		case <-r.reconnCh:
+			// 終了前に始まった再接続。終了した試合の状態は送り直さない
+		case <-r.graceCh:
+			// 受付中の切断では猶予タイマーを動かさない
+		case <-timer.C:
+			for i := range r.players {
+				r.sendRematchCanceled(i, rematchCanceledExpired)
+			}
+			return
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// startRematch は同じメンバー・同じチーム分けの新しいルームを作成し、全員に room_id を送る
+// プレイヤーは新しいルームの WebSocket に接続し直す（ルームの作成以降はマッチングと同じ流れ）
+func (r *GameRoom) startRematch() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var teams [2][]uuid.UUID
+	for _, p := range r.players {
+		teams[p.team] = append(teams[p.team], p.user.ID)
+	}
+	room := entity.NewRoom(uuid.New(), teams, time.Now())
+	if err := r.roomRepo.Create(ctx, room); err != nil {
+		log.Printf("game room %s: failed to create rematch room: %v", r.id, err)
+		for i := range r.players {
+			r.sendRematchCanceled(i, rematchCanceledFailed)
+		}
+		return
+	}
+
+	log.Printf("game room %s: rematch room %s created", r.id, room.ID)
+	for _, p := range r.players {
+		p.send(WSMessage{
+			Type: "ev_rematch_ready",
+			Payload: map[string]any{
+				"room_id": room.ID.String(),
+			},
+		})
+	}
+}
+
+func (r *GameRoom) sendRematchCanceled(idx int, reason string) {
+	r.players[idx].send(WSMessage{
+		Type: "ev_rematch_canceled",
+		Payload: map[string]any{
+			"reason": reason,
+		},
+	})
+}
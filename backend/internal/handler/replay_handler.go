@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/usecase"
+)
+
+type ReplayHandler struct {
+	replayUsecase *usecase.ReplayUsecase
+	userRepo      repository.UserRepository
+}
+
+func NewReplayHandler(uc *usecase.ReplayUsecase, userRepo repository.UserRepository) *ReplayHandler {
+	return &ReplayHandler{replayUsecase: uc, userRepo: userRepo}
+}
+
+// GetReplay は GET /api/v1/rooms/:id/replay を処理する
+// 終了した試合で自分に送られたイベントを返す。?view=full の場合は両プレイヤーの分を返す
+func (h *ReplayHandler) GetReplay(c echo.Context) error {
+	identity, ok := authenticatedIdentity(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+	}
+	roomID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid room id"})
+	}
+	view := usecase.ReplayView(c.QueryParam("view"))
+	switch view {
+	case "":
+		view = usecase.ReplayViewOwn
+	case usecase.ReplayViewOwn, usecase.ReplayViewFull:
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "view must be own or full"})
+	}
+
+	ctx := c.Request().Context()
+	// 一度も試合をしていないユーザーはどのルームの参加者でもない
+	user, err := h.userRepo.GetByGitHubID(ctx, identity.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "not a member of the room"})
+		}
+		log.Printf("replay: failed to get user %s: %v", identity.Login, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+
+	replay, err := h.replayUsecase.GetReplay(ctx, roomID, user.ID, view)
+	switch {
+	case errors.Is(err, usecase.ErrReplayNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "room not found"})
+	case errors.Is(err, usecase.ErrReplayForbidden):
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "not a member of the room"})
+	case errors.Is(err, usecase.ErrReplayNotReady):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "match has not ended"})
+	case err != nil:
+		log.Printf("replay: failed to get replay of room %s: %v", roomID, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+	return c.JSON(http.StatusOK, replay)
+}
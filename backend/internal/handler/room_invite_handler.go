@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/usecase"
+)
+
+const (
+	roomInviteStatusPending  = "pending"
+	roomInviteStatusRedeemed = "redeemed"
+)
+
+// roomInviteResponse は招待 API のレスポンス
+type roomInviteResponse struct {
+	ExpiresAt time.Time  `json:"expires_at"`
+	RoomID    *uuid.UUID `json:"room_id,omitempty"` // 招待が使われた後のみ
+	Code      string     `json:"code"`
+	Status    string     `json:"status"` // pending / redeemed
+}
+
+func newRoomInviteResponse(invite *entity.RoomInvite) roomInviteResponse {
+	res := roomInviteResponse{Code: invite.Code, Status: roomInviteStatusPending, ExpiresAt: invite.ExpiresAt}
+	if invite.Redeemed() {
+		res.Status = roomInviteStatusRedeemed
+		res.RoomID = &invite.RoomID
+	}
+	return res
+}
+
+type RoomInviteHandler struct {
+	inviteUsecase *usecase.RoomInviteUsecase
+	userRepo      repository.UserRepository
+}
+
+func NewRoomInviteHandler(uc *usecase.RoomInviteUsecase, userRepo repository.UserRepository) *RoomInviteHandler {
+	return &RoomInviteHandler{inviteUsecase: uc, userRepo: userRepo}
+}
+
+// CreateInvite は POST /api/v1/invites を処理する
+// プライベートルームへの招待コードを発行する。ルームは招待が使われたときに作成する
+func (h *RoomInviteHandler) CreateInvite(c echo.Context) error {
+	identity, ok := authenticatedIdentity(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+	}
+	ctx := c.Request().Context()
+	user, err := getOrCreateUser(ctx, h.userRepo, identity)
+	if err != nil {
+		log.Printf("invite: failed to get or create user %s: %v", identity.Login, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+
+	invite, err := h.inviteUsecase.Create(ctx, user.ID)
+	if err != nil {
+		log.Printf("invite: failed to create invite for %s: %v", identity.Login, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+	return c.JSON(http.StatusCreated, newRoomInviteResponse(invite))
+}
+
+// GetInvite は GET /api/v1/invites/:code を処理する
+// 作成者はこれで招待が使われたかを確かめ、room_id のルームへ接続する
+func (h *RoomInviteHandler) GetInvite(c echo.Context) error {
+	identity, ok := authenticatedIdentity(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+	}
+	ctx := c.Request().Context()
+	user, ok, err := h.existingUser(c, identity)
+	if !ok {
+		return err
+	}
+
+	invite, err := h.inviteUsecase.Get(ctx, c.Param("code"), user.ID)
+	if err != nil {
+		return h.inviteError(c, err)
+	}
+	return c.JSON(http.StatusOK, newRoomInviteResponse(invite))
+}
+
+// RedeemInvite は POST /api/v1/invites/:code/redeem を処理する
+// 招待を使って2人目の席を取り、作成したルームを返す。両プレイヤーはこのルームの WebSocket に接続する
+func (h *RoomInviteHandler) RedeemInvite(c echo.Context) error {
+	identity, ok := authenticatedIdentity(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+	}
+	ctx := c.Request().Context()
+	user, err := getOrCreateUser(ctx, h.userRepo, identity)
+	if err != nil {
+		log.Printf("invite: failed to get or create user %s: %v", identity.Login, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+
+	room, err := h.inviteUsecase.Redeem(ctx, c.Param("code"), user.ID)
+	if err != nil {
+		return h.inviteError(c, err)
+	}
+	return c.JSON(http.StatusOK, room)
+}
+
+// CancelInvite は DELETE /api/v1/invites/:code を処理する
+// 未使用の招待を取り消す。取り消せるのは作成者のみ
+func (h *RoomInviteHandler) CancelInvite(c echo.Context) error {
+	identity, ok := authenticatedIdentity(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+	}
+	ctx := c.Request().Context()
+	user, ok, err := h.existingUser(c, identity)
+	if !ok {
+		return err
+	}
+
+	if err := h.inviteUsecase.Cancel(ctx, c.Param("code"), user.ID); err != nil {
+		return h.inviteError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// existingUser は登録済みのユーザーを返す。ok が false の場合は err をそのまま返す
+// 未登録のユーザーは招待を作っても使ってもいないため 403 とする
+func (h *RoomInviteHandler) existingUser(c echo.Context, identity entity.GitHubIdentity) (*entity.User, bool, error) {
+	user, err := h.userRepo.GetByGitHubID(c.Request().Context(), identity.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, c.JSON(http.StatusForbidden, map[string]string{"error": "not allowed to access the invite"})
+		}
+		log.Printf("invite: failed to get user %s: %v", identity.Login, err)
+		return nil, false, c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+	return user, true, nil
+}
+
+// inviteError は招待の操作の失敗をレスポンスに変換する
+func (h *RoomInviteHandler) inviteError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, usecase.ErrInviteNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "invite not found"})
+	case errors.Is(err, usecase.ErrInviteForbidden):
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "not allowed to access the invite"})
+	case errors.Is(err, usecase.ErrInviteRedeemed):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "invite already redeemed"})
+	case errors.Is(err, usecase.ErrInviteOwn):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "cannot redeem own invite"})
+	}
+	log.Printf("invite: failed to handle invite %q: %v", c.Param("code"), err)
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+}
@@ -6,47 +6,146 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/gorilla/websocket"
-	"github.com/lib/pq"
 	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
 	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/usecase"
 )
 
-// ErrInvalidGitHubID は github_id のパースに失敗したことを示す
-var ErrInvalidGitHubID = errors.New("invalid github_id")
+var (
+	// ErrRoomNotFound はマッチングで作成されていないルームへの参加を示す
+	ErrRoomNotFound = errors.New("room not found")
+	// ErrNotRoomMember はルームの対戦者以外の参加を示す
+	ErrNotRoomMember = errors.New("not a member of the room")
+	// ErrRoomFinished は試合が終了したルームへの参加を示す
+	ErrRoomFinished = errors.New("room is finished")
+	// ErrServerDraining はサーバーの停止中に新しいルームを作ろうとしたことを示す
+	ErrServerDraining = errors.New("server is draining")
+)
+
+const (
+	// drainPollInterval は Drain が稼働中のルームの数を確認する間隔
+	drainPollInterval = 500 * time.Millisecond
+	// drainAbortGrace は期限切れで打ち切ったルームが保存や精算を終えるのを待つ上限
+	drainAbortGrace = 15 * time.Second
+)
 
 // RoomManager はゲームルームのレジストリ
+// router を設定すると、ルームの担当を Redis に登録し、他のインスタンスが担当するルームへの接続を中継する
 type RoomManager struct {
 	rooms    map[uuid.UUID]*GameRoom
 	userRepo repository.UserRepository
-	mu       sync.RWMutex
+	roomRepo repository.RoomRepository
+	router   *InstanceRouter // nil の場合は単一インスタンスで動作する
+	// remoteConns は他のインスタンスから中継されているプレイヤーの接続 (connID → 接続)
+	remoteConns map[string]*relayConn
+	// relayedConns はこのインスタンスが担当インスタンスへ中継している接続 (connID → 接続)
+	relayedConns map[string]*relayedConn
+	// runCtx はゲームループに渡すコンテキスト。Drain の期限切れで cancelRun が呼ばれる
+	runCtx    context.Context
+	cancelRun context.CancelFunc
+	deps      gameRoomDeps
+	rules     entity.MatchRules
+	mu        sync.RWMutex
+	relayMu   sync.Mutex
+	draining  atomic.Bool // true の間は新しいルームを作らない
 }
 
-func NewRoomManager(userRepo repository.UserRepository) *RoomManager {
-	return &RoomManager{
-		rooms:    make(map[uuid.UUID]*GameRoom),
-		userRepo: userRepo,
+func NewRoomManager(
+	userRepo repository.UserRepository,
+	roomRepo repository.RoomRepository,
+	matchRepo repository.MatchRepository,
+	gnuLedger repository.GnuLedgerRepository,
+	ratingUC *usecase.RatingUsecase,
+	leaderboardUC *usecase.LeaderboardUsecase,
+	statsUC *usecase.UserStatsUsecase,
+	tournamentUC *usecase.TournamentUsecase,
+	questionUC *usecase.QuestionUsecase,
+	rules entity.MatchRules,
+	maxSpectators int,
+	router *InstanceRouter,
+) *RoomManager {
+	runCtx, cancelRun := context.WithCancel(context.Background())
+	m := &RoomManager{
+		runCtx:       runCtx,
+		cancelRun:    cancelRun,
+		rooms:        make(map[uuid.UUID]*GameRoom),
+		userRepo:     userRepo,
+		roomRepo:     roomRepo,
+		router:       router,
+		remoteConns:  make(map[string]*relayConn),
+		relayedConns: make(map[string]*relayedConn),
+		rules:        rules,
+		deps: gameRoomDeps{
+			roomRepo:      roomRepo,
+			matchRepo:     matchRepo,
+			gnuLedger:     gnuLedger,
+			rating:        ratingUC,
+			leaderboard:   leaderboardUC,
+			stats:         statsUC,
+			tournaments:   tournamentUC,
+			questions:     questionUC,
+			maxSpectators: maxSpectators,
+		},
 	}
+	if router != nil {
+		// 担当インスタンスとして受け取るメッセージ
+		router.Handle(entity.RelayKindJoin, m.handleRelayJoin)
+		router.Handle(entity.RelayKindPlayerMessage, m.handleRelayPlayerMessage)
+		router.Handle(entity.RelayKindLeave, m.handleRelayLeave)
+		router.Handle(entity.RelayKindSpectate, m.handleRelaySpectate)
+		// 中継インスタンスとして受け取るメッセージ
+		router.Handle(entity.RelayKindSend, m.handleRelaySend)
+		router.Handle(entity.RelayKindClose, m.handleRelayClose)
+	}
+	return m
 }
 
 // getOrCreate はルームを取得または新規作成する
-func (m *RoomManager) getOrCreate(roomID uuid.UUID) *GameRoom {
+// 呼び出し前に authorize でルームの存在と参加資格を検証すること
+// snapshot が渡された場合は保存した状態からルームを復元し、再接続を待つゲームループを起動する
+// Drain 中は既存のルームのみ返し、新規作成は ErrServerDraining で拒否する
+func (m *RoomManager) getOrCreate(dbRoom *entity.Room, snapshot *entity.RoomSnapshot) (*GameRoom, error) {
+	roomID := dbRoom.ID
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if room, ok := m.rooms[roomID]; ok {
-		return room
+		return room, nil
+	}
+	if m.draining.Load() {
+		return nil, ErrServerDraining
 	}
-	room := newGameRoom(roomID, m.userRepo, func() {
+	onClose := func() {
 		m.remove(roomID)
 		log.Printf("room manager: removed room %s", roomID)
-	})
+	}
+	var room *GameRoom
+	if snapshot != nil {
+		room = newRecoveredGameRoom(snapshot, dbRoom.Members(), m.deps, onClose)
+		log.Printf("room manager: recovered room %s after turn %d", roomID, snapshot.CompletedTurns)
+	} else {
+		room = newGameRoom(roomID, dbRoom.Members(), m.rules, m.deps, onClose)
+		log.Printf("room manager: created room %s", roomID)
+	}
 	m.rooms[roomID] = room
-	log.Printf("room manager: created room %s", roomID)
-	return room
+	if m.router != nil {
+		go m.keepOwnership(roomID, room.closedCh)
+	}
+	if snapshot != nil {
+		go room.run(m.runCtx)
+	}
+	return room, nil
+}
+
+// Get は稼働中のルームを返す。存在しない場合は nil
+func (m *RoomManager) Get(roomID uuid.UUID) *GameRoom {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.rooms[roomID]
 }
 
 // remove はルームをレジストリから削除する
@@ -56,52 +155,302 @@ func (m *RoomManager) remove(roomID uuid.UUID) {
 	delete(m.rooms, roomID)
 }
 
-// GetOrCreateUser は github_login でユーザーを取得し、存在しなければ作成して返す
-func (m *RoomManager) GetOrCreateUser(ctx context.Context, githubLogin string, githubIDStr string) (*entity.User, error) {
-	user, err := m.userRepo.GetByGitHubLogin(ctx, githubLogin)
-	if err == nil {
-		return user, nil
+// JoinResult は Join の結果
+type JoinResult struct {
+	Room   *GameRoom
+	DoneCh <-chan struct{} // 接続終了時に close される
+	Idx    int
+	// Reconnected は既存プレイヤーの接続を差し替えたかどうか
+	// true の場合、ゲームループは既に起動済みで現在の状態がリプレイされる
+	Reconnected bool
+}
+
+// authorize は DB のルーム情報から user がルームに参加できるかを検証し、ルームを返す
+func (m *RoomManager) authorize(ctx context.Context, roomID uuid.UUID, userID uuid.UUID) (*entity.Room, error) {
+	room, err := m.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRoomNotFound
+		}
+		return nil, fmt.Errorf("get room: %w", err)
 	}
-	if !errors.Is(err, sql.ErrNoRows) {
-		return nil, fmt.Errorf("failed to get user: %w", err)
+	if room.Status.IsEnded() {
+		return nil, ErrRoomFinished
 	}
-	githubID, parseErr := strconv.ParseInt(githubIDStr, 10, 64)
-	if parseErr != nil {
-		return nil, ErrInvalidGitHubID
+	if !room.HasMember(userID) {
+		return nil, ErrNotRoomMember
 	}
-	user = &entity.User{
-		GitHubID:    githubID,
-		GitHubLogin: githubLogin,
+	return room, nil
+}
+
+// loadSnapshot は稼働していない対戦中のルームについて、保存された試合の状態を返す
+// 再起動前に問題の生成まで進まなかったルームなど、状態がない場合は nil を返し、最初からやり直す
+func (m *RoomManager) loadSnapshot(ctx context.Context, room *entity.Room) (*entity.RoomSnapshot, error) {
+	if room.Status != entity.RoomStatusInProgress || m.Get(room.ID) != nil {
+		return nil, nil
 	}
-	if createErr := m.userRepo.Create(ctx, user); createErr != nil {
-		// UNIQUE 制約違反 (23505) は同時リクエストによる競合。既存レコードを取得して返す
-		var pqErr *pq.Error
-		if errors.As(createErr, &pqErr) && pqErr.Code == "23505" {
-			existing, getErr := m.userRepo.GetByGitHubLogin(ctx, githubLogin)
-			if getErr == nil {
-				return existing, nil
-			}
-		}
-		return nil, fmt.Errorf("failed to create user: %w", createErr)
+	snapshot, err := m.roomRepo.GetSnapshot(ctx, room.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get snapshot: %w", err)
 	}
-	log.Printf("room manager: auto-created user %s (id=%s)", githubLogin, user.ID)
-	return user, nil
+	return snapshot, nil
 }
 
 // Join はプレイヤーをルームに参加させ、接続終了を知らせる doneCh を返す
-// idx==0 のとき、呼び出し元はゲームループを goroutine で起動すること
+// ルームはマッチングで作成済みで、user がその対戦者である必要がある
+// 新規参加で Idx==0 のとき、呼び出し元はゲームループを goroutine で起動すること
 func (m *RoomManager) Join(
 	ctx context.Context,
 	roomID uuid.UUID,
-	conn *websocket.Conn,
+	conn playerConn,
 	user *entity.User,
-) (int, <-chan struct{}, *GameRoom, error) {
-	room := m.getOrCreate(roomID)
-	idx, doneCh, err := room.join(conn, user)
+) (*JoinResult, error) {
+	dbRoom, err := m.authorize(ctx, roomID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := m.loadSnapshot(ctx, dbRoom)
+	if err != nil {
+		return nil, err
+	}
+	room, err := m.getOrCreate(dbRoom, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	idx, doneCh, reconnected, err := room.join(conn, user)
+	if err != nil {
+		return nil, fmt.Errorf("join room %s: %w", roomID, err)
+	}
+	if reconnected {
+		log.Printf("room manager: player[%d] %s reconnected to room %s", idx, user.GitHubLogin, roomID)
+	} else {
+		log.Printf("room manager: player[%d] %s joined room %s", idx, user.GitHubLogin, roomID)
+	}
+
+	return &JoinResult{
+		Room:        room,
+		DoneCh:      doneCh,
+		Idx:         idx,
+		Reconnected: reconnected,
+	}, nil
+}
+
+// Connect はプレイヤーの接続をルームにつなぎ、接続が閉じるまでブロックする
+// ルームを他のインスタンスが担当している場合は、担当インスタンスへメッセージを中継する
+func (m *RoomManager) Connect(ctx context.Context, roomID uuid.UUID, conn playerConn, user *entity.User) {
+	owner, local, err := m.resolveOwner(ctx, roomID, user.ID)
+	if err != nil {
+		log.Printf("room %s: failed to resolve owner for %s: %v", roomID, user.GitHubLogin, err)
+		sendJoinError(conn, err)
+		return
+	}
+	if local {
+		m.serve(ctx, roomID, conn, user)
+		return
+	}
+	log.Printf("room %s: relaying %s to instance %s", roomID, user.GitHubLogin, owner)
+	m.relayPlayer(owner, roomID, conn, user)
+}
+
+// spectateOwner は観戦先のルームを担当するインスタンスを返す。このインスタンスが担当していれば空を返す
+// どのインスタンスでも稼働していなければ ErrRoomNotFound を返す
+func (m *RoomManager) spectateOwner(ctx context.Context, roomID uuid.UUID) (string, error) {
+	if m.Get(roomID) != nil {
+		return "", nil
+	}
+	if m.router == nil {
+		return "", ErrRoomNotFound
+	}
+	owner, err := m.router.routing.GetOwner(ctx, roomID)
+	if err != nil {
+		return "", err
+	}
+	// 自分が担当として登録されたままでも、ルームが手元になければ終了している
+	if owner == "" || owner == m.router.ID() {
+		return "", ErrRoomNotFound
+	}
+	return owner, nil
+}
+
+// Spectate は観戦者の接続を owner が担当するルームにつなぎ、接続が閉じるまでブロックする
+// owner が空の場合はこのインスタンスのルームを観戦する
+func (m *RoomManager) Spectate(roomID uuid.UUID, owner string, conn playerConn) {
+	if owner != "" {
+		log.Printf("room %s: relaying spectator to instance %s", roomID, owner)
+		m.relaySpectator(owner, roomID, conn)
+		return
+	}
+	room := m.Get(roomID)
+	if room == nil {
+		sendSpectateError(conn, errRoomClosed)
+		return
+	}
+	room.spectate(conn)
+}
+
+// resolveOwner はルームを担当するインスタンスを返す。担当がいなければこのインスタンスが担当になる
+// local はこのインスタンスが担当かどうか
+func (m *RoomManager) resolveOwner(ctx context.Context, roomID uuid.UUID, userID uuid.UUID) (string, bool, error) {
+	if m.router == nil || m.Get(roomID) != nil {
+		return "", true, nil
+	}
+	// 停止中のインスタンスは担当にも中継にもならず、別のインスタンスへの再接続を促す
+	if m.draining.Load() {
+		return "", false, ErrServerDraining
+	}
+	// 参加資格のないユーザーの接続で担当を登録しないよう、先に検証する
+	if _, err := m.authorize(ctx, roomID, userID); err != nil {
+		return "", false, err
+	}
+	owner, err := m.router.routing.ClaimOwner(ctx, roomID, m.router.ID(), roomOwnerTTL)
+	if err != nil {
+		return "", false, err
+	}
+	return owner, owner == m.router.ID(), nil
+}
+
+// serve はこのインスタンスが担当するルームにプレイヤーを参加させ、接続が閉じるまでブロックする
+func (m *RoomManager) serve(ctx context.Context, roomID uuid.UUID, conn playerConn, user *entity.User) {
+	res, err := m.Join(ctx, roomID, conn, user)
+	if err != nil {
+		log.Printf("room %s: join failed for %s: %v", roomID, user.GitHubLogin, err)
+		sendJoinError(conn, err)
+		return
+	}
+
+	// idx==0 のプレイヤーがゲームループを起動する（再接続時は起動済み）
+	if res.Idx == 0 && !res.Reconnected {
+		go res.Room.run(m.runCtx)
+	}
+
+	// 接続の読み取りは startReaderLoop に委譲する
+	go res.Room.startReaderLoop(res.Idx)
+
+	// 接続が閉じるまでブロック（doneCh は startReaderLoop が close する）
+	<-res.DoneCh
+}
+
+// keepOwnership はルームが閉じるまで担当の登録を延長し、閉じたら登録を削除する
+func (m *RoomManager) keepOwnership(roomID uuid.UUID, closedCh <-chan struct{}) {
+	routing := m.router.routing
+	ticker := time.NewTicker(roomOwnerRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closedCh:
+			ctx, cancel := context.WithTimeout(context.Background(), relayPublishTimeout)
+			if err := routing.ReleaseOwner(ctx, roomID, m.router.ID()); err != nil {
+				log.Printf("room manager: failed to release owner of room %s: %v", roomID, err)
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), relayPublishTimeout)
+			if err := routing.RefreshOwner(ctx, roomID, m.router.ID(), roomOwnerTTL); err != nil {
+				log.Printf("room manager: failed to refresh owner of room %s: %v", roomID, err)
+			}
+			cancel()
+		}
+	}
+}
+
+// Recover は試合の状態が保存されているルームを復元し、両プレイヤーの再接続を待つ
+// 待ち時間内に揃わなかったルームは完了したターンまでで精算して中止する
+// 起動時、インスタンス間の購読を始めた後に呼ぶ。他のインスタンスが担当しているルームは復元しない
+func (m *RoomManager) Recover(ctx context.Context) error {
+	ids, err := m.roomRepo.ListSnapshotRoomIDs(ctx)
+	if err != nil {
+		return fmt.Errorf("list recoverable rooms: %w", err)
+	}
+	for _, id := range ids {
+		m.recoverRoom(ctx, id)
+	}
+	return nil
+}
+
+func (m *RoomManager) recoverRoom(ctx context.Context, roomID uuid.UUID) {
+	if m.Get(roomID) != nil {
+		return
+	}
+	room, err := m.roomRepo.GetByID(ctx, roomID)
 	if err != nil {
-		return -1, nil, nil, fmt.Errorf("join room %s: %w", roomID, err)
+		log.Printf("room manager: failed to get recoverable room %s: %v", roomID, err)
+		return
+	}
+	snapshot, err := m.roomRepo.GetSnapshot(ctx, roomID)
+	if err != nil {
+		log.Printf("room manager: failed to get snapshot of room %s: %v", roomID, err)
+		return
+	}
+	// 状態が失効したか、別の経路で終了したルームは対象から外す
+	if snapshot == nil || room.Status != entity.RoomStatusInProgress {
+		if err := m.roomRepo.DeleteSnapshot(ctx, roomID); err != nil {
+			log.Printf("room manager: failed to delete stale snapshot of room %s: %v", roomID, err)
+		}
+		return
+	}
+	if m.router != nil {
+		owner, err := m.router.routing.ClaimOwner(ctx, roomID, m.router.ID(), roomOwnerTTL)
+		if err != nil {
+			log.Printf("room manager: failed to claim recoverable room %s: %v", roomID, err)
+			return
+		}
+		if owner != m.router.ID() {
+			return
+		}
+	}
+	if _, err := m.getOrCreate(room, snapshot); err != nil {
+		log.Printf("room manager: failed to recover room %s: %v", roomID, err)
+	}
+}
+
+// count は稼働中のルームの数を返す
+func (m *RoomManager) count() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.rooms)
+}
+
+// Drain は新しいルームの受け付けを止め、稼働中のルームが終わるのを ctx が終わるまで待つ
+// 期限までに終わらなかったルームはゲームループを打ち切る。状態を保存できているルームは再起動後に再開し、
+// 保存できていないルームは中止して完了したターンまでのヌーを精算する
+// 最後にこのインスタンスが中継している接続を閉じ、プレイヤーに別のインスタンスへ再接続させる
+func (m *RoomManager) Drain(ctx context.Context) {
+	m.draining.Store(true)
+	log.Printf("room manager: draining %d rooms", m.count())
+
+	if !m.waitRooms(ctx) {
+		log.Printf("room manager: drain deadline exceeded, stopping %d rooms", m.count())
+		m.cancelRun()
+		abortCtx, cancel := context.WithTimeout(context.Background(), drainAbortGrace)
+		defer cancel()
+		if !m.waitRooms(abortCtx) {
+			log.Printf("room manager: %d rooms did not stop in time", m.count())
+		}
 	}
-	log.Printf("room manager: player[%d] %s joined room %s", idx, user.GitHubLogin, roomID)
 
-	return idx, doneCh, room, nil
+	m.relayMu.Lock()
+	relayed := make([]*relayedConn, 0, len(m.relayedConns))
+	for _, rc := range m.relayedConns {
+		relayed = append(relayed, rc)
+	}
+	m.relayMu.Unlock()
+	for _, rc := range relayed {
+		rc.interrupt()
+	}
+	log.Println("room manager: drained")
+}
+
+// waitRooms は稼働中のルームがなくなるまで待つ。ctx が先に終わった場合は false を返す
+func (m *RoomManager) waitRooms(ctx context.Context) bool {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for m.count() > 0 {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
 }
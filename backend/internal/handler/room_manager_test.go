@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/testutil"
+)
+
+func newTestRoomManager(room *entity.Room) *RoomManager {
+	roomRepo := &testutil.MockRoomRepository{
+		GetByIDFunc: func(_ context.Context, id uuid.UUID) (*entity.Room, error) {
+			if room == nil || id != room.ID {
+				return nil, fmt.Errorf("get room by id: %w", sql.ErrNoRows)
+			}
+			return room, nil
+		},
+	}
+	return NewRoomManager(nil, roomRepo, nil, nil, nil, nil, nil, nil, nil, entity.DefaultMatchRules(), 0, nil)
+}
+
+func TestRoomManager_Join_Member(t *testing.T) {
+	p1 := &entity.User{ID: uuid.New()}
+	p2 := &entity.User{ID: uuid.New()}
+	room := &entity.Room{ID: uuid.New(), Player1ID: p1.ID, Player2ID: p2.ID, Status: entity.RoomStatusWaiting}
+	m := newTestRoomManager(room)
+
+	res, err := m.Join(context.Background(), room.ID, &websocket.Conn{}, p2)
+	require.NoError(t, err)
+	assert.Equal(t, 0, res.Idx)
+	assert.Same(t, res.Room, m.Get(room.ID))
+
+	res, err = m.Join(context.Background(), room.ID, &websocket.Conn{}, p1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.Idx)
+}
+
+func TestRoomManager_Join_Rejections(t *testing.T) {
+	p1 := &entity.User{ID: uuid.New()}
+	p2 := &entity.User{ID: uuid.New()}
+	waiting := &entity.Room{ID: uuid.New(), Player1ID: p1.ID, Player2ID: p2.ID, Status: entity.RoomStatusWaiting}
+	finished := &entity.Room{ID: uuid.New(), Player1ID: p1.ID, Player2ID: p2.ID, Status: entity.RoomStatusFinished}
+	aborted := &entity.Room{ID: uuid.New(), Player1ID: p1.ID, Player2ID: p2.ID, Status: entity.RoomStatusAborted}
+
+	tests := []struct {
+		room     *entity.Room
+		user     *entity.User
+		wantErr  error
+		name     string
+		wantCode string
+		roomID   uuid.UUID
+	}{
+		{name: "unknown room", room: waiting, roomID: uuid.New(), user: p1, wantErr: ErrRoomNotFound, wantCode: "room_not_found"},
+		{name: "outsider", room: waiting, roomID: waiting.ID, user: &entity.User{ID: uuid.New()}, wantErr: ErrNotRoomMember, wantCode: "not_room_member"},
+		{name: "finished", room: finished, roomID: finished.ID, user: p1, wantErr: ErrRoomFinished, wantCode: "room_finished"},
+		{name: "aborted", room: aborted, roomID: aborted.ID, user: p2, wantErr: ErrRoomFinished, wantCode: "room_finished"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestRoomManager(tt.room)
+
+			_, err := m.Join(context.Background(), tt.roomID, &websocket.Conn{}, tt.user)
+
+			require.ErrorIs(t, err, tt.wantErr)
+			code, _ := joinErrorCode(err)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Nil(t, m.Get(tt.roomID), "rejected join must not create a room")
+		})
+	}
+}
+
+func TestRoomManager_Drain_RejectsNewRooms(t *testing.T) {
+	p1 := &entity.User{ID: uuid.New()}
+	p2 := &entity.User{ID: uuid.New()}
+	room := &entity.Room{ID: uuid.New(), Player1ID: p1.ID, Player2ID: p2.ID, Status: entity.RoomStatusWaiting}
+	other := &entity.Room{ID: uuid.New(), Player1ID: p1.ID, Player2ID: p2.ID, Status: entity.RoomStatusWaiting}
+	roomRepo := &testutil.MockRoomRepository{
+		GetByIDFunc: func(_ context.Context, id uuid.UUID) (*entity.Room, error) {
+			if id == other.ID {
+				return other, nil
+			}
+			return room, nil
+		},
+	}
+	m := NewRoomManager(nil, roomRepo, nil, nil, nil, nil, nil, nil, nil, entity.DefaultMatchRules(), 0, nil)
+	res, err := m.Join(context.Background(), room.ID, &websocket.Conn{}, p1)
+	require.NoError(t, err)
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		m.Drain(context.Background())
+	}()
+	require.Eventually(t, m.draining.Load, time.Second, 10*time.Millisecond)
+
+	// 稼働中のルームには引き続き参加できる
+	_, err = m.Join(context.Background(), room.ID, &websocket.Conn{}, p2)
+	require.NoError(t, err)
+
+	// 新しいルームは作らない
+	_, err = m.Join(context.Background(), other.ID, &websocket.Conn{}, p1)
+	require.ErrorIs(t, err, ErrServerDraining)
+	code, _ := joinErrorCode(err)
+	assert.Equal(t, "server_draining", code)
+	assert.Nil(t, m.Get(other.ID))
+
+	// 稼働中のルームが終わると Drain が戻る
+	res.Room.close()
+	select {
+	case <-drained:
+	case <-time.After(2 * time.Second):
+		t.Fatal("drain did not return after the last room closed")
+	}
+}
+
+// newTestSnapshot は2ターンの試合の completed ターン目までを保存したスナップショットを返す
+func newTestSnapshot(roomID uuid.UUID, p1, p2 *entity.User, completed int) *entity.RoomSnapshot {
+	rules := entity.DefaultMatchRules()
+	rules.TotalTurns = 2
+	question := func(text string) []entity.Question {
+		return []entity.Question{{
+			QuestionText:  text,
+			CorrectAnswer: "a",
+			Choices:       []string{"a", "b", "c", "d"},
+		}}
+	}
+	snapshot := &entity.RoomSnapshot{
+		RoomID:         roomID,
+		Rules:          rules,
+		CompletedTurns: completed,
+		Players: []entity.RoomSnapshotPlayer{
+			{UserID: p1.ID, GitHubLogin: p1.GitHubLogin, GnuBalance: 110, MyQuestions: question("q1-my"), ForOpponent: question("q1-for")},
+			{UserID: p2.ID, GitHubLogin: p2.GitHubLogin, GnuBalance: 90, MyQuestions: question("q2-my"), ForOpponent: question("q2-for")},
+		},
+	}
+	for turn := 1; turn <= completed; turn++ {
+		snapshot.Turns = append(snapshot.Turns,
+			entity.MatchTurn{UserID: p1.ID, Turn: turn, Bet: 10, GnuDelta: 10, IsCorrect: true},
+			entity.MatchTurn{UserID: p2.ID, Turn: turn, Bet: 10, GnuDelta: -10},
+		)
+	}
+	return snapshot
+}
+
+func TestRoomManager_Join_ResumesFromSnapshot(t *testing.T) {
+	p1 := &entity.User{ID: uuid.New(), GitHubLogin: "alice"}
+	p2 := &entity.User{ID: uuid.New(), GitHubLogin: "bob"}
+	room := &entity.Room{ID: uuid.New(), Player1ID: p1.ID, Player2ID: p2.ID, Status: entity.RoomStatusInProgress}
+	snapshot := newTestSnapshot(room.ID, p1, p2, 1)
+	roomRepo := &testutil.MockRoomRepository{
+		GetByIDFunc: func(context.Context, uuid.UUID) (*entity.Room, error) { return room, nil },
+		GetSnapshotFunc: func(context.Context, uuid.UUID) (*entity.RoomSnapshot, error) {
+			return snapshot, nil
+		},
+	}
+	m := NewRoomManager(nil, roomRepo, nil, nil, nil, nil, nil, nil, nil, entity.DefaultMatchRules(), 0, nil)
+
+	clients := make([]*websocket.Conn, 2)
+	for i, user := range []*entity.User{p1, p2} {
+		server, client := newTestConn(t)
+		clients[i] = client
+		go m.Connect(context.Background(), room.ID, server, user)
+	}
+
+	// 保存した残高で2ターン目から再開する
+	for i, want := range []int{110, 90} {
+		msg := readUntil(t, clients[i], "ev_turn_start")
+		payload := msg.Payload.(map[string]any)
+		assert.EqualValues(t, 2, payload["turn"])
+		assert.EqualValues(t, want, payload["your_gnu_balance"])
+	}
+}
+
+func TestRoomManager_Recover_SettlesWhenPlayersDoNotReturn(t *testing.T) {
+	p1 := &entity.User{ID: uuid.New(), GitHubLogin: "alice"}
+	p2 := &entity.User{ID: uuid.New(), GitHubLogin: "bob"}
+	room := &entity.Room{ID: uuid.New(), Player1ID: p1.ID, Player2ID: p2.ID, Status: entity.RoomStatusInProgress}
+	snapshot := newTestSnapshot(room.ID, p1, p2, 1)
+	snapshot.Rules.JoinWaitLimit = 50 * time.Millisecond
+
+	var mu sync.Mutex
+	var applied []entity.GnuTransaction
+	var statuses []entity.RoomEndReason
+	deleted := 0
+	roomRepo := &testutil.MockRoomRepository{
+		GetByIDFunc: func(context.Context, uuid.UUID) (*entity.Room, error) { return room, nil },
+		GetSnapshotFunc: func(context.Context, uuid.UUID) (*entity.RoomSnapshot, error) {
+			return snapshot, nil
+		},
+		ListSnapshotRoomIDsFunc: func(context.Context) ([]uuid.UUID, error) {
+			return []uuid.UUID{room.ID}, nil
+		},
+		UpdateStatusFunc: func(_ context.Context, _ uuid.UUID, _ entity.RoomStatus, reason entity.RoomEndReason) error {
+			mu.Lock()
+			defer mu.Unlock()
+			statuses = append(statuses, reason)
+			return nil
+		},
+		DeleteSnapshotFunc: func(context.Context, uuid.UUID) error {
+			mu.Lock()
+			defer mu.Unlock()
+			deleted++
+			return nil
+		},
+	}
+	ledger := &testutil.MockGnuLedgerRepository{
+		ApplyFunc: func(_ context.Context, txs []entity.GnuTransaction) (map[uuid.UUID]int, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			applied = append(applied, txs...)
+			return map[uuid.UUID]int{}, nil
+		},
+	}
+	var saved *entity.MatchResult
+	matchRepo := &testutil.MockMatchRepository{
+		SaveFunc: func(_ context.Context, result *entity.MatchResult) error {
+			mu.Lock()
+			defer mu.Unlock()
+			saved = result
+			return nil
+		},
+	}
+	m := NewRoomManager(nil, roomRepo, matchRepo, ledger, nil, nil, nil, nil, nil, entity.DefaultMatchRules(), 0, nil)
+
+	require.NoError(t, m.Recover(context.Background()))
+	require.NotNil(t, m.Get(room.ID), "recovered room waits for the players")
+	require.Eventually(t, func() bool { return m.Get(room.ID) == nil }, 2*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	// 完了した1ターン目だけを精算する（進行中だった2ターン目のベットは戻る）
+	require.Len(t, applied, 2)
+	for _, tx := range applied {
+		assert.Equal(t, 1, tx.Turn)
+	}
+	assert.Equal(t, []entity.RoomEndReason{entity.RoomEndReasonInterrupted}, statuses)
+	assert.Equal(t, 1, deleted)
+	require.NotNil(t, saved, "the interrupted match is saved with its completed turns")
+	assert.Equal(t, entity.MatchEndReasonInterrupted, saved.EndReason)
+	assert.Equal(t, 1, saved.TotalTurns)
+	assert.False(t, saved.WinnerID.Valid)
+}
@@ -0,0 +1,420 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+)
+
+const (
+	relayInboxSize  = 32               // 担当側で中継されたプレイヤーのメッセージを溜める数
+	relaySendBuffer = 64               // 中継している接続ごとの送信キュー長
+	relayWriteWait  = 10 * time.Second // 中継している接続への1メッセージあたりの書き込み期限
+)
+
+// errRelayClosed は中継が終了した接続への読み書きを示す
+var errRelayClosed = errors.New("relay connection closed")
+
+// relayConn は他のインスタンスで接続したプレイヤーを担当インスタンス側で表す接続
+// 読み取りは中継されたメッセージを返し、書き込みは中継インスタンスへの送信になる
+type relayConn struct {
+	router   *InstanceRouter
+	inbox    chan []byte
+	closedCh chan struct{}
+	instance string // 中継インスタンスの ID
+	connID   string
+	once     sync.Once
+	roomID   uuid.UUID
+	userID   uuid.UUID
+}
+
+func newRelayConn(router *InstanceRouter, msg entity.RelayMessage) *relayConn {
+	return &relayConn{
+		router:   router,
+		inbox:    make(chan []byte, relayInboxSize),
+		closedCh: make(chan struct{}),
+		instance: msg.From,
+		connID:   msg.ConnID,
+		roomID:   msg.RoomID,
+		userID:   msg.UserID,
+	}
+}
+
+func (c *relayConn) ReadMessage() (int, []byte, error) {
+	select {
+	case data := <-c.inbox:
+		return websocket.TextMessage, data, nil
+	case <-c.closedCh:
+		return 0, nil, errRelayClosed
+	}
+}
+
+func (c *relayConn) WriteMessage(_ int, data []byte) error {
+	select {
+	case <-c.closedCh:
+		return errRelayClosed
+	default:
+	}
+	return c.router.Publish(c.instance, entity.RelayMessage{
+		Kind:   entity.RelayKindSend,
+		ConnID: c.connID,
+		RoomID: c.roomID,
+		UserID: c.userID,
+		Data:   data,
+	})
+}
+
+// SetReadDeadline は現在時刻以前が渡された場合に中継を終了し、中継インスタンスに接続を閉じさせる
+func (c *relayConn) SetReadDeadline(t time.Time) error {
+	if t.IsZero() || t.After(time.Now()) {
+		return nil
+	}
+	c.close(true)
+	return nil
+}
+
+// SetWriteDeadline は何もしない。書き込みは Publish が relayPublishTimeout で打ち切る
+func (c *relayConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+// deliver は中継されたプレイヤーのメッセージを読み取り側に渡す
+// ルーターの購読 goroutine から呼ばれるためブロックしない。読み取りが追いつかない場合は中継を終了する
+func (c *relayConn) deliver(data []byte) {
+	select {
+	case c.inbox <- data:
+	case <-c.closedCh:
+	default:
+		log.Printf("room %s: inbox of relayed conn %s is full, closing", c.roomID, c.connID)
+		c.close(true)
+	}
+}
+
+// close は中継を終了する。notify が true の場合は中継インスタンスにも接続を閉じさせる
+func (c *relayConn) close(notify bool) {
+	c.once.Do(func() {
+		close(c.closedCh)
+		if !notify {
+			return
+		}
+		err := c.router.Publish(c.instance, entity.RelayMessage{
+			Kind:   entity.RelayKindClose,
+			ConnID: c.connID,
+			RoomID: c.roomID,
+			UserID: c.userID,
+		})
+		if err != nil {
+			log.Printf("room %s: failed to close relayed conn %s: %v", c.roomID, c.connID, err)
+		}
+	})
+}
+
+// relayedConn はこのインスタンスで受けて担当インスタンスへ中継している接続
+// 中継メッセージはルーターの購読 goroutine から届くため、その場では書かずに送信キュー経由で writeLoop が書き出す
+// キューが詰まった接続は閉じる（プレイヤーは再接続すると状態を受け取り直す）
+type relayedConn struct {
+	conn      playerConn
+	sendCh    chan []byte
+	closeCh   chan struct{} // 閉じると writeLoop が積んだメッセージを送り切ってから読み取りを打ち切る
+	closeOnce sync.Once
+}
+
+func newRelayedConn(conn playerConn) *relayedConn {
+	return &relayedConn{
+		conn:    conn,
+		sendCh:  make(chan []byte, relaySendBuffer),
+		closeCh: make(chan struct{}),
+	}
+}
+
+// enqueue は送信キューに data を積む。キューが詰まっていれば接続を閉じる
+func (c *relayedConn) enqueue(data []byte) {
+	select {
+	case c.sendCh <- data:
+	default:
+		log.Printf("room relay: send queue full, closing conn")
+		c.interrupt()
+	}
+}
+
+func (c *relayedConn) send(msg WSMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("room relay: marshal error: %v", err)
+		return
+	}
+	c.enqueue(data)
+}
+
+// closeAfterFlush は積んだメッセージを送り切ってから接続を閉じさせる
+func (c *relayedConn) closeAfterFlush() {
+	c.closeOnce.Do(func() { close(c.closeCh) })
+}
+
+// writeLoop は送信キューを WebSocket に書き出す。中継の終了（done）まで戻らない
+func (c *relayedConn) writeLoop(done <-chan struct{}) {
+	for {
+		select {
+		case data := <-c.sendCh:
+			if !c.write(data) {
+				c.interrupt()
+				return
+			}
+		case <-c.closeCh:
+			for {
+				select {
+				case data := <-c.sendCh:
+					if !c.write(data) {
+						c.interrupt()
+						return
+					}
+				default:
+					c.interrupt()
+					return
+				}
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+func (c *relayedConn) write(data []byte) bool {
+	if err := c.conn.SetWriteDeadline(time.Now().Add(relayWriteWait)); err != nil {
+		log.Printf("room relay: set write deadline error: %v", err)
+	}
+	if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		log.Printf("room relay: write error: %v", err)
+		return false
+	}
+	return true
+}
+
+// interrupt は読み取りを打ち切り、relayPlayer を終了させる
+func (c *relayedConn) interrupt() {
+	if err := c.conn.SetReadDeadline(time.Now()); err != nil {
+		log.Printf("room relay: failed to interrupt conn: %v", err)
+	}
+}
+
+// trackRelayed は担当インスタンスへ中継する接続を登録して書き込みを始め、接続 ID と登録を解除する関数を返す
+func (m *RoomManager) trackRelayed(conn playerConn) (string, *relayedConn, func()) {
+	connID := uuid.NewString()
+	rc := newRelayedConn(conn)
+	m.relayMu.Lock()
+	m.relayedConns[connID] = rc
+	m.relayMu.Unlock()
+	done := make(chan struct{})
+	go rc.writeLoop(done)
+	return connID, rc, func() {
+		m.relayMu.Lock()
+		delete(m.relayedConns, connID)
+		m.relayMu.Unlock()
+		close(done)
+	}
+}
+
+// relayPlayer は owner が担当するルームへプレイヤーの接続を中継し、接続が閉じるまでブロックする
+func (m *RoomManager) relayPlayer(owner string, roomID uuid.UUID, conn playerConn, user *entity.User) {
+	connID, rc, untrack := m.trackRelayed(conn)
+	defer untrack()
+
+	base := entity.RelayMessage{ConnID: connID, RoomID: roomID, UserID: user.ID}
+	join := base
+	join.Kind = entity.RelayKindJoin
+	join.User = user
+	if err := m.router.Publish(owner, join); err != nil {
+		log.Printf("room %s: failed to relay join of %s: %v", roomID, user.GitHubLogin, err)
+		sendJoinError(conn, err)
+		return
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go m.watchOwner(owner, roomID, rc, done)
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("room %s: relayed player %s unexpected close: %v", roomID, user.GitHubLogin, err)
+			}
+			break
+		}
+		msg := base
+		msg.Kind = entity.RelayKindPlayerMessage
+		msg.Data = data
+		if err := m.router.Publish(owner, msg); err != nil {
+			log.Printf("room %s: failed to relay message of %s: %v", roomID, user.GitHubLogin, err)
+		}
+	}
+
+	leave := base
+	leave.Kind = entity.RelayKindLeave
+	if err := m.router.Publish(owner, leave); err != nil {
+		log.Printf("room %s: failed to relay leave of %s: %v", roomID, user.GitHubLogin, err)
+	}
+}
+
+// relaySpectator は owner が担当するルームの観戦を中継し、接続が閉じるまでブロックする
+// 観戦者から受信したメッセージは担当へ送らずに破棄する
+func (m *RoomManager) relaySpectator(owner string, roomID uuid.UUID, conn playerConn) {
+	connID, rc, untrack := m.trackRelayed(conn)
+	defer untrack()
+
+	base := entity.RelayMessage{ConnID: connID, RoomID: roomID}
+	spectate := base
+	spectate.Kind = entity.RelayKindSpectate
+	if err := m.router.Publish(owner, spectate); err != nil {
+		log.Printf("room %s: failed to relay spectator: %v", roomID, err)
+		sendSpectateError(conn, err)
+		return
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go m.watchOwner(owner, roomID, rc, done)
+
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+
+	leave := base
+	leave.Kind = entity.RelayKindLeave
+	if err := m.router.Publish(owner, leave); err != nil {
+		log.Printf("room %s: failed to relay leave of spectator: %v", roomID, err)
+	}
+}
+
+// watchOwner は中継中に担当インスタンスが入れ替わったり失効したりしていないかを確認する
+// 担当がいなくなった場合はプレイヤーに通知して接続を閉じる
+func (m *RoomManager) watchOwner(owner string, roomID uuid.UUID, rc *relayedConn, done <-chan struct{}) {
+	ticker := time.NewTicker(roomOwnerRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), relayPublishTimeout)
+			current, err := m.router.routing.GetOwner(ctx, roomID)
+			cancel()
+			if err != nil {
+				log.Printf("room %s: failed to check owner: %v", roomID, err)
+				continue
+			}
+			if current == owner {
+				continue
+			}
+			log.Printf("room %s: owner %s is gone, closing relayed conn", roomID, owner)
+			rc.send(WSMessage{
+				Type: "ev_error",
+				Payload: map[string]any{
+					"code":    "room_closed",
+					"message": "ルームは閉じられました",
+				},
+			})
+			rc.closeAfterFlush()
+			return
+		}
+	}
+}
+
+// handleRelayJoin は他のインスタンスで接続したプレイヤーを担当ルームに参加させる
+func (m *RoomManager) handleRelayJoin(msg entity.RelayMessage) {
+	if msg.User == nil || msg.User.ID != msg.UserID {
+		log.Printf("room %s: invalid relayed join from %s", msg.RoomID, msg.From)
+		return
+	}
+	conn := newRelayConn(m.router, msg)
+	// 続くメッセージを取りこぼさないよう、ハンドラの中で登録してから参加処理を始める
+	m.relayMu.Lock()
+	m.remoteConns[msg.ConnID] = conn
+	m.relayMu.Unlock()
+
+	go func() {
+		defer func() {
+			m.relayMu.Lock()
+			delete(m.remoteConns, msg.ConnID)
+			m.relayMu.Unlock()
+			conn.close(true)
+		}()
+		log.Printf("room %s: player %s connected via %s", msg.RoomID, msg.User.GitHubLogin, msg.From)
+		m.serve(context.Background(), msg.RoomID, conn, msg.User)
+		log.Printf("room %s: player %s disconnected from %s", msg.RoomID, msg.User.GitHubLogin, msg.From)
+	}()
+}
+
+// handleRelaySpectate は他のインスタンスで接続した観戦者を担当ルームにつなぐ
+func (m *RoomManager) handleRelaySpectate(msg entity.RelayMessage) {
+	conn := newRelayConn(m.router, msg)
+	// 観戦者のメッセージは中継されないが、leave で接続を閉じられるよう登録する
+	m.relayMu.Lock()
+	m.remoteConns[msg.ConnID] = conn
+	m.relayMu.Unlock()
+
+	go func() {
+		defer func() {
+			m.relayMu.Lock()
+			delete(m.remoteConns, msg.ConnID)
+			m.relayMu.Unlock()
+			conn.close(true)
+		}()
+		room := m.Get(msg.RoomID)
+		if room == nil {
+			sendSpectateError(conn, errRoomClosed)
+			return
+		}
+		log.Printf("room %s: spectator connected via %s", msg.RoomID, msg.From)
+		room.spectate(conn)
+		log.Printf("room %s: spectator disconnected from %s", msg.RoomID, msg.From)
+	}()
+}
+
+func (m *RoomManager) remoteConn(connID string) *relayConn {
+	m.relayMu.Lock()
+	defer m.relayMu.Unlock()
+	return m.remoteConns[connID]
+}
+
+func (m *RoomManager) relayedConn(connID string) *relayedConn {
+	m.relayMu.Lock()
+	defer m.relayMu.Unlock()
+	return m.relayedConns[connID]
+}
+
+func (m *RoomManager) handleRelayPlayerMessage(msg entity.RelayMessage) {
+	if conn := m.remoteConn(msg.ConnID); conn != nil {
+		conn.deliver(msg.Data)
+	}
+}
+
+func (m *RoomManager) handleRelayLeave(msg entity.RelayMessage) {
+	if conn := m.remoteConn(msg.ConnID); conn != nil {
+		conn.close(false)
+	}
+}
+
+// handleRelaySend は担当から届いたメッセージを送信キューに積む
+// ルーターの購読 goroutine を遅い接続で止めないよう、その場では書き込まない
+func (m *RoomManager) handleRelaySend(msg entity.RelayMessage) {
+	if rc := m.relayedConn(msg.ConnID); rc != nil {
+		rc.enqueue(msg.Data)
+	}
+}
+
+// handleRelayClose は積んだメッセージを送り切ってから接続を閉じさせる
+func (m *RoomManager) handleRelayClose(msg entity.RelayMessage) {
+	if rc := m.relayedConn(msg.ConnID); rc != nil {
+		rc.closeAfterFlush()
+	}
+}
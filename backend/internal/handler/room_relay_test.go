@@ -0,0 +1,357 @@
+package handler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/infrastructure/llm"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/testutil"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/usecase"
+)
+
+// memoryRouting はメモリ上で動く RoutingRepository。同じ値を共有するインスタンス間で中継できる
+type memoryRouting struct {
+	owners map[uuid.UUID]string
+	users  map[uuid.UUID]string
+	subs   map[string]chan entity.RelayMessage
+	mu     sync.Mutex
+}
+
+func newMemoryRouting() *memoryRouting {
+	return &memoryRouting{
+		owners: make(map[uuid.UUID]string),
+		users:  make(map[uuid.UUID]string),
+		subs:   make(map[string]chan entity.RelayMessage),
+	}
+}
+
+func (r *memoryRouting) ClaimOwner(_ context.Context, roomID uuid.UUID, instanceID string, _ time.Duration) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if owner, ok := r.owners[roomID]; ok {
+		return owner, nil
+	}
+	r.owners[roomID] = instanceID
+	return instanceID, nil
+}
+
+func (r *memoryRouting) GetOwner(_ context.Context, roomID uuid.UUID) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.owners[roomID], nil
+}
+
+func (r *memoryRouting) RefreshOwner(context.Context, uuid.UUID, string, time.Duration) error {
+	return nil
+}
+
+func (r *memoryRouting) ReleaseOwner(_ context.Context, roomID uuid.UUID, instanceID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.owners[roomID] == instanceID {
+		delete(r.owners, roomID)
+	}
+	return nil
+}
+
+func (r *memoryRouting) SetUserInstance(_ context.Context, userID uuid.UUID, instanceID string, _ time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[userID] = instanceID
+	return nil
+}
+
+func (r *memoryRouting) GetUserInstance(_ context.Context, userID uuid.UUID) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.users[userID], nil
+}
+
+func (r *memoryRouting) ClearUserInstance(_ context.Context, userID uuid.UUID, instanceID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.users[userID] == instanceID {
+		delete(r.users, userID)
+	}
+	return nil
+}
+
+func (r *memoryRouting) Publish(_ context.Context, instanceID string, msg entity.RelayMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Redis と同じく、購読者がいなければメッセージは捨てられる
+	if ch, ok := r.subs[instanceID]; ok {
+		ch <- msg
+	}
+	return nil
+}
+
+func (r *memoryRouting) Subscribe(ctx context.Context, instanceID string) (<-chan entity.RelayMessage, error) {
+	ch := make(chan entity.RelayMessage, 256)
+	r.mu.Lock()
+	r.subs[instanceID] = ch
+	r.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.subs, instanceID)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// startRouter はテスト終了まで router の購読を続ける
+// ハンドラは NewRoomManager / NewHub で登録されるため、それらの後に呼ぶこと
+func startRouter(t *testing.T, router *InstanceRouter) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	require.NoError(t, router.Start(ctx))
+}
+
+func TestRoomManager_Connect_RelaysAcrossInstances(t *testing.T) {
+	p1 := &entity.User{ID: uuid.New(), GitHubLogin: "alice", GnuBalance: 100}
+	p2 := &entity.User{ID: uuid.New(), GitHubLogin: "bob", GnuBalance: 100}
+	room := &entity.Room{ID: uuid.New(), Player1ID: p1.ID, Player2ID: p2.ID, Status: entity.RoomStatusWaiting}
+	roomRepo := &testutil.MockRoomRepository{
+		GetByIDFunc: func(context.Context, uuid.UUID) (*entity.Room, error) {
+			return room, nil
+		},
+	}
+	fileRepo := &testutil.MockRepositoryFileRepository{
+		ListLatestByOwnerFunc: func(_ context.Context, owner string) ([]entity.RepositoryFile, error) {
+			return []entity.RepositoryFile{{FilePath: owner + ".go", Content: "package " + owner}}, nil
+		},
+	}
+	questionUC := usecase.NewQuestionUsecase(llm.NewFakeGenerator(), fileRepo)
+
+	routing := newMemoryRouting()
+	routerA := NewInstanceRouter(routing, "instance-a")
+	routerB := NewInstanceRouter(routing, "instance-b")
+	managerA := NewRoomManager(nil, roomRepo, nil, nil, nil, nil, nil, nil, questionUC, entity.DefaultMatchRules(), 0, routerA)
+	managerB := NewRoomManager(nil, roomRepo, nil, nil, nil, nil, nil, nil, questionUC, entity.DefaultMatchRules(), 0, routerB)
+	startRouter(t, routerA)
+	startRouter(t, routerB)
+
+	// alice はインスタンス A、bob はインスタンス B に接続する
+	server1, client1 := newTestConn(t)
+	go managerA.Connect(context.Background(), room.ID, server1, p1)
+	require.Eventually(t, func() bool { return managerA.Get(room.ID) != nil }, time.Second, 10*time.Millisecond)
+	server2, client2 := newTestConn(t)
+	go managerB.Connect(context.Background(), room.ID, server2, p2)
+
+	// ゲームは担当の A だけで動き、bob には B 経由でイベントが届く
+	readUntil(t, client1, "ev_room_ready")
+	readUntil(t, client2, "ev_room_ready")
+	assert.Nil(t, managerB.Get(room.ID), "relaying instance must not host the room")
+	owner, err := routing.GetOwner(context.Background(), room.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "instance-a", owner)
+
+	readUntil(t, client2, "ev_turn_start")
+
+	// bob の操作は B から A に中継される
+	require.NoError(t, client2.WriteJSON(WSMessage{Type: "act_bet_gnu", Payload: map[string]any{"amount": 10}}))
+	msg := readUntil(t, client2, "ev_bet_confirmed")
+	assert.EqualValues(t, 10, msg.Payload.(map[string]any)["amount"])
+}
+
+func TestRoomManager_RelayPlayer_OwnerRejectsJoin(t *testing.T) {
+	p1 := &entity.User{ID: uuid.New(), GitHubLogin: "alice"}
+	p2 := &entity.User{ID: uuid.New(), GitHubLogin: "bob"}
+	room := &entity.Room{ID: uuid.New(), Player1ID: p1.ID, Player2ID: p2.ID, Status: entity.RoomStatusWaiting}
+	roomRepo := &testutil.MockRoomRepository{
+		GetByIDFunc: func(context.Context, uuid.UUID) (*entity.Room, error) {
+			return room, nil
+		},
+	}
+	routing := newMemoryRouting()
+	routerA := NewInstanceRouter(routing, "instance-a")
+	routerB := NewInstanceRouter(routing, "instance-b")
+	managerA := NewRoomManager(nil, roomRepo, nil, nil, nil, nil, nil, nil, nil, entity.DefaultMatchRules(), 0, routerA)
+	managerB := NewRoomManager(nil, roomRepo, nil, nil, nil, nil, nil, nil, nil, entity.DefaultMatchRules(), 0, routerB)
+	startRouter(t, routerA)
+	startRouter(t, routerB)
+
+	// 中継された参加も担当インスタンスで参加資格を検証する
+	outsider := &entity.User{ID: uuid.New(), GitHubLogin: "mallory"}
+	server, client := newTestConn(t)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		managerB.relayPlayer("instance-a", room.ID, server, outsider)
+	}()
+
+	msg := readUntil(t, client, "ev_error")
+	assert.Equal(t, "not_room_member", msg.Payload.(map[string]any)["code"])
+	assert.Nil(t, managerA.Get(room.ID), "rejected join must not create a room")
+
+	// 担当からの close で中継側の接続も閉じられる
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("relayed connection was not closed")
+	}
+}
+
+func TestRoomManager_Spectate_RelaysAcrossInstances(t *testing.T) {
+	p1 := &entity.User{ID: uuid.New(), GitHubLogin: "alice"}
+	p2 := &entity.User{ID: uuid.New(), GitHubLogin: "bob"}
+	room := &entity.Room{ID: uuid.New(), Player1ID: p1.ID, Player2ID: p2.ID, Status: entity.RoomStatusWaiting}
+	roomRepo := &testutil.MockRoomRepository{
+		GetByIDFunc: func(context.Context, uuid.UUID) (*entity.Room, error) {
+			return room, nil
+		},
+	}
+	routing := newMemoryRouting()
+	routerA := NewInstanceRouter(routing, "instance-a")
+	routerB := NewInstanceRouter(routing, "instance-b")
+	managerA := NewRoomManager(nil, roomRepo, nil, nil, nil, nil, nil, nil, nil, entity.DefaultMatchRules(), 1, routerA)
+	managerB := NewRoomManager(nil, roomRepo, nil, nil, nil, nil, nil, nil, nil, entity.DefaultMatchRules(), 1, routerB)
+	startRouter(t, routerA)
+	startRouter(t, routerB)
+
+	server1, _ := newTestConn(t)
+	go managerA.Connect(context.Background(), room.ID, server1, p1)
+	require.Eventually(t, func() bool { return managerA.Get(room.ID) != nil }, time.Second, 10*time.Millisecond)
+
+	// 担当でない B に来た観戦者は A のルームを観戦する
+	owner, err := managerB.spectateOwner(context.Background(), room.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "instance-a", owner)
+	server2, client2 := newTestConn(t)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		managerB.Spectate(room.ID, owner, server2)
+	}()
+
+	msg := readUntil(t, client2, "ev_spectate_ready")
+	players := msg.Payload.(map[string]any)["players"].([]any)
+	require.Len(t, players, 1)
+	assert.Equal(t, "alice", players[0].(map[string]any)["github_login"])
+
+	// 観戦者の切断は担当に伝わり、枠が空く
+	require.NoError(t, client2.Close())
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("relayed spectator did not finish")
+	}
+	hosted := managerA.Get(room.ID)
+	require.Eventually(t, func() bool {
+		hosted.specMu.Lock()
+		defer hosted.specMu.Unlock()
+		return len(hosted.spectators) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	// どのインスタンスも担当していないルームは観戦できない
+	_, err = managerB.spectateOwner(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, ErrRoomNotFound)
+}
+
+func TestHub_SendToUser_RelaysToOtherInstance(t *testing.T) {
+	routing := newMemoryRouting()
+	routerA := NewInstanceRouter(routing, "instance-a")
+	routerB := NewInstanceRouter(routing, "instance-b")
+	hubA := NewHub(nil, routerA, time.Minute, false)
+	hubB := NewHub(nil, routerB, time.Minute, false)
+	startRouter(t, routerA)
+	startRouter(t, routerB)
+
+	// マッチを成立させた A には接続がなく、B が接続を持っている
+	userID := uuid.New()
+	server, client := newTestConn(t)
+	_, err := hubB.Register(userID, server)
+	require.NoError(t, err)
+
+	hubA.SendToUser(userID, WSMessage{Type: "ev_match_found", Payload: map[string]any{"room_id": "r1"}})
+
+	msg := readUntil(t, client, "ev_match_found")
+	assert.Equal(t, "r1", msg.Payload.(map[string]any)["room_id"])
+}
+
+// stalledConn は書き込みが unblock まで返らない接続。読み取りの打ち切りを interrupted で知らせる
+type stalledConn struct {
+	unblock     chan struct{}
+	interrupted chan struct{}
+	once        sync.Once
+}
+
+func newStalledConn() *stalledConn {
+	return &stalledConn{unblock: make(chan struct{}), interrupted: make(chan struct{})}
+}
+
+func (c *stalledConn) ReadMessage() (int, []byte, error) {
+	<-c.interrupted
+	return 0, nil, errRelayClosed
+}
+
+func (c *stalledConn) WriteMessage(int, []byte) error {
+	<-c.unblock
+	return nil
+}
+
+func (c *stalledConn) SetReadDeadline(time.Time) error {
+	c.once.Do(func() { close(c.interrupted) })
+	return nil
+}
+
+func (c *stalledConn) SetWriteDeadline(time.Time) error { return nil }
+
+func TestRoomManager_HandleRelaySend_DoesNotBlockOnSlowConn(t *testing.T) {
+	m := NewRoomManager(nil, nil, nil, nil, nil, nil, nil, nil, nil, entity.DefaultMatchRules(), 0, NewInstanceRouter(newMemoryRouting(), "instance-a"))
+	conn := newStalledConn()
+	defer close(conn.unblock)
+	connID, _, untrack := m.trackRelayed(conn)
+	defer untrack()
+
+	// 書き込みが詰まっても購読 goroutine（ここではテスト）は止まらず、溢れた接続は閉じられる
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		for range relaySendBuffer + 2 {
+			m.handleRelaySend(entity.RelayMessage{ConnID: connID, Data: []byte(`{}`)})
+		}
+	}()
+	select {
+	case <-sent:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handleRelaySend blocked on a slow connection")
+	}
+	select {
+	case <-conn.interrupted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("overflowing connection was not closed")
+	}
+}
+
+func TestRelayConn_DeliverDoesNotBlockWhenInboxIsFull(t *testing.T) {
+	conn := newRelayConn(NewInstanceRouter(newMemoryRouting(), "instance-a"), entity.RelayMessage{ConnID: "conn"})
+
+	// 読み取られない接続への配送は止まらず、中継を終了する
+	delivered := make(chan struct{})
+	go func() {
+		defer close(delivered)
+		for range relayInboxSize + 1 {
+			conn.deliver([]byte(`{}`))
+		}
+	}()
+	select {
+	case <-delivered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("deliver blocked on a full inbox")
+	}
+	_, _, err := conn.ReadMessage()
+	for err == nil {
+		_, _, err = conn.ReadMessage()
+	}
+	assert.ErrorIs(t, err, errRelayClosed)
+}
@@ -9,9 +9,15 @@ import (
 )
 
 func NewRouter(
+	auth *GitHubAuthenticator,
 	userHandler *UserHandler,
 	matchmakeHandler *MatchmakeHandler,
 	roomHandler *RoomHandler,
+	replayHandler *ReplayHandler,
+	inviteHandler *RoomInviteHandler,
+	tournamentHandler *TournamentHandler,
+	battleRoyaleHandler *BattleRoyaleHandler,
+	leaderboardHandler *LeaderboardHandler,
 	devHandler *DevHandler,
 ) *echo.Echo {
 	e := echo.New()
@@ -31,12 +37,33 @@ func NewRouter(
 
 	// REST API
 	api := e.Group("/api/v1")
-	api.GET("/users/me", userHandler.GetMe, GitHubAuthMiddleware)
+	api.GET("/users/me", userHandler.GetMe, auth.Middleware)
+	api.GET("/users/:login/stats", userHandler.GetStats, auth.Middleware)
+	api.POST("/ws-tickets", auth.IssueWSTicket, auth.Middleware)
+	api.GET("/matchmaking/metrics", matchmakeHandler.GetMetrics)
+	api.GET("/rooms/:id/replay", replayHandler.GetReplay, auth.Middleware)
+	api.POST("/invites", inviteHandler.CreateInvite, auth.Middleware)
+	api.GET("/invites/:code", inviteHandler.GetInvite, auth.Middleware)
+	api.POST("/invites/:code/redeem", inviteHandler.RedeemInvite, auth.Middleware)
+	api.DELETE("/invites/:code", inviteHandler.CancelInvite, auth.Middleware)
+	api.POST("/tournaments", tournamentHandler.CreateTournament, auth.Middleware)
+	api.GET("/tournaments", tournamentHandler.ListTournaments)
+	api.GET("/tournaments/:id", tournamentHandler.GetTournament)
+	api.POST("/tournaments/:id/participants", tournamentHandler.RegisterParticipant, auth.Middleware)
+	api.POST("/tournaments/:id/start", tournamentHandler.StartTournament, auth.Middleware)
+	api.POST("/battle-royales", battleRoyaleHandler.CreateBattleRoyale, auth.Middleware)
+	api.GET("/battle-royales/:id", battleRoyaleHandler.GetBattleRoyale)
+	api.POST("/battle-royales/:id/start", battleRoyaleHandler.StartBattleRoyale, auth.Middleware)
+	api.GET("/leaderboards/:kind", leaderboardHandler.GetTop)
+	api.GET("/leaderboards/:kind/me", leaderboardHandler.GetMine, auth.Middleware)
 
 	// WebSocket
 	ws := e.Group("/ws")
-	ws.GET("/matchmake", matchmakeHandler.HandleMatchmake)
-	ws.GET("/room/:room_id", roomHandler.HandleRoom)
+	ws.GET("/matchmake", matchmakeHandler.HandleMatchmake, auth.WSMiddleware)
+	ws.GET("/room/:room_id", roomHandler.HandleRoom, auth.WSMiddleware)
+	ws.GET("/room/:room_id/spectate", roomHandler.HandleSpectate, auth.WSMiddleware)
+	ws.GET("/tournament/:tournament_id", tournamentHandler.HandleTournament)
+	ws.GET("/battle-royale/:battle_royale_id", battleRoyaleHandler.HandleBattleRoyale, auth.WSMiddleware)
 
 	// Dev API (development only)
 	if os.Getenv("ENV") == "development" && devHandler != nil {
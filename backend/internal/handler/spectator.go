@@ -0,0 +1,304 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"maps"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/usecase"
+)
+
+const (
+	spectatorSendBuffer = 16               // 観戦者ごとの送信キュー長
+	spectatorWriteWait  = 10 * time.Second // 観戦者への1メッセージあたりの書き込み期限
+)
+
+// ErrSpectatorLimit は観戦者数が上限に達していることを示す
+var ErrSpectatorLimit = errors.New("spectator limit reached")
+
+// spectator は読み取り専用の観戦者接続
+// ゲームループを遅い接続で止めないよう、送信はキュー経由で serveSpectator が行う
+type spectator struct {
+	conn   playerConn
+	sendCh chan WSMessage
+}
+
+// spectate は観戦者をルームにつなぎ、観戦者の切断またはルームの終了までブロックする
+// 観戦者から受信したメッセージは破棄する
+func (r *GameRoom) spectate(conn playerConn) {
+	s, err := r.addSpectator(conn)
+	if err != nil {
+		sendSpectateError(conn, err)
+		return
+	}
+	defer r.removeSpectator(s)
+
+	s.write(r.spectateReady())
+
+	// 切断検知のためだけに読み取る
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	r.serveSpectator(s, readerDone)
+}
+
+// sendSpectateError は観戦の開始の失敗を ev_error で通知する
+func sendSpectateError(conn playerConn, err error) {
+	code := "spectate_failed"
+	message := "観戦を開始できませんでした"
+	switch {
+	case errors.Is(err, ErrSpectatorLimit):
+		code = "spectator_limit"
+		message = "観戦者数が上限に達しています"
+	case errors.Is(err, errRoomClosed):
+		code = "room_closed"
+		message = "試合は終了しました"
+	}
+	sendWSMessage(conn, WSMessage{
+		Type: "ev_error",
+		Payload: map[string]any{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
+
+// addSpectator は観戦者を登録する
+func (r *GameRoom) addSpectator(conn playerConn) (*spectator, error) {
+	select {
+	case <-r.closedCh:
+		return nil, errRoomClosed
+	default:
+	}
+
+	r.specMu.Lock()
+	defer r.specMu.Unlock()
+	if len(r.spectators) >= r.maxSpectators {
+		return nil, ErrSpectatorLimit
+	}
+	s := &spectator{
+		conn:   conn,
+		sendCh: make(chan WSMessage, spectatorSendBuffer),
+	}
+	// ターンの途中から観戦を始めた場合も、次のターンを待たずに進行中のターンを表示できるようにする
+	if r.specTurn != nil {
+		s.sendCh <- r.spectatorTurnSnapshot()
+	}
+	r.spectators[s] = struct{}{}
+	return s, nil
+}
+
+// spectatorTurnSnapshot は記録した進行中のターンの ev_turn_start を、残り時間に合わせて返す
+// specMu を保持して呼ぶこと
+func (r *GameRoom) spectatorTurnSnapshot() WSMessage {
+	remaining := max(time.Until(r.specTurnEnd), 0)
+	payload := maps.Clone(r.specTurn.Payload.(map[string]any))
+	payload["time_limit_sec"] = int((remaining + time.Second - 1) / time.Second)
+	return WSMessage{Type: r.specTurn.Type, Payload: payload}
+}
+
+// removeSpectator は観戦者の登録を解除する
+func (r *GameRoom) removeSpectator(s *spectator) {
+	r.specMu.Lock()
+	defer r.specMu.Unlock()
+	delete(r.spectators, s)
+}
+
+// broadcastSpectatorTurnStart は観戦者に ev_turn_start を送り、途中から観戦を始めた観戦者に送れるよう記録する
+func (r *GameRoom) broadcastSpectatorTurnStart(ts *turnState) {
+	msg := r.spectatorTurnStart(ts)
+	r.specMu.Lock()
+	defer r.specMu.Unlock()
+	r.specTurn = &msg
+	r.specTurnEnd = ts.deadline
+	r.queueSpectatorsLocked(msg)
+}
+
+// broadcastSpectators は全観戦者の送信キューにメッセージを積む
+// ev_turn_start 以外の通知（ターンの結果・試合の終了）でターンは終わるため、記録した進行中のターンを消す
+func (r *GameRoom) broadcastSpectators(msg WSMessage) {
+	r.specMu.Lock()
+	defer r.specMu.Unlock()
+	r.specTurn = nil
+	r.queueSpectatorsLocked(msg)
+}
+
+// queueSpectatorsLocked は全観戦者の送信キューにメッセージを積む。specMu を保持して呼ぶこと
+// キューが詰まっている観戦者にはメッセージを送らない（ゲームループをブロックしない）
+func (r *GameRoom) queueSpectatorsLocked(msg WSMessage) {
+	for s := range r.spectators {
+		select {
+		case s.sendCh <- msg:
+		default:
+			log.Printf("game room %s: spectator queue full, dropping %s", r.id, msg.Type)
+		}
+	}
+}
+
+// serveSpectator は観戦者の送信キューを WebSocket に書き出す
+// 観戦者の切断（readerDone）またはルーム終了まで戻らない
+func (r *GameRoom) serveSpectator(s *spectator, readerDone <-chan struct{}) {
+	for {
+		select {
+		case msg := <-s.sendCh:
+			s.write(msg)
+		case <-readerDone:
+			return
+		case <-r.closedCh:
+			// ev_game_end などの残りを送り切ってから抜ける
+			for {
+				select {
+				case msg := <-s.sendCh:
+					s.write(msg)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *spectator) write(msg WSMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("spectate: marshal error: %v", err)
+		return
+	}
+	if err := s.conn.SetWriteDeadline(time.Now().Add(spectatorWriteWait)); err != nil {
+		log.Printf("spectate: set write deadline error: %v", err)
+	}
+	if err := s.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		log.Printf("spectate: write error: %v", err)
+	}
+}
+
+// spectateReady は観戦開始時に送る ev_spectate_ready を組み立てる
+// 参加前のプレイヤーは含めない
+func (r *GameRoom) spectateReady() WSMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	players := make([]map[string]any, 0, len(r.players))
+	for i, p := range r.players {
+		if p == nil {
+			continue
+		}
+		players = append(players, map[string]any{
+			"index":        i,
+			"team":         p.team,
+			"id":           p.user.ID.String(),
+			"github_login": p.user.GitHubLogin,
+			"rate":         p.user.Rate,
+		})
+	}
+	return WSMessage{
+		Type: "ev_spectate_ready",
+		Payload: map[string]any{
+			"room_id": r.id.String(),
+			"players": players,
+		},
+	}
+}
+
+// spectatorTurnStart は観戦者向けの ev_turn_start を組み立てる
+// 正解はターン終了まで伏せる
+func (r *GameRoom) spectatorTurnStart(ts *turnState) WSMessage {
+	players := make([]map[string]any, len(r.players))
+	for i, p := range r.players {
+		q := ts.questions[i]
+		players[i] = map[string]any{
+			"github_login":  p.user.GitHubLogin,
+			"team":          p.team,
+			"gnu_balance":   p.gnuBalance,
+			"question_type": q.Kind(),
+			"difficulty":    q.Difficulty,
+			"question_text": q.QuestionText,
+			"choices":       q.Choices,
+		}
+	}
+	return WSMessage{
+		Type: "ev_turn_start",
+		Payload: map[string]any{
+			"turn":           ts.turn,
+			"total_turns":    r.rules.TotalTurns,
+			"time_limit_sec": int(r.rules.TurnDuration / time.Second),
+			"players":        players,
+		},
+	}
+}
+
+// spectatorTurnResult は観戦者向けの ev_turn_result を組み立てる
+func (r *GameRoom) spectatorTurnResult(ts *turnState, corrects []bool, gnuDeltas []int) WSMessage {
+	players := make([]map[string]any, len(r.players))
+	for i, p := range r.players {
+		q := ts.questions[i]
+		players[i] = map[string]any{
+			"github_login":    p.user.GitHubLogin,
+			"team":            p.team,
+			"answer":          ts.answerIndex(i),
+			"answer_detail":   ts.answerDetail(i),
+			"question_type":   q.Kind(),
+			"correct_answer":  q.CorrectAnswer,
+			"correct_answers": q.CorrectAnswers,
+			"correct_index":   q.CorrectIndex(),
+			"correct_indexes": q.CorrectIndexes(),
+			"is_correct":      corrects[i],
+			"bet":             ts.bets[i],
+			"gnu_delta":       gnuDeltas[i],
+			"gnu_balance":     p.gnuBalance,
+		}
+	}
+	return WSMessage{
+		Type: "ev_turn_result",
+		Payload: map[string]any{
+			"turn":    ts.turn,
+			"players": players,
+		},
+	}
+}
+
+// spectatorGameEnd は観戦者向けの ev_game_end を組み立てる
+// winnerTeam が -1 の場合は引き分け。winner_index は1対1の勝者のインデックス（チーム戦と引き分けでは -1）
+func (r *GameRoom) spectatorGameEnd(
+	reason entity.MatchEndReason,
+	winnerTeam int,
+	rateChanges []usecase.RatingChange,
+) WSMessage {
+	winnerIdx := -1
+	players := make([]map[string]any, len(r.players))
+	for i, p := range r.players {
+		if !r.isTeamRoom() && p.team == winnerTeam {
+			winnerIdx = i
+		}
+		players[i] = map[string]any{
+			"github_login":  p.user.GitHubLogin,
+			"team":          p.team,
+			"correct_count": r.correctCounts[i],
+			"correct_score": correctScore(r.correctPoints[i]),
+			"final_gnu":     p.gnuBalance,
+			"rate_before":   rateChanges[i].Before,
+			"rate_after":    rateChanges[i].After,
+			"rate_delta":    rateChanges[i].Delta,
+		}
+	}
+	return WSMessage{
+		Type: "ev_game_end",
+		Payload: map[string]any{
+			"end_reason":   reason,
+			"winner_index": winnerIdx,
+			"winner_team":  winnerTeam,
+			"total_turns":  len(r.turnRecords) / len(r.players),
+			"players":      players,
+		},
+	}
+}
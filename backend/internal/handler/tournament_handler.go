@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/usecase"
+)
+
+const (
+	defaultTournamentLimit = 20
+	maxTournamentLimit     = 100
+)
+
+// createTournamentRequest は POST /api/v1/tournaments のリクエストボディ
+type createTournamentRequest struct {
+	Name string `json:"name"`
+}
+
+type TournamentHandler struct {
+	tournamentUsecase *usecase.TournamentUsecase
+	userRepo          repository.UserRepository
+}
+
+func NewTournamentHandler(uc *usecase.TournamentUsecase, userRepo repository.UserRepository) *TournamentHandler {
+	return &TournamentHandler{tournamentUsecase: uc, userRepo: userRepo}
+}
+
+// CreateTournament は POST /api/v1/tournaments を処理する
+// ログインユーザーが主催するトーナメントを作成する。作成者も参加する場合は別途参加登録する
+func (h *TournamentHandler) CreateTournament(c echo.Context) error {
+	identity, ok := authenticatedIdentity(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+	}
+	var req createTournamentRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	ctx := c.Request().Context()
+	user, err := getOrCreateUser(ctx, h.userRepo, identity)
+	if err != nil {
+		log.Printf("tournament: failed to get or create user %s: %v", identity.Login, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+
+	tournament, err := h.tournamentUsecase.Create(ctx, user.ID, req.Name)
+	if err != nil {
+		return h.tournamentError(c, err)
+	}
+	return c.JSON(http.StatusCreated, tournament)
+}
+
+// ListTournaments は GET /api/v1/tournaments を処理する
+// 新しい順に ?limit 件（既定 20、最大 100）を返す
+func (h *TournamentHandler) ListTournaments(c echo.Context) error {
+	limit, ok := queryInt(c, "limit", defaultTournamentLimit, maxTournamentLimit)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "limit must be a positive integer"})
+	}
+	tournaments, err := h.tournamentUsecase.List(c.Request().Context(), limit)
+	if err != nil {
+		log.Printf("tournament: failed to list tournaments: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"tournaments": tournaments})
+}
+
+// GetTournament は GET /api/v1/tournaments/:id を処理する
+// トーナメントと参加者・全試合（トーナメント表）を返す
+func (h *TournamentHandler) GetTournament(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid tournament id"})
+	}
+	bracket, err := h.tournamentUsecase.Get(c.Request().Context(), id)
+	if err != nil {
+		return h.tournamentError(c, err)
+	}
+	return c.JSON(http.StatusOK, bracket)
+}
+
+// RegisterParticipant は POST /api/v1/tournaments/:id/participants を処理する
+// ログインユーザーを参加登録し、更新後のトーナメント表を返す
+func (h *TournamentHandler) RegisterParticipant(c echo.Context) error {
+	identity, ok := authenticatedIdentity(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid tournament id"})
+	}
+	ctx := c.Request().Context()
+	user, err := getOrCreateUser(ctx, h.userRepo, identity)
+	if err != nil {
+		log.Printf("tournament: failed to get or create user %s: %v", identity.Login, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+
+	bracket, err := h.tournamentUsecase.Register(ctx, id, user)
+	if err != nil {
+		return h.tournamentError(c, err)
+	}
+	return c.JSON(http.StatusOK, bracket)
+}
+
+// StartTournament は POST /api/v1/tournaments/:id/start を処理する
+// 参加者をシードして1回戦のルームを作成する。開始できるのは作成者のみ
+func (h *TournamentHandler) StartTournament(c echo.Context) error {
+	identity, ok := authenticatedIdentity(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid tournament id"})
+	}
+	ctx := c.Request().Context()
+	user, err := getOrCreateUser(ctx, h.userRepo, identity)
+	if err != nil {
+		log.Printf("tournament: failed to get or create user %s: %v", identity.Login, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+
+	bracket, err := h.tournamentUsecase.Start(ctx, id, user.ID)
+	if err != nil {
+		return h.tournamentError(c, err)
+	}
+	return c.JSON(http.StatusOK, bracket)
+}
+
+// HandleTournament は ws://{host}/ws/tournament/:tournament_id を処理する
+// 接続時と更新のたびに ev_tournament_bracket でトーナメント表全体を送る
+// クライアントから受信したメッセージは破棄する
+func (h *TournamentHandler) HandleTournament(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("tournament_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid tournament_id")
+	}
+	ctx := c.Request().Context()
+	if _, err := h.tournamentUsecase.Get(ctx, id); err != nil {
+		if errors.Is(err, usecase.ErrTournamentNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "tournament not found")
+		}
+		log.Printf("tournament %s: failed to get bracket: %v", id, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal server error")
+	}
+
+	ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := ws.Close(); closeErr != nil {
+			log.Printf("tournament %s: ws close error: %v", id, closeErr)
+		}
+	}()
+
+	// 切断検知のためだけに読み取る
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// 購読を始めてから最初のトーナメント表を読むことで、その間の更新を取りこぼさない
+	updates, err := h.tournamentUsecase.Watch(watchCtx, id)
+	if err != nil {
+		log.Printf("tournament %s: failed to watch updates: %v", id, err)
+		return nil
+	}
+	for {
+		bracket, err := h.tournamentUsecase.Get(watchCtx, id)
+		if err != nil {
+			if watchCtx.Err() == nil {
+				log.Printf("tournament %s: failed to get bracket: %v", id, err)
+			}
+			return nil
+		}
+		sendWSMessage(ws, WSMessage{Type: "ev_tournament_bracket", Payload: bracket})
+		if _, ok := <-updates; !ok {
+			return nil
+		}
+	}
+}
+
+// tournamentError はトーナメントの操作の失敗をレスポンスに変換する
+func (h *TournamentHandler) tournamentError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, usecase.ErrTournamentNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "tournament not found"})
+	case errors.Is(err, usecase.ErrTournamentForbidden):
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "not allowed to manage the tournament"})
+	case errors.Is(err, usecase.ErrTournamentNotOpen):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "tournament is not open for registration"})
+	case errors.Is(err, usecase.ErrTournamentFull):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "tournament is full"})
+	case errors.Is(err, usecase.ErrTournamentAlreadyRegistered):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "already registered to the tournament"})
+	case errors.Is(err, usecase.ErrTournamentTooFew):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "tournament needs at least 2 participants"})
+	case errors.Is(err, usecase.ErrTournamentInvalidName):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "name must be 1 to 100 characters"})
+	}
+	log.Printf("tournament: failed to handle tournament %q: %v", c.Param("id"), err)
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+}
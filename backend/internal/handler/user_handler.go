@@ -1,18 +1,27 @@
 package handler
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
 	"github.com/tobakuro/hackathon_nulabcup/backend/internal/usecase"
 )
 
 type UserHandler struct {
-	userUsecase *usecase.UserUsecase
+	userUsecase  *usecase.UserUsecase
+	statsUsecase *usecase.UserStatsUsecase
 }
 
-func NewUserHandler(uc *usecase.UserUsecase) *UserHandler {
-	return &UserHandler{userUsecase: uc}
+func NewUserHandler(uc *usecase.UserUsecase, statsUC *usecase.UserStatsUsecase) *UserHandler {
+	return &UserHandler{userUsecase: uc, statsUsecase: statsUC}
 }
 
 // GetMe は現在のログインユーザー情報を返す
@@ -30,3 +39,46 @@ func (h *UserHandler) GetMe(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, user)
 }
+
+// GetStats は GET /api/v1/users/:login/stats を処理する
+// 試合の記録から集計した通算成績を返す（集計結果は試合の終了まで Redis にキャッシュする）
+func (h *UserHandler) GetStats(c echo.Context) error {
+	stats, err := h.statsUsecase.GetByLogin(c.Request().Context(), c.Param("login"))
+	if err != nil {
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "user not found"})
+		}
+		log.Printf("user: failed to get stats of %s: %v", c.Param("login"), err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+	return c.JSON(http.StatusOK, stats)
+}
+
+// getOrCreateUser は認証済みの GitHub ユーザーに対応するユーザーを取得し、存在しなければ作成して返す
+// login は変更されうるため、検索には GitHub ID を使う
+func getOrCreateUser(ctx context.Context, userRepo repository.UserRepository, identity entity.GitHubIdentity) (*entity.User, error) {
+	user, err := userRepo.GetByGitHubID(ctx, identity.ID)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	user = &entity.User{
+		GitHubID:    identity.ID,
+		GitHubLogin: identity.Login,
+	}
+	if createErr := userRepo.Create(ctx, user); createErr != nil {
+		// UNIQUE 制約違反 (23505) は同時リクエストによる競合。既存レコードを取得して返す
+		var pqErr *pq.Error
+		if errors.As(createErr, &pqErr) && pqErr.Code == "23505" {
+			existing, getErr := userRepo.GetByGitHubID(ctx, identity.ID)
+			if getErr == nil {
+				return existing, nil
+			}
+		}
+		return nil, fmt.Errorf("failed to create user: %w", createErr)
+	}
+	log.Printf("user: auto-created user %s (id=%s)", identity.Login, user.ID)
+	return user, nil
+}
@@ -1,9 +1,9 @@
 package handler
 
 import (
-	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -34,6 +34,8 @@ func allowedOrigins() []string {
 }
 
 var upgrader = websocket.Upgrader{
+	// トークンをサブプロトコルで送るクライアントには "bearer" を選択して応答する
+	Subprotocols: []string{wsAuthSubprotocol},
 	CheckOrigin: func(r *http.Request) bool {
 		origin := r.Header.Get("Origin")
 		for _, allowed := range allowedOrigins() {
@@ -55,37 +57,37 @@ func NewMatchmakeHandler(hub *Hub, userRepo repository.UserRepository) *Matchmak
 	return &MatchmakeHandler{hub: hub, userRepo: userRepo}
 }
 
+// GetMetrics は GET /api/v1/matchmaking/metrics を処理する
+// このインスタンスのマッチング遅延と Redis 呼び出し回数の累計を返す
+func (h *MatchmakeHandler) GetMetrics(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.hub.usecase.Metrics())
+}
+
+// HandleMatchmake は ws://{host}/ws/matchmake?team_size={n} を処理する
+// team_size は1チームの人数（省略時は 1 = 1対1、2 = 2対2）
+// 接続は WSMiddleware で認証済みのユーザーに紐づける
 func (h *MatchmakeHandler) HandleMatchmake(c echo.Context) error {
-	githubLogin := c.QueryParam("github_login")
-	if githubLogin == "" {
-		return echo.NewHTTPError(http.StatusBadRequest, "github_login is required")
+	identity, ok := authenticatedIdentity(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	githubLogin := identity.Login
+
+	teamSize := 1
+	if v := c.QueryParam("team_size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > entity.MaxTeamSize {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("team_size must be between 1 and %d", entity.MaxTeamSize))
+		}
+		teamSize = n
 	}
 
 	ctx := c.Request().Context()
 
-	// GitHub login からユーザーを検索（存在しなければ自動作成）
-	user, err := h.userRepo.GetByGitHubLogin(ctx, githubLogin)
+	user, err := getOrCreateUser(ctx, h.userRepo, identity)
 	if err != nil {
-		if !errors.Is(err, sql.ErrNoRows) {
-			log.Printf("matchmake: failed to get user %s: %v", githubLogin, err)
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get user")
-		}
-		log.Printf("matchmake: user %s not found, creating...", githubLogin)
-		githubIDStr := c.QueryParam("github_id")
-		githubID, parseErr := strconv.ParseInt(githubIDStr, 10, 64)
-		if parseErr != nil {
-			log.Printf("matchmake: invalid github_id %q: %v", githubIDStr, parseErr)
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid github_id")
-		}
-		user = &entity.User{
-			GitHubID:    githubID,
-			GitHubLogin: githubLogin,
-		}
-		if createErr := h.userRepo.Create(ctx, user); createErr != nil {
-			log.Printf("matchmake: failed to create user %s: %v", githubLogin, createErr)
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to create user")
-		}
-		log.Printf("matchmake: created user %s with id %s", githubLogin, user.ID)
+		log.Printf("matchmake: failed to get or create user %s: %v", githubLogin, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get user")
 	}
 
 	userID := user.ID
@@ -100,8 +102,13 @@ func (h *MatchmakeHandler) HandleMatchmake(c echo.Context) error {
 		}
 	}()
 
-	// JoinQueue を先に呼び出し、成功後に Register する
-	if err := h.hub.usecase.JoinQueue(ctx, userID); err != nil {
+	if h.hub.Draining() {
+		sendWSMessage(ws, serverDrainingMessage())
+		return nil
+	}
+
+	// 先に参加中として記録し、同じユーザーの二重参加を接続の登録より前に弾く
+	if err := h.hub.usecase.ReserveQueue(ctx, userID); err != nil {
 		if errors.Is(err, usecase.ErrAlreadyInQueue) {
 			sendWSMessage(ws, WSMessage{
 				Type: "ev_error",
@@ -112,29 +119,40 @@ func (h *MatchmakeHandler) HandleMatchmake(c echo.Context) error {
 			})
 		} else {
 			log.Printf("matchmake: join queue error for %s: %v", userID, err)
-			sendWSMessage(ws, WSMessage{
-				Type: "ev_error",
-				Payload: map[string]any{
-					"code":    "queue_error",
-					"message": "キューへの参加に失敗しました",
-				},
-			})
+			sendWSMessage(ws, queueErrorMessage())
 		}
 		return nil
 	}
 
-	h.hub.Register(userID, ws)
+	// キューに追加するとすぐにマッチが成立しうるため、ev_match_found の送り先を先に登録する
+	conn, err := h.hub.Register(userID, ws)
+	if err != nil {
+		// ReserveQueue の後に Drain が始まった場合は参加を取り消して終える
+		if err := h.hub.usecase.LeaveQueue(ctx, userID); err != nil {
+			log.Printf("matchmake: failed to leave queue for %s: %v", userID, err)
+		}
+		sendWSMessage(ws, serverDrainingMessage())
+		return nil
+	}
 	defer h.hub.Unregister(userID)
 
 	log.Printf("matchmake: user %s (%s) connected", githubLogin, userID)
 
-	sendWSMessage(ws, WSMessage{
+	// 以降の書き込みはマッチングループや Drain と重なるため conn で行う
+	// ev_match_found より先に届くよう、キューに追加する前に送る
+	conn.send(WSMessage{
 		Type: "ev_queue_joined",
 		Payload: map[string]any{
 			"message": "マッチング待機中...",
 		},
 	})
 
+	if err := h.hub.usecase.EnqueueReserved(ctx, user, teamSize); err != nil {
+		log.Printf("matchmake: join queue error for %s: %v", userID, err)
+		conn.send(queueErrorMessage())
+		return nil
+	}
+
 	// メッセージ読み取りループ
 	for {
 		_, msg, err := ws.ReadMessage()
@@ -162,7 +180,18 @@ func (h *MatchmakeHandler) HandleMatchmake(c echo.Context) error {
 	return nil
 }
 
-func sendWSMessage(ws *websocket.Conn, msg WSMessage) {
+// queueErrorMessage はキューへの参加の失敗を知らせるメッセージ
+func queueErrorMessage() WSMessage {
+	return WSMessage{
+		Type: "ev_error",
+		Payload: map[string]any{
+			"code":    "queue_error",
+			"message": "キューへの参加に失敗しました",
+		},
+	}
+}
+
+func sendWSMessage(ws playerConn, msg WSMessage) {
 	data, err := json.Marshal(msg)
 	if err != nil {
 		log.Printf("matchmake: marshal error: %v", err)
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"context"
 	"errors"
 	"log"
 	"net/http"
@@ -20,7 +19,8 @@ func NewRoomHandler(manager *RoomManager) *RoomHandler {
 }
 
 // HandleRoom は ws://{host}/ws/room/:room_id を処理する
-// クエリパラメータ: github_login (必須), github_id (ユーザー未登録時に必須)
+// 接続は WSMiddleware で認証済みのユーザーに紐づける
+// 対戦中に切断したプレイヤーは再接続猶予の間に同じ URL へ接続し直すと復帰できる
 func (h *RoomHandler) HandleRoom(c echo.Context) error {
 	roomIDStr := c.Param("room_id")
 	roomID, err := uuid.Parse(roomIDStr)
@@ -28,19 +28,16 @@ func (h *RoomHandler) HandleRoom(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid room_id")
 	}
 
-	githubLogin := c.QueryParam("github_login")
-	if githubLogin == "" {
-		return echo.NewHTTPError(http.StatusBadRequest, "github_login is required")
+	identity, ok := authenticatedIdentity(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
 	}
 
 	ctx := c.Request().Context()
 
-	user, err := h.manager.GetOrCreateUser(ctx, githubLogin, c.QueryParam("github_id"))
+	user, err := getOrCreateUser(ctx, h.manager.userRepo, identity)
 	if err != nil {
-		log.Printf("room %s: failed to get or create user %s: %v", roomID, githubLogin, err)
-		if errors.Is(err, ErrInvalidGitHubID) {
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid github_id")
-		}
+		log.Printf("room %s: failed to get or create user %s: %v", roomID, identity.Login, err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get or create user")
 	}
 
@@ -56,30 +53,82 @@ func (h *RoomHandler) HandleRoom(c echo.Context) error {
 
 	log.Printf("room %s: player %s connected", roomID, user.GitHubLogin)
 
-	idx, doneCh, room, err := h.manager.Join(context.Background(), roomID, ws, user)
+	h.manager.Connect(ctx, roomID, ws, user)
+
+	log.Printf("room %s: player %s disconnected", roomID, user.GitHubLogin)
+	return nil
+}
+
+// sendJoinError はルーム参加の失敗を ev_error で通知する
+func sendJoinError(conn playerConn, err error) {
+	code, message := joinErrorCode(err)
+	sendWSMessage(conn, WSMessage{
+		Type: "ev_error",
+		Payload: map[string]any{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
+
+// joinErrorCode はルーム参加の失敗理由を ev_error の code とメッセージに変換する
+func joinErrorCode(err error) (string, string) {
+	switch {
+	case errors.Is(err, ErrRoomNotFound):
+		return "room_not_found", "ルームが見つかりません"
+	case errors.Is(err, ErrNotRoomMember):
+		return "not_room_member", "このルームの対戦者ではありません"
+	case errors.Is(err, ErrRoomFinished):
+		return "room_finished", "この試合は終了しています"
+	case errors.Is(err, errRoomFull):
+		return "room_full", "ルームが満員です"
+	case errors.Is(err, errRoomClosed):
+		return "room_closed", "ルームは閉じられました"
+	case errors.Is(err, ErrServerDraining):
+		return "server_draining", "サーバーの再起動中です。しばらくしてから接続し直してください"
+	default:
+		return "join_failed", "ルームへの参加に失敗しました"
+	}
+}
+
+// HandleSpectate は ws://{host}/ws/room/:room_id/spectate を処理する
+// 接続は WSMiddleware で認証済みのユーザーに限る
+// 稼働中のルームに読み取り専用で接続し、両プレイヤーの進行を受信する
+// ルームを他のインスタンスが担当している場合は、担当インスタンスから中継する
+func (h *RoomHandler) HandleSpectate(c echo.Context) error {
+	roomID, err := uuid.Parse(c.Param("room_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid room_id")
+	}
+
+	identity, ok := authenticatedIdentity(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	owner, err := h.manager.spectateOwner(c.Request().Context(), roomID)
 	if err != nil {
-		log.Printf("room %s: join failed for %s: %v", roomID, user.GitHubLogin, err)
-		sendWSMessage(ws, WSMessage{
-			Type: "ev_error",
-			Payload: map[string]any{
-				"code":    "join_failed",
-				"message": "ルームへの参加に失敗しました: " + err.Error(),
-			},
-		})
-		return nil
+		if errors.Is(err, ErrRoomNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "room not found")
+		}
+		log.Printf("room %s: failed to resolve owner for spectator %s: %v", roomID, identity.Login, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to resolve room")
 	}
 
-	// idx==0 のプレイヤーがゲームループを起動する
-	if idx == 0 {
-		go room.run(context.Background())
+	ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return err
 	}
+	defer func() {
+		if closeErr := ws.Close(); closeErr != nil {
+			log.Printf("room %s: spectator ws close error: %v", roomID, closeErr)
+		}
+	}()
 
-	// WebSocket の読み取りは startReaderLoop に委譲する
-	go room.startReaderLoop(idx)
+	log.Printf("room %s: spectator %s connected", roomID, identity.Login)
 
-	// 接続が閉じるまでブロック（doneCh は startReaderLoop が close する）
-	<-doneCh
+	h.manager.Spectate(roomID, owner, ws)
 
-	log.Printf("room %s: player %s disconnected", roomID, user.GitHubLogin)
+	log.Printf("room %s: spectator %s disconnected", roomID, identity.Login)
 	return nil
 }
@@ -0,0 +1,54 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/usecase"
+)
+
+// fakeDifficulties は FakeGenerator が順番に割り当てる難易度
+var fakeDifficulties = []entity.Difficulty{entity.DifficultyEasy, entity.DifficultyNormal, entity.DifficultyHard}
+
+// fakePlaceholderFiles はファイルが渡されなかったときの出題元
+var fakePlaceholderFiles = []entity.RepositoryFile{
+	{FilePath: "README.md", Content: "# sample"},
+	{FilePath: "main.go", Content: "package main\n\nfunc main() {}\n"},
+}
+
+// FakeGenerator は LLM を呼ばずに決定的な問題を生成する（テスト・ローカル開発用）
+// 同じファイル一覧と問題数からは常に同じ問題を返す
+type FakeGenerator struct{}
+
+func NewFakeGenerator() *FakeGenerator {
+	return &FakeGenerator{}
+}
+
+var _ usecase.QuestionGenerator = (*FakeGenerator)(nil)
+
+func (g *FakeGenerator) Generate(_ context.Context, files []entity.RepositoryFile, count int) ([]entity.Question, error) {
+	if len(files) == 0 {
+		// 解析済みリポジトリがないユーザー（Bot など）でも対戦できるようにする
+		files = fakePlaceholderFiles
+	}
+	questions := make([]entity.Question, count)
+	for i := range questions {
+		f := files[i%len(files)]
+		// 正解の位置をずらして選択肢の並びが毎回同じにならないようにする
+		choices := make([]string, entity.NumChoices)
+		correct := i % entity.NumChoices
+		for j := range choices {
+			offset := (j - correct + entity.NumChoices) % entity.NumChoices
+			choices[j] = fmt.Sprintf("%d bytes", len(f.Content)+offset*10)
+		}
+		questions[i] = entity.Question{
+			Difficulty:    fakeDifficulties[i%len(fakeDifficulties)],
+			QuestionText:  fmt.Sprintf("Q%d: %s のファイルサイズは？", i+1, f.FilePath),
+			CorrectAnswer: choices[correct],
+			Tips:          fmt.Sprintf("%s は %d バイトです。", f.FilePath, len(f.Content)),
+			Choices:       choices,
+		}
+	}
+	return questions, nil
+}
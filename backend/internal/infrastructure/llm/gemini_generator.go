@@ -0,0 +1,261 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/usecase"
+)
+
+// DefaultGeminiBaseURL は Gemini API のベース URL
+const DefaultGeminiBaseURL = "https://generativelanguage.googleapis.com"
+
+// geminiTimeout は1回の生成リクエストのタイムアウト
+const geminiTimeout = 90 * time.Second
+
+// geminiSystemPrompt はフロントエンドの対戦用プロンプトの出題方針・出力形式に、サーバーで扱う問題の形式を加えたもの
+const geminiSystemPrompt = `指示
+あなたは優秀なフルスタックエンジニア兼プログラミング講師です。
+提供されたリポジトリのソースコードを深く分析し、そのコードを書いた本人または技術的な知識がある人が解けるクイズを生成してください。
+プロダクト固有の問題（実装の詳細）と技術一般の問題（使われている技術の知識）を混ぜて出題してください。
+厳守事項（ハルシネーション対策）
+偽情報の禁止: 実在しないライブラリ名や関数名を「正解」として扱うことは厳禁です。
+難易度: Lv1（Easy）・Lv2（Normal）・Lv3（Hard）を均等に出題してください。
+クイズ形式
+4択問題（正解は常に1つ）を中心に、次の形式を全体の半分以下で混ぜてください。形式は "type" で指定します（省略時は single_choice）。
+- single_choice: 4択。options に4つの選択肢、answerIndex に正解のインデックス
+- multi_select: 4択から正解をすべて選ぶ。options に4つの選択肢、answerIndexes に正解のインデックスすべて（1つ以上）
+- true_false: 正誤問題。options は ["正しい", "誤り"]、answerIndex に正解のインデックス
+- ordering: コードの行の並べ替え。lines に3〜8行のコードを正しい順で記述（重複する行は禁止）
+- free_text: 短い記述。answer に関数名・識別子などの短い正解（100文字以内）、acceptedAnswers に別の正しい表記
+Tips（解説）はMarkdown形式で記述してください。
+出力形式 (JSON)
+必ず以下のスキーマに従った1つのJSONオブジェクトとして出力してください。
+{
+"quizzes": [
+{
+"difficulty": "Lv1",
+"question": "問題文をここに記述",
+"options": ["選択肢1", "選択肢2", "選択肢3", "選択肢4"],
+"answerIndex": 0,
+"tips": "### 解説\nここにMarkdownで記述",
+"relatedFile": "src/components/Example.tsx"
+},
+{
+"type": "ordering",
+"difficulty": "Lv2",
+"question": "問題文をここに記述",
+"lines": ["1行目", "2行目", "3行目"],
+"tips": "### 解説\nここにMarkdownで記述",
+"relatedFile": "src/main.go"
+}
+]
+}`
+
+// geminiDifficulty は LLM の難易度表記を entity.Question の表記に変換する
+var geminiDifficulty = map[string]entity.Difficulty{
+	"Lv1": entity.DifficultyEasy,
+	"Lv2": entity.DifficultyNormal,
+	"Lv3": entity.DifficultyHard,
+}
+
+// GeminiGenerator は Gemini 互換の generateContent API で問題を生成する
+type GeminiGenerator struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+	model   string
+}
+
+// NewGeminiGenerator は GeminiGenerator を生成する
+// baseURL が空の場合は DefaultGeminiBaseURL を使う
+func NewGeminiGenerator(baseURL, apiKey, model string) *GeminiGenerator {
+	if baseURL == "" {
+		baseURL = DefaultGeminiBaseURL
+	}
+	return &GeminiGenerator{
+		client:  &http.Client{Timeout: geminiTimeout},
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+	}
+}
+
+var _ usecase.QuestionGenerator = (*GeminiGenerator)(nil)
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiContent struct {
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiRequest struct {
+	GenerationConfig map[string]any  `json:"generationConfig"`
+	Contents         []geminiContent `json:"contents"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content geminiContent `json:"content"`
+	} `json:"candidates"`
+}
+
+type geminiQuiz struct {
+	Type            entity.QuestionType `json:"type"`
+	Difficulty      string              `json:"difficulty"`
+	Question        string              `json:"question"`
+	Tips            string              `json:"tips"`
+	Answer          string              `json:"answer"`
+	Options         []string            `json:"options"`
+	AnswerIndexes   []int               `json:"answerIndexes"`
+	Lines           []string            `json:"lines"`
+	AcceptedAnswers []string            `json:"acceptedAnswers"`
+	AnswerIndex     int                 `json:"answerIndex"`
+}
+
+type geminiQuizBatch struct {
+	Quizzes []geminiQuiz `json:"quizzes"`
+}
+
+func (g *GeminiGenerator) Generate(ctx context.Context, files []entity.RepositoryFile, count int) ([]entity.Question, error) {
+	if len(files) == 0 {
+		return nil, usecase.ErrNoRepositoryFiles
+	}
+	body, err := json.Marshal(geminiRequest{
+		Contents: []geminiContent{{Parts: []geminiPart{{Text: buildPrompt(files, count)}}}},
+		GenerationConfig: map[string]any{
+			"responseMimeType": "application/json",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/v1beta/models/%s:generateContent", g.baseURL, g.model)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", g.apiKey)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("gemini api: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("gemini: failed to close response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("gemini api returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var gr geminiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&gr); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if len(gr.Candidates) == 0 || len(gr.Candidates[0].Content.Parts) == 0 {
+		return nil, fmt.Errorf("empty response")
+	}
+
+	var batch geminiQuizBatch
+	if err := json.Unmarshal([]byte(gr.Candidates[0].Content.Parts[0].Text), &batch); err != nil {
+		return nil, fmt.Errorf("decode quizzes: %w", err)
+	}
+
+	questions := make([]entity.Question, 0, len(batch.Quizzes))
+	for _, qz := range batch.Quizzes {
+		q, ok := qz.toQuestion()
+		if !ok {
+			continue
+		}
+		questions = append(questions, q)
+		if len(questions) == count {
+			break
+		}
+	}
+	return questions, nil
+}
+
+// toQuestion は LLM の出力を形式ごとに entity.Question に変換する
+// 正解のインデックスが選択肢の範囲外の場合は false を返す（その他の検証は Question.Validate に任せる）
+func (qz geminiQuiz) toQuestion() (entity.Question, bool) {
+	// "Lv1" 形式のほか "Easy" などの表記も受け付け、解釈できない場合は normal とする
+	difficulty, ok := geminiDifficulty[qz.Difficulty]
+	if !ok {
+		var err error
+		if difficulty, err = entity.ParseDifficulty(qz.Difficulty); err != nil {
+			difficulty = entity.DifficultyNormal
+		}
+	}
+	q := entity.Question{
+		Type:         qz.Type,
+		Difficulty:   difficulty,
+		QuestionText: qz.Question,
+		Tips:         qz.Tips,
+		Choices:      qz.Options,
+	}
+	switch qz.Type {
+	case "", entity.QuestionTypeSingleChoice, entity.QuestionTypeTrueFalse:
+		if qz.Type == entity.QuestionTypeSingleChoice {
+			q.Type = "" // 4択は従来の問題と同じ表現にする
+		}
+		if qz.AnswerIndex < 0 || qz.AnswerIndex >= len(qz.Options) {
+			return entity.Question{}, false
+		}
+		q.CorrectAnswer = qz.Options[qz.AnswerIndex]
+	case entity.QuestionTypeMultiSelect:
+		for _, i := range qz.AnswerIndexes {
+			if i < 0 || i >= len(qz.Options) {
+				return entity.Question{}, false
+			}
+			q.CorrectAnswers = append(q.CorrectAnswers, qz.Options[i])
+		}
+	case entity.QuestionTypeOrdering:
+		q.CorrectAnswers = qz.Lines
+		q.Choices = shuffledLines(qz.Lines)
+	case entity.QuestionTypeFreeText:
+		q.Choices = nil
+		q.CorrectAnswer = qz.Answer
+		q.AcceptedAnswers = qz.AcceptedAnswers
+	}
+	return q, true
+}
+
+// shuffledLines は並べ替え問題の出題用に lines を混ぜたコピーを返す
+// 混ぜた結果が正しい順と同じになった場合は1行ずらす
+func shuffledLines(lines []string) []string {
+	shuffled := slices.Clone(lines)
+	rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	if len(shuffled) > 1 && slices.Equal(shuffled, lines) {
+		shuffled = append(shuffled[1:], shuffled[0])
+	}
+	return shuffled
+}
+
+// buildPrompt はシステムプロンプトとソースコードから生成リクエストの本文を組み立てる
+func buildPrompt(files []entity.RepositoryFile, count int) string {
+	var sb strings.Builder
+	sb.WriteString(geminiSystemPrompt)
+	fmt.Fprintf(&sb, "\n\n# 追加制約\n- 出題数は必ず %d 問\n- 難易度はLv1・Lv2・Lv3をできる限り均等に\n\n# 解析対象ソースコード", count)
+	for _, f := range files {
+		fmt.Fprintf(&sb, "\n\n=== FILE: %s ===\n%s", f.FilePath, f.Content)
+	}
+	return sb.String()
+}
@@ -0,0 +1,131 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/usecase"
+)
+
+func TestGeminiGenerator_Generate(t *testing.T) {
+	quizzes := `{"quizzes":[
+		{"difficulty":"Lv1","question":"q1","options":["a","b","c","d"],"answerIndex":2,"tips":"t1"},
+		{"difficulty":"Lv3","question":"q2","options":["a","b","c","d"],"answerIndex":9,"tips":"out of range"},
+		{"difficulty":"Lv9","question":"q3","options":["a","b","c","d"],"answerIndex":0,"tips":"t3"}
+	]}`
+
+	var gotPath, gotKey, gotPrompt string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotKey = r.Header.Get("x-goog-api-key")
+		var req geminiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err == nil && len(req.Contents) > 0 {
+			gotPrompt = req.Contents[0].Parts[0].Text
+		}
+		resp := map[string]any{
+			"candidates": []any{
+				map[string]any{"content": map[string]any{"parts": []any{map[string]any{"text": quizzes}}}},
+			},
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	g := NewGeminiGenerator(srv.URL, "secret", "gemini-test")
+	qs, err := g.Generate(context.Background(), []entity.RepositoryFile{{FilePath: "main.go", Content: "package main"}}, 3)
+
+	require.NoError(t, err)
+	assert.Equal(t, "/v1beta/models/gemini-test:generateContent", gotPath)
+	assert.Equal(t, "secret", gotKey)
+	assert.Contains(t, gotPrompt, "=== FILE: main.go ===\npackage main")
+	assert.Contains(t, gotPrompt, "出題数は必ず 3 問")
+
+	require.Len(t, qs, 2, "quiz with out-of-range answerIndex should be skipped")
+	assert.Equal(t, entity.Question{
+		Difficulty:    entity.DifficultyEasy,
+		QuestionText:  "q1",
+		CorrectAnswer: "c",
+		Tips:          "t1",
+		Choices:       []string{"a", "b", "c", "d"},
+	}, qs[0])
+	assert.Equal(t, entity.DifficultyNormal, qs[1].Difficulty, "unknown difficulty falls back to normal")
+}
+
+func TestGeminiQuiz_ToQuestion_Types(t *testing.T) {
+	var batch geminiQuizBatch
+	require.NoError(t, json.Unmarshal([]byte(`{"quizzes":[
+		{"type":"single_choice","difficulty":"Lv1","question":"q1","options":["a","b","c","d"],"answerIndex":1},
+		{"type":"multi_select","difficulty":"Lv2","question":"q2","options":["a","b","c","d"],"answerIndexes":[0,3]},
+		{"type":"multi_select","difficulty":"Lv2","question":"q3","options":["a","b","c","d"],"answerIndexes":[4]},
+		{"type":"true_false","difficulty":"Hard","question":"q4","options":["正しい","誤り"],"answerIndex":1},
+		{"type":"ordering","difficulty":"Lv3","question":"q5","lines":["a","b","c"]},
+		{"type":"free_text","difficulty":"Lv3","question":"q6","options":["x"],"answer":"GetMe","acceptedAnswers":["get_me"]}
+	]}`), &batch))
+
+	qs := make([]entity.Question, 0, len(batch.Quizzes))
+	for _, qz := range batch.Quizzes {
+		if q, ok := qz.toQuestion(); ok {
+			require.NoError(t, q.Validate(), q.QuestionText)
+			qs = append(qs, q)
+		}
+	}
+
+	require.Len(t, qs, 5, "multi select with out-of-range answerIndexes should be skipped")
+	assert.Empty(t, qs[0].Type, "single choice keeps the untyped representation")
+	assert.Equal(t, "b", qs[0].CorrectAnswer)
+	assert.Equal(t, []string{"a", "d"}, qs[1].CorrectAnswers)
+	assert.Equal(t, "誤り", qs[2].CorrectAnswer)
+	assert.Equal(t, entity.DifficultyHard, qs[2].Difficulty, "difficulty names are parsed case-insensitively")
+	assert.Equal(t, []string{"a", "b", "c"}, qs[3].CorrectAnswers)
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, qs[3].Choices)
+	assert.NotEqual(t, qs[3].CorrectAnswers, qs[3].Choices, "lines are presented out of order")
+	assert.Equal(t, "GetMe", qs[4].CorrectAnswer)
+	assert.Empty(t, qs[4].Choices)
+	assert.Equal(t, []string{"get_me"}, qs[4].AcceptedAnswers)
+}
+
+func TestGeminiGenerator_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	g := NewGeminiGenerator(srv.URL, "secret", "gemini-test")
+	_, err := g.Generate(context.Background(), []entity.RepositoryFile{{FilePath: "main.go"}}, 1)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "429")
+}
+
+func TestGeminiGenerator_NoFiles(t *testing.T) {
+	g := NewGeminiGenerator("http://127.0.0.1:1", "secret", "gemini-test")
+	_, err := g.Generate(context.Background(), nil, 1)
+
+	require.ErrorIs(t, err, usecase.ErrNoRepositoryFiles)
+}
+
+func TestFakeGenerator_Deterministic(t *testing.T) {
+	files := []entity.RepositoryFile{{FilePath: "main.go", Content: "package main"}}
+	g := NewFakeGenerator()
+
+	first, err := g.Generate(context.Background(), files, 6)
+	require.NoError(t, err)
+	second, err := g.Generate(context.Background(), files, 6)
+	require.NoError(t, err)
+
+	require.Len(t, first, 6)
+	assert.Equal(t, first, second)
+	for _, q := range first {
+		require.NoError(t, q.Validate())
+	}
+
+	placeholder, err := g.Generate(context.Background(), nil, 2)
+	require.NoError(t, err)
+	assert.Len(t, placeholder, 2, "fake generator should work without repository files")
+}
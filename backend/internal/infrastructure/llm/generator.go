@@ -0,0 +1,24 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/usecase"
+)
+
+// NewGenerator はプロバイダ名から QuestionGenerator を生成する
+// provider: gemini / fake
+func NewGenerator(provider, baseURL, apiKey, model string) (usecase.QuestionGenerator, error) {
+	switch provider {
+	case "", "gemini":
+		if apiKey == "" {
+			return nil, errors.New("GEMINI_API_KEY is required for the gemini question generator")
+		}
+		return NewGeminiGenerator(baseURL, apiKey, model), nil
+	case "fake":
+		return NewFakeGenerator(), nil
+	default:
+		return nil, fmt.Errorf("unknown question generator %q", provider)
+	}
+}
@@ -0,0 +1,117 @@
+package persistence
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/infrastructure/postgres/sqlc"
+)
+
+type gnuLedgerRepository struct {
+	db *sql.DB
+	q  *sqlc.Queries
+}
+
+func NewGnuLedgerRepository(db *sql.DB, q *sqlc.Queries) repository.GnuLedgerRepository {
+	return &gnuLedgerRepository{db: db, q: q}
+}
+
+func (r *gnuLedgerRepository) Apply(ctx context.Context, txs []entity.GnuTransaction) (map[uuid.UUID]int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("gnu ledger repository: rollback error: %v", rbErr)
+		}
+	}()
+	qtx := r.q.WithTx(tx)
+
+	// 同じユーザーを含む試合が並行して精算されてもデッドロックしないよう、ID 順に行ロックを取る
+	userIDs := make([]uuid.UUID, 0, len(txs))
+	for _, t := range txs {
+		if !slices.Contains(userIDs, t.UserID) {
+			userIDs = append(userIDs, t.UserID)
+		}
+	}
+	slices.SortFunc(userIDs, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+	balances := make(map[uuid.UUID]int, len(userIDs))
+	for _, id := range userIDs {
+		balance, err := qtx.LockUserGnuBalance(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("lock user %s: %w", id, err)
+		}
+		balances[id] = int(balance)
+	}
+
+	changed := make(map[uuid.UUID]bool, len(userIDs))
+	for _, t := range txs {
+		// 残高以上の減算は残高までに抑え、台帳には実際に反映した差分を残す
+		delta := max(t.Delta, -balances[t.UserID])
+		after := balances[t.UserID] + delta
+		turn := sql.NullInt32{}
+		if t.Turn > 0 {
+			turn = sql.NullInt32{Int32: int32(t.Turn), Valid: true}
+		}
+		_, err := qtx.CreateGnuTransaction(ctx, sqlc.CreateGnuTransactionParams{
+			UserID:         t.UserID,
+			RoomID:         t.RoomID,
+			Turn:           turn,
+			Reason:         string(t.Reason),
+			Delta:          int32(delta),
+			BalanceAfter:   int32(after),
+			IdempotencyKey: t.IdempotencyKey,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				// ON CONFLICT DO NOTHING: 反映済みの取引
+				continue
+			}
+			return nil, fmt.Errorf("create gnu transaction %s: %w", t.IdempotencyKey, err)
+		}
+		balances[t.UserID] = after
+		changed[t.UserID] = true
+	}
+
+	for _, id := range userIDs {
+		if !changed[id] {
+			continue
+		}
+		if err := qtx.SetUserGnuBalance(ctx, sqlc.SetUserGnuBalanceParams{
+			ID:         id,
+			GnuBalance: int32(balances[id]),
+		}); err != nil {
+			return nil, fmt.Errorf("set gnu balance for %s: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return balances, nil
+}
+
+func (r *gnuLedgerRepository) ListMismatches(ctx context.Context) ([]entity.GnuBalanceMismatch, error) {
+	rows, err := r.q.ListGnuBalanceMismatches(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list gnu balance mismatches: %w", err)
+	}
+	mismatches := make([]entity.GnuBalanceMismatch, len(rows))
+	for i, row := range rows {
+		mismatches[i] = entity.GnuBalanceMismatch{
+			UserID:        row.UserID,
+			CachedBalance: int(row.CachedBalance),
+			LedgerBalance: int(row.LedgerBalance),
+		}
+	}
+	return mismatches, nil
+}
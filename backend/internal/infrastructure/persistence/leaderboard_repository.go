@@ -0,0 +1,155 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+)
+
+const (
+	// leaderboardLoginsKey はランキングに表示する GitHub login（HASH, userID → login）
+	leaderboardLoginsKey = "leaderboard:logins"
+	// weeklyLeaderboardRetention は週間ランキングを週の始まりから残す期間
+	weeklyLeaderboardRetention = 4 * 7 * 24 * time.Hour
+)
+
+type leaderboardRepository struct {
+	rdb *redis.Client
+}
+
+func NewLeaderboardRepository(rdb *redis.Client) repository.LeaderboardRepository {
+	return &leaderboardRepository{rdb: rdb}
+}
+
+func (r *leaderboardRepository) SetScores(ctx context.Context, board entity.Leaderboard, entries []entity.LeaderboardEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	key := leaderboardKey(board)
+	pipe := r.rdb.TxPipeline()
+	pipe.ZAdd(ctx, key, leaderboardMembers(entries)...)
+	pipe.HSet(ctx, leaderboardLoginsKey, leaderboardLogins(entries))
+	expireLeaderboard(ctx, pipe, board, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis set leaderboard scores: %w", err)
+	}
+	return nil
+}
+
+func (r *leaderboardRepository) IncrementScore(ctx context.Context, board entity.Leaderboard, entry entity.LeaderboardEntry, delta int) error {
+	key := leaderboardKey(board)
+	pipe := r.rdb.TxPipeline()
+	pipe.ZIncrBy(ctx, key, float64(delta), entry.UserID.String())
+	pipe.HSet(ctx, leaderboardLoginsKey, entry.UserID.String(), entry.GitHubLogin)
+	expireLeaderboard(ctx, pipe, board, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis increment leaderboard score: %w", err)
+	}
+	return nil
+}
+
+func (r *leaderboardRepository) Top(ctx context.Context, board entity.Leaderboard, limit int) ([]entity.LeaderboardEntry, error) {
+	return r.rangeByRank(ctx, board, 0, int64(limit)-1)
+}
+
+func (r *leaderboardRepository) Around(ctx context.Context, board entity.Leaderboard, userID uuid.UUID, radius int) ([]entity.LeaderboardEntry, error) {
+	rank, err := r.rdb.ZRevRank(ctx, leaderboardKey(board), userID.String()).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("redis get leaderboard rank: %w", err)
+	}
+	start := max(rank-int64(radius), 0)
+	return r.rangeByRank(ctx, board, start, rank+int64(radius))
+}
+
+// Replace は一時キーに書き込んでから RENAME で差し替え、再構築中も古いランキングを返せるようにする
+func (r *leaderboardRepository) Replace(ctx context.Context, board entity.Leaderboard, entries []entity.LeaderboardEntry) error {
+	key := leaderboardKey(board)
+	tmpKey := key + ":rebuild"
+	pipe := r.rdb.TxPipeline()
+	pipe.Del(ctx, tmpKey)
+	if len(entries) == 0 {
+		pipe.Del(ctx, key)
+	} else {
+		pipe.ZAdd(ctx, tmpKey, leaderboardMembers(entries)...)
+		pipe.HSet(ctx, leaderboardLoginsKey, leaderboardLogins(entries))
+		pipe.Rename(ctx, tmpKey, key)
+		expireLeaderboard(ctx, pipe, board, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis replace leaderboard: %w", err)
+	}
+	return nil
+}
+
+// rangeByRank は 0 始まりの順位 start から stop までのエントリを返す
+func (r *leaderboardRepository) rangeByRank(ctx context.Context, board entity.Leaderboard, start, stop int64) ([]entity.LeaderboardEntry, error) {
+	members, err := r.rdb.ZRevRangeWithScores(ctx, leaderboardKey(board), start, stop).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis get leaderboard range: %w", err)
+	}
+	if len(members) == 0 {
+		return []entity.LeaderboardEntry{}, nil
+	}
+	ids := make([]string, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.Member.(string))
+	}
+	logins, err := r.rdb.HMGet(ctx, leaderboardLoginsKey, ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis get leaderboard logins: %w", err)
+	}
+	entries := make([]entity.LeaderboardEntry, 0, len(members))
+	for i, m := range members {
+		id, err := uuid.Parse(ids[i])
+		if err != nil {
+			continue
+		}
+		login, _ := logins[i].(string)
+		entries = append(entries, entity.LeaderboardEntry{
+			UserID:      id,
+			GitHubLogin: login,
+			Rank:        int(start) + i + 1,
+			Score:       int(m.Score),
+		})
+	}
+	return entries, nil
+}
+
+func leaderboardMembers(entries []entity.LeaderboardEntry) []redis.Z {
+	members := make([]redis.Z, 0, len(entries))
+	for _, e := range entries {
+		members = append(members, redis.Z{Score: float64(e.Score), Member: e.UserID.String()})
+	}
+	return members
+}
+
+func leaderboardLogins(entries []entity.LeaderboardEntry) map[string]any {
+	logins := make(map[string]any, len(entries))
+	for _, e := range entries {
+		logins[e.UserID.String()] = e.GitHubLogin
+	}
+	return logins
+}
+
+// expireLeaderboard は週間ランキングに期限を設定する（他のランキングは期限なし）
+func expireLeaderboard(ctx context.Context, pipe redis.Pipeliner, board entity.Leaderboard, key string) {
+	if board.Kind == entity.LeaderboardKindWeeklyWins {
+		pipe.ExpireAt(ctx, key, board.Week.Add(weeklyLeaderboardRetention))
+	}
+}
+
+func leaderboardKey(board entity.Leaderboard) string {
+	if board.Kind == entity.LeaderboardKindWeeklyWins {
+		return fmt.Sprintf("leaderboard:%s:%s", board.Kind, board.Week.Format("2006-01-02"))
+	}
+	return fmt.Sprintf("leaderboard:%s", board.Kind)
+}
@@ -0,0 +1,76 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+)
+
+func TestLeaderboardRepository_TopAndAround(t *testing.T) {
+	rdb := setupTestRedis(t)
+	repo := NewLeaderboardRepository(rdb)
+	ctx := context.Background()
+	board := entity.Leaderboard{Kind: entity.LeaderboardKindRate}
+	defer cleanupKeys(t, rdb, leaderboardKey(board), leaderboardLoginsKey)
+
+	entries := make([]entity.LeaderboardEntry, 5)
+	for i := range entries {
+		entries[i] = entity.LeaderboardEntry{UserID: uuid.New(), GitHubLogin: string(rune('a' + i)), Score: 1500 + i*10}
+	}
+	require.NoError(t, repo.Replace(ctx, board, entries))
+
+	top, err := repo.Top(ctx, board, 2)
+	require.NoError(t, err)
+	require.Len(t, top, 2)
+	assert.Equal(t, entity.LeaderboardEntry{UserID: entries[4].UserID, GitHubLogin: "e", Rank: 1, Score: 1540}, top[0])
+	assert.Equal(t, 2, top[1].Rank)
+
+	// entries[0] は最下位（5位）なので、後ろの隣人はいない
+	around, err := repo.Around(ctx, board, entries[0].UserID, 1)
+	require.NoError(t, err)
+	require.Len(t, around, 2)
+	assert.Equal(t, 4, around[0].Rank)
+	assert.Equal(t, 5, around[1].Rank)
+	assert.Equal(t, "a", around[1].GitHubLogin)
+
+	around, err = repo.Around(ctx, board, uuid.New(), 1)
+	require.NoError(t, err)
+	assert.Nil(t, around, "unranked user")
+
+	// スコアの更新で順位が入れ替わる
+	require.NoError(t, repo.SetScores(ctx, board, []entity.LeaderboardEntry{{UserID: entries[0].UserID, GitHubLogin: "a", Score: 1600}}))
+	top, err = repo.Top(ctx, board, 1)
+	require.NoError(t, err)
+	assert.Equal(t, entries[0].UserID, top[0].UserID)
+}
+
+func TestLeaderboardRepository_WeeklyWins(t *testing.T) {
+	rdb := setupTestRedis(t)
+	repo := NewLeaderboardRepository(rdb)
+	ctx := context.Background()
+	board := entity.Leaderboard{Kind: entity.LeaderboardKindWeeklyWins, Week: entity.LeaderboardWeekStart(time.Now())}
+	key := leaderboardKey(board)
+	defer cleanupKeys(t, rdb, key, leaderboardLoginsKey)
+
+	alice := entity.LeaderboardEntry{UserID: uuid.New(), GitHubLogin: "alice"}
+	require.NoError(t, repo.IncrementScore(ctx, board, alice, 1))
+	require.NoError(t, repo.IncrementScore(ctx, board, alice, 1))
+
+	top, err := repo.Top(ctx, board, 10)
+	require.NoError(t, err)
+	require.Len(t, top, 1)
+	assert.Equal(t, 2, top[0].Score)
+	ttl, err := rdb.TTL(ctx, key).Result()
+	require.NoError(t, err)
+	assert.Positive(t, ttl, "weekly boards expire")
+
+	require.NoError(t, repo.Replace(ctx, board, nil))
+	top, err = repo.Top(ctx, board, 10)
+	require.NoError(t, err)
+	assert.Empty(t, top)
+}
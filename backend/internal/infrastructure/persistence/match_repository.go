@@ -0,0 +1,333 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/infrastructure/postgres/sqlc"
+)
+
+type matchRepository struct {
+	db *sql.DB
+	q  *sqlc.Queries
+}
+
+func NewMatchRepository(db *sql.DB, q *sqlc.Queries) repository.MatchRepository {
+	return &matchRepository{db: db, q: q}
+}
+
+func (r *matchRepository) Save(ctx context.Context, result *entity.MatchResult) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("match repository: rollback error: %v", rbErr)
+		}
+	}()
+	qtx := r.q.WithTx(tx)
+
+	created, err := qtx.CreateMatchResult(ctx, sqlc.CreateMatchResultParams{
+		RoomID:              result.RoomID,
+		Player1ID:           result.Player1ID,
+		Player2ID:           result.Player2ID,
+		WinnerID:            result.WinnerID,
+		EndReason:           string(result.EndReason),
+		Player1CorrectCount: int32(result.Player1CorrectCount),
+		Player2CorrectCount: int32(result.Player2CorrectCount),
+		Player1GnuEarned:    int32(result.Player1GnuEarned),
+		Player2GnuEarned:    int32(result.Player2GnuEarned),
+		Player1FinalGnu:     int32(result.Player1FinalGnu),
+		Player2FinalGnu:     int32(result.Player2FinalGnu),
+		TotalTurns:          int32(result.TotalTurns),
+		StartedAt:           result.StartedAt,
+		FinishedAt:          result.FinishedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("create match result: %w", err)
+	}
+
+	for _, p := range result.Players {
+		if err := qtx.CreateMatchResultPlayer(ctx, sqlc.CreateMatchResultPlayerParams{
+			MatchID:      created.ID,
+			UserID:       p.UserID,
+			Team:         int32(p.Team),
+			Position:     int32(p.Position),
+			Outcome:      string(p.Outcome),
+			CorrectCount: int32(p.CorrectCount),
+			GnuEarned:    int32(p.GnuEarned),
+			FinalGnu:     int32(p.FinalGnu),
+		}); err != nil {
+			return fmt.Errorf("create match result player %s: %w", p.UserID, err)
+		}
+	}
+
+	for _, t := range result.Turns {
+		question, err := json.Marshal(t.Question)
+		if err != nil {
+			return fmt.Errorf("marshal question: %w", err)
+		}
+		answerTime := sql.NullInt32{}
+		if t.AnswerTimeMs != nil {
+			answerTime = sql.NullInt32{Int32: int32(*t.AnswerTimeMs), Valid: true}
+		}
+		answer := pqtype.NullRawMessage{}
+		if t.Answer != nil {
+			data, err := json.Marshal(t.Answer)
+			if err != nil {
+				return fmt.Errorf("marshal answer: %w", err)
+			}
+			answer = pqtype.NullRawMessage{RawMessage: data, Valid: true}
+		}
+		if err := qtx.CreateMatchTurn(ctx, sqlc.CreateMatchTurnParams{
+			MatchID:      created.ID,
+			Turn:         int32(t.Turn),
+			UserID:       t.UserID,
+			Question:     question,
+			ChoiceIndex:  int32(t.ChoiceIndex),
+			IsCorrect:    t.IsCorrect,
+			Bet:          int32(t.Bet),
+			GnuDelta:     int32(t.GnuDelta),
+			AnswerTimeMs: answerTime,
+			Answer:       answer,
+		}); err != nil {
+			return fmt.Errorf("create match turn %d: %w", t.Turn, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	result.ID = created.ID
+	result.FinishedAt = created.FinishedAt
+	return nil
+}
+
+func (r *matchRepository) GetByRoomID(ctx context.Context, roomID uuid.UUID) (*entity.MatchResult, error) {
+	row, err := r.q.GetMatchResultByRoomID(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("get match result by room id: %w", err)
+	}
+	result := toEntityMatchResult(row)
+
+	players, err := r.q.ListMatchResultPlayersByMatchID(ctx, row.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list match result players: %w", err)
+	}
+	result.Players = make([]entity.MatchResultPlayer, 0, len(players))
+	for _, p := range players {
+		result.Players = append(result.Players, entity.MatchResultPlayer{
+			UserID:       p.UserID,
+			Team:         int(p.Team),
+			Position:     int(p.Position),
+			Outcome:      entity.MatchOutcome(p.Outcome),
+			CorrectCount: int(p.CorrectCount),
+			GnuEarned:    int(p.GnuEarned),
+			FinalGnu:     int(p.FinalGnu),
+		})
+	}
+
+	turns, err := r.q.ListMatchTurnsByMatchID(ctx, row.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list match turns: %w", err)
+	}
+	result.Turns = make([]entity.MatchTurn, 0, len(turns))
+	for _, t := range turns {
+		turn, err := toEntityMatchTurn(t)
+		if err != nil {
+			return nil, err
+		}
+		result.Turns = append(result.Turns, turn)
+	}
+	return result, nil
+}
+
+func (r *matchRepository) ListByUserID(ctx context.Context, userID uuid.UUID, limit int) ([]*entity.MatchResult, error) {
+	rows, err := r.q.ListMatchResultsByUserID(ctx, sqlc.ListMatchResultsByUserIDParams{
+		UserID: userID,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list match results by user id: %w", err)
+	}
+	results := make([]*entity.MatchResult, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, toEntityMatchResult(row))
+	}
+	return results, nil
+}
+
+func (r *matchRepository) CountWinsSince(ctx context.Context, since time.Time) (map[uuid.UUID]int, error) {
+	rows, err := r.q.CountWinsSince(ctx, since)
+	if err != nil {
+		return nil, fmt.Errorf("count wins since: %w", err)
+	}
+	wins := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		wins[row.UserID] = int(row.Wins)
+	}
+	return wins, nil
+}
+
+func (r *matchRepository) GetUserStats(ctx context.Context, userID uuid.UUID, historySince time.Time) (*entity.UserStats, error) {
+	summary, err := r.q.GetUserMatchSummary(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user match summary: %w", err)
+	}
+	streak, err := r.q.GetUserBestWinStreak(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user best win streak: %w", err)
+	}
+	turns, err := r.q.ListUserTurnStatsByDifficulty(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list user turn stats: %w", err)
+	}
+	days, err := r.q.ListUserDailyGnuEarned(ctx, sqlc.ListUserDailyGnuEarnedParams{UserID: userID, Since: historySince})
+	if err != nil {
+		return nil, fmt.Errorf("list user daily gnu earned: %w", err)
+	}
+
+	stats := &entity.UserStats{
+		MatchesPlayed: int(summary.MatchesPlayed),
+		Wins:          int(summary.Wins),
+		Losses:        int(summary.Losses),
+		Draws:         int(summary.Draws),
+		TKOWins:       int(summary.TkoWins),
+		TKOLosses:     int(summary.TkoLosses),
+		GnuEarned:     int(summary.GnuEarned),
+		BestWinStreak: int(streak),
+		Accuracy:      make([]entity.DifficultyAccuracy, 0, len(turns)),
+		GnuHistory:    make([]entity.DailyGnuEarned, 0, len(days)),
+	}
+	var bets, betTotal int64
+	for _, t := range turns {
+		acc := entity.DifficultyAccuracy{Difficulty: t.Difficulty, Turns: int(t.Turns), Correct: int(t.Correct)}
+		if t.Turns > 0 {
+			acc.Accuracy = float64(t.Correct) / float64(t.Turns)
+		}
+		stats.Accuracy = append(stats.Accuracy, acc)
+		bets += t.Bets
+		betTotal += t.BetTotal
+	}
+	if bets > 0 {
+		stats.AverageBet = float64(betTotal) / float64(bets)
+	}
+	for _, d := range days {
+		stats.GnuHistory = append(stats.GnuHistory, entity.DailyGnuEarned{
+			Date:      d.Day.Format("2006-01-02"),
+			Matches:   int(d.Matches),
+			GnuEarned: int(d.GnuEarned),
+		})
+	}
+	return stats, nil
+}
+
+func (r *matchRepository) SaveEvents(ctx context.Context, events []entity.MatchEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("match repository: rollback error: %v", rbErr)
+		}
+	}()
+	qtx := r.q.WithTx(tx)
+
+	for _, ev := range events {
+		if err := qtx.CreateMatchEvent(ctx, sqlc.CreateMatchEventParams{
+			RoomID:    ev.RoomID,
+			UserID:    ev.UserID,
+			EventType: ev.Type,
+			Payload:   ev.Payload,
+			SentAt:    ev.SentAt,
+		}); err != nil {
+			return fmt.Errorf("create match event %s: %w", ev.Type, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+func (r *matchRepository) ListEventsByRoomID(ctx context.Context, roomID uuid.UUID) ([]entity.MatchEvent, error) {
+	rows, err := r.q.ListMatchEventsByRoomID(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("list match events by room id: %w", err)
+	}
+	events := make([]entity.MatchEvent, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, entity.MatchEvent{
+			RoomID:  row.RoomID,
+			UserID:  row.UserID,
+			Type:    row.EventType,
+			Payload: row.Payload,
+			SentAt:  row.SentAt,
+		})
+	}
+	return events, nil
+}
+
+func toEntityMatchResult(m sqlc.MatchResult) *entity.MatchResult {
+	return &entity.MatchResult{
+		ID:                  m.ID,
+		RoomID:              m.RoomID,
+		Player1ID:           m.Player1ID,
+		Player2ID:           m.Player2ID,
+		WinnerID:            m.WinnerID,
+		EndReason:           entity.MatchEndReason(m.EndReason),
+		Player1CorrectCount: int(m.Player1CorrectCount),
+		Player2CorrectCount: int(m.Player2CorrectCount),
+		Player1GnuEarned:    int(m.Player1GnuEarned),
+		Player2GnuEarned:    int(m.Player2GnuEarned),
+		Player1FinalGnu:     int(m.Player1FinalGnu),
+		Player2FinalGnu:     int(m.Player2FinalGnu),
+		TotalTurns:          int(m.TotalTurns),
+		StartedAt:           m.StartedAt,
+		FinishedAt:          m.FinishedAt,
+	}
+}
+
+func toEntityMatchTurn(t sqlc.MatchTurn) (entity.MatchTurn, error) {
+	var q entity.Question
+	if err := json.Unmarshal(t.Question, &q); err != nil {
+		return entity.MatchTurn{}, fmt.Errorf("unmarshal question: %w", err)
+	}
+	turn := entity.MatchTurn{
+		Turn:        int(t.Turn),
+		UserID:      t.UserID,
+		Question:    q,
+		ChoiceIndex: int(t.ChoiceIndex),
+		IsCorrect:   t.IsCorrect,
+		Bet:         int(t.Bet),
+		GnuDelta:    int(t.GnuDelta),
+	}
+	if t.AnswerTimeMs.Valid {
+		ms := int(t.AnswerTimeMs.Int32)
+		turn.AnswerTimeMs = &ms
+	}
+	if t.Answer.Valid {
+		var a entity.Answer
+		if err := json.Unmarshal(t.Answer.RawMessage, &a); err != nil {
+			return entity.MatchTurn{}, fmt.Errorf("unmarshal answer: %w", err)
+		}
+		turn.Answer = &a
+	}
+	return turn, nil
+}
@@ -4,31 +4,138 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
 	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
 )
 
-// dequeueScript は 2 件をアトミックに LPOP する Lua スクリプト
+// dequeueScript はレート差が許容幅に収まる2人をアトミックに取り出す Lua スクリプト
+// KEYS[1]: キュー (ZSET, score=rate), KEYS[2]: 参加時刻 (HASH, userID → unix ms)
+// ARGV: now_ms, base, widen_per_sec, max
+// 許容幅は各プレイヤーの待ち時間から計算し、両者の許容幅に収まるペアのみ成立させる
+// 候補が複数ある場合は最も長く待っているプレイヤーを含むペアを優先し、その中ではレート差が最も小さいペアを選ぶ
 var dequeueScript = redis.NewScript(`
-local first = redis.call('LPOP', KEYS[1])
-if not first then
+local members = redis.call('ZRANGE', KEYS[1], 0, -1, 'WITHSCORES')
+local n = #members / 2
+if n < 2 then
   return {}
 end
-local second = redis.call('LPOP', KEYS[1])
-if not second then
-  redis.call('RPUSH', KEYS[1], first)
+local now = tonumber(ARGV[1])
+local base = tonumber(ARGV[2])
+local widen = tonumber(ARGV[3])
+local maxw = tonumber(ARGV[4])
+
+local ids, rates, joined, windows = {}, {}, {}, {}
+for i = 1, n do
+  ids[i] = members[2 * i - 1]
+  rates[i] = tonumber(members[2 * i])
+  joined[i] = tonumber(redis.call('HGET', KEYS[2], ids[i])) or now
+  local w = base + widen * math.max(0, now - joined[i]) / 1000
+  if w > maxw then
+    w = maxw
+  end
+  windows[i] = w
+end
+
+local bi, bj, oldest, bestDiff = nil, nil, nil, nil
+for i = 1, n - 1 do
+  for j = i + 1, n do
+    local diff = rates[j] - rates[i]
+    if diff > windows[i] then
+      break
+    end
+    if diff <= windows[j] then
+      local o = math.min(joined[i], joined[j])
+      if oldest == nil or o < oldest or (o == oldest and diff < bestDiff) then
+        bi, bj, oldest, bestDiff = i, j, o, diff
+      end
+    end
+  end
+end
+if bi == nil then
+  return {}
+end
+
+redis.call('ZREM', KEYS[1], ids[bi], ids[bj])
+redis.call('HDEL', KEYS[2], ids[bi], ids[bj])
+return {
+  ids[bi], tostring(rates[bi]), tostring(joined[bi]),
+  ids[bj], tostring(rates[bj]), tostring(joined[bj]),
+}
+`)
+
+// dequeueTeamsScript はレート順に連続する ARGV[5] 人をアトミックに取り出す Lua スクリプト
+// KEYS・ARGV[1..4] は dequeueScript と同じ。最高レートと最低レートの差が全員の許容幅に収まる組み合わせのみ成立させる
+// 候補が複数ある場合は最も長く待っているプレイヤーを含む組み合わせを優先し、その中ではレートの幅が最も小さい組み合わせを選ぶ
+var dequeueTeamsScript = redis.NewScript(`
+local size = tonumber(ARGV[5])
+local members = redis.call('ZRANGE', KEYS[1], 0, -1, 'WITHSCORES')
+local n = #members / 2
+if n < size then
+  return {}
+end
+local now = tonumber(ARGV[1])
+local base = tonumber(ARGV[2])
+local widen = tonumber(ARGV[3])
+local maxw = tonumber(ARGV[4])
+
+local ids, rates, joined, windows = {}, {}, {}, {}
+for i = 1, n do
+  ids[i] = members[2 * i - 1]
+  rates[i] = tonumber(members[2 * i])
+  joined[i] = tonumber(redis.call('HGET', KEYS[2], ids[i])) or now
+  local w = base + widen * math.max(0, now - joined[i]) / 1000
+  if w > maxw then
+    w = maxw
+  end
+  windows[i] = w
+end
+
+local best, oldest, bestDiff = nil, nil, nil
+for i = 1, n - size + 1 do
+  local diff = rates[i + size - 1] - rates[i]
+  local ok, o = true, nil
+  for j = i, i + size - 1 do
+    if diff > windows[j] then
+      ok = false
+      break
+    end
+    if o == nil or joined[j] < o then
+      o = joined[j]
+    end
+  end
+  if ok and (oldest == nil or o < oldest or (o == oldest and diff < bestDiff)) then
+    best, oldest, bestDiff = i, o, diff
+  end
+end
+if best == nil then
   return {}
 end
-return {first, second}
+
+local out = {}
+for j = best, best + size - 1 do
+  redis.call('ZREM', KEYS[1], ids[j])
+  redis.call('HDEL', KEYS[2], ids[j])
+  table.insert(out, ids[j])
+  table.insert(out, tostring(rates[j]))
+  table.insert(out, tostring(joined[j]))
+end
+return out
 `)
 
 const (
-	matchmakingQueueKey  = "matchmaking:queue"
-	matchmakingActiveKey = "matchmaking:active:"
-	matchmakingActiveTTL = 300 * time.Second
+	// 1対1のキュー。チーム戦のキューは末尾に ":{n}v{n}" を付ける（queueKeys を参照）
+	matchmakingQueueKey    = "matchmaking:queue"
+	matchmakingJoinedAtKey = "matchmaking:joined_at"
+	matchmakingActiveKey   = "matchmaking:active:"
+	matchmakingActiveTTL   = 300 * time.Second
+	// matchmakingChangedChannel は Enqueue のたびに通知する pub/sub チャネル
+	matchmakingChangedChannel = "matchmaking:queue_changed"
 )
 
 type matchmakingRepository struct {
@@ -39,48 +146,162 @@ func NewMatchmakingRepository(rdb *redis.Client) repository.MatchmakingRepositor
 	return &matchmakingRepository{rdb: rdb}
 }
 
-func (r *matchmakingRepository) Enqueue(ctx context.Context, userID uuid.UUID) error {
-	return r.rdb.RPush(ctx, matchmakingQueueKey, userID.String()).Err()
+// queueKeys は1チーム teamSize 人の形式のキューと参加時刻のキーを返す（1以下は1対1）
+func queueKeys(teamSize int) (queue, joinedAt string) {
+	if teamSize <= 1 {
+		return matchmakingQueueKey, matchmakingJoinedAtKey
+	}
+	suffix := fmt.Sprintf(":%dv%d", teamSize, teamSize)
+	return matchmakingQueueKey + suffix, matchmakingJoinedAtKey + suffix
+}
+
+func (r *matchmakingRepository) Enqueue(ctx context.Context, entry entity.QueueEntry) error {
+	return r.add(ctx, entry, true)
+}
+
+func (r *matchmakingRepository) Requeue(ctx context.Context, entry entity.QueueEntry) error {
+	return r.add(ctx, entry, false)
+}
+
+// add はキューにユーザーを追加する。notify の場合は同じラウンドトリップで通知し、マッチングループを起こす
+func (r *matchmakingRepository) add(ctx context.Context, entry entity.QueueEntry, notify bool) error {
+	enqueuedAt := entry.EnqueuedAt
+	if enqueuedAt.IsZero() {
+		enqueuedAt = time.Now()
+	}
+	member := entry.UserID.String()
+	queueKey, joinedAtKey := queueKeys(entry.TeamSize)
+	pipe := r.rdb.TxPipeline()
+	pipe.ZAdd(ctx, queueKey, redis.Z{Score: float64(entry.Rate), Member: member})
+	pipe.HSet(ctx, joinedAtKey, member, enqueuedAt.UnixMilli())
+	if notify {
+		pipe.Publish(ctx, matchmakingChangedChannel, member)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("enqueue: %w", err)
+	}
+	return nil
 }
 
-func (r *matchmakingRepository) Dequeue(ctx context.Context) (uuid.UUID, uuid.UUID, error) {
-	raw, err := dequeueScript.Run(ctx, r.rdb, []string{matchmakingQueueKey}).Result()
+func (r *matchmakingRepository) Dequeue(
+	ctx context.Context,
+	window entity.RateWindow,
+	now time.Time,
+) (*entity.QueueEntry, *entity.QueueEntry, error) {
+	raw, err := dequeueScript.Run(ctx, r.rdb,
+		[]string{matchmakingQueueKey, matchmakingJoinedAtKey},
+		now.UnixMilli(), window.Base, window.WidenPerSec, window.Max,
+	).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
-			return uuid.Nil, uuid.Nil, nil
+			return nil, nil, nil
 		}
-		return uuid.Nil, uuid.Nil, fmt.Errorf("dequeue script: %w", err)
+		return nil, nil, fmt.Errorf("dequeue script: %w", err)
 	}
 
 	items, ok := raw.([]interface{})
-	if !ok || len(items) != 2 {
-		return uuid.Nil, uuid.Nil, nil
+	if !ok || len(items) != 6 {
+		return nil, nil, nil
 	}
 
-	result := make([]string, 2)
+	fields := make([]string, len(items))
 	for i, item := range items {
 		s, ok := item.(string)
 		if !ok || s == "" {
-			return uuid.Nil, uuid.Nil, nil
+			return nil, nil, nil
 		}
-		result[i] = s
+		fields[i] = s
 	}
 
-	firstID, err := uuid.Parse(result[0])
+	first, err := parseQueueEntry(fields[0], fields[1], fields[2])
 	if err != nil {
-		return uuid.Nil, uuid.Nil, fmt.Errorf("parse first uuid: %w", err)
+		return nil, nil, fmt.Errorf("parse first entry: %w", err)
 	}
+	second, err := parseQueueEntry(fields[3], fields[4], fields[5])
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse second entry: %w", err)
+	}
+
+	return first, second, nil
+}
 
-	secondID, err := uuid.Parse(result[1])
+func (r *matchmakingRepository) DequeueTeams(
+	ctx context.Context,
+	teamSize int,
+	window entity.RateWindow,
+	now time.Time,
+) ([]*entity.QueueEntry, error) {
+	queueKey, joinedAtKey := queueKeys(teamSize)
+	size := 2 * teamSize
+	raw, err := dequeueTeamsScript.Run(ctx, r.rdb,
+		[]string{queueKey, joinedAtKey},
+		now.UnixMilli(), window.Base, window.WidenPerSec, window.Max, size,
+	).Result()
 	if err != nil {
-		return uuid.Nil, uuid.Nil, fmt.Errorf("parse second uuid: %w", err)
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("dequeue teams script: %w", err)
+	}
+
+	items, ok := raw.([]interface{})
+	if !ok || len(items) != 3*size {
+		return nil, nil
 	}
 
-	return firstID, secondID, nil
+	entries := make([]*entity.QueueEntry, 0, size)
+	for i := 0; i < len(items); i += 3 {
+		fields := make([]string, 3)
+		for j := range fields {
+			s, ok := items[i+j].(string)
+			if !ok || s == "" {
+				return nil, nil
+			}
+			fields[j] = s
+		}
+		entry, err := parseQueueEntry(fields[0], fields[1], fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("parse entry %d: %w", i/3, err)
+		}
+		entry.TeamSize = teamSize
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseQueueEntry は Lua スクリプトが返す文字列からキューエントリを復元する
+func parseQueueEntry(id, rate, joinedAtMs string) (*entity.QueueEntry, error) {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("parse uuid: %w", err)
+	}
+	r, err := strconv.ParseFloat(rate, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse rate: %w", err)
+	}
+	ms, err := strconv.ParseFloat(joinedAtMs, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse joined_at: %w", err)
+	}
+	return &entity.QueueEntry{
+		UserID:     userID,
+		Rate:       int(r),
+		EnqueuedAt: time.UnixMilli(int64(ms)),
+	}, nil
 }
 
 func (r *matchmakingRepository) Remove(ctx context.Context, userID uuid.UUID) error {
-	return r.rdb.LRem(ctx, matchmakingQueueKey, 1, userID.String()).Err()
+	member := userID.String()
+	pipe := r.rdb.TxPipeline()
+	for teamSize := 1; teamSize <= entity.MaxTeamSize; teamSize++ {
+		queueKey, joinedAtKey := queueKeys(teamSize)
+		pipe.ZRem(ctx, queueKey, member)
+		pipe.HDel(ctx, joinedAtKey, member)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("remove: %w", err)
+	}
+	return nil
 }
 
 func (r *matchmakingRepository) SetActive(ctx context.Context, userID uuid.UUID) (bool, error) {
@@ -101,3 +322,41 @@ func (r *matchmakingRepository) SetActive(ctx context.Context, userID uuid.UUID)
 func (r *matchmakingRepository) ClearActive(ctx context.Context, userID uuid.UUID) error {
 	return r.rdb.Del(ctx, matchmakingActiveKey+userID.String()).Err()
 }
+
+func (r *matchmakingRepository) SubscribeQueueChanges(ctx context.Context) (<-chan struct{}, error) {
+	sub := r.rdb.Subscribe(ctx, matchmakingChangedChannel)
+	// 購読の確立を待ってから返す（直後の Enqueue の通知を取りこぼさないため）
+	if _, err := sub.Receive(ctx); err != nil {
+		if closeErr := sub.Close(); closeErr != nil {
+			log.Printf("matchmaking: failed to close subscription: %v", closeErr)
+		}
+		return nil, fmt.Errorf("subscribe queue changes: %w", err)
+	}
+
+	// バッファ 1 で送れなければ捨てることで、未処理の通知を1つにまとめる
+	out := make(chan struct{}, 1)
+	go func() {
+		defer close(out)
+		defer func() {
+			if err := sub.Close(); err != nil {
+				log.Printf("matchmaking: failed to close subscription: %v", err)
+			}
+		}()
+		ch := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-ch:
+				if !ok {
+					return
+				}
+				select {
+				case out <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return out, nil
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
 )
 
 func setupTestRedis(t *testing.T) *redis.Client {
@@ -42,59 +44,154 @@ func cleanupKeys(t *testing.T, rdb *redis.Client, keys ...string) {
 	}
 }
 
+var testRateWindow = entity.RateWindow{Base: 100, WidenPerSec: 10, Max: 500}
+
 func TestMatchmakingRepository_EnqueueAndDequeue(t *testing.T) {
 	rdb := setupTestRedis(t)
-	defer cleanupKeys(t, rdb, matchmakingQueueKey)
+	defer cleanupKeys(t, rdb, matchmakingQueueKey, matchmakingJoinedAtKey)
+	cleanupKeys(t, rdb, matchmakingQueueKey, matchmakingJoinedAtKey)
 
 	repo := NewMatchmakingRepository(rdb)
 	ctx := context.Background()
+	now := time.Now()
 
 	id1 := uuid.New()
 	id2 := uuid.New()
 
-	require.NoError(t, repo.Enqueue(ctx, id1))
-	require.NoError(t, repo.Enqueue(ctx, id2))
+	require.NoError(t, repo.Enqueue(ctx, entity.QueueEntry{UserID: id1, Rate: 1500, EnqueuedAt: now}))
+	require.NoError(t, repo.Enqueue(ctx, entity.QueueEntry{UserID: id2, Rate: 1550, EnqueuedAt: now}))
+
+	first, second, err := repo.Dequeue(ctx, testRateWindow, now)
+	require.NoError(t, err)
+	require.NotNil(t, first)
+	require.NotNil(t, second)
+	assert.Equal(t, id1, first.UserID, "lower rate should come first")
+	assert.Equal(t, 1500, first.Rate)
+	assert.Equal(t, id2, second.UserID)
+	assert.Equal(t, now.UnixMilli(), first.EnqueuedAt.UnixMilli())
+
+	n, err := rdb.ZCard(ctx, matchmakingQueueKey).Result()
+	require.NoError(t, err)
+	assert.Zero(t, n, "matched players should be removed from queue")
+}
+
+func TestMatchmakingRepository_Dequeue_OutsideWindow(t *testing.T) {
+	rdb := setupTestRedis(t)
+	defer cleanupKeys(t, rdb, matchmakingQueueKey, matchmakingJoinedAtKey)
+	cleanupKeys(t, rdb, matchmakingQueueKey, matchmakingJoinedAtKey)
+
+	repo := NewMatchmakingRepository(rdb)
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, repo.Enqueue(ctx, entity.QueueEntry{UserID: uuid.New(), Rate: 1200, EnqueuedAt: now}))
+	require.NoError(t, repo.Enqueue(ctx, entity.QueueEntry{UserID: uuid.New(), Rate: 1500, EnqueuedAt: now}))
 
-	first, second, err := repo.Dequeue(ctx)
+	first, second, err := repo.Dequeue(ctx, testRateWindow, now)
 	require.NoError(t, err)
-	assert.Equal(t, id1, first, "FIFO: first enqueued should be dequeued first")
-	assert.Equal(t, id2, second, "FIFO: second enqueued should be dequeued second")
+	assert.Nil(t, first, "rate diff 300 exceeds the initial window")
+	assert.Nil(t, second)
+
+	// 20 秒待つと許容幅は 100+10*20=300 まで広がる
+	first, second, err = repo.Dequeue(ctx, testRateWindow, now.Add(20*time.Second))
+	require.NoError(t, err)
+	require.NotNil(t, first)
+	require.NotNil(t, second)
+	assert.Equal(t, 1200, first.Rate)
+	assert.Equal(t, 1500, second.Rate)
+}
+
+func TestMatchmakingRepository_Dequeue_PrefersOldestWaiter(t *testing.T) {
+	rdb := setupTestRedis(t)
+	defer cleanupKeys(t, rdb, matchmakingQueueKey, matchmakingJoinedAtKey)
+	cleanupKeys(t, rdb, matchmakingQueueKey, matchmakingJoinedAtKey)
+
+	repo := NewMatchmakingRepository(rdb)
+	ctx := context.Background()
+	now := time.Now()
+
+	oldest := uuid.New()
+	require.NoError(t, repo.Enqueue(ctx, entity.QueueEntry{UserID: uuid.New(), Rate: 1500, EnqueuedAt: now}))
+	require.NoError(t, repo.Enqueue(ctx, entity.QueueEntry{UserID: uuid.New(), Rate: 1510, EnqueuedAt: now}))
+	require.NoError(t, repo.Enqueue(ctx, entity.QueueEntry{UserID: oldest, Rate: 1580, EnqueuedAt: now.Add(-time.Minute)}))
+
+	first, second, err := repo.Dequeue(ctx, testRateWindow, now)
+	require.NoError(t, err)
+	require.NotNil(t, first)
+	require.NotNil(t, second)
+	assert.Equal(t, oldest, second.UserID, "pair containing the longest waiter should be chosen")
+	assert.Equal(t, 1510, first.Rate, "among pairs with the longest waiter, the smallest rate difference wins")
 }
 
 func TestMatchmakingRepository_Dequeue_Empty(t *testing.T) {
 	rdb := setupTestRedis(t)
-	defer cleanupKeys(t, rdb, matchmakingQueueKey)
-	cleanupKeys(t, rdb, matchmakingQueueKey)
+	defer cleanupKeys(t, rdb, matchmakingQueueKey, matchmakingJoinedAtKey)
+	cleanupKeys(t, rdb, matchmakingQueueKey, matchmakingJoinedAtKey)
 
 	repo := NewMatchmakingRepository(rdb)
 	ctx := context.Background()
 
-	first, second, err := repo.Dequeue(ctx)
+	first, second, err := repo.Dequeue(ctx, testRateWindow, time.Now())
 	require.NoError(t, err)
-	assert.Equal(t, uuid.Nil, first)
-	assert.Equal(t, uuid.Nil, second)
+	assert.Nil(t, first)
+	assert.Nil(t, second)
 }
 
 func TestMatchmakingRepository_Dequeue_OnlyOneInQueue(t *testing.T) {
 	rdb := setupTestRedis(t)
-	defer cleanupKeys(t, rdb, matchmakingQueueKey)
-	cleanupKeys(t, rdb, matchmakingQueueKey)
+	defer cleanupKeys(t, rdb, matchmakingQueueKey, matchmakingJoinedAtKey)
+	cleanupKeys(t, rdb, matchmakingQueueKey, matchmakingJoinedAtKey)
 
 	repo := NewMatchmakingRepository(rdb)
 	ctx := context.Background()
 
 	id1 := uuid.New()
-	require.NoError(t, repo.Enqueue(ctx, id1))
+	require.NoError(t, repo.Enqueue(ctx, entity.QueueEntry{UserID: id1, Rate: 1500}))
 
-	first, second, err := repo.Dequeue(ctx)
+	first, second, err := repo.Dequeue(ctx, testRateWindow, time.Now())
 	require.NoError(t, err)
-	assert.Equal(t, uuid.Nil, first, "should return Nil when only one in queue")
-	assert.Equal(t, uuid.Nil, second)
+	assert.Nil(t, first, "should return nil when only one in queue")
+	assert.Nil(t, second)
+
+	// 1人だけの場合はキューに残る
+	_, err = rdb.ZScore(ctx, matchmakingQueueKey, id1.String()).Result()
+	require.NoError(t, err, "single user should remain in queue")
+}
 
-	// Verify the single user was pushed back (rollback)
-	val, err := rdb.LPop(ctx, matchmakingQueueKey).Result()
+func TestMatchmakingRepository_DequeueTeams(t *testing.T) {
+	rdb := setupTestRedis(t)
+	queueKey, joinedAtKey := queueKeys(2)
+	defer cleanupKeys(t, rdb, queueKey, joinedAtKey, matchmakingQueueKey, matchmakingJoinedAtKey)
+	cleanupKeys(t, rdb, queueKey, joinedAtKey, matchmakingQueueKey, matchmakingJoinedAtKey)
+
+	repo := NewMatchmakingRepository(rdb)
+	ctx := context.Background()
+	now := time.Now()
+
+	// 1対1のキューの参加者はチーム戦の対象にならない
+	require.NoError(t, repo.Enqueue(ctx, entity.QueueEntry{UserID: uuid.New(), Rate: 1500, EnqueuedAt: now}))
+	ids := make([]uuid.UUID, 0, 5)
+	for _, rate := range []int{1500, 1520, 1540, 1560, 2000} {
+		id := uuid.New()
+		ids = append(ids, id)
+		require.NoError(t, repo.Enqueue(ctx, entity.QueueEntry{UserID: id, Rate: rate, TeamSize: 2, EnqueuedAt: now}))
+	}
+
+	entries, err := repo.DequeueTeams(ctx, 2, testRateWindow, now)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	for i, e := range entries {
+		assert.Equal(t, ids[i], e.UserID)
+		assert.Equal(t, 2, e.TeamSize)
+	}
+
+	// 残りの1人では成立しない
+	entries, err = repo.DequeueTeams(ctx, 2, testRateWindow, now)
+	require.NoError(t, err)
+	assert.Nil(t, entries)
+	n, err := rdb.ZCard(ctx, matchmakingQueueKey).Result()
 	require.NoError(t, err)
-	assert.Equal(t, id1.String(), val, "single user should be pushed back to queue")
+	assert.Equal(t, int64(1), n, "1v1 queue is untouched")
 }
 
 func TestMatchmakingRepository_SetActive_First(t *testing.T) {
@@ -151,25 +248,69 @@ func TestMatchmakingRepository_ClearActiveAndReSetActive(t *testing.T) {
 
 func TestMatchmakingRepository_Remove(t *testing.T) {
 	rdb := setupTestRedis(t)
-	defer cleanupKeys(t, rdb, matchmakingQueueKey)
-	cleanupKeys(t, rdb, matchmakingQueueKey)
+	defer cleanupKeys(t, rdb, matchmakingQueueKey, matchmakingJoinedAtKey)
+	cleanupKeys(t, rdb, matchmakingQueueKey, matchmakingJoinedAtKey)
 
 	repo := NewMatchmakingRepository(rdb)
 	ctx := context.Background()
 
 	id1 := uuid.New()
 	id2 := uuid.New()
-	require.NoError(t, repo.Enqueue(ctx, id1))
-	require.NoError(t, repo.Enqueue(ctx, id2))
+	require.NoError(t, repo.Enqueue(ctx, entity.QueueEntry{UserID: id1, Rate: 1500}))
+	require.NoError(t, repo.Enqueue(ctx, entity.QueueEntry{UserID: id2, Rate: 1500}))
 
 	require.NoError(t, repo.Remove(ctx, id1))
 
 	// Only id2 should remain
-	val, err := rdb.LPop(ctx, matchmakingQueueKey).Result()
+	members, err := rdb.ZRange(ctx, matchmakingQueueKey, 0, -1).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{id2.String()}, members)
+
+	_, err = rdb.HGet(ctx, matchmakingJoinedAtKey, id1.String()).Result()
+	assert.Equal(t, redis.Nil, err, "joined_at should be removed with the queue entry")
+}
+
+func TestMatchmakingRepository_EnqueueNotifiesSubscribers(t *testing.T) {
+	rdb := setupTestRedis(t)
+	defer cleanupKeys(t, rdb, matchmakingQueueKey, matchmakingJoinedAtKey)
+	cleanupKeys(t, rdb, matchmakingQueueKey, matchmakingJoinedAtKey)
+	repo := NewMatchmakingRepository(rdb)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, err := repo.SubscribeQueueChanges(ctx)
 	require.NoError(t, err)
-	assert.Equal(t, id2.String(), val)
 
-	// Queue should be empty now
-	_, err = rdb.LPop(ctx, matchmakingQueueKey).Result()
-	assert.Equal(t, redis.Nil, err)
+	require.NoError(t, repo.Enqueue(ctx, entity.QueueEntry{UserID: uuid.New(), Rate: 1500}))
+
+	select {
+	case <-changes:
+	case <-time.After(2 * time.Second):
+		t.Fatal("enqueue did not notify subscribers")
+	}
+}
+
+func TestMatchmakingRepository_RequeueDoesNotNotify(t *testing.T) {
+	rdb := setupTestRedis(t)
+	defer cleanupKeys(t, rdb, matchmakingQueueKey, matchmakingJoinedAtKey)
+	cleanupKeys(t, rdb, matchmakingQueueKey, matchmakingJoinedAtKey)
+	repo := NewMatchmakingRepository(rdb)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, err := repo.SubscribeQueueChanges(ctx)
+	require.NoError(t, err)
+
+	joined := time.Now().Add(-time.Minute)
+	id := uuid.New()
+	require.NoError(t, repo.Requeue(ctx, entity.QueueEntry{UserID: id, Rate: 1500, EnqueuedAt: joined}))
+
+	select {
+	case <-changes:
+		t.Fatal("requeue should not notify subscribers")
+	case <-time.After(200 * time.Millisecond):
+	}
+	got, err := rdb.HGet(ctx, matchmakingJoinedAtKey, id.String()).Int64()
+	require.NoError(t, err)
+	assert.Equal(t, joined.UnixMilli(), got, "requeue keeps the original join time")
 }
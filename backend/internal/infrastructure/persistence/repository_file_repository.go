@@ -0,0 +1,59 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+)
+
+// listLatestRepositoryFilesByOwner は owner の最新の解析済みリポジトリのファイルを取得する
+// repositories / repository_files は Drizzle (frontend) が管理しており goose のスキーマに含まれないため、
+// sqlc ではなく database/sql で直接クエリする
+const listLatestRepositoryFilesByOwner = `
+SELECT rf.file_path, rf.content
+FROM repository_files rf
+WHERE rf.repository_id = (
+    SELECT r.id FROM repositories r
+    WHERE r.owner = $1
+    ORDER BY r.updated_at DESC
+    LIMIT 1
+)
+ORDER BY rf.file_path
+`
+
+type repositoryFileRepository struct {
+	db *sql.DB
+}
+
+func NewRepositoryFileRepository(db *sql.DB) repository.RepositoryFileRepository {
+	return &repositoryFileRepository{db: db}
+}
+
+func (r *repositoryFileRepository) ListLatestByOwner(ctx context.Context, owner string) ([]entity.RepositoryFile, error) {
+	rows, err := r.db.QueryContext(ctx, listLatestRepositoryFilesByOwner, owner)
+	if err != nil {
+		return nil, fmt.Errorf("query repository files: %w", err)
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			log.Printf("repository files: rows close error: %v", closeErr)
+		}
+	}()
+
+	var files []entity.RepositoryFile
+	for rows.Next() {
+		var f entity.RepositoryFile
+		if err := rows.Scan(&f.FilePath, &f.Content); err != nil {
+			return nil, fmt.Errorf("scan repository file: %w", err)
+		}
+		files = append(files, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate repository files: %w", err)
+	}
+	return files, nil
+}
@@ -0,0 +1,144 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+)
+
+const roomInviteKeyPrefix = "room_invite:"
+
+// createInviteScript は招待がなければ保存して 1 を返す Lua スクリプト
+// KEYS[1]: 招待キー, ARGV[1]: 作成者 ID, ARGV[2]: 作成時刻 (unix ms), ARGV[3]: 期限 (unix ms)
+var createInviteScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+  return 0
+end
+redis.call('HSET', KEYS[1], 'creator_id', ARGV[1], 'created_at', ARGV[2], 'expires_at', ARGV[3])
+redis.call('PEXPIREAT', KEYS[1], ARGV[3])
+return 1
+`)
+
+// claimInviteScript は未使用の招待に参加者とルームを記録して 1 を返す Lua スクリプト
+// 期限切れで消えた招待を HSETNX で作り直さないよう、先に存在を確かめる
+// KEYS[1]: 招待キー, ARGV[1]: ルーム ID, ARGV[2]: 参加者 ID
+var claimInviteScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+  return 0
+end
+if redis.call('HSETNX', KEYS[1], 'room_id', ARGV[1]) == 0 then
+  return 0
+end
+redis.call('HSET', KEYS[1], 'guest_id', ARGV[2])
+return 1
+`)
+
+// releaseInviteScript はルーム ID が ARGV[1] の場合に限り招待を未使用に戻す Lua スクリプト
+var releaseInviteScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'room_id') == ARGV[1] then
+  return redis.call('HDEL', KEYS[1], 'room_id', 'guest_id')
+end
+return 0
+`)
+
+// deleteUnclaimedInviteScript は未使用の招待に限り削除する Lua スクリプト
+var deleteUnclaimedInviteScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 or redis.call('HEXISTS', KEYS[1], 'room_id') == 1 then
+  return 0
+end
+return redis.call('DEL', KEYS[1])
+`)
+
+type roomInviteRepository struct {
+	rdb *redis.Client
+}
+
+func NewRoomInviteRepository(rdb *redis.Client) repository.RoomInviteRepository {
+	return &roomInviteRepository{rdb: rdb}
+}
+
+func roomInviteKey(code string) string {
+	return roomInviteKeyPrefix + code
+}
+
+func (r *roomInviteRepository) Create(ctx context.Context, invite *entity.RoomInvite) (bool, error) {
+	created, err := createInviteScript.Run(ctx, r.rdb, []string{roomInviteKey(invite.Code)},
+		invite.CreatorID.String(), invite.CreatedAt.UnixMilli(), invite.ExpiresAt.UnixMilli()).Int()
+	if err != nil {
+		return false, fmt.Errorf("create room invite: %w", err)
+	}
+	return created == 1, nil
+}
+
+func (r *roomInviteRepository) Get(ctx context.Context, code string) (*entity.RoomInvite, error) {
+	fields, err := r.rdb.HGetAll(ctx, roomInviteKey(code)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("get room invite: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	invite := &entity.RoomInvite{Code: code}
+	if invite.CreatorID, err = uuid.Parse(fields["creator_id"]); err != nil {
+		return nil, fmt.Errorf("parse invite creator_id: %w", err)
+	}
+	if invite.CreatedAt, err = parseUnixMilli(fields["created_at"]); err != nil {
+		return nil, fmt.Errorf("parse invite created_at: %w", err)
+	}
+	if invite.ExpiresAt, err = parseUnixMilli(fields["expires_at"]); err != nil {
+		return nil, fmt.Errorf("parse invite expires_at: %w", err)
+	}
+	// Redis の期限切れの削除は遅れることがあるため、期限を過ぎた招待は存在しないものとして扱う
+	if !invite.ExpiresAt.After(time.Now()) {
+		return nil, nil
+	}
+	if v, ok := fields["room_id"]; ok {
+		if invite.RoomID, err = uuid.Parse(v); err != nil {
+			return nil, fmt.Errorf("parse invite room_id: %w", err)
+		}
+	}
+	if v, ok := fields["guest_id"]; ok {
+		if invite.GuestID, err = uuid.Parse(v); err != nil {
+			return nil, fmt.Errorf("parse invite guest_id: %w", err)
+		}
+	}
+	return invite, nil
+}
+
+func (r *roomInviteRepository) Claim(ctx context.Context, code string, guestID, roomID uuid.UUID) (bool, error) {
+	claimed, err := claimInviteScript.Run(ctx, r.rdb, []string{roomInviteKey(code)}, roomID.String(), guestID.String()).Int()
+	if err != nil {
+		return false, fmt.Errorf("claim room invite: %w", err)
+	}
+	return claimed == 1, nil
+}
+
+func (r *roomInviteRepository) Release(ctx context.Context, code string, roomID uuid.UUID) error {
+	if err := releaseInviteScript.Run(ctx, r.rdb, []string{roomInviteKey(code)}, roomID.String()).Err(); err != nil {
+		return fmt.Errorf("release room invite: %w", err)
+	}
+	return nil
+}
+
+func (r *roomInviteRepository) DeleteUnclaimed(ctx context.Context, code string) (bool, error) {
+	deleted, err := deleteUnclaimedInviteScript.Run(ctx, r.rdb, []string{roomInviteKey(code)}).Int()
+	if err != nil {
+		return false, fmt.Errorf("delete room invite: %w", err)
+	}
+	return deleted == 1, nil
+}
+
+func parseUnixMilli(s string) (time.Time, error) {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(ms), nil
+}
@@ -0,0 +1,143 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+)
+
+func newTestInvite(code string, ttl time.Duration) *entity.RoomInvite {
+	now := time.Now().Truncate(time.Millisecond)
+	return &entity.RoomInvite{
+		Code:      code,
+		CreatorID: uuid.New(),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+}
+
+func TestRoomInviteRepository_CreateAndGet(t *testing.T) {
+	rdb := setupTestRedis(t)
+	repo := NewRoomInviteRepository(rdb)
+	ctx := context.Background()
+	cleanupKeys(t, rdb, roomInviteKey("INV1"))
+
+	invite := newTestInvite("INV1", time.Minute)
+	created, err := repo.Create(ctx, invite)
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	// 同じコードでは作れない
+	created, err = repo.Create(ctx, newTestInvite("INV1", time.Minute))
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	got, err := repo.Get(ctx, "INV1")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, invite.CreatorID, got.CreatorID)
+	assert.True(t, invite.CreatedAt.Equal(got.CreatedAt))
+	assert.True(t, invite.ExpiresAt.Equal(got.ExpiresAt))
+	assert.False(t, got.Redeemed())
+
+	missing, err := repo.Get(ctx, "MISSING")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestRoomInviteRepository_ClaimOnce(t *testing.T) {
+	rdb := setupTestRedis(t)
+	repo := NewRoomInviteRepository(rdb)
+	ctx := context.Background()
+	cleanupKeys(t, rdb, roomInviteKey("INV2"), roomInviteKey("GONE"))
+
+	_, err := repo.Create(ctx, newTestInvite("INV2", time.Minute))
+	require.NoError(t, err)
+
+	guest, roomID := uuid.New(), uuid.New()
+	claimed, err := repo.Claim(ctx, "INV2", guest, roomID)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = repo.Claim(ctx, "INV2", uuid.New(), uuid.New())
+	require.NoError(t, err)
+	assert.False(t, claimed, "second seat is already taken")
+
+	got, err := repo.Get(ctx, "INV2")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.True(t, got.Redeemed())
+	assert.Equal(t, guest, got.GuestID)
+	assert.Equal(t, roomID, got.RoomID)
+
+	// 使用済みの招待は削除できない
+	deleted, err := repo.DeleteUnclaimed(ctx, "INV2")
+	require.NoError(t, err)
+	assert.False(t, deleted)
+
+	// 存在しない招待は作り直さない
+	claimed, err = repo.Claim(ctx, "GONE", guest, roomID)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	n, err := rdb.Exists(ctx, roomInviteKey("GONE")).Result()
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestRoomInviteRepository_Release(t *testing.T) {
+	rdb := setupTestRedis(t)
+	repo := NewRoomInviteRepository(rdb)
+	ctx := context.Background()
+	cleanupKeys(t, rdb, roomInviteKey("INV3"))
+
+	_, err := repo.Create(ctx, newTestInvite("INV3", time.Minute))
+	require.NoError(t, err)
+	roomID := uuid.New()
+	_, err = repo.Claim(ctx, "INV3", uuid.New(), roomID)
+	require.NoError(t, err)
+
+	// 別のルーム ID では戻さない
+	require.NoError(t, repo.Release(ctx, "INV3", uuid.New()))
+	got, err := repo.Get(ctx, "INV3")
+	require.NoError(t, err)
+	assert.True(t, got.Redeemed())
+
+	require.NoError(t, repo.Release(ctx, "INV3", roomID))
+	got, err = repo.Get(ctx, "INV3")
+	require.NoError(t, err)
+	assert.False(t, got.Redeemed())
+	assert.Equal(t, uuid.Nil, got.GuestID)
+
+	deleted, err := repo.DeleteUnclaimed(ctx, "INV3")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	got, err = repo.Get(ctx, "INV3")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestRoomInviteRepository_Expired(t *testing.T) {
+	rdb := setupTestRedis(t)
+	repo := NewRoomInviteRepository(rdb)
+	ctx := context.Background()
+	cleanupKeys(t, rdb, roomInviteKey("INV4"))
+
+	invite := newTestInvite("INV4", 30*time.Second)
+	_, err := repo.Create(ctx, invite)
+	require.NoError(t, err)
+	ttl, err := rdb.PTTL(ctx, roomInviteKey("INV4")).Result()
+	require.NoError(t, err)
+	assert.Positive(t, ttl, "invites expire")
+	assert.LessOrEqual(t, ttl, 30*time.Second)
+
+	// Redis にまだ残っていても、期限を過ぎた招待は返さない
+	require.NoError(t, rdb.HSet(ctx, roomInviteKey("INV4"), "expires_at", time.Now().UnixMilli()).Err())
+	got, err := repo.Get(ctx, "INV4")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
@@ -2,7 +2,11 @@ package persistence
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,20 +16,37 @@ import (
 	"github.com/tobakuro/hackathon_nulabcup/backend/internal/infrastructure/postgres/sqlc"
 )
 
-const roomStateTTL = 24 * time.Hour
+const (
+	roomStateTTL = 24 * time.Hour
+	// recoverableRoomsKey は試合の状態が保存されているルーム ID の集合（再起動後の復旧に使う）
+	recoverableRoomsKey = "rooms:recoverable"
+)
 
 type roomRepository struct {
+	db  *sql.DB
 	q   *sqlc.Queries
 	rdb *redis.Client
 }
 
-func NewRoomRepository(q *sqlc.Queries, rdb *redis.Client) repository.RoomRepository {
-	return &roomRepository{q: q, rdb: rdb}
+func NewRoomRepository(db *sql.DB, q *sqlc.Queries, rdb *redis.Client) repository.RoomRepository {
+	return &roomRepository{db: db, q: q, rdb: rdb}
 }
 
+// Create はルームとチーム戦の参加者を1トランザクションで保存する
 func (r *roomRepository) Create(ctx context.Context, room *entity.Room) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("room repository: rollback error: %v", rbErr)
+		}
+	}()
+	qtx := r.q.WithTx(tx)
+
 	// PostgreSQL に先に永続化して正確なタイムスタンプを取得
-	created, err := r.q.CreateRoom(ctx, sqlc.CreateRoomParams{
+	created, err := qtx.CreateRoom(ctx, sqlc.CreateRoomParams{
 		ID:        room.ID,
 		Player1ID: room.Player1ID,
 		Player2ID: room.Player2ID,
@@ -34,12 +55,27 @@ func (r *roomRepository) Create(ctx context.Context, room *entity.Room) error {
 	if err != nil {
 		return fmt.Errorf("create room: %w", err)
 	}
+	positions := [2]int32{}
+	for _, p := range room.Players {
+		if err := qtx.CreateRoomPlayer(ctx, sqlc.CreateRoomPlayerParams{
+			RoomID:   room.ID,
+			UserID:   p.UserID,
+			Team:     int32(p.Team),
+			Position: positions[p.Team],
+		}); err != nil {
+			return fmt.Errorf("create room player %s: %w", p.UserID, err)
+		}
+		positions[p.Team]++
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
 
 	room.CreatedAt = created.CreatedAt
 	room.UpdatedAt = created.UpdatedAt
 
 	// Redis に状態を保存
-	key := fmt.Sprintf("room:%s:state", room.ID.String())
+	key := roomStateKey(room.ID)
 	if err := r.rdb.HSet(ctx, key, map[string]any{
 		"player1_id": room.Player1ID.String(),
 		"player2_id": room.Player2ID.String(),
@@ -61,12 +97,113 @@ func (r *roomRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.Roo
 	if err != nil {
 		return nil, fmt.Errorf("get room by id: %w", err)
 	}
-	return &entity.Room{
+	players, err := r.q.ListRoomPlayers(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("list room players: %w", err)
+	}
+	room := &entity.Room{
 		ID:        row.ID,
 		Player1ID: row.Player1ID,
 		Player2ID: row.Player2ID,
 		Status:    entity.RoomStatus(row.Status),
+		EndReason: entity.RoomEndReason(row.EndReason.String),
 		CreatedAt: row.CreatedAt,
 		UpdatedAt: row.UpdatedAt,
-	}, nil
+	}
+	for _, p := range players {
+		room.Players = append(room.Players, entity.RoomPlayer{UserID: p.UserID, Team: int(p.Team)})
+	}
+	return room, nil
+}
+
+func (r *roomRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status entity.RoomStatus, reason entity.RoomEndReason) error {
+	if err := r.q.UpdateRoomStatus(ctx, sqlc.UpdateRoomStatusParams{
+		ID:        id,
+		Status:    string(status),
+		EndReason: sql.NullString{String: string(reason), Valid: reason != entity.RoomEndReasonNone},
+	}); err != nil {
+		return fmt.Errorf("update room status: %w", err)
+	}
+
+	// Redis の状態も合わせる（TTL は最後の更新から数え直す）
+	key := roomStateKey(id)
+	pipe := r.rdb.TxPipeline()
+	pipe.HSet(ctx, key, map[string]any{
+		"status":     string(status),
+		"end_reason": string(reason),
+		"updated_at": time.Now().Format("2006-01-02T15:04:05Z07:00"),
+	})
+	pipe.Expire(ctx, key, roomStateTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis update room status: %w", err)
+	}
+	return nil
+}
+
+// SaveSnapshot は room:{id}:state の snapshot フィールドに試合の状態を JSON で保存する
+func (r *roomRepository) SaveSnapshot(ctx context.Context, snapshot *entity.RoomSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("marshal room snapshot: %w", err)
+	}
+	key := roomStateKey(snapshot.RoomID)
+	pipe := r.rdb.TxPipeline()
+	pipe.HSet(ctx, key, map[string]any{
+		"snapshot":   data,
+		"turn":       snapshot.CompletedTurns,
+		"updated_at": snapshot.SavedAt.Format("2006-01-02T15:04:05Z07:00"),
+	})
+	pipe.Expire(ctx, key, roomStateTTL)
+	pipe.SAdd(ctx, recoverableRoomsKey, snapshot.RoomID.String())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis save room snapshot: %w", err)
+	}
+	return nil
+}
+
+func (r *roomRepository) GetSnapshot(ctx context.Context, id uuid.UUID) (*entity.RoomSnapshot, error) {
+	data, err := r.rdb.HGet(ctx, roomStateKey(id), "snapshot").Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("redis get room snapshot: %w", err)
+	}
+	var snapshot entity.RoomSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("unmarshal room snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+func (r *roomRepository) DeleteSnapshot(ctx context.Context, id uuid.UUID) error {
+	pipe := r.rdb.TxPipeline()
+	pipe.HDel(ctx, roomStateKey(id), "snapshot", "turn")
+	pipe.SRem(ctx, recoverableRoomsKey, id.String())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis delete room snapshot: %w", err)
+	}
+	return nil
+}
+
+// ListSnapshotRoomIDs は集合に残った不正な ID を読み飛ばす
+// 状態の TTL が切れたルームも含まれるため、GetSnapshot が nil を返した場合は DeleteSnapshot で外すこと
+func (r *roomRepository) ListSnapshotRoomIDs(ctx context.Context) ([]uuid.UUID, error) {
+	members, err := r.rdb.SMembers(ctx, recoverableRoomsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis list recoverable rooms: %w", err)
+	}
+	ids := make([]uuid.UUID, 0, len(members))
+	for _, m := range members {
+		id, err := uuid.Parse(m)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func roomStateKey(id uuid.UUID) string {
+	return fmt.Sprintf("room:%s:state", id.String())
 }
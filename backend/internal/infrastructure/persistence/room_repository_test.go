@@ -0,0 +1,55 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+)
+
+func TestRoomRepository_Snapshot(t *testing.T) {
+	rdb := setupTestRedis(t)
+	repo := NewRoomRepository(nil, nil, rdb)
+	ctx := context.Background()
+	roomID := uuid.New()
+	defer cleanupKeys(t, rdb, roomStateKey(roomID))
+
+	got, err := repo.GetSnapshot(ctx, roomID)
+	require.NoError(t, err)
+	assert.Nil(t, got, "no snapshot before the first checkpoint")
+
+	snapshot := &entity.RoomSnapshot{
+		RoomID:         roomID,
+		Rules:          entity.DefaultMatchRules(),
+		CompletedTurns: 1,
+		SavedAt:        time.Now().UTC().Truncate(time.Second),
+		Players: []entity.RoomSnapshotPlayer{
+			{UserID: uuid.New(), GitHubLogin: "alice", GnuBalance: 110, CorrectCount: 1, GnuEarned: 10},
+			{UserID: uuid.New(), GitHubLogin: "bob", GnuBalance: 95, GnuEarned: -5},
+		},
+		Turns: []entity.MatchTurn{{Turn: 1, Bet: 10, GnuDelta: 10, IsCorrect: true}},
+	}
+	require.NoError(t, repo.SaveSnapshot(ctx, snapshot))
+
+	got, err = repo.GetSnapshot(ctx, roomID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, snapshot.Players, got.Players)
+	assert.Equal(t, snapshot.Rules, got.Rules)
+	assert.Equal(t, 1, got.CompletedTurns)
+	ids, err := repo.ListSnapshotRoomIDs(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, ids, roomID)
+
+	require.NoError(t, repo.DeleteSnapshot(ctx, roomID))
+	got, err = repo.GetSnapshot(ctx, roomID)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	ids, err = repo.ListSnapshotRoomIDs(ctx)
+	require.NoError(t, err)
+	assert.NotContains(t, ids, roomID)
+}
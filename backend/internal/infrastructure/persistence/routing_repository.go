@@ -0,0 +1,170 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+)
+
+const (
+	instanceChannelPrefix = "instance:"
+	matchmakingConnPrefix = "matchmaking:conn:"
+	relayBufferSize       = 256
+)
+
+// claimOwnerScript は担当がいなければ登録し、担当インスタンスの ID を返す Lua スクリプト
+// KEYS[1]: 担当キー, ARGV[1]: インスタンス ID, ARGV[2]: TTL (ミリ秒)
+var claimOwnerScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+  return ARGV[1]
+end
+return redis.call('GET', KEYS[1])
+`)
+
+// refreshIfOwnerScript は値が ARGV[1] の場合に限り TTL を延長する Lua スクリプト
+var refreshIfOwnerScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// deleteIfOwnerScript は値が ARGV[1] の場合に限り削除する Lua スクリプト
+var deleteIfOwnerScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+type routingRepository struct {
+	rdb *redis.Client
+}
+
+func NewRoutingRepository(rdb *redis.Client) repository.RoutingRepository {
+	return &routingRepository{rdb: rdb}
+}
+
+func roomOwnerKey(roomID uuid.UUID) string {
+	return fmt.Sprintf("room:%s:owner", roomID.String())
+}
+
+func (r *routingRepository) ClaimOwner(ctx context.Context, roomID uuid.UUID, instanceID string, ttl time.Duration) (string, error) {
+	owner, err := claimOwnerScript.Run(ctx, r.rdb, []string{roomOwnerKey(roomID)}, instanceID, ttl.Milliseconds()).Text()
+	if err != nil {
+		return "", fmt.Errorf("claim room owner: %w", err)
+	}
+	return owner, nil
+}
+
+func (r *routingRepository) GetOwner(ctx context.Context, roomID uuid.UUID) (string, error) {
+	owner, err := r.rdb.Get(ctx, roomOwnerKey(roomID)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", nil
+		}
+		return "", fmt.Errorf("get room owner: %w", err)
+	}
+	return owner, nil
+}
+
+func (r *routingRepository) RefreshOwner(ctx context.Context, roomID uuid.UUID, instanceID string, ttl time.Duration) error {
+	if err := refreshIfOwnerScript.Run(ctx, r.rdb, []string{roomOwnerKey(roomID)}, instanceID, ttl.Milliseconds()).Err(); err != nil {
+		return fmt.Errorf("refresh room owner: %w", err)
+	}
+	return nil
+}
+
+func (r *routingRepository) ReleaseOwner(ctx context.Context, roomID uuid.UUID, instanceID string) error {
+	if err := deleteIfOwnerScript.Run(ctx, r.rdb, []string{roomOwnerKey(roomID)}, instanceID).Err(); err != nil {
+		return fmt.Errorf("release room owner: %w", err)
+	}
+	return nil
+}
+
+func (r *routingRepository) SetUserInstance(ctx context.Context, userID uuid.UUID, instanceID string, ttl time.Duration) error {
+	if err := r.rdb.Set(ctx, matchmakingConnPrefix+userID.String(), instanceID, ttl).Err(); err != nil {
+		return fmt.Errorf("set user instance: %w", err)
+	}
+	return nil
+}
+
+func (r *routingRepository) GetUserInstance(ctx context.Context, userID uuid.UUID) (string, error) {
+	instanceID, err := r.rdb.Get(ctx, matchmakingConnPrefix+userID.String()).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", nil
+		}
+		return "", fmt.Errorf("get user instance: %w", err)
+	}
+	return instanceID, nil
+}
+
+func (r *routingRepository) ClearUserInstance(ctx context.Context, userID uuid.UUID, instanceID string) error {
+	if err := deleteIfOwnerScript.Run(ctx, r.rdb, []string{matchmakingConnPrefix + userID.String()}, instanceID).Err(); err != nil {
+		return fmt.Errorf("clear user instance: %w", err)
+	}
+	return nil
+}
+
+func (r *routingRepository) Publish(ctx context.Context, instanceID string, msg entity.RelayMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal relay message: %w", err)
+	}
+	if err := r.rdb.Publish(ctx, instanceChannelPrefix+instanceID, data).Err(); err != nil {
+		return fmt.Errorf("publish relay message: %w", err)
+	}
+	return nil
+}
+
+func (r *routingRepository) Subscribe(ctx context.Context, instanceID string) (<-chan entity.RelayMessage, error) {
+	sub := r.rdb.Subscribe(ctx, instanceChannelPrefix+instanceID)
+	// 購読の確立を待ってから返す（直後に届くメッセージを取りこぼさないため）
+	if _, err := sub.Receive(ctx); err != nil {
+		if closeErr := sub.Close(); closeErr != nil {
+			log.Printf("routing: failed to close subscription: %v", closeErr)
+		}
+		return nil, fmt.Errorf("subscribe %s: %w", instanceID, err)
+	}
+
+	out := make(chan entity.RelayMessage, relayBufferSize)
+	go func() {
+		defer close(out)
+		defer func() {
+			if err := sub.Close(); err != nil {
+				log.Printf("routing: failed to close subscription: %v", err)
+			}
+		}()
+		ch := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-ch:
+				if !ok {
+					return
+				}
+				var msg entity.RelayMessage
+				if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+					log.Printf("routing: invalid relay message: %v", err)
+					continue
+				}
+				select {
+				case out <- msg:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,91 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+)
+
+func TestRoutingRepository_ClaimOwner(t *testing.T) {
+	rdb := setupTestRedis(t)
+	repo := NewRoutingRepository(rdb)
+	ctx := context.Background()
+	roomID := uuid.New()
+	defer cleanupKeys(t, rdb, roomOwnerKey(roomID))
+
+	owner, err := repo.ClaimOwner(ctx, roomID, "a", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "a", owner)
+
+	// 2台目は既存の担当を受け取る
+	owner, err = repo.ClaimOwner(ctx, roomID, "b", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "a", owner)
+
+	// 担当以外は解放できない
+	require.NoError(t, repo.ReleaseOwner(ctx, roomID, "b"))
+	owner, err = repo.GetOwner(ctx, roomID)
+	require.NoError(t, err)
+	assert.Equal(t, "a", owner)
+
+	require.NoError(t, repo.ReleaseOwner(ctx, roomID, "a"))
+	owner, err = repo.GetOwner(ctx, roomID)
+	require.NoError(t, err)
+	assert.Empty(t, owner)
+}
+
+func TestRoutingRepository_UserInstance(t *testing.T) {
+	rdb := setupTestRedis(t)
+	repo := NewRoutingRepository(rdb)
+	ctx := context.Background()
+	userID := uuid.New()
+	defer cleanupKeys(t, rdb, matchmakingConnPrefix+userID.String())
+
+	require.NoError(t, repo.SetUserInstance(ctx, userID, "a", time.Minute))
+	// 別インスタンスへ再接続した後に古いインスタンスが消しても登録は残る
+	require.NoError(t, repo.SetUserInstance(ctx, userID, "b", time.Minute))
+	require.NoError(t, repo.ClearUserInstance(ctx, userID, "a"))
+
+	got, err := repo.GetUserInstance(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, "b", got)
+
+	require.NoError(t, repo.ClearUserInstance(ctx, userID, "b"))
+	got, err = repo.GetUserInstance(ctx, userID)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestRoutingRepository_PublishSubscribe(t *testing.T) {
+	rdb := setupTestRedis(t)
+	repo := NewRoutingRepository(rdb)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := repo.Subscribe(ctx, "instance-test")
+	require.NoError(t, err)
+
+	msg := entity.RelayMessage{
+		Kind:   entity.RelayKindPlayerMessage,
+		From:   "other",
+		ConnID: "c1",
+		RoomID: uuid.New(),
+		UserID: uuid.New(),
+		Data:   []byte(`{"type":"act_submit_answer"}`),
+	}
+	require.NoError(t, repo.Publish(ctx, "instance-test", msg))
+
+	select {
+	case got := <-ch:
+		assert.Equal(t, msg.Kind, got.Kind)
+		assert.Equal(t, msg.ConnID, got.ConnID)
+		assert.JSONEq(t, string(msg.Data), string(got.Data))
+	case <-time.After(2 * time.Second):
+		t.Fatal("relay message not received")
+	}
+}
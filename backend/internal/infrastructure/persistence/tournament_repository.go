@@ -0,0 +1,228 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/infrastructure/postgres/sqlc"
+)
+
+type tournamentRepository struct {
+	db *sql.DB
+	q  *sqlc.Queries
+}
+
+func NewTournamentRepository(db *sql.DB, q *sqlc.Queries) repository.TournamentRepository {
+	return &tournamentRepository{db: db, q: q}
+}
+
+func (r *tournamentRepository) Create(ctx context.Context, tournament *entity.Tournament) error {
+	created, err := r.q.CreateTournament(ctx, sqlc.CreateTournamentParams{
+		ID:        tournament.ID,
+		Name:      tournament.Name,
+		CreatedBy: tournament.CreatedBy,
+	})
+	if err != nil {
+		return fmt.Errorf("create tournament: %w", err)
+	}
+	*tournament = toEntityTournament(created)
+	return nil
+}
+
+func (r *tournamentRepository) List(ctx context.Context, limit int) ([]*entity.Tournament, error) {
+	rows, err := r.q.ListTournaments(ctx, int32(limit))
+	if err != nil {
+		return nil, fmt.Errorf("list tournaments: %w", err)
+	}
+	tournaments := make([]*entity.Tournament, 0, len(rows))
+	for _, row := range rows {
+		t := toEntityTournament(row)
+		tournaments = append(tournaments, &t)
+	}
+	return tournaments, nil
+}
+
+func (r *tournamentRepository) GetBracket(ctx context.Context, id uuid.UUID) (*entity.TournamentBracket, error) {
+	row, err := r.q.GetTournamentByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get tournament by id: %w", err)
+	}
+	return loadBracket(ctx, r.q, row)
+}
+
+// Update は tournaments の行を FOR UPDATE でロックし、同じトーナメントへの更新を直列化する
+func (r *tournamentRepository) Update(
+	ctx context.Context,
+	id uuid.UUID,
+	fn func(*entity.TournamentBracket) error,
+) (*entity.TournamentBracket, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("tournament repository: rollback error: %v", rbErr)
+		}
+	}()
+	qtx := r.q.WithTx(tx)
+
+	row, err := qtx.LockTournament(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("lock tournament: %w", err)
+	}
+	bracket, err := loadBracket(ctx, qtx, row)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(bracket); err != nil {
+		return nil, err
+	}
+
+	t := bracket.Tournament
+	if err := qtx.UpdateTournament(ctx, sqlc.UpdateTournamentParams{
+		ID:         id,
+		Status:     string(t.Status),
+		WinnerID:   t.WinnerID,
+		StartedAt:  toNullTime(t.StartedAt),
+		FinishedAt: toNullTime(t.FinishedAt),
+	}); err != nil {
+		return nil, fmt.Errorf("update tournament: %w", err)
+	}
+	for _, p := range bracket.Participants {
+		if err := qtx.UpsertTournamentParticipant(ctx, sqlc.UpsertTournamentParticipantParams{
+			TournamentID: id,
+			UserID:       p.UserID,
+			Seed:         sql.NullInt32{Int32: int32(p.Seed), Valid: p.Seed > 0},
+			RegisteredAt: p.RegisteredAt,
+		}); err != nil {
+			return nil, fmt.Errorf("upsert tournament participant %s: %w", p.UserID, err)
+		}
+	}
+	for _, m := range bracket.Matches {
+		if err := qtx.UpsertTournamentMatch(ctx, sqlc.UpsertTournamentMatchParams{
+			ID:           m.ID,
+			TournamentID: id,
+			Round:        int32(m.Round),
+			Position:     int32(m.Position),
+			Player1ID:    m.Player1ID,
+			Player2ID:    m.Player2ID,
+			RoomID:       m.RoomID,
+			WinnerID:     m.WinnerID,
+			Status:       string(m.Status),
+			Result:       sql.NullString{String: string(m.Result), Valid: m.Result != entity.TournamentMatchResultNone},
+			Attempts:     int32(m.Attempts),
+		}); err != nil {
+			return nil, fmt.Errorf("upsert tournament match %d-%d: %w", m.Round, m.Position, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return bracket, nil
+}
+
+func (r *tournamentRepository) GetMatchByRoomID(ctx context.Context, roomID uuid.UUID) (*entity.TournamentMatch, error) {
+	row, err := r.q.GetTournamentMatchByRoomID(ctx, uuid.NullUUID{UUID: roomID, Valid: true})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get tournament match by room id: %w", err)
+	}
+	return toEntityTournamentMatch(row), nil
+}
+
+func (r *tournamentRepository) ListStaleMatches(ctx context.Context, createdBefore time.Time) ([]*entity.TournamentMatch, error) {
+	rows, err := r.q.ListStaleTournamentMatches(ctx, createdBefore)
+	if err != nil {
+		return nil, fmt.Errorf("list stale tournament matches: %w", err)
+	}
+	matches := make([]*entity.TournamentMatch, 0, len(rows))
+	for _, row := range rows {
+		matches = append(matches, toEntityTournamentMatch(row))
+	}
+	return matches, nil
+}
+
+func loadBracket(ctx context.Context, q *sqlc.Queries, row sqlc.Tournament) (*entity.TournamentBracket, error) {
+	participants, err := q.ListTournamentParticipants(ctx, row.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list tournament participants: %w", err)
+	}
+	matches, err := q.ListTournamentMatches(ctx, row.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list tournament matches: %w", err)
+	}
+	bracket := &entity.TournamentBracket{
+		Tournament:   toEntityTournament(row),
+		Participants: make([]entity.TournamentParticipant, 0, len(participants)),
+		Matches:      make([]*entity.TournamentMatch, 0, len(matches)),
+	}
+	for _, p := range participants {
+		bracket.Participants = append(bracket.Participants, entity.TournamentParticipant{
+			RegisteredAt: p.RegisteredAt,
+			GitHubLogin:  p.GithubLogin,
+			UserID:       p.UserID,
+			Rate:         int(p.Rate),
+			Seed:         int(p.Seed.Int32),
+		})
+	}
+	for _, m := range matches {
+		bracket.Matches = append(bracket.Matches, toEntityTournamentMatch(m))
+	}
+	return bracket, nil
+}
+
+func toEntityTournament(row sqlc.Tournament) entity.Tournament {
+	return entity.Tournament{
+		ID:         row.ID,
+		Name:       row.Name,
+		Status:     entity.TournamentStatus(row.Status),
+		CreatedBy:  row.CreatedBy,
+		WinnerID:   row.WinnerID,
+		StartedAt:  fromNullTime(row.StartedAt),
+		FinishedAt: fromNullTime(row.FinishedAt),
+		CreatedAt:  row.CreatedAt,
+		UpdatedAt:  row.UpdatedAt,
+	}
+}
+
+func toEntityTournamentMatch(row sqlc.TournamentMatch) *entity.TournamentMatch {
+	return &entity.TournamentMatch{
+		ID:           row.ID,
+		TournamentID: row.TournamentID,
+		Round:        int(row.Round),
+		Position:     int(row.Position),
+		Player1ID:    row.Player1ID,
+		Player2ID:    row.Player2ID,
+		RoomID:       row.RoomID,
+		WinnerID:     row.WinnerID,
+		Status:       entity.TournamentMatchStatus(row.Status),
+		Result:       entity.TournamentMatchResult(row.Result.String),
+		Attempts:     int(row.Attempts),
+		UpdatedAt:    row.UpdatedAt,
+	}
+}
+
+func toNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+func fromNullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,68 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+)
+
+type tournamentUpdateRepository struct {
+	rdb *redis.Client
+}
+
+func NewTournamentUpdateRepository(rdb *redis.Client) repository.TournamentUpdateRepository {
+	return &tournamentUpdateRepository{rdb: rdb}
+}
+
+func (r *tournamentUpdateRepository) Publish(ctx context.Context, tournamentID uuid.UUID) error {
+	if err := r.rdb.Publish(ctx, tournamentUpdatedChannel(tournamentID), tournamentID.String()).Err(); err != nil {
+		return fmt.Errorf("redis publish tournament update: %w", err)
+	}
+	return nil
+}
+
+func (r *tournamentUpdateRepository) Subscribe(ctx context.Context, tournamentID uuid.UUID) (<-chan struct{}, error) {
+	sub := r.rdb.Subscribe(ctx, tournamentUpdatedChannel(tournamentID))
+	// 購読の確立を待ってから返す（直後の更新の通知を取りこぼさないため）
+	if _, err := sub.Receive(ctx); err != nil {
+		if closeErr := sub.Close(); closeErr != nil {
+			log.Printf("tournament: failed to close subscription: %v", closeErr)
+		}
+		return nil, fmt.Errorf("subscribe tournament updates: %w", err)
+	}
+
+	// バッファ 1 で送れなければ捨てることで、未処理の通知を1つにまとめる
+	out := make(chan struct{}, 1)
+	go func() {
+		defer close(out)
+		defer func() {
+			if err := sub.Close(); err != nil {
+				log.Printf("tournament: failed to close subscription: %v", err)
+			}
+		}()
+		ch := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-ch:
+				if !ok {
+					return
+				}
+				select {
+				case out <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+func tournamentUpdatedChannel(id uuid.UUID) string {
+	return fmt.Sprintf("tournament:%s:updated", id.String())
+}
@@ -0,0 +1,43 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTournamentUpdateRepository_PublishAndSubscribe(t *testing.T) {
+	rdb := setupTestRedis(t)
+	repo := NewTournamentUpdateRepository(rdb)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	id := uuid.New()
+	ch, err := repo.Subscribe(ctx, id)
+	require.NoError(t, err)
+
+	// 別のトーナメントの更新は届かない
+	require.NoError(t, repo.Publish(ctx, uuid.New()))
+	require.NoError(t, repo.Publish(ctx, id))
+	select {
+	case <-ch:
+	case <-time.After(2 * time.Second):
+		t.Fatal("update was not delivered")
+	}
+	select {
+	case <-ch:
+		t.Fatal("unexpected update")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		require.False(t, ok)
+	case <-time.After(2 * time.Second):
+		t.Fatal("channel was not closed")
+	}
+}
@@ -1,8 +1,13 @@
 package persistence
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"log"
+	"slices"
 
 	"github.com/google/uuid"
 	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
@@ -11,11 +16,12 @@ import (
 )
 
 type userRepository struct {
-	q *sqlc.Queries
+	db *sql.DB
+	q  *sqlc.Queries
 }
 
-func NewUserRepository(q *sqlc.Queries) repository.UserRepository {
-	return &userRepository{q: q}
+func NewUserRepository(db *sql.DB, q *sqlc.Queries) repository.UserRepository {
+	return &userRepository{db: db, q: q}
 }
 
 func (r *userRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.User, error) {
@@ -54,27 +60,82 @@ func (r *userRepository) Create(ctx context.Context, user *entity.User) error {
 	user.ID = created.ID
 	user.GnuBalance = int(created.GnuBalance)
 	user.Rate = int(created.Rate)
+	user.RatingDeviation = created.RatingDeviation
+	user.RatingVolatility = created.RatingVolatility
 	user.CreatedAt = created.CreatedAt
 	user.UpdatedAt = created.UpdatedAt
 	return nil
 }
 
-func (r *userRepository) UpdateGnuBalance(ctx context.Context, id uuid.UUID, balance int) error {
-	return r.q.UpdateGnuBalance(ctx, sqlc.UpdateGnuBalanceParams{
-		ID:         id,
-		GnuBalance: int32(balance),
-	})
+func (r *userRepository) UpdateRatings(ctx context.Context, ids []uuid.UUID, fn func(map[uuid.UUID]*entity.User) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("user repository: rollback error: %v", rbErr)
+		}
+	}()
+	qtx := r.q.WithTx(tx)
+
+	// 同じユーザーを含む試合が並行して終わってもデッドロックしないよう、ID 順に行ロックを取る
+	locked := slices.Clone(ids)
+	slices.SortFunc(locked, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+	locked = slices.Compact(locked)
+	users := make(map[uuid.UUID]*entity.User, len(locked))
+	for _, id := range locked {
+		u, err := qtx.LockUserByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("lock user %s: %w", id, err)
+		}
+		users[id] = toEntityUser(u)
+	}
+	if err := fn(users); err != nil {
+		return err
+	}
+
+	for _, id := range locked {
+		u := users[id]
+		if err := qtx.UpdateUserRating(ctx, sqlc.UpdateUserRatingParams{
+			ID:               id,
+			Rate:             int32(u.Rate),
+			RatingDeviation:  u.RatingDeviation,
+			RatingVolatility: u.RatingVolatility,
+		}); err != nil {
+			return fmt.Errorf("update rating of %s: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+func (r *userRepository) ListWithMatches(ctx context.Context) ([]*entity.User, error) {
+	rows, err := r.q.ListUsersWithMatches(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list users with matches: %w", err)
+	}
+	users := make([]*entity.User, 0, len(rows))
+	for _, u := range rows {
+		users = append(users, toEntityUser(u))
+	}
+	return users, nil
 }
 
 func toEntityUser(u sqlc.User) *entity.User {
 	return &entity.User{
-		ID:             u.ID,
-		GitHubID:       u.GithubID,
-		GitHubLogin:    u.GithubLogin,
-		GnuBalance:     int(u.GnuBalance),
-		Rate:           int(u.Rate),
-		EncryptedToken: u.EncryptedToken,
-		CreatedAt:      u.CreatedAt,
-		UpdatedAt:      u.UpdatedAt,
+		ID:               u.ID,
+		GitHubID:         u.GithubID,
+		GitHubLogin:      u.GithubLogin,
+		GnuBalance:       int(u.GnuBalance),
+		Rate:             int(u.Rate),
+		RatingDeviation:  u.RatingDeviation,
+		RatingVolatility: u.RatingVolatility,
+		EncryptedToken:   u.EncryptedToken,
+		CreatedAt:        u.CreatedAt,
+		UpdatedAt:        u.UpdatedAt,
 	}
 }
@@ -0,0 +1,66 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+)
+
+type userStatsCacheRepository struct {
+	rdb *redis.Client
+}
+
+func NewUserStatsCacheRepository(rdb *redis.Client) repository.UserStatsCacheRepository {
+	return &userStatsCacheRepository{rdb: rdb}
+}
+
+func (r *userStatsCacheRepository) Get(ctx context.Context, userID uuid.UUID) (*entity.UserStats, error) {
+	data, err := r.rdb.Get(ctx, userStatsKey(userID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("redis get user stats: %w", err)
+	}
+	var stats entity.UserStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil, fmt.Errorf("unmarshal user stats: %w", err)
+	}
+	return &stats, nil
+}
+
+func (r *userStatsCacheRepository) Set(ctx context.Context, stats *entity.UserStats, ttl time.Duration) error {
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return fmt.Errorf("marshal user stats: %w", err)
+	}
+	if err := r.rdb.Set(ctx, userStatsKey(stats.UserID), data, ttl).Err(); err != nil {
+		return fmt.Errorf("redis set user stats: %w", err)
+	}
+	return nil
+}
+
+func (r *userStatsCacheRepository) Delete(ctx context.Context, userIDs ...uuid.UUID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		keys = append(keys, userStatsKey(id))
+	}
+	if err := r.rdb.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("redis delete user stats: %w", err)
+	}
+	return nil
+}
+
+func userStatsKey(userID uuid.UUID) string {
+	return fmt.Sprintf("user:%s:stats", userID.String())
+}
@@ -0,0 +1,50 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+)
+
+const wsTicketKeyPrefix = "ws_ticket:"
+
+type wsTicketRepository struct {
+	rdb *redis.Client
+}
+
+func NewWSTicketRepository(rdb *redis.Client) repository.WSTicketRepository {
+	return &wsTicketRepository{rdb: rdb}
+}
+
+func (r *wsTicketRepository) Save(ctx context.Context, ticket string, identity entity.GitHubIdentity, ttl time.Duration) error {
+	data, err := json.Marshal(identity)
+	if err != nil {
+		return fmt.Errorf("marshal ticket: %w", err)
+	}
+	if err := r.rdb.Set(ctx, wsTicketKeyPrefix+ticket, data, ttl).Err(); err != nil {
+		return fmt.Errorf("save ticket: %w", err)
+	}
+	return nil
+}
+
+func (r *wsTicketRepository) Consume(ctx context.Context, ticket string) (*entity.GitHubIdentity, error) {
+	// GETDEL で取り出しと削除を同時に行い、同じチケットでの二重接続を防ぐ
+	data, err := r.rdb.GetDel(ctx, wsTicketKeyPrefix+ticket).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("consume ticket: %w", err)
+	}
+	var identity entity.GitHubIdentity
+	if err := json.Unmarshal(data, &identity); err != nil {
+		return nil, fmt.Errorf("unmarshal ticket: %w", err)
+	}
+	return &identity, nil
+}
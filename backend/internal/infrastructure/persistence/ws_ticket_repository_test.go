@@ -0,0 +1,50 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+)
+
+func TestWSTicketRepository_ConsumeOnce(t *testing.T) {
+	rdb := setupTestRedis(t)
+	repo := NewWSTicketRepository(rdb)
+	ctx := context.Background()
+	cleanupKeys(t, rdb, wsTicketKeyPrefix+"t1")
+
+	identity := entity.GitHubIdentity{Login: "alice", ID: 1}
+	require.NoError(t, repo.Save(ctx, "t1", identity, time.Minute))
+
+	got, err := repo.Consume(ctx, "t1")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, identity, *got)
+
+	// 2回目は使えない
+	got, err = repo.Consume(ctx, "t1")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestWSTicketRepository_Expired(t *testing.T) {
+	rdb := setupTestRedis(t)
+	repo := NewWSTicketRepository(rdb)
+	ctx := context.Background()
+	cleanupKeys(t, rdb, wsTicketKeyPrefix+"t2")
+
+	require.NoError(t, repo.Save(ctx, "t2", entity.GitHubIdentity{Login: "bob", ID: 2}, 30*time.Second))
+	ttl, err := rdb.PTTL(ctx, wsTicketKeyPrefix+"t2").Result()
+	require.NoError(t, err)
+	assert.Positive(t, ttl, "tickets expire")
+	assert.LessOrEqual(t, ttl, 30*time.Second)
+
+	// Redis が期限切れで消したチケットは使えない
+	require.NoError(t, rdb.Del(ctx, wsTicketKeyPrefix+"t2").Err())
+	got, err := repo.Consume(ctx, "t2")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
@@ -5,12 +5,81 @@
 package sqlc
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sqlc-dev/pqtype"
 )
 
+type GnuTransaction struct {
+	ID             uuid.UUID     `json:"id"`
+	UserID         uuid.UUID     `json:"user_id"`
+	RoomID         uuid.NullUUID `json:"room_id"`
+	Turn           sql.NullInt32 `json:"turn"`
+	Reason         string        `json:"reason"`
+	Delta          int32         `json:"delta"`
+	BalanceAfter   int32         `json:"balance_after"`
+	IdempotencyKey string        `json:"idempotency_key"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+type MatchEvent struct {
+	ID        int64           `json:"id"`
+	RoomID    uuid.UUID       `json:"room_id"`
+	UserID    uuid.UUID       `json:"user_id"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+	SentAt    time.Time       `json:"sent_at"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type MatchResult struct {
+	ID                  uuid.UUID     `json:"id"`
+	RoomID              uuid.UUID     `json:"room_id"`
+	Player1ID           uuid.UUID     `json:"player1_id"`
+	Player2ID           uuid.UUID     `json:"player2_id"`
+	WinnerID            uuid.NullUUID `json:"winner_id"`
+	EndReason           string        `json:"end_reason"`
+	Player1CorrectCount int32         `json:"player1_correct_count"`
+	Player2CorrectCount int32         `json:"player2_correct_count"`
+	Player1GnuEarned    int32         `json:"player1_gnu_earned"`
+	Player2GnuEarned    int32         `json:"player2_gnu_earned"`
+	Player1FinalGnu     int32         `json:"player1_final_gnu"`
+	Player2FinalGnu     int32         `json:"player2_final_gnu"`
+	TotalTurns          int32         `json:"total_turns"`
+	StartedAt           time.Time     `json:"started_at"`
+	FinishedAt          time.Time     `json:"finished_at"`
+	CreatedAt           time.Time     `json:"created_at"`
+}
+
+type MatchResultPlayer struct {
+	MatchID      uuid.UUID `json:"match_id"`
+	UserID       uuid.UUID `json:"user_id"`
+	Team         int32     `json:"team"`
+	Position     int32     `json:"position"`
+	Outcome      string    `json:"outcome"`
+	CorrectCount int32     `json:"correct_count"`
+	GnuEarned    int32     `json:"gnu_earned"`
+	FinalGnu     int32     `json:"final_gnu"`
+}
+
+type MatchTurn struct {
+	ID           uuid.UUID             `json:"id"`
+	MatchID      uuid.UUID             `json:"match_id"`
+	Turn         int32                 `json:"turn"`
+	UserID       uuid.UUID             `json:"user_id"`
+	Question     json.RawMessage       `json:"question"`
+	ChoiceIndex  int32                 `json:"choice_index"`
+	IsCorrect    bool                  `json:"is_correct"`
+	Bet          int32                 `json:"bet"`
+	GnuDelta     int32                 `json:"gnu_delta"`
+	AnswerTimeMs sql.NullInt32         `json:"answer_time_ms"`
+	CreatedAt    time.Time             `json:"created_at"`
+	Answer       pqtype.NullRawMessage `json:"answer"`
+}
+
 type Repository struct {
 	ID          uuid.UUID             `json:"id"`
 	Owner       string                `json:"owner"`
@@ -30,21 +99,65 @@ type RepositoryFile struct {
 }
 
 type Room struct {
-	ID        uuid.UUID `json:"id"`
-	Player1ID uuid.UUID `json:"player1_id"`
-	Player2ID uuid.UUID `json:"player2_id"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uuid.UUID      `json:"id"`
+	Player1ID uuid.UUID      `json:"player1_id"`
+	Player2ID uuid.UUID      `json:"player2_id"`
+	Status    string         `json:"status"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	EndReason sql.NullString `json:"end_reason"`
+}
+
+type RoomPlayer struct {
+	RoomID   uuid.UUID `json:"room_id"`
+	UserID   uuid.UUID `json:"user_id"`
+	Team     int32     `json:"team"`
+	Position int32     `json:"position"`
+}
+
+type Tournament struct {
+	ID         uuid.UUID     `json:"id"`
+	Name       string        `json:"name"`
+	Status     string        `json:"status"`
+	CreatedBy  uuid.UUID     `json:"created_by"`
+	WinnerID   uuid.NullUUID `json:"winner_id"`
+	StartedAt  sql.NullTime  `json:"started_at"`
+	FinishedAt sql.NullTime  `json:"finished_at"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+}
+
+type TournamentMatch struct {
+	ID           uuid.UUID      `json:"id"`
+	TournamentID uuid.UUID      `json:"tournament_id"`
+	Round        int32          `json:"round"`
+	Position     int32          `json:"position"`
+	Player1ID    uuid.NullUUID  `json:"player1_id"`
+	Player2ID    uuid.NullUUID  `json:"player2_id"`
+	RoomID       uuid.NullUUID  `json:"room_id"`
+	WinnerID     uuid.NullUUID  `json:"winner_id"`
+	Status       string         `json:"status"`
+	Result       sql.NullString `json:"result"`
+	Attempts     int32          `json:"attempts"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+type TournamentParticipant struct {
+	TournamentID uuid.UUID     `json:"tournament_id"`
+	UserID       uuid.UUID     `json:"user_id"`
+	Seed         sql.NullInt32 `json:"seed"`
+	RegisteredAt time.Time     `json:"registered_at"`
 }
 
 type User struct {
-	ID             uuid.UUID `json:"id"`
-	GithubID       int64     `json:"github_id"`
-	GithubLogin    string    `json:"github_login"`
-	GnuBalance     int32     `json:"gnu_balance"`
-	Rate           int32     `json:"rate"`
-	EncryptedToken string    `json:"encrypted_token"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID               uuid.UUID `json:"id"`
+	GithubID         int64     `json:"github_id"`
+	GithubLogin      string    `json:"github_login"`
+	GnuBalance       int32     `json:"gnu_balance"`
+	Rate             int32     `json:"rate"`
+	EncryptedToken   string    `json:"encrypted_token"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	RatingDeviation  float64   `json:"rating_deviation"`
+	RatingVolatility float64   `json:"rating_volatility"`
 }
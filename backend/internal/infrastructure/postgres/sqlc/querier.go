@@ -6,19 +6,53 @@ package sqlc
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Querier interface {
+	CountWinsSince(ctx context.Context, finishedAt time.Time) ([]CountWinsSinceRow, error)
+	CreateGnuTransaction(ctx context.Context, arg CreateGnuTransactionParams) (GnuTransaction, error)
+	CreateMatchEvent(ctx context.Context, arg CreateMatchEventParams) error
+	CreateMatchResult(ctx context.Context, arg CreateMatchResultParams) (MatchResult, error)
+	CreateMatchResultPlayer(ctx context.Context, arg CreateMatchResultPlayerParams) error
+	CreateMatchTurn(ctx context.Context, arg CreateMatchTurnParams) error
 	CreateRoom(ctx context.Context, arg CreateRoomParams) (Room, error)
+	CreateRoomPlayer(ctx context.Context, arg CreateRoomPlayerParams) error
+	CreateTournament(ctx context.Context, arg CreateTournamentParams) (Tournament, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	GetMatchResultByRoomID(ctx context.Context, roomID uuid.UUID) (MatchResult, error)
 	GetRoomByID(ctx context.Context, id uuid.UUID) (Room, error)
+	GetTournamentByID(ctx context.Context, id uuid.UUID) (Tournament, error)
+	GetTournamentMatchByRoomID(ctx context.Context, roomID uuid.NullUUID) (TournamentMatch, error)
+	GetUserBestWinStreak(ctx context.Context, userID uuid.UUID) (int32, error)
 	GetUserByGitHubID(ctx context.Context, githubID int64) (User, error)
 	GetUserByGitHubLogin(ctx context.Context, githubLogin string) (User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (User, error)
-	UpdateGnuBalance(ctx context.Context, arg UpdateGnuBalanceParams) error
+	GetUserMatchSummary(ctx context.Context, userID uuid.UUID) (GetUserMatchSummaryRow, error)
+	ListGnuBalanceMismatches(ctx context.Context) ([]ListGnuBalanceMismatchesRow, error)
+	ListMatchEventsByRoomID(ctx context.Context, roomID uuid.UUID) ([]MatchEvent, error)
+	ListMatchResultPlayersByMatchID(ctx context.Context, matchID uuid.UUID) ([]MatchResultPlayer, error)
+	ListMatchResultsByUserID(ctx context.Context, arg ListMatchResultsByUserIDParams) ([]MatchResult, error)
+	ListMatchTurnsByMatchID(ctx context.Context, matchID uuid.UUID) ([]MatchTurn, error)
+	ListRoomPlayers(ctx context.Context, roomID uuid.UUID) ([]RoomPlayer, error)
+	ListStaleTournamentMatches(ctx context.Context, createdAt time.Time) ([]TournamentMatch, error)
+	ListTournamentMatches(ctx context.Context, tournamentID uuid.UUID) ([]TournamentMatch, error)
+	ListTournamentParticipants(ctx context.Context, tournamentID uuid.UUID) ([]ListTournamentParticipantsRow, error)
+	ListTournaments(ctx context.Context, limit int32) ([]Tournament, error)
+	ListUserDailyGnuEarned(ctx context.Context, arg ListUserDailyGnuEarnedParams) ([]ListUserDailyGnuEarnedRow, error)
+	ListUserTurnStatsByDifficulty(ctx context.Context, userID uuid.UUID) ([]ListUserTurnStatsByDifficultyRow, error)
+	ListUsersWithMatches(ctx context.Context) ([]User, error)
+	LockUserByID(ctx context.Context, id uuid.UUID) (User, error)
+	LockTournament(ctx context.Context, id uuid.UUID) (Tournament, error)
+	LockUserGnuBalance(ctx context.Context, id uuid.UUID) (int32, error)
+	SetUserGnuBalance(ctx context.Context, arg SetUserGnuBalanceParams) error
 	UpdateRoomStatus(ctx context.Context, arg UpdateRoomStatusParams) error
+	UpdateTournament(ctx context.Context, arg UpdateTournamentParams) error
+	UpdateUserRating(ctx context.Context, arg UpdateUserRatingParams) error
+	UpsertTournamentMatch(ctx context.Context, arg UpsertTournamentMatchParams) error
+	UpsertTournamentParticipant(ctx context.Context, arg UpsertTournamentParticipantParams) error
 }
 
 var _ Querier = (*Queries)(nil)
@@ -7,6 +7,7 @@ package sqlc
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
 )
@@ -14,7 +15,7 @@ import (
 const createRoom = `-- name: CreateRoom :one
 INSERT INTO rooms (id, player1_id, player2_id, status)
 VALUES ($1, $2, $3, $4)
-RETURNING id, player1_id, player2_id, status, created_at, updated_at
+RETURNING id, player1_id, player2_id, status, created_at, updated_at, end_reason
 `
 
 type CreateRoomParams struct {
@@ -39,12 +40,35 @@ func (q *Queries) CreateRoom(ctx context.Context, arg CreateRoomParams) (Room, e
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EndReason,
 	)
 	return i, err
 }
 
+const createRoomPlayer = `-- name: CreateRoomPlayer :exec
+INSERT INTO room_players (room_id, user_id, team, position)
+VALUES ($1, $2, $3, $4)
+`
+
+type CreateRoomPlayerParams struct {
+	RoomID   uuid.UUID `json:"room_id"`
+	UserID   uuid.UUID `json:"user_id"`
+	Team     int32     `json:"team"`
+	Position int32     `json:"position"`
+}
+
+func (q *Queries) CreateRoomPlayer(ctx context.Context, arg CreateRoomPlayerParams) error {
+	_, err := q.db.ExecContext(ctx, createRoomPlayer,
+		arg.RoomID,
+		arg.UserID,
+		arg.Team,
+		arg.Position,
+	)
+	return err
+}
+
 const getRoomByID = `-- name: GetRoomByID :one
-SELECT id, player1_id, player2_id, status, created_at, updated_at FROM rooms WHERE id = $1
+SELECT id, player1_id, player2_id, status, created_at, updated_at, end_reason FROM rooms WHERE id = $1
 `
 
 func (q *Queries) GetRoomByID(ctx context.Context, id uuid.UUID) (Room, error) {
@@ -57,20 +81,54 @@ func (q *Queries) GetRoomByID(ctx context.Context, id uuid.UUID) (Room, error) {
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EndReason,
 	)
 	return i, err
 }
 
+const listRoomPlayers = `-- name: ListRoomPlayers :many
+SELECT room_id, user_id, team, position FROM room_players WHERE room_id = $1 ORDER BY team, position
+`
+
+func (q *Queries) ListRoomPlayers(ctx context.Context, roomID uuid.UUID) ([]RoomPlayer, error) {
+	rows, err := q.db.QueryContext(ctx, listRoomPlayers, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RoomPlayer
+	for rows.Next() {
+		var i RoomPlayer
+		if err := rows.Scan(
+			&i.RoomID,
+			&i.UserID,
+			&i.Team,
+			&i.Position,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateRoomStatus = `-- name: UpdateRoomStatus :exec
-UPDATE rooms SET status = $2, updated_at = NOW() WHERE id = $1
+UPDATE rooms SET status = $2, end_reason = $3, updated_at = NOW() WHERE id = $1
 `
 
 type UpdateRoomStatusParams struct {
-	ID     uuid.UUID `json:"id"`
-	Status string    `json:"status"`
+	ID        uuid.UUID      `json:"id"`
+	Status    string         `json:"status"`
+	EndReason sql.NullString `json:"end_reason"`
 }
 
 func (q *Queries) UpdateRoomStatus(ctx context.Context, arg UpdateRoomStatusParams) error {
-	_, err := q.db.ExecContext(ctx, updateRoomStatus, arg.ID, arg.Status)
+	_, err := q.db.ExecContext(ctx, updateRoomStatus, arg.ID, arg.Status, arg.EndReason)
 	return err
 }
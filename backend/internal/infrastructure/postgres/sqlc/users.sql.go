@@ -14,7 +14,7 @@ import (
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (github_id, github_login, encrypted_token)
 VALUES ($1, $2, $3)
-RETURNING id, github_id, github_login, gnu_balance, rate, encrypted_token, created_at, updated_at
+RETURNING id, github_id, github_login, gnu_balance, rate, encrypted_token, created_at, updated_at, rating_deviation, rating_volatility
 `
 
 type CreateUserParams struct {
@@ -35,12 +35,14 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.EncryptedToken,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RatingDeviation,
+		&i.RatingVolatility,
 	)
 	return i, err
 }
 
 const getUserByGitHubID = `-- name: GetUserByGitHubID :one
-SELECT id, github_id, github_login, gnu_balance, rate, encrypted_token, created_at, updated_at FROM users WHERE github_id = $1
+SELECT id, github_id, github_login, gnu_balance, rate, encrypted_token, created_at, updated_at, rating_deviation, rating_volatility FROM users WHERE github_id = $1
 `
 
 func (q *Queries) GetUserByGitHubID(ctx context.Context, githubID int64) (User, error) {
@@ -55,12 +57,14 @@ func (q *Queries) GetUserByGitHubID(ctx context.Context, githubID int64) (User,
 		&i.EncryptedToken,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RatingDeviation,
+		&i.RatingVolatility,
 	)
 	return i, err
 }
 
 const getUserByGitHubLogin = `-- name: GetUserByGitHubLogin :one
-SELECT id, github_id, github_login, gnu_balance, rate, encrypted_token, created_at, updated_at FROM users WHERE github_login = $1
+SELECT id, github_id, github_login, gnu_balance, rate, encrypted_token, created_at, updated_at, rating_deviation, rating_volatility FROM users WHERE github_login = $1
 `
 
 func (q *Queries) GetUserByGitHubLogin(ctx context.Context, githubLogin string) (User, error) {
@@ -75,12 +79,14 @@ func (q *Queries) GetUserByGitHubLogin(ctx context.Context, githubLogin string)
 		&i.EncryptedToken,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RatingDeviation,
+		&i.RatingVolatility,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, github_id, github_login, gnu_balance, rate, encrypted_token, created_at, updated_at FROM users WHERE id = $1
+SELECT id, github_id, github_login, gnu_balance, rate, encrypted_token, created_at, updated_at, rating_deviation, rating_volatility FROM users WHERE id = $1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
@@ -95,20 +101,95 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.EncryptedToken,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RatingDeviation,
+		&i.RatingVolatility,
 	)
 	return i, err
 }
 
-const updateGnuBalance = `-- name: UpdateGnuBalance :exec
-UPDATE users SET gnu_balance = GREATEST(0, $2), updated_at = NOW() WHERE id = $1
+const listUsersWithMatches = `-- name: ListUsersWithMatches :many
+SELECT id, github_id, github_login, gnu_balance, rate, encrypted_token, created_at, updated_at, rating_deviation, rating_volatility FROM users u
+WHERE EXISTS (
+    SELECT 1 FROM match_result_players p WHERE p.user_id = u.id
+)
+ORDER BY u.id
+`
+
+func (q *Queries) ListUsersWithMatches(ctx context.Context) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersWithMatches)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.GithubID,
+			&i.GithubLogin,
+			&i.GnuBalance,
+			&i.Rate,
+			&i.EncryptedToken,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.RatingDeviation,
+			&i.RatingVolatility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockUserByID = `-- name: LockUserByID :one
+SELECT id, github_id, github_login, gnu_balance, rate, encrypted_token, created_at, updated_at, rating_deviation, rating_volatility FROM users WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) LockUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, lockUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.GithubID,
+		&i.GithubLogin,
+		&i.GnuBalance,
+		&i.Rate,
+		&i.EncryptedToken,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.RatingDeviation,
+		&i.RatingVolatility,
+	)
+	return i, err
+}
+
+const updateUserRating = `-- name: UpdateUserRating :exec
+UPDATE users
+SET rate = $2, rating_deviation = $3, rating_volatility = $4, updated_at = NOW()
+WHERE id = $1
 `
 
-type UpdateGnuBalanceParams struct {
-	ID         uuid.UUID `json:"id"`
-	GnuBalance int32     `json:"gnu_balance"`
+type UpdateUserRatingParams struct {
+	ID               uuid.UUID `json:"id"`
+	Rate             int32     `json:"rate"`
+	RatingDeviation  float64   `json:"rating_deviation"`
+	RatingVolatility float64   `json:"rating_volatility"`
 }
 
-func (q *Queries) UpdateGnuBalance(ctx context.Context, arg UpdateGnuBalanceParams) error {
-	_, err := q.db.ExecContext(ctx, updateGnuBalance, arg.ID, arg.GnuBalance)
+func (q *Queries) UpdateUserRating(ctx context.Context, arg UpdateUserRatingParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserRating,
+		arg.ID,
+		arg.Rate,
+		arg.RatingDeviation,
+		arg.RatingVolatility,
+	)
 	return err
 }
@@ -2,6 +2,7 @@ package testutil
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
@@ -9,25 +10,51 @@ import (
 
 // MockMatchmakingRepository is a mock implementation of repository.MatchmakingRepository.
 type MockMatchmakingRepository struct {
-	EnqueueFunc     func(ctx context.Context, userID uuid.UUID) error
-	DequeueFunc     func(ctx context.Context) (uuid.UUID, uuid.UUID, error)
-	RemoveFunc      func(ctx context.Context, userID uuid.UUID) error
-	SetActiveFunc   func(ctx context.Context, userID uuid.UUID) (bool, error)
-	ClearActiveFunc func(ctx context.Context, userID uuid.UUID) error
+	EnqueueFunc               func(ctx context.Context, entry entity.QueueEntry) error
+	RequeueFunc               func(ctx context.Context, entry entity.QueueEntry) error
+	DequeueFunc               func(ctx context.Context, window entity.RateWindow, now time.Time) (*entity.QueueEntry, *entity.QueueEntry, error)
+	DequeueTeamsFunc          func(ctx context.Context, teamSize int, window entity.RateWindow, now time.Time) ([]*entity.QueueEntry, error)
+	RemoveFunc                func(ctx context.Context, userID uuid.UUID) error
+	SetActiveFunc             func(ctx context.Context, userID uuid.UUID) (bool, error)
+	ClearActiveFunc           func(ctx context.Context, userID uuid.UUID) error
+	SubscribeQueueChangesFunc func(ctx context.Context) (<-chan struct{}, error)
 }
 
-func (m *MockMatchmakingRepository) Enqueue(ctx context.Context, userID uuid.UUID) error {
+func (m *MockMatchmakingRepository) Enqueue(ctx context.Context, entry entity.QueueEntry) error {
 	if m.EnqueueFunc == nil {
 		return nil
 	}
-	return m.EnqueueFunc(ctx, userID)
+	return m.EnqueueFunc(ctx, entry)
 }
 
-func (m *MockMatchmakingRepository) Dequeue(ctx context.Context) (uuid.UUID, uuid.UUID, error) {
+func (m *MockMatchmakingRepository) Requeue(ctx context.Context, entry entity.QueueEntry) error {
+	if m.RequeueFunc == nil {
+		return nil
+	}
+	return m.RequeueFunc(ctx, entry)
+}
+
+func (m *MockMatchmakingRepository) Dequeue(
+	ctx context.Context,
+	window entity.RateWindow,
+	now time.Time,
+) (*entity.QueueEntry, *entity.QueueEntry, error) {
 	if m.DequeueFunc == nil {
-		return uuid.Nil, uuid.Nil, nil
+		return nil, nil, nil
 	}
-	return m.DequeueFunc(ctx)
+	return m.DequeueFunc(ctx, window, now)
+}
+
+func (m *MockMatchmakingRepository) DequeueTeams(
+	ctx context.Context,
+	teamSize int,
+	window entity.RateWindow,
+	now time.Time,
+) ([]*entity.QueueEntry, error) {
+	if m.DequeueTeamsFunc == nil {
+		return nil, nil
+	}
+	return m.DequeueTeamsFunc(ctx, teamSize, window, now)
 }
 
 func (m *MockMatchmakingRepository) Remove(ctx context.Context, userID uuid.UUID) error {
@@ -51,10 +78,23 @@ func (m *MockMatchmakingRepository) ClearActive(ctx context.Context, userID uuid
 	return m.ClearActiveFunc(ctx, userID)
 }
 
+func (m *MockMatchmakingRepository) SubscribeQueueChanges(ctx context.Context) (<-chan struct{}, error) {
+	if m.SubscribeQueueChangesFunc == nil {
+		return make(chan struct{}), nil
+	}
+	return m.SubscribeQueueChangesFunc(ctx)
+}
+
 // MockRoomRepository is a mock implementation of repository.RoomRepository.
 type MockRoomRepository struct {
-	CreateFunc  func(ctx context.Context, room *entity.Room) error
-	GetByIDFunc func(ctx context.Context, id uuid.UUID) (*entity.Room, error)
+	CreateFunc       func(ctx context.Context, room *entity.Room) error
+	GetByIDFunc      func(ctx context.Context, id uuid.UUID) (*entity.Room, error)
+	UpdateStatusFunc func(ctx context.Context, id uuid.UUID, status entity.RoomStatus, reason entity.RoomEndReason) error
+	// Snapshot funcs default to a no-op store that holds nothing
+	SaveSnapshotFunc        func(ctx context.Context, snapshot *entity.RoomSnapshot) error
+	GetSnapshotFunc         func(ctx context.Context, id uuid.UUID) (*entity.RoomSnapshot, error)
+	DeleteSnapshotFunc      func(ctx context.Context, id uuid.UUID) error
+	ListSnapshotRoomIDsFunc func(ctx context.Context) ([]uuid.UUID, error)
 }
 
 func (m *MockRoomRepository) Create(ctx context.Context, room *entity.Room) error {
@@ -65,13 +105,49 @@ func (m *MockRoomRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity
 	return m.GetByIDFunc(ctx, id)
 }
 
+func (m *MockRoomRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status entity.RoomStatus, reason entity.RoomEndReason) error {
+	if m.UpdateStatusFunc == nil {
+		return nil
+	}
+	return m.UpdateStatusFunc(ctx, id, status, reason)
+}
+
+func (m *MockRoomRepository) SaveSnapshot(ctx context.Context, snapshot *entity.RoomSnapshot) error {
+	if m.SaveSnapshotFunc == nil {
+		return nil
+	}
+	return m.SaveSnapshotFunc(ctx, snapshot)
+}
+
+func (m *MockRoomRepository) GetSnapshot(ctx context.Context, id uuid.UUID) (*entity.RoomSnapshot, error) {
+	if m.GetSnapshotFunc == nil {
+		return nil, nil
+	}
+	return m.GetSnapshotFunc(ctx, id)
+}
+
+func (m *MockRoomRepository) DeleteSnapshot(ctx context.Context, id uuid.UUID) error {
+	if m.DeleteSnapshotFunc == nil {
+		return nil
+	}
+	return m.DeleteSnapshotFunc(ctx, id)
+}
+
+func (m *MockRoomRepository) ListSnapshotRoomIDs(ctx context.Context) ([]uuid.UUID, error) {
+	if m.ListSnapshotRoomIDsFunc == nil {
+		return nil, nil
+	}
+	return m.ListSnapshotRoomIDsFunc(ctx)
+}
+
 // MockUserRepository is a mock implementation of repository.UserRepository.
 type MockUserRepository struct {
 	GetByIDFunc          func(ctx context.Context, id uuid.UUID) (*entity.User, error)
 	GetByGitHubIDFunc    func(ctx context.Context, githubID int64) (*entity.User, error)
 	GetByGitHubLoginFunc func(ctx context.Context, login string) (*entity.User, error)
 	CreateFunc           func(ctx context.Context, user *entity.User) error
-	UpdateGnuBalanceFunc func(ctx context.Context, id uuid.UUID, balance int) error
+	UpdateRatingsFunc    func(ctx context.Context, ids []uuid.UUID, fn func(map[uuid.UUID]*entity.User) error) error
+	ListWithMatchesFunc  func(ctx context.Context) ([]*entity.User, error)
 }
 
 func (m *MockUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.User, error) {
@@ -90,6 +166,226 @@ func (m *MockUserRepository) Create(ctx context.Context, user *entity.User) erro
 	return m.CreateFunc(ctx, user)
 }
 
-func (m *MockUserRepository) UpdateGnuBalance(ctx context.Context, id uuid.UUID, balance int) error {
-	return m.UpdateGnuBalanceFunc(ctx, id, balance)
+func (m *MockUserRepository) UpdateRatings(ctx context.Context, ids []uuid.UUID, fn func(map[uuid.UUID]*entity.User) error) error {
+	return m.UpdateRatingsFunc(ctx, ids, fn)
+}
+
+func (m *MockUserRepository) ListWithMatches(ctx context.Context) ([]*entity.User, error) {
+	return m.ListWithMatchesFunc(ctx)
+}
+
+// MockMatchRepository is a mock implementation of repository.MatchRepository.
+type MockMatchRepository struct {
+	SaveFunc           func(ctx context.Context, result *entity.MatchResult) error
+	GetByRoomIDFunc    func(ctx context.Context, roomID uuid.UUID) (*entity.MatchResult, error)
+	ListByUserIDFunc   func(ctx context.Context, userID uuid.UUID, limit int) ([]*entity.MatchResult, error)
+	CountWinsSinceFunc func(ctx context.Context, since time.Time) (map[uuid.UUID]int, error)
+	GetUserStatsFunc   func(ctx context.Context, userID uuid.UUID, historySince time.Time) (*entity.UserStats, error)
+	// SaveEventsFunc defaults to discarding the events
+	SaveEventsFunc         func(ctx context.Context, events []entity.MatchEvent) error
+	ListEventsByRoomIDFunc func(ctx context.Context, roomID uuid.UUID) ([]entity.MatchEvent, error)
+}
+
+func (m *MockMatchRepository) Save(ctx context.Context, result *entity.MatchResult) error {
+	if m.SaveFunc == nil {
+		return nil
+	}
+	return m.SaveFunc(ctx, result)
+}
+
+func (m *MockMatchRepository) GetByRoomID(ctx context.Context, roomID uuid.UUID) (*entity.MatchResult, error) {
+	return m.GetByRoomIDFunc(ctx, roomID)
+}
+
+func (m *MockMatchRepository) ListByUserID(ctx context.Context, userID uuid.UUID, limit int) ([]*entity.MatchResult, error) {
+	return m.ListByUserIDFunc(ctx, userID, limit)
+}
+
+func (m *MockMatchRepository) CountWinsSince(ctx context.Context, since time.Time) (map[uuid.UUID]int, error) {
+	return m.CountWinsSinceFunc(ctx, since)
+}
+
+func (m *MockMatchRepository) GetUserStats(ctx context.Context, userID uuid.UUID, historySince time.Time) (*entity.UserStats, error) {
+	return m.GetUserStatsFunc(ctx, userID, historySince)
+}
+
+func (m *MockMatchRepository) SaveEvents(ctx context.Context, events []entity.MatchEvent) error {
+	if m.SaveEventsFunc == nil {
+		return nil
+	}
+	return m.SaveEventsFunc(ctx, events)
+}
+
+func (m *MockMatchRepository) ListEventsByRoomID(ctx context.Context, roomID uuid.UUID) ([]entity.MatchEvent, error) {
+	return m.ListEventsByRoomIDFunc(ctx, roomID)
+}
+
+// MockRepositoryFileRepository is a mock implementation of repository.RepositoryFileRepository.
+type MockRepositoryFileRepository struct {
+	ListLatestByOwnerFunc func(ctx context.Context, owner string) ([]entity.RepositoryFile, error)
+}
+
+func (m *MockRepositoryFileRepository) ListLatestByOwner(ctx context.Context, owner string) ([]entity.RepositoryFile, error) {
+	return m.ListLatestByOwnerFunc(ctx, owner)
+}
+
+// MockWSTicketRepository is a mock implementation of repository.WSTicketRepository.
+type MockWSTicketRepository struct {
+	SaveFunc    func(ctx context.Context, ticket string, identity entity.GitHubIdentity, ttl time.Duration) error
+	ConsumeFunc func(ctx context.Context, ticket string) (*entity.GitHubIdentity, error)
+}
+
+func (m *MockWSTicketRepository) Save(ctx context.Context, ticket string, identity entity.GitHubIdentity, ttl time.Duration) error {
+	return m.SaveFunc(ctx, ticket, identity, ttl)
+}
+
+func (m *MockWSTicketRepository) Consume(ctx context.Context, ticket string) (*entity.GitHubIdentity, error) {
+	return m.ConsumeFunc(ctx, ticket)
+}
+
+// MockGnuLedgerRepository is a mock implementation of repository.GnuLedgerRepository.
+type MockGnuLedgerRepository struct {
+	ApplyFunc          func(ctx context.Context, txs []entity.GnuTransaction) (map[uuid.UUID]int, error)
+	ListMismatchesFunc func(ctx context.Context) ([]entity.GnuBalanceMismatch, error)
+}
+
+func (m *MockGnuLedgerRepository) Apply(ctx context.Context, txs []entity.GnuTransaction) (map[uuid.UUID]int, error) {
+	return m.ApplyFunc(ctx, txs)
+}
+
+func (m *MockGnuLedgerRepository) ListMismatches(ctx context.Context) ([]entity.GnuBalanceMismatch, error) {
+	return m.ListMismatchesFunc(ctx)
+}
+
+// MockLeaderboardRepository is a mock implementation of repository.LeaderboardRepository.
+type MockLeaderboardRepository struct {
+	SetScoresFunc      func(ctx context.Context, board entity.Leaderboard, entries []entity.LeaderboardEntry) error
+	IncrementScoreFunc func(ctx context.Context, board entity.Leaderboard, entry entity.LeaderboardEntry, delta int) error
+	TopFunc            func(ctx context.Context, board entity.Leaderboard, limit int) ([]entity.LeaderboardEntry, error)
+	AroundFunc         func(ctx context.Context, board entity.Leaderboard, userID uuid.UUID, radius int) ([]entity.LeaderboardEntry, error)
+	ReplaceFunc        func(ctx context.Context, board entity.Leaderboard, entries []entity.LeaderboardEntry) error
+}
+
+func (m *MockLeaderboardRepository) SetScores(ctx context.Context, board entity.Leaderboard, entries []entity.LeaderboardEntry) error {
+	return m.SetScoresFunc(ctx, board, entries)
+}
+
+func (m *MockLeaderboardRepository) IncrementScore(ctx context.Context, board entity.Leaderboard, entry entity.LeaderboardEntry, delta int) error {
+	return m.IncrementScoreFunc(ctx, board, entry, delta)
+}
+
+func (m *MockLeaderboardRepository) Top(ctx context.Context, board entity.Leaderboard, limit int) ([]entity.LeaderboardEntry, error) {
+	return m.TopFunc(ctx, board, limit)
+}
+
+func (m *MockLeaderboardRepository) Around(ctx context.Context, board entity.Leaderboard, userID uuid.UUID, radius int) ([]entity.LeaderboardEntry, error) {
+	return m.AroundFunc(ctx, board, userID, radius)
+}
+
+func (m *MockLeaderboardRepository) Replace(ctx context.Context, board entity.Leaderboard, entries []entity.LeaderboardEntry) error {
+	return m.ReplaceFunc(ctx, board, entries)
+}
+
+// MockUserStatsCacheRepository is a mock implementation of repository.UserStatsCacheRepository.
+type MockUserStatsCacheRepository struct {
+	GetFunc    func(ctx context.Context, userID uuid.UUID) (*entity.UserStats, error)
+	SetFunc    func(ctx context.Context, stats *entity.UserStats, ttl time.Duration) error
+	DeleteFunc func(ctx context.Context, userIDs ...uuid.UUID) error
+}
+
+func (m *MockUserStatsCacheRepository) Get(ctx context.Context, userID uuid.UUID) (*entity.UserStats, error) {
+	return m.GetFunc(ctx, userID)
+}
+
+func (m *MockUserStatsCacheRepository) Set(ctx context.Context, stats *entity.UserStats, ttl time.Duration) error {
+	return m.SetFunc(ctx, stats, ttl)
+}
+
+func (m *MockUserStatsCacheRepository) Delete(ctx context.Context, userIDs ...uuid.UUID) error {
+	return m.DeleteFunc(ctx, userIDs...)
+}
+
+// MockRoomInviteRepository is a mock implementation of repository.RoomInviteRepository.
+type MockRoomInviteRepository struct {
+	CreateFunc          func(ctx context.Context, invite *entity.RoomInvite) (bool, error)
+	GetFunc             func(ctx context.Context, code string) (*entity.RoomInvite, error)
+	ClaimFunc           func(ctx context.Context, code string, guestID, roomID uuid.UUID) (bool, error)
+	ReleaseFunc         func(ctx context.Context, code string, roomID uuid.UUID) error
+	DeleteUnclaimedFunc func(ctx context.Context, code string) (bool, error)
+}
+
+func (m *MockRoomInviteRepository) Create(ctx context.Context, invite *entity.RoomInvite) (bool, error) {
+	return m.CreateFunc(ctx, invite)
+}
+
+func (m *MockRoomInviteRepository) Get(ctx context.Context, code string) (*entity.RoomInvite, error) {
+	return m.GetFunc(ctx, code)
+}
+
+func (m *MockRoomInviteRepository) Claim(ctx context.Context, code string, guestID, roomID uuid.UUID) (bool, error) {
+	return m.ClaimFunc(ctx, code, guestID, roomID)
+}
+
+func (m *MockRoomInviteRepository) Release(ctx context.Context, code string, roomID uuid.UUID) error {
+	return m.ReleaseFunc(ctx, code, roomID)
+}
+
+func (m *MockRoomInviteRepository) DeleteUnclaimed(ctx context.Context, code string) (bool, error) {
+	return m.DeleteUnclaimedFunc(ctx, code)
+}
+
+// MockTournamentRepository is a mock implementation of repository.TournamentRepository.
+type MockTournamentRepository struct {
+	CreateFunc           func(ctx context.Context, tournament *entity.Tournament) error
+	ListFunc             func(ctx context.Context, limit int) ([]*entity.Tournament, error)
+	GetBracketFunc       func(ctx context.Context, id uuid.UUID) (*entity.TournamentBracket, error)
+	UpdateFunc           func(ctx context.Context, id uuid.UUID, fn func(*entity.TournamentBracket) error) (*entity.TournamentBracket, error)
+	GetMatchByRoomIDFunc func(ctx context.Context, roomID uuid.UUID) (*entity.TournamentMatch, error)
+	ListStaleMatchesFunc func(ctx context.Context, createdBefore time.Time) ([]*entity.TournamentMatch, error)
+}
+
+func (m *MockTournamentRepository) Create(ctx context.Context, tournament *entity.Tournament) error {
+	return m.CreateFunc(ctx, tournament)
+}
+
+func (m *MockTournamentRepository) List(ctx context.Context, limit int) ([]*entity.Tournament, error) {
+	return m.ListFunc(ctx, limit)
+}
+
+func (m *MockTournamentRepository) GetBracket(ctx context.Context, id uuid.UUID) (*entity.TournamentBracket, error) {
+	return m.GetBracketFunc(ctx, id)
+}
+
+func (m *MockTournamentRepository) Update(
+	ctx context.Context,
+	id uuid.UUID,
+	fn func(*entity.TournamentBracket) error,
+) (*entity.TournamentBracket, error) {
+	return m.UpdateFunc(ctx, id, fn)
+}
+
+func (m *MockTournamentRepository) GetMatchByRoomID(ctx context.Context, roomID uuid.UUID) (*entity.TournamentMatch, error) {
+	return m.GetMatchByRoomIDFunc(ctx, roomID)
+}
+
+func (m *MockTournamentRepository) ListStaleMatches(ctx context.Context, createdBefore time.Time) ([]*entity.TournamentMatch, error) {
+	return m.ListStaleMatchesFunc(ctx, createdBefore)
+}
+
+// MockTournamentUpdateRepository is a mock implementation of repository.TournamentUpdateRepository.
+// When PublishFunc is nil, Publish is a no-op.
+type MockTournamentUpdateRepository struct {
+	PublishFunc   func(ctx context.Context, tournamentID uuid.UUID) error
+	SubscribeFunc func(ctx context.Context, tournamentID uuid.UUID) (<-chan struct{}, error)
+}
+
+func (m *MockTournamentUpdateRepository) Publish(ctx context.Context, tournamentID uuid.UUID) error {
+	if m.PublishFunc == nil {
+		return nil
+	}
+	return m.PublishFunc(ctx, tournamentID)
+}
+
+func (m *MockTournamentUpdateRepository) Subscribe(ctx context.Context, tournamentID uuid.UUID) (<-chan struct{}, error) {
+	return m.SubscribeFunc(ctx, tournamentID)
 }
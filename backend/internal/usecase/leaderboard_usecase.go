@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+)
+
+// LeaderboardRebuildResult は再構築で書き込んだ件数
+type LeaderboardRebuildResult struct {
+	Users   int // rate・gnu ランキングに載せたユーザー数
+	Winners int // 今週1勝以上したユーザー数
+}
+
+type LeaderboardUsecase struct {
+	repo      repository.LeaderboardRepository
+	userRepo  repository.UserRepository
+	matchRepo repository.MatchRepository
+}
+
+func NewLeaderboardUsecase(
+	repo repository.LeaderboardRepository,
+	userRepo repository.UserRepository,
+	matchRepo repository.MatchRepository,
+) *LeaderboardUsecase {
+	return &LeaderboardUsecase{repo: repo, userRepo: userRepo, matchRepo: matchRepo}
+}
+
+// Board は kind のランキングのうち now の時点で集計中のものを返す
+func (uc *LeaderboardUsecase) Board(kind entity.LeaderboardKind, now time.Time) entity.Leaderboard {
+	board := entity.Leaderboard{Kind: kind}
+	if kind == entity.LeaderboardKindWeeklyWins {
+		board.Week = entity.LeaderboardWeekStart(now)
+	}
+	return board
+}
+
+// RecordMatch は試合の終了時に全プレイヤーのレートとヌー残高、勝者の今週の勝利数を反映する
+// レーティングとヌーの精算は先に DB へ反映されているため、最新のユーザーを取得して使う
+// winnerIDs はチーム戦では勝利チームの全員。空の場合は引き分け（勝利数は増やさない）
+func (uc *LeaderboardUsecase) RecordMatch(ctx context.Context, playerIDs, winnerIDs []uuid.UUID, finishedAt time.Time) error {
+	rates := make([]entity.LeaderboardEntry, 0, len(playerIDs))
+	gnus := make([]entity.LeaderboardEntry, 0, len(playerIDs))
+	winners := make([]entity.LeaderboardEntry, 0, len(winnerIDs))
+	for _, id := range playerIDs {
+		user, err := uc.userRepo.GetByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("get user %s: %w", id, err)
+		}
+		rates = append(rates, entity.LeaderboardEntry{UserID: user.ID, GitHubLogin: user.GitHubLogin, Score: user.Rate})
+		gnus = append(gnus, entity.LeaderboardEntry{UserID: user.ID, GitHubLogin: user.GitHubLogin, Score: user.GnuBalance})
+		if slices.Contains(winnerIDs, user.ID) {
+			winners = append(winners, entity.LeaderboardEntry{UserID: user.ID, GitHubLogin: user.GitHubLogin})
+		}
+	}
+
+	if err := uc.repo.SetScores(ctx, uc.Board(entity.LeaderboardKindRate, finishedAt), rates); err != nil {
+		return fmt.Errorf("update rate leaderboard: %w", err)
+	}
+	if err := uc.repo.SetScores(ctx, uc.Board(entity.LeaderboardKindGnu, finishedAt), gnus); err != nil {
+		return fmt.Errorf("update gnu leaderboard: %w", err)
+	}
+	for _, w := range winners {
+		if err := uc.repo.IncrementScore(ctx, uc.Board(entity.LeaderboardKindWeeklyWins, finishedAt), w, 1); err != nil {
+			return fmt.Errorf("update weekly wins leaderboard: %w", err)
+		}
+	}
+	return nil
+}
+
+// Top はランキングの上位 limit 人を返す
+func (uc *LeaderboardUsecase) Top(ctx context.Context, board entity.Leaderboard, limit int) ([]entity.LeaderboardEntry, error) {
+	return uc.repo.Top(ctx, board, limit)
+}
+
+// Around は userID の順位と前後 radius 人を返す。ランキングにいない場合は nil を返す
+func (uc *LeaderboardUsecase) Around(ctx context.Context, board entity.Leaderboard, userID uuid.UUID, radius int) ([]entity.LeaderboardEntry, error) {
+	return uc.repo.Around(ctx, board, userID, radius)
+}
+
+// Rebuild は PostgreSQL の users と match_results からランキングを作り直す
+// Redis を失った場合や、試合の終了時の更新に失敗した場合に使う
+func (uc *LeaderboardUsecase) Rebuild(ctx context.Context, now time.Time) (LeaderboardRebuildResult, error) {
+	users, err := uc.userRepo.ListWithMatches(ctx)
+	if err != nil {
+		return LeaderboardRebuildResult{}, fmt.Errorf("list users: %w", err)
+	}
+	rates := make([]entity.LeaderboardEntry, 0, len(users))
+	gnus := make([]entity.LeaderboardEntry, 0, len(users))
+	logins := make(map[uuid.UUID]string, len(users))
+	for _, u := range users {
+		rates = append(rates, entity.LeaderboardEntry{UserID: u.ID, GitHubLogin: u.GitHubLogin, Score: u.Rate})
+		gnus = append(gnus, entity.LeaderboardEntry{UserID: u.ID, GitHubLogin: u.GitHubLogin, Score: u.GnuBalance})
+		logins[u.ID] = u.GitHubLogin
+	}
+
+	week := uc.Board(entity.LeaderboardKindWeeklyWins, now)
+	wins, err := uc.matchRepo.CountWinsSince(ctx, week.Week)
+	if err != nil {
+		return LeaderboardRebuildResult{}, fmt.Errorf("count weekly wins: %w", err)
+	}
+	winners := make([]entity.LeaderboardEntry, 0, len(wins))
+	for id, n := range wins {
+		winners = append(winners, entity.LeaderboardEntry{UserID: id, GitHubLogin: logins[id], Score: n})
+	}
+
+	if err := uc.repo.Replace(ctx, uc.Board(entity.LeaderboardKindRate, now), rates); err != nil {
+		return LeaderboardRebuildResult{}, fmt.Errorf("replace rate leaderboard: %w", err)
+	}
+	if err := uc.repo.Replace(ctx, uc.Board(entity.LeaderboardKindGnu, now), gnus); err != nil {
+		return LeaderboardRebuildResult{}, fmt.Errorf("replace gnu leaderboard: %w", err)
+	}
+	if err := uc.repo.Replace(ctx, week, winners); err != nil {
+		return LeaderboardRebuildResult{}, fmt.Errorf("replace weekly wins leaderboard: %w", err)
+	}
+	return LeaderboardRebuildResult{Users: len(users), Winners: len(winners)}, nil
+}
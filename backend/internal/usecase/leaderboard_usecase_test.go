@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/testutil"
+)
+
+func TestLeaderboardUsecase_RecordMatch(t *testing.T) {
+	alice := &entity.User{ID: uuid.New(), GitHubLogin: "alice", Rate: 1516, GnuBalance: 120}
+	bob := &entity.User{ID: uuid.New(), GitHubLogin: "bob", Rate: 1484, GnuBalance: 80}
+	userRepo := &testutil.MockUserRepository{
+		GetByIDFunc: func(_ context.Context, id uuid.UUID) (*entity.User, error) {
+			if id == alice.ID {
+				return alice, nil
+			}
+			return bob, nil
+		},
+	}
+	scores := map[entity.LeaderboardKind][]entity.LeaderboardEntry{}
+	var incremented []entity.LeaderboardEntry
+	var weeklyBoard entity.Leaderboard
+	repo := &testutil.MockLeaderboardRepository{
+		SetScoresFunc: func(_ context.Context, board entity.Leaderboard, entries []entity.LeaderboardEntry) error {
+			assert.True(t, board.Week.IsZero(), "only the weekly board has a week")
+			scores[board.Kind] = entries
+			return nil
+		},
+		IncrementScoreFunc: func(_ context.Context, board entity.Leaderboard, entry entity.LeaderboardEntry, delta int) error {
+			weeklyBoard = board
+			assert.Equal(t, 1, delta)
+			incremented = append(incremented, entry)
+			return nil
+		},
+	}
+	uc := NewLeaderboardUsecase(repo, userRepo, nil)
+
+	finishedAt := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	err := uc.RecordMatch(context.Background(), []uuid.UUID{alice.ID, bob.ID}, []uuid.UUID{alice.ID}, finishedAt)
+	require.NoError(t, err)
+
+	assert.Equal(t, []entity.LeaderboardEntry{
+		{UserID: alice.ID, GitHubLogin: "alice", Score: 1516},
+		{UserID: bob.ID, GitHubLogin: "bob", Score: 1484},
+	}, scores[entity.LeaderboardKindRate])
+	assert.Equal(t, []entity.LeaderboardEntry{
+		{UserID: alice.ID, GitHubLogin: "alice", Score: 120},
+		{UserID: bob.ID, GitHubLogin: "bob", Score: 80},
+	}, scores[entity.LeaderboardKindGnu])
+	assert.Equal(t, []entity.LeaderboardEntry{{UserID: alice.ID, GitHubLogin: "alice"}}, incremented)
+	assert.Equal(t, entity.Leaderboard{Kind: entity.LeaderboardKindWeeklyWins, Week: time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)}, weeklyBoard)
+
+	// 引き分けでは勝利数を増やさない
+	incremented = nil
+	require.NoError(t, uc.RecordMatch(context.Background(), []uuid.UUID{alice.ID, bob.ID}, nil, finishedAt))
+	assert.Empty(t, incremented)
+
+	// チーム戦では勝利チームの全員に加算する
+	require.NoError(t, uc.RecordMatch(context.Background(), []uuid.UUID{alice.ID, bob.ID}, []uuid.UUID{alice.ID, bob.ID}, finishedAt))
+	assert.Equal(t, []entity.LeaderboardEntry{
+		{UserID: alice.ID, GitHubLogin: "alice"},
+		{UserID: bob.ID, GitHubLogin: "bob"},
+	}, incremented)
+}
+
+func TestLeaderboardUsecase_Rebuild(t *testing.T) {
+	alice := &entity.User{ID: uuid.New(), GitHubLogin: "alice", Rate: 1600, GnuBalance: 300}
+	bob := &entity.User{ID: uuid.New(), GitHubLogin: "bob", Rate: 1400, GnuBalance: 50}
+	userRepo := &testutil.MockUserRepository{
+		ListWithMatchesFunc: func(context.Context) ([]*entity.User, error) {
+			return []*entity.User{alice, bob}, nil
+		},
+	}
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	matchRepo := &testutil.MockMatchRepository{
+		CountWinsSinceFunc: func(_ context.Context, since time.Time) (map[uuid.UUID]int, error) {
+			assert.Equal(t, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), since)
+			return map[uuid.UUID]int{bob.ID: 3}, nil
+		},
+	}
+	replaced := map[entity.LeaderboardKind][]entity.LeaderboardEntry{}
+	repo := &testutil.MockLeaderboardRepository{
+		ReplaceFunc: func(_ context.Context, board entity.Leaderboard, entries []entity.LeaderboardEntry) error {
+			replaced[board.Kind] = entries
+			return nil
+		},
+	}
+	uc := NewLeaderboardUsecase(repo, userRepo, matchRepo)
+
+	result, err := uc.Rebuild(context.Background(), now)
+	require.NoError(t, err)
+
+	assert.Equal(t, LeaderboardRebuildResult{Users: 2, Winners: 1}, result)
+	assert.Len(t, replaced[entity.LeaderboardKindRate], 2)
+	assert.Equal(t, 300, replaced[entity.LeaderboardKindGnu][0].Score)
+	assert.Equal(t, []entity.LeaderboardEntry{{UserID: bob.ID, GitHubLogin: "bob", Score: 3}}, replaced[entity.LeaderboardKindWeeklyWins])
+}
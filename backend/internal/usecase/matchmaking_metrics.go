@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+)
+
+// MatchTrigger はマッチングを試行したきっかけ
+type MatchTrigger string
+
+const (
+	MatchTriggerQueueChange MatchTrigger = "queue_change" // キューへの追加の通知
+	MatchTriggerSweep       MatchTrigger = "sweep"        // 定期的な見回り（許容幅の拡大や通知の取りこぼしを拾う）
+)
+
+// DurationSummary は所要時間の集計
+type DurationSummary struct {
+	AvgMs float64 `json:"avg_ms"`
+	Count int64   `json:"count"`
+	MaxMs int64   `json:"max_ms"`
+}
+
+func (s *DurationSummary) observe(d time.Duration) {
+	ms := d.Milliseconds()
+	s.AvgMs = (s.AvgMs*float64(s.Count) + float64(ms)) / float64(s.Count+1)
+	s.Count++
+	if ms > s.MaxMs {
+		s.MaxMs = ms
+	}
+}
+
+// MatchmakingMetricsSnapshot はマッチングの集計値（インスタンスの起動からの累計）
+type MatchmakingMetricsSnapshot struct {
+	// Attempts はきっかけごとの TryMatch の試行回数
+	Attempts map[MatchTrigger]int64 `json:"attempts"`
+	// Wait は各プレイヤーのキュー参加からマッチ成立までの時間
+	Wait DurationSummary `json:"wait"`
+	// PairLatency はマッチの最後に参加したプレイヤーの参加からマッチ成立までの時間
+	// 参加直後に成立するマッチではマッチングループの反応の遅れがそのまま表れる
+	PairLatency   DurationSummary `json:"pair_latency"`
+	Matches       int64           `json:"matches"`
+	EmptyAttempts int64           `json:"empty_attempts"` // マッチが見つからなかった試行の回数
+	RedisCalls    int64           `json:"redis_calls"`    // MatchmakingRepository が行った Redis へのラウンドトリップ数
+}
+
+// MatchmakingMetrics はマッチングの遅延と Redis 呼び出し回数を集計する
+type MatchmakingMetrics struct {
+	attempts      map[MatchTrigger]int64
+	wait          DurationSummary
+	pairLatency   DurationSummary
+	matches       int64
+	emptyAttempts int64
+	redisCalls    atomic.Int64
+	mu            sync.Mutex
+}
+
+func newMatchmakingMetrics() *MatchmakingMetrics {
+	return &MatchmakingMetrics{attempts: make(map[MatchTrigger]int64)}
+}
+
+func (m *MatchmakingMetrics) recordAttempt(trigger MatchTrigger, matched bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attempts[trigger]++
+	if !matched {
+		m.emptyAttempts++
+	}
+}
+
+func (m *MatchmakingMetrics) recordMatch(entries []*entity.QueueEntry, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.matches++
+	var latest time.Time
+	for _, e := range entries {
+		m.wait.observe(now.Sub(e.EnqueuedAt))
+		if e.EnqueuedAt.After(latest) {
+			latest = e.EnqueuedAt
+		}
+	}
+	m.pairLatency.observe(now.Sub(latest))
+}
+
+// Snapshot は現在の集計値を返す
+func (m *MatchmakingMetrics) Snapshot() MatchmakingMetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	attempts := make(map[MatchTrigger]int64, len(m.attempts))
+	for k, v := range m.attempts {
+		attempts[k] = v
+	}
+	return MatchmakingMetricsSnapshot{
+		Attempts:      attempts,
+		Wait:          m.wait,
+		PairLatency:   m.pairLatency,
+		Matches:       m.matches,
+		EmptyAttempts: m.emptyAttempts,
+		RedisCalls:    m.redisCalls.Load(),
+	}
+}
+
+// countingMatchmakingRepository は呼び出しごとに Redis へのラウンドトリップを1回として数える
+// 各メソッドはパイプラインまたは Lua スクリプトで1回のラウンドトリップにまとまっている
+type countingMatchmakingRepository struct {
+	repository.MatchmakingRepository
+	calls *atomic.Int64
+}
+
+func (r *countingMatchmakingRepository) Enqueue(ctx context.Context, entry entity.QueueEntry) error {
+	r.calls.Add(1)
+	return r.MatchmakingRepository.Enqueue(ctx, entry)
+}
+
+func (r *countingMatchmakingRepository) Requeue(ctx context.Context, entry entity.QueueEntry) error {
+	r.calls.Add(1)
+	return r.MatchmakingRepository.Requeue(ctx, entry)
+}
+
+func (r *countingMatchmakingRepository) Dequeue(
+	ctx context.Context,
+	window entity.RateWindow,
+	now time.Time,
+) (*entity.QueueEntry, *entity.QueueEntry, error) {
+	r.calls.Add(1)
+	return r.MatchmakingRepository.Dequeue(ctx, window, now)
+}
+
+func (r *countingMatchmakingRepository) DequeueTeams(
+	ctx context.Context,
+	teamSize int,
+	window entity.RateWindow,
+	now time.Time,
+) ([]*entity.QueueEntry, error) {
+	r.calls.Add(1)
+	return r.MatchmakingRepository.DequeueTeams(ctx, teamSize, window, now)
+}
+
+func (r *countingMatchmakingRepository) Remove(ctx context.Context, userID uuid.UUID) error {
+	r.calls.Add(1)
+	return r.MatchmakingRepository.Remove(ctx, userID)
+}
+
+func (r *countingMatchmakingRepository) SetActive(ctx context.Context, userID uuid.UUID) (bool, error) {
+	r.calls.Add(1)
+	return r.MatchmakingRepository.SetActive(ctx, userID)
+}
+
+func (r *countingMatchmakingRepository) ClearActive(ctx context.Context, userID uuid.UUID) error {
+	r.calls.Add(1)
+	return r.MatchmakingRepository.ClearActive(ctx, userID)
+}
+
+func (r *countingMatchmakingRepository) SubscribeQueueChanges(ctx context.Context) (<-chan struct{}, error) {
+	r.calls.Add(1)
+	return r.MatchmakingRepository.SubscribeQueueChanges(ctx)
+}
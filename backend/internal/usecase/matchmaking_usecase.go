@@ -15,31 +15,63 @@ import (
 // ErrAlreadyInQueue はユーザーが既にマッチングキューにいる場合のエラー
 var ErrAlreadyInQueue = errors.New("already_in_queue")
 
+// MatchmakingResult は成立したマッチ
+// Players はルームの参加者（Room.Members）と同じ順で、1対1では Player1・Player2 の順
 type MatchmakingResult struct {
 	Room    *entity.Room
-	Player1 *entity.User
-	Player2 *entity.User
+	Players []*entity.User
 }
 
 type MatchmakingUsecase struct {
 	matchmakingRepo repository.MatchmakingRepository
 	roomRepo        repository.RoomRepository
 	userRepo        repository.UserRepository
+	metrics         *MatchmakingMetrics
+	window          entity.RateWindow
 }
 
 func NewMatchmakingUsecase(
 	matchmakingRepo repository.MatchmakingRepository,
 	roomRepo repository.RoomRepository,
 	userRepo repository.UserRepository,
+	window entity.RateWindow,
 ) *MatchmakingUsecase {
+	metrics := newMatchmakingMetrics()
 	return &MatchmakingUsecase{
-		matchmakingRepo: matchmakingRepo,
+		matchmakingRepo: &countingMatchmakingRepository{MatchmakingRepository: matchmakingRepo, calls: &metrics.redisCalls},
 		roomRepo:        roomRepo,
 		userRepo:        userRepo,
+		metrics:         metrics,
+		window:          window,
 	}
 }
 
-func (uc *MatchmakingUsecase) JoinQueue(ctx context.Context, userID uuid.UUID) error {
+// Metrics はマッチングの集計値を返す
+func (uc *MatchmakingUsecase) Metrics() MatchmakingMetricsSnapshot {
+	return uc.metrics.Snapshot()
+}
+
+// WatchQueue はキューへの追加を知らせるチャネルを返す。ctx が終わるとチャネルは閉じられる
+func (uc *MatchmakingUsecase) WatchQueue(ctx context.Context) (<-chan struct{}, error) {
+	ch, err := uc.matchmakingRepo.SubscribeQueueChanges(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe queue changes: %w", err)
+	}
+	return ch, nil
+}
+
+// JoinQueue はユーザーを現在のレートで、1チーム teamSize 人の形式のマッチングキューに追加する（1 は1対1）
+func (uc *MatchmakingUsecase) JoinQueue(ctx context.Context, user *entity.User, teamSize int) error {
+	if err := uc.ReserveQueue(ctx, user.ID); err != nil {
+		return err
+	}
+	return uc.EnqueueReserved(ctx, user, teamSize)
+}
+
+// ReserveQueue はユーザーをキューに参加中として記録する。既に参加中の場合は ErrAlreadyInQueue を返す
+// キューにはまだ追加しないため、マッチの通知先を用意してから EnqueueReserved を呼ぶ
+// 成功した場合、呼び出し元は EnqueueReserved か LeaveQueue を必ず呼ぶこと
+func (uc *MatchmakingUsecase) ReserveQueue(ctx context.Context, userID uuid.UUID) error {
 	ok, err := uc.matchmakingRepo.SetActive(ctx, userID)
 	if err != nil {
 		return fmt.Errorf("set active: %w", err)
@@ -47,9 +79,20 @@ func (uc *MatchmakingUsecase) JoinQueue(ctx context.Context, userID uuid.UUID) e
 	if !ok {
 		return ErrAlreadyInQueue
 	}
+	return nil
+}
 
-	if err := uc.matchmakingRepo.Enqueue(ctx, userID); err != nil {
-		if clearErr := uc.matchmakingRepo.ClearActive(ctx, userID); clearErr != nil {
+// EnqueueReserved は ReserveQueue 済みのユーザーをキューに追加し、マッチングループを起こす
+// 追加に失敗した場合は参加中の記録も取り消す
+func (uc *MatchmakingUsecase) EnqueueReserved(ctx context.Context, user *entity.User, teamSize int) error {
+	entry := entity.QueueEntry{
+		UserID:     user.ID,
+		Rate:       user.Rate,
+		TeamSize:   teamSize,
+		EnqueuedAt: time.Now(),
+	}
+	if err := uc.matchmakingRepo.Enqueue(ctx, entry); err != nil {
+		if clearErr := uc.matchmakingRepo.ClearActive(ctx, user.ID); clearErr != nil {
 			log.Printf("matchmaking: clear active on enqueue failure: %v", clearErr)
 		}
 		return fmt.Errorf("enqueue: %w", err)
@@ -67,69 +110,85 @@ func (uc *MatchmakingUsecase) LeaveQueue(ctx context.Context, userID uuid.UUID)
 	return nil
 }
 
-func (uc *MatchmakingUsecase) TryMatch(ctx context.Context) (*MatchmakingResult, error) {
-	p1ID, p2ID, err := uc.matchmakingRepo.Dequeue(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("dequeue: %w", err)
+// TryMatch は1チーム teamSize 人の形式のキューから、レート差が許容幅に収まる全員を取り出してルームを作成する
+// 1対1は先に参加した側をチーム 0 とし、チーム戦はレートの合計が近くなるようにチームを分ける
+// 許容幅は待ち時間に応じて広がるため、同じキューでも呼び出し時刻によって結果が変わる
+// trigger は試行のきっかけで、集計にのみ使う
+func (uc *MatchmakingUsecase) TryMatch(ctx context.Context, teamSize int, trigger MatchTrigger) (*MatchmakingResult, error) {
+	now := time.Now()
+	var teams [2][]*entity.QueueEntry
+	if teamSize <= 1 {
+		e1, e2, err := uc.matchmakingRepo.Dequeue(ctx, uc.window, now)
+		if err != nil {
+			return nil, fmt.Errorf("dequeue: %w", err)
+		}
+		if e1 != nil && e2 != nil {
+			teams = [2][]*entity.QueueEntry{{e1}, {e2}}
+		}
+	} else {
+		entries, err := uc.matchmakingRepo.DequeueTeams(ctx, teamSize, uc.window, now)
+		if err != nil {
+			return nil, fmt.Errorf("dequeue teams: %w", err)
+		}
+		if len(entries) > 0 {
+			teams = entity.BalanceTeams(entries)
+		}
 	}
-	if p1ID == uuid.Nil || p2ID == uuid.Nil {
+	if len(teams[0]) == 0 || len(teams[1]) == 0 {
+		uc.metrics.recordAttempt(trigger, false)
 		return nil, nil
 	}
+	uc.metrics.recordAttempt(trigger, true)
+	entries := append(append([]*entity.QueueEntry{}, teams[0]...), teams[1]...)
 
 	// Dequeue 成功後のエラーパスでは active フラグをクリアしてキューに戻す
-	clearBoth := func() {
-		if clearErr := uc.matchmakingRepo.ClearActive(ctx, p1ID); clearErr != nil {
-			log.Printf("matchmaking: clear active p1: %v", clearErr)
-		}
-		if clearErr := uc.matchmakingRepo.ClearActive(ctx, p2ID); clearErr != nil {
-			log.Printf("matchmaking: clear active p2: %v", clearErr)
+	clearAll := func() {
+		for _, e := range entries {
+			if clearErr := uc.matchmakingRepo.ClearActive(ctx, e.UserID); clearErr != nil {
+				log.Printf("matchmaking: clear active %s: %v", e.UserID, clearErr)
+			}
 		}
 	}
-	requeueBoth := func() {
-		if reqErr := uc.matchmakingRepo.Enqueue(ctx, p1ID); reqErr != nil {
-			log.Printf("matchmaking: requeue p1: %v", reqErr)
-		}
-		if reqErr := uc.matchmakingRepo.Enqueue(ctx, p2ID); reqErr != nil {
-			log.Printf("matchmaking: requeue p2: %v", reqErr)
+	// 元の参加時刻を保ったまま戻し、広がった許容幅を失わないようにする
+	// キューの変更は通知せず、次の見回りで再び試みる（DB の障害中に試行が連鎖しないようにする）
+	requeueAll := func() {
+		for _, e := range entries {
+			if reqErr := uc.matchmakingRepo.Requeue(ctx, *e); reqErr != nil {
+				log.Printf("matchmaking: requeue %s: %v", e.UserID, reqErr)
+			}
 		}
 	}
 
-	// ユーザー情報取得
-	player1, err := uc.userRepo.GetByID(ctx, p1ID)
-	if err != nil {
-		requeueBoth()
-		clearBoth()
-		return nil, fmt.Errorf("get player1: %w", err)
-	}
-	player2, err := uc.userRepo.GetByID(ctx, p2ID)
-	if err != nil {
-		requeueBoth()
-		clearBoth()
-		return nil, fmt.Errorf("get player2: %w", err)
+	// ユーザー情報取得（ルームの参加者と同じ順に並べる）
+	var teamIDs [2][]uuid.UUID
+	players := make([]*entity.User, 0, len(entries))
+	for t, team := range teams {
+		for _, e := range team {
+			user, err := uc.userRepo.GetByID(ctx, e.UserID)
+			if err != nil {
+				requeueAll()
+				clearAll()
+				return nil, fmt.Errorf("get player %s: %w", e.UserID, err)
+			}
+			teamIDs[t] = append(teamIDs[t], e.UserID)
+			players = append(players, user)
+		}
 	}
 
 	// ルーム生成
-	room := &entity.Room{
-		ID:        uuid.New(),
-		Player1ID: p1ID,
-		Player2ID: p2ID,
-		Status:    entity.RoomStatusWaiting,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-
+	room := entity.NewRoom(uuid.New(), teamIDs, time.Now())
 	if err := uc.roomRepo.Create(ctx, room); err != nil {
-		requeueBoth()
-		clearBoth()
+		requeueAll()
+		clearAll()
 		return nil, fmt.Errorf("create room: %w", err)
 	}
 
 	// active フラグをクリア（正常系）
-	clearBoth()
+	clearAll()
+	uc.metrics.recordMatch(entries, now)
 
 	return &MatchmakingResult{
 		Room:    room,
-		Player1: player1,
-		Player2: player2,
+		Players: players,
 	}, nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -12,25 +13,29 @@ import (
 	"github.com/tobakuro/hackathon_nulabcup/backend/internal/testutil"
 )
 
+var testRateWindow = entity.RateWindow{Base: 100, WidenPerSec: 10, Max: 500}
+
 func TestJoinQueue_Success(t *testing.T) {
-	userID := uuid.New()
-	var enqueuedID uuid.UUID
+	user := &entity.User{ID: uuid.New(), Rate: 1720}
+	var enqueued entity.QueueEntry
 
 	mmRepo := &testutil.MockMatchmakingRepository{
 		SetActiveFunc: func(_ context.Context, id uuid.UUID) (bool, error) {
 			return true, nil
 		},
-		EnqueueFunc: func(_ context.Context, id uuid.UUID) error {
-			enqueuedID = id
+		EnqueueFunc: func(_ context.Context, entry entity.QueueEntry) error {
+			enqueued = entry
 			return nil
 		},
 	}
 
-	uc := NewMatchmakingUsecase(mmRepo, nil, nil)
-	err := uc.JoinQueue(context.Background(), userID)
+	uc := NewMatchmakingUsecase(mmRepo, nil, nil, testRateWindow)
+	err := uc.JoinQueue(context.Background(), user, 1)
 
 	require.NoError(t, err)
-	assert.Equal(t, userID, enqueuedID)
+	assert.Equal(t, user.ID, enqueued.UserID)
+	assert.Equal(t, 1720, enqueued.Rate)
+	assert.False(t, enqueued.EnqueuedAt.IsZero())
 }
 
 func TestJoinQueue_AlreadyInQueue(t *testing.T) {
@@ -40,8 +45,8 @@ func TestJoinQueue_AlreadyInQueue(t *testing.T) {
 		},
 	}
 
-	uc := NewMatchmakingUsecase(mmRepo, nil, nil)
-	err := uc.JoinQueue(context.Background(), uuid.New())
+	uc := NewMatchmakingUsecase(mmRepo, nil, nil, testRateWindow)
+	err := uc.JoinQueue(context.Background(), &entity.User{ID: uuid.New()}, 1)
 
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "already_in_queue")
@@ -54,8 +59,8 @@ func TestJoinQueue_SetActiveFails(t *testing.T) {
 		},
 	}
 
-	uc := NewMatchmakingUsecase(mmRepo, nil, nil)
-	err := uc.JoinQueue(context.Background(), uuid.New())
+	uc := NewMatchmakingUsecase(mmRepo, nil, nil, testRateWindow)
+	err := uc.JoinQueue(context.Background(), &entity.User{ID: uuid.New()}, 1)
 
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "set active")
@@ -68,7 +73,7 @@ func TestJoinQueue_EnqueueFails_ClearsActive(t *testing.T) {
 		SetActiveFunc: func(_ context.Context, _ uuid.UUID) (bool, error) {
 			return true, nil
 		},
-		EnqueueFunc: func(_ context.Context, _ uuid.UUID) error {
+		EnqueueFunc: func(_ context.Context, _ entity.QueueEntry) error {
 			return errors.New("enqueue error")
 		},
 		ClearActiveFunc: func(_ context.Context, _ uuid.UUID) error {
@@ -77,8 +82,8 @@ func TestJoinQueue_EnqueueFails_ClearsActive(t *testing.T) {
 		},
 	}
 
-	uc := NewMatchmakingUsecase(mmRepo, nil, nil)
-	err := uc.JoinQueue(context.Background(), uuid.New())
+	uc := NewMatchmakingUsecase(mmRepo, nil, nil, testRateWindow)
+	err := uc.JoinQueue(context.Background(), &entity.User{ID: uuid.New()}, 1)
 
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "enqueue")
@@ -102,7 +107,7 @@ func TestLeaveQueue_Success(t *testing.T) {
 		},
 	}
 
-	uc := NewMatchmakingUsecase(mmRepo, nil, nil)
+	uc := NewMatchmakingUsecase(mmRepo, nil, nil, testRateWindow)
 	err := uc.LeaveQueue(context.Background(), uuid.New())
 
 	require.NoError(t, err)
@@ -117,7 +122,7 @@ func TestLeaveQueue_RemoveFails(t *testing.T) {
 		},
 	}
 
-	uc := NewMatchmakingUsecase(mmRepo, nil, nil)
+	uc := NewMatchmakingUsecase(mmRepo, nil, nil, testRateWindow)
 	err := uc.LeaveQueue(context.Background(), uuid.New())
 
 	require.Error(t, err)
@@ -133,8 +138,8 @@ func TestTryMatch_Success(t *testing.T) {
 	player2 := &entity.User{ID: p2ID, GitHubLogin: "player2", Rate: 1600}
 
 	mmRepo := &testutil.MockMatchmakingRepository{
-		DequeueFunc: func(_ context.Context) (uuid.UUID, uuid.UUID, error) {
-			return p1ID, p2ID, nil
+		DequeueFunc: func(_ context.Context, _ entity.RateWindow, _ time.Time) (*entity.QueueEntry, *entity.QueueEntry, error) {
+			return &entity.QueueEntry{UserID: p1ID}, &entity.QueueEntry{UserID: p2ID}, nil
 		},
 		ClearActiveFunc: func(_ context.Context, id uuid.UUID) error {
 			clearedIDs = append(clearedIDs, id)
@@ -157,13 +162,13 @@ func TestTryMatch_Success(t *testing.T) {
 		},
 	}
 
-	uc := NewMatchmakingUsecase(mmRepo, roomRepo, userRepo)
-	result, err := uc.TryMatch(context.Background())
+	uc := NewMatchmakingUsecase(mmRepo, roomRepo, userRepo, testRateWindow)
+	result, err := uc.TryMatch(context.Background(), 1, MatchTriggerSweep)
 
 	require.NoError(t, err)
 	require.NotNil(t, result)
-	assert.Equal(t, player1, result.Player1)
-	assert.Equal(t, player2, result.Player2)
+	assert.Equal(t, []*entity.User{player1, player2}, result.Players)
+	assert.Empty(t, result.Room.Players, "1v1 rooms keep members in player1_id/player2_id")
 	assert.Equal(t, p1ID, result.Room.Player1ID)
 	assert.Equal(t, p2ID, result.Room.Player2ID)
 	assert.Equal(t, entity.RoomStatusWaiting, result.Room.Status)
@@ -172,15 +177,67 @@ func TestTryMatch_Success(t *testing.T) {
 	assert.Contains(t, clearedIDs, p2ID)
 }
 
+func TestTryMatch_Teams(t *testing.T) {
+	users := map[uuid.UUID]*entity.User{}
+	var entries []*entity.QueueEntry
+	for _, rate := range []int{1400, 1500, 1600, 1700} {
+		u := &entity.User{ID: uuid.New(), Rate: rate}
+		users[u.ID] = u
+		entries = append(entries, &entity.QueueEntry{UserID: u.ID, Rate: rate, TeamSize: 2})
+	}
+	var gotTeamSize int
+	mmRepo := &testutil.MockMatchmakingRepository{
+		DequeueFunc: func(context.Context, entity.RateWindow, time.Time) (*entity.QueueEntry, *entity.QueueEntry, error) {
+			t.Fatal("team matches must not use the 1v1 queue")
+			return nil, nil, nil
+		},
+		DequeueTeamsFunc: func(_ context.Context, teamSize int, _ entity.RateWindow, _ time.Time) ([]*entity.QueueEntry, error) {
+			gotTeamSize = teamSize
+			return entries, nil
+		},
+	}
+	userRepo := &testutil.MockUserRepository{
+		GetByIDFunc: func(_ context.Context, id uuid.UUID) (*entity.User, error) {
+			return users[id], nil
+		},
+	}
+	var created *entity.Room
+	roomRepo := &testutil.MockRoomRepository{
+		CreateFunc: func(_ context.Context, room *entity.Room) error {
+			created = room
+			return nil
+		},
+	}
+
+	uc := NewMatchmakingUsecase(mmRepo, roomRepo, userRepo, testRateWindow)
+	result, err := uc.TryMatch(context.Background(), 2, MatchTriggerSweep)
+
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, 2, gotTeamSize)
+	assert.Same(t, created, result.Room)
+	// 1700+1400 と 1600+1500 に分ける
+	members := result.Room.Members()
+	require.Len(t, members, 4)
+	require.Len(t, result.Players, 4)
+	var totals [2]int
+	for i, m := range members {
+		assert.Equal(t, m.UserID, result.Players[i].ID, "players follow the room members")
+		totals[m.Team] += users[m.UserID].Rate
+	}
+	assert.Equal(t, [2]int{3100, 3100}, totals)
+	assert.Equal(t, members[0].UserID, result.Room.Player1ID)
+}
+
 func TestTryMatch_QueueInsufficient(t *testing.T) {
 	mmRepo := &testutil.MockMatchmakingRepository{
-		DequeueFunc: func(_ context.Context) (uuid.UUID, uuid.UUID, error) {
-			return uuid.Nil, uuid.Nil, nil
+		DequeueFunc: func(_ context.Context, _ entity.RateWindow, _ time.Time) (*entity.QueueEntry, *entity.QueueEntry, error) {
+			return nil, nil, nil
 		},
 	}
 
-	uc := NewMatchmakingUsecase(mmRepo, nil, nil)
-	result, err := uc.TryMatch(context.Background())
+	uc := NewMatchmakingUsecase(mmRepo, nil, nil, testRateWindow)
+	result, err := uc.TryMatch(context.Background(), 1, MatchTriggerSweep)
 
 	require.NoError(t, err)
 	assert.Nil(t, result)
@@ -192,8 +249,8 @@ func TestTryMatch_GetPlayer1Fails(t *testing.T) {
 	var clearedIDs []uuid.UUID
 
 	mmRepo := &testutil.MockMatchmakingRepository{
-		DequeueFunc: func(_ context.Context) (uuid.UUID, uuid.UUID, error) {
-			return p1ID, p2ID, nil
+		DequeueFunc: func(_ context.Context, _ entity.RateWindow, _ time.Time) (*entity.QueueEntry, *entity.QueueEntry, error) {
+			return &entity.QueueEntry{UserID: p1ID}, &entity.QueueEntry{UserID: p2ID}, nil
 		},
 		ClearActiveFunc: func(_ context.Context, id uuid.UUID) error {
 			clearedIDs = append(clearedIDs, id)
@@ -207,12 +264,12 @@ func TestTryMatch_GetPlayer1Fails(t *testing.T) {
 		},
 	}
 
-	uc := NewMatchmakingUsecase(mmRepo, nil, userRepo)
-	result, err := uc.TryMatch(context.Background())
+	uc := NewMatchmakingUsecase(mmRepo, nil, userRepo, testRateWindow)
+	result, err := uc.TryMatch(context.Background(), 1, MatchTriggerSweep)
 
 	require.Error(t, err)
 	assert.Nil(t, result)
-	assert.Contains(t, err.Error(), "get player1")
+	assert.Contains(t, err.Error(), "get player "+p1ID.String())
 	assert.Len(t, clearedIDs, 2, "ClearActive should be called for both players on error")
 	assert.Contains(t, clearedIDs, p1ID)
 	assert.Contains(t, clearedIDs, p2ID)
@@ -225,8 +282,8 @@ func TestTryMatch_GetPlayer2Fails(t *testing.T) {
 	var clearedIDs []uuid.UUID
 
 	mmRepo := &testutil.MockMatchmakingRepository{
-		DequeueFunc: func(_ context.Context) (uuid.UUID, uuid.UUID, error) {
-			return p1ID, p2ID, nil
+		DequeueFunc: func(_ context.Context, _ entity.RateWindow, _ time.Time) (*entity.QueueEntry, *entity.QueueEntry, error) {
+			return &entity.QueueEntry{UserID: p1ID}, &entity.QueueEntry{UserID: p2ID}, nil
 		},
 		ClearActiveFunc: func(_ context.Context, id uuid.UUID) error {
 			clearedIDs = append(clearedIDs, id)
@@ -243,12 +300,12 @@ func TestTryMatch_GetPlayer2Fails(t *testing.T) {
 		},
 	}
 
-	uc := NewMatchmakingUsecase(mmRepo, nil, userRepo)
-	result, err := uc.TryMatch(context.Background())
+	uc := NewMatchmakingUsecase(mmRepo, nil, userRepo, testRateWindow)
+	result, err := uc.TryMatch(context.Background(), 1, MatchTriggerSweep)
 
 	require.Error(t, err)
 	assert.Nil(t, result)
-	assert.Contains(t, err.Error(), "get player2")
+	assert.Contains(t, err.Error(), "get player "+p2ID.String())
 	assert.Len(t, clearedIDs, 2, "ClearActive should be called for both players on error")
 	assert.Contains(t, clearedIDs, p1ID)
 	assert.Contains(t, clearedIDs, p2ID)
@@ -260,8 +317,8 @@ func TestTryMatch_CreateRoomFails(t *testing.T) {
 	var clearedIDs []uuid.UUID
 
 	mmRepo := &testutil.MockMatchmakingRepository{
-		DequeueFunc: func(_ context.Context) (uuid.UUID, uuid.UUID, error) {
-			return p1ID, p2ID, nil
+		DequeueFunc: func(_ context.Context, _ entity.RateWindow, _ time.Time) (*entity.QueueEntry, *entity.QueueEntry, error) {
+			return &entity.QueueEntry{UserID: p1ID}, &entity.QueueEntry{UserID: p2ID}, nil
 		},
 		ClearActiveFunc: func(_ context.Context, id uuid.UUID) error {
 			clearedIDs = append(clearedIDs, id)
@@ -281,8 +338,8 @@ func TestTryMatch_CreateRoomFails(t *testing.T) {
 		},
 	}
 
-	uc := NewMatchmakingUsecase(mmRepo, roomRepo, userRepo)
-	result, err := uc.TryMatch(context.Background())
+	uc := NewMatchmakingUsecase(mmRepo, roomRepo, userRepo, testRateWindow)
+	result, err := uc.TryMatch(context.Background(), 1, MatchTriggerSweep)
 
 	require.Error(t, err)
 	assert.Nil(t, result)
@@ -291,3 +348,87 @@ func TestTryMatch_CreateRoomFails(t *testing.T) {
 	assert.Contains(t, clearedIDs, p1ID)
 	assert.Contains(t, clearedIDs, p2ID)
 }
+
+func TestTryMatch_PassesRateWindow(t *testing.T) {
+	var gotWindow entity.RateWindow
+
+	mmRepo := &testutil.MockMatchmakingRepository{
+		DequeueFunc: func(_ context.Context, window entity.RateWindow, _ time.Time) (*entity.QueueEntry, *entity.QueueEntry, error) {
+			gotWindow = window
+			return nil, nil, nil
+		},
+	}
+
+	uc := NewMatchmakingUsecase(mmRepo, nil, nil, testRateWindow)
+	_, err := uc.TryMatch(context.Background(), 1, MatchTriggerSweep)
+
+	require.NoError(t, err)
+	assert.Equal(t, testRateWindow, gotWindow)
+}
+
+func TestTryMatch_RequeuePreservesEnqueuedAt(t *testing.T) {
+	joined := time.Now().Add(-30 * time.Second)
+	e1 := &entity.QueueEntry{UserID: uuid.New(), Rate: 1500, EnqueuedAt: joined}
+	e2 := &entity.QueueEntry{UserID: uuid.New(), Rate: 1800, EnqueuedAt: joined.Add(5 * time.Second)}
+	var requeued []entity.QueueEntry
+
+	mmRepo := &testutil.MockMatchmakingRepository{
+		DequeueFunc: func(_ context.Context, _ entity.RateWindow, _ time.Time) (*entity.QueueEntry, *entity.QueueEntry, error) {
+			return e1, e2, nil
+		},
+		RequeueFunc: func(_ context.Context, entry entity.QueueEntry) error {
+			requeued = append(requeued, entry)
+			return nil
+		},
+	}
+
+	userRepo := &testutil.MockUserRepository{
+		GetByIDFunc: func(_ context.Context, _ uuid.UUID) (*entity.User, error) {
+			return nil, errors.New("user not found")
+		},
+	}
+
+	uc := NewMatchmakingUsecase(mmRepo, nil, userRepo, testRateWindow)
+	_, err := uc.TryMatch(context.Background(), 1, MatchTriggerSweep)
+
+	require.Error(t, err)
+	require.Len(t, requeued, 2)
+	assert.Equal(t, *e1, requeued[0], "requeue should keep the original wait time")
+	assert.Equal(t, *e2, requeued[1])
+}
+
+func TestTryMatch_RecordsMetrics(t *testing.T) {
+	p1 := &entity.User{ID: uuid.New()}
+	p2 := &entity.User{ID: uuid.New()}
+	now := time.Now()
+	mmRepo := &testutil.MockMatchmakingRepository{
+		DequeueFunc: func(_ context.Context, _ entity.RateWindow, _ time.Time) (*entity.QueueEntry, *entity.QueueEntry, error) {
+			return &entity.QueueEntry{UserID: p1.ID, EnqueuedAt: now.Add(-3 * time.Second)},
+				&entity.QueueEntry{UserID: p2.ID, EnqueuedAt: now.Add(-time.Second)}, nil
+		},
+	}
+	userRepo := &testutil.MockUserRepository{
+		GetByIDFunc: func(_ context.Context, id uuid.UUID) (*entity.User, error) {
+			return &entity.User{ID: id}, nil
+		},
+	}
+	roomRepo := &testutil.MockRoomRepository{
+		CreateFunc: func(context.Context, *entity.Room) error { return nil },
+	}
+
+	uc := NewMatchmakingUsecase(mmRepo, roomRepo, userRepo, testRateWindow)
+	_, err := uc.TryMatch(context.Background(), 1, MatchTriggerQueueChange)
+	require.NoError(t, err)
+
+	m := uc.Metrics()
+	assert.Equal(t, int64(1), m.Matches)
+	assert.Equal(t, int64(1), m.Attempts[MatchTriggerQueueChange])
+	assert.Zero(t, m.EmptyAttempts)
+	// Dequeue と2人分の ClearActive
+	assert.Equal(t, int64(3), m.RedisCalls)
+	assert.Equal(t, int64(2), m.Wait.Count)
+	assert.GreaterOrEqual(t, m.Wait.MaxMs, int64(3000))
+	assert.Equal(t, int64(1), m.PairLatency.Count)
+	assert.GreaterOrEqual(t, m.PairLatency.MaxMs, int64(1000))
+	assert.Less(t, m.PairLatency.MaxMs, int64(3000), "pair latency is measured from the later player")
+}
@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+)
+
+const (
+	// maxSourceBytes は1回の生成で LLM に渡すソースコードの上限
+	maxSourceBytes = 80000
+	// poolConcurrency は GeneratePool が同時に問題を生成するリポジトリの数
+	poolConcurrency = 8
+)
+
+// ErrNoRepositoryFiles は出題元となる解析済みリポジトリがないことを示す
+var ErrNoRepositoryFiles = errors.New("no analyzed repository files")
+
+// QuestionGenerator はソースコードから4択問題を生成する
+// 返す問題数は count 未満でもよい（不足分は呼び出し側でエラーにする）
+// files が空の場合に生成できない実装は ErrNoRepositoryFiles を返す
+type QuestionGenerator interface {
+	Generate(ctx context.Context, files []entity.RepositoryFile, count int) ([]entity.Question, error)
+}
+
+type QuestionUsecase struct {
+	generator QuestionGenerator
+	fileRepo  repository.RepositoryFileRepository
+}
+
+func NewQuestionUsecase(generator QuestionGenerator, fileRepo repository.RepositoryFileRepository) *QuestionUsecase {
+	return &QuestionUsecase{
+		generator: generator,
+		fileRepo:  fileRepo,
+	}
+}
+
+// GenerateForOwner は githubLogin が最後に解析したリポジトリから count 問を生成する
+// 解析済みリポジトリがない場合も空のファイル一覧で generator を呼び出す
+// 検証に通らない問題は捨て、count 問に満たない場合はエラーを返す
+func (uc *QuestionUsecase) GenerateForOwner(ctx context.Context, githubLogin string, count int) ([]entity.Question, error) {
+	files, err := uc.fileRepo.ListLatestByOwner(ctx, githubLogin)
+	if err != nil {
+		return nil, fmt.Errorf("list repository files: %w", err)
+	}
+	generated, err := uc.generator.Generate(ctx, limitSourceFiles(files, maxSourceBytes), count)
+	if err != nil {
+		return nil, fmt.Errorf("generate questions: %w", err)
+	}
+
+	questions := make([]entity.Question, 0, count)
+	for _, q := range generated {
+		if err := q.Validate(); err != nil {
+			log.Printf("question: dropping invalid generated question for %s: %v", githubLogin, err)
+			continue
+		}
+		questions = append(questions, q)
+		if len(questions) == count {
+			return questions, nil
+		}
+	}
+	return nil, fmt.Errorf("insufficient questions for %s: got %d, want %d", githubLogin, len(questions), count)
+}
+
+// GeneratePool は logins の各ユーザーのリポジトリから問題を生成し、count 問の出題プールを返す
+// 出題元が偏らないよう logins の順に1問ずつ交互に並べる
+// 生成に失敗したユーザーは飛ばし、残りで count 問に満たない場合は各ユーザーの失敗を添えてエラーを返す
+func (uc *QuestionUsecase) GeneratePool(ctx context.Context, logins []string, count int) ([]entity.Question, error) {
+	if len(logins) == 0 {
+		return nil, errors.New("no question owners")
+	}
+	perOwner := (count + len(logins) - 1) / len(logins)
+	generated := make([][]entity.Question, len(logins))
+	errs := make([]error, len(logins))
+	sem := make(chan struct{}, poolConcurrency)
+	var wg sync.WaitGroup
+	for i, login := range logins {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			generated[i], errs[i] = uc.GenerateForOwner(ctx, login, perOwner)
+			if errs[i] != nil {
+				log.Printf("question: skipping %s for the question pool: %v", login, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	pool := make([]entity.Question, 0, count)
+	for k := 0; k < perOwner && len(pool) < count; k++ {
+		for _, qs := range generated {
+			if k < len(qs) && len(pool) < count {
+				pool = append(pool, qs[k])
+			}
+		}
+	}
+	if len(pool) < count {
+		return nil, fmt.Errorf("insufficient questions for the pool: got %d, want %d: %w", len(pool), count, errors.Join(errs...))
+	}
+	return pool, nil
+}
+
+// limitSourceFiles はファイル内容の合計が maxBytes に収まるよう先頭から切り詰める
+func limitSourceFiles(files []entity.RepositoryFile, maxBytes int) []entity.RepositoryFile {
+	limited := make([]entity.RepositoryFile, 0, len(files))
+	remaining := maxBytes
+	for _, f := range files {
+		if remaining <= 0 {
+			break
+		}
+		if len(f.Content) > remaining {
+			// マルチバイト文字の途中で切らないよう文字境界まで戻す
+			cut := remaining
+			for cut > 0 && !utf8.RuneStart(f.Content[cut]) {
+				cut--
+			}
+			f.Content = f.Content[:cut]
+			remaining = 0
+		} else {
+			remaining -= len(f.Content)
+		}
+		limited = append(limited, f)
+	}
+	return limited
+}
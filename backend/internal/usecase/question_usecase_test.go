@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/testutil"
+)
+
+// stubGenerator は渡された引数を記録して固定の問題を返す
+type stubGenerator struct {
+	files     []entity.RepositoryFile
+	questions []entity.Question
+}
+
+func (g *stubGenerator) Generate(_ context.Context, files []entity.RepositoryFile, _ int) ([]entity.Question, error) {
+	g.files = files
+	return g.questions, nil
+}
+
+func validQuestion(text string) entity.Question {
+	return entity.Question{
+		QuestionText:  text,
+		Choices:       []string{"a", "b", "c", "d"},
+		CorrectAnswer: "a",
+	}
+}
+
+func TestQuestionUsecase_GenerateForOwner_DropsInvalid(t *testing.T) {
+	invalid := validQuestion("broken")
+	invalid.CorrectAnswer = "not a choice"
+	gen := &stubGenerator{questions: []entity.Question{
+		validQuestion("q1"), invalid, validQuestion("q2"), validQuestion("q3"),
+	}}
+	fileRepo := &testutil.MockRepositoryFileRepository{
+		ListLatestByOwnerFunc: func(_ context.Context, owner string) ([]entity.RepositoryFile, error) {
+			assert.Equal(t, "alice", owner)
+			return []entity.RepositoryFile{{FilePath: "main.go", Content: "package main"}}, nil
+		},
+	}
+
+	uc := NewQuestionUsecase(gen, fileRepo)
+	qs, err := uc.GenerateForOwner(context.Background(), "alice", 2)
+
+	require.NoError(t, err)
+	require.Len(t, qs, 2)
+	assert.Equal(t, "q1", qs[0].QuestionText)
+	assert.Equal(t, "q2", qs[1].QuestionText)
+}
+
+func TestQuestionUsecase_GenerateForOwner_Insufficient(t *testing.T) {
+	gen := &stubGenerator{questions: []entity.Question{validQuestion("q1")}}
+	fileRepo := &testutil.MockRepositoryFileRepository{
+		ListLatestByOwnerFunc: func(_ context.Context, _ string) ([]entity.RepositoryFile, error) {
+			return []entity.RepositoryFile{{FilePath: "main.go"}}, nil
+		},
+	}
+
+	uc := NewQuestionUsecase(gen, fileRepo)
+	_, err := uc.GenerateForOwner(context.Background(), "alice", 3)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "insufficient questions")
+}
+
+func TestQuestionUsecase_GenerateForOwner_LimitsSource(t *testing.T) {
+	gen := &stubGenerator{questions: []entity.Question{validQuestion("q1")}}
+	big := strings.Repeat("あ", maxSourceBytes) // 3 バイト文字で上限を超える
+	fileRepo := &testutil.MockRepositoryFileRepository{
+		ListLatestByOwnerFunc: func(_ context.Context, _ string) ([]entity.RepositoryFile, error) {
+			return []entity.RepositoryFile{
+				{FilePath: "a.txt", Content: big},
+				{FilePath: "b.txt", Content: "dropped"},
+			}, nil
+		},
+	}
+
+	uc := NewQuestionUsecase(gen, fileRepo)
+	_, err := uc.GenerateForOwner(context.Background(), "alice", 1)
+
+	require.NoError(t, err)
+	require.Len(t, gen.files, 1, "files beyond the limit should not be sent")
+	assert.LessOrEqual(t, len(gen.files[0].Content), maxSourceBytes)
+	assert.True(t, strings.HasSuffix(gen.files[0].Content, "あ"), "content should be cut on a rune boundary")
+}
+
+// ownerGenerator は渡されたファイル名を問題文にした問題を count 問返す
+type ownerGenerator struct{}
+
+func (ownerGenerator) Generate(_ context.Context, files []entity.RepositoryFile, count int) ([]entity.Question, error) {
+	qs := make([]entity.Question, count)
+	for i := range qs {
+		qs[i] = validQuestion(fmt.Sprintf("%s-%d", files[0].FilePath, i))
+	}
+	return qs, nil
+}
+
+func TestQuestionUsecase_GeneratePool_InterleavesOwners(t *testing.T) {
+	fileRepo := &testutil.MockRepositoryFileRepository{
+		ListLatestByOwnerFunc: func(_ context.Context, owner string) ([]entity.RepositoryFile, error) {
+			if owner == "broken" {
+				return nil, errors.New("db down")
+			}
+			return []entity.RepositoryFile{{FilePath: owner}}, nil
+		},
+	}
+	uc := NewQuestionUsecase(ownerGenerator{}, fileRepo)
+
+	pool, err := uc.GeneratePool(context.Background(), []string{"alice", "broken", "bob"}, 4)
+
+	require.NoError(t, err)
+	texts := make([]string, len(pool))
+	for i, q := range pool {
+		texts[i] = q.QuestionText
+	}
+	// 失敗したユーザーを飛ばし、出題元を交互に並べる
+	assert.Equal(t, []string{"alice-0", "bob-0", "alice-1", "bob-1"}, texts)
+}
+
+func TestQuestionUsecase_GeneratePool_Insufficient(t *testing.T) {
+	fileRepo := &testutil.MockRepositoryFileRepository{
+		ListLatestByOwnerFunc: func(_ context.Context, owner string) ([]entity.RepositoryFile, error) {
+			if owner == "broken" {
+				return nil, errors.New("db down")
+			}
+			return []entity.RepositoryFile{{FilePath: owner}}, nil
+		},
+	}
+	uc := NewQuestionUsecase(ownerGenerator{}, fileRepo)
+
+	_, err := uc.GeneratePool(context.Background(), []string{"alice", "broken"}, 2)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "insufficient questions for the pool")
+	assert.Contains(t, err.Error(), "db down")
+}
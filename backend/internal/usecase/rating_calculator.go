@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	defaultRate             = 1500
+	defaultRatingDeviation  = 350.0
+	defaultRatingVolatility = 0.06
+)
+
+// PlayerRating はレーティング計算の入出力
+// Deviation と Volatility は Glicko-2 でのみ使用する
+type PlayerRating struct {
+	Deviation  float64
+	Volatility float64
+	Rate       int
+}
+
+// RatingCalculator は1試合の結果から両プレイヤーの新しいレーティングを計算する
+// scoreA はプレイヤー A から見た結果（勝ち=1, 引き分け=0.5, 負け=0）
+type RatingCalculator interface {
+	Calculate(a, b PlayerRating, scoreA float64) (PlayerRating, PlayerRating)
+}
+
+// NewRatingCalculator はアルゴリズム名から RatingCalculator を生成する
+func NewRatingCalculator(algorithm string, eloK int, glicko2Tau float64) (RatingCalculator, error) {
+	switch algorithm {
+	case "", "elo":
+		return &EloCalculator{K: eloK}, nil
+	case "glicko2":
+		return &Glicko2Calculator{Tau: glicko2Tau}, nil
+	default:
+		return nil, fmt.Errorf("unknown rating algorithm %q", algorithm)
+	}
+}
+
+// EloCalculator はイロレーティングによる計算
+type EloCalculator struct {
+	K int
+}
+
+func (c *EloCalculator) Calculate(a, b PlayerRating, scoreA float64) (PlayerRating, PlayerRating) {
+	expectedA := 1 / (1 + math.Pow(10, float64(b.Rate-a.Rate)/400))
+	delta := int(math.Round(float64(c.K) * (scoreA - expectedA)))
+	// ゼロサムを保つため B の変動は A の符号反転とする
+	a.Rate += delta
+	b.Rate -= delta
+	return a, b
+}
+
+// glicko2Scale は Glicko と Glicko-2 のスケール変換係数
+const glicko2Scale = 173.7178
+
+// glicko2Epsilon はボラティリティ反復計算の収束判定値
+const glicko2Epsilon = 0.000001
+
+// Glicko2Calculator は Glicko-2 による計算（1試合を1レーティング期間として扱う）
+type Glicko2Calculator struct {
+	Tau float64 // ボラティリティの変化量を制約するシステム定数（0.3〜1.2 程度）
+}
+
+func (c *Glicko2Calculator) Calculate(a, b PlayerRating, scoreA float64) (PlayerRating, PlayerRating) {
+	return c.update(a, b, scoreA), c.update(b, a, 1-scoreA)
+}
+
+// update は対戦相手 opp とのスコア s からプレイヤー p の新しいレーティングを計算する
+func (c *Glicko2Calculator) update(p, opp PlayerRating, s float64) PlayerRating {
+	p = withRatingDefaults(p)
+	opp = withRatingDefaults(opp)
+
+	mu := float64(p.Rate-defaultRate) / glicko2Scale
+	phi := p.Deviation / glicko2Scale
+	muJ := float64(opp.Rate-defaultRate) / glicko2Scale
+	phiJ := opp.Deviation / glicko2Scale
+
+	g := 1 / math.Sqrt(1+3*phiJ*phiJ/(math.Pi*math.Pi))
+	e := 1 / (1 + math.Exp(-g*(mu-muJ)))
+	v := 1 / (g * g * e * (1 - e))
+	delta := v * g * (s - e)
+
+	sigma := c.volatility(phi, p.Volatility, v, delta)
+
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*g*(s-e)
+
+	return PlayerRating{
+		Rate:       int(math.Round(glicko2Scale*newMu)) + defaultRate,
+		Deviation:  glicko2Scale * newPhi,
+		Volatility: sigma,
+	}
+}
+
+// volatility は Illinois 法で新しいボラティリティを求める
+func (c *Glicko2Calculator) volatility(phi, sigma, v, delta float64) float64 {
+	tau := c.Tau
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		num := ex * (delta*delta - phi*phi - v - ex)
+		den := 2 * math.Pow(phi*phi+v+ex, 2)
+		return num/den - (x-a)/(tau*tau)
+	}
+
+	bigA := a
+	var bigB float64
+	if delta*delta > phi*phi+v {
+		bigB = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		bigB = a - k*tau
+	}
+
+	fA, fB := f(bigA), f(bigB)
+	for math.Abs(bigB-bigA) > glicko2Epsilon {
+		bigC := bigA + (bigA-bigB)*fA/(fB-fA)
+		fC := f(bigC)
+		if fC*fB <= 0 {
+			bigA, fA = bigB, fB
+		} else {
+			fA /= 2
+		}
+		bigB, fB = bigC, fC
+	}
+	return math.Exp(bigA / 2)
+}
+
+// withRatingDefaults は未設定のパラメータに初期値を補う
+func withRatingDefaults(p PlayerRating) PlayerRating {
+	if p.Deviation <= 0 {
+		p.Deviation = defaultRatingDeviation
+	}
+	if p.Volatility <= 0 {
+		p.Volatility = defaultRatingVolatility
+	}
+	return p
+}
@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+)
+
+// RatingChange は1試合でのレーティング変動
+type RatingChange struct {
+	Before int `json:"rate_before"`
+	After  int `json:"rate_after"`
+	Delta  int `json:"rate_delta"`
+}
+
+type RatingUsecase struct {
+	calculator RatingCalculator
+	userRepo   repository.UserRepository
+}
+
+func NewRatingUsecase(calculator RatingCalculator, userRepo repository.UserRepository) *RatingUsecase {
+	return &RatingUsecase{
+		calculator: calculator,
+		userRepo:   userRepo,
+	}
+}
+
+// ApplyMatchResult は試合結果から両プレイヤーのレーティングを更新する
+// winnerID が無効値の場合は引き分けとして扱う
+// 試合中にレートが変わっている可能性があるため、両ユーザーを行ロックしてから最新の値で計算して保存する
+func (uc *RatingUsecase) ApplyMatchResult(
+	ctx context.Context,
+	player1ID, player2ID uuid.UUID,
+	winnerID uuid.NullUUID,
+) (RatingChange, RatingChange, error) {
+	score := 0.5
+	if winnerID.Valid {
+		switch winnerID.UUID {
+		case player1ID:
+			score = 1
+		case player2ID:
+			score = 0
+		}
+	}
+
+	var c1, c2 RatingChange
+	err := uc.userRepo.UpdateRatings(ctx, []uuid.UUID{player1ID, player2ID}, func(users map[uuid.UUID]*entity.User) error {
+		p1, p2 := users[player1ID], users[player2ID]
+		if p1 == nil || p2 == nil {
+			return fmt.Errorf("players %s and %s: %w", player1ID, player2ID, ErrUserNotFound)
+		}
+		newP1, newP2 := uc.calculator.Calculate(toPlayerRating(p1), toPlayerRating(p2), score)
+		c1 = applyRating(p1, newP1)
+		c2 = applyRating(p2, newP2)
+		return nil
+	})
+	if err != nil {
+		return RatingChange{}, RatingChange{}, fmt.Errorf("update ratings: %w", err)
+	}
+	return c1, c2, nil
+}
+
+// ApplyTeamMatchResult はチーム戦の結果から全参加者のレーティングを更新し、ユーザーごとの変動を返す
+// 各参加者を、相手チームの平均（レート・RD・ボラティリティ）を持つ1人のプレイヤーと対戦したものとして計算する
+// winnerTeam が -1 の場合は引き分けとして扱う
+// 全参加者を行ロックしてから計算し、1トランザクションで保存する
+func (uc *RatingUsecase) ApplyTeamMatchResult(
+	ctx context.Context,
+	teams [2][]uuid.UUID,
+	winnerTeam int,
+) (map[uuid.UUID]RatingChange, error) {
+	for t, ids := range teams {
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("team %d has no players", t)
+		}
+	}
+
+	var changes map[uuid.UUID]RatingChange
+	err := uc.userRepo.UpdateRatings(ctx, slices.Concat(teams[0], teams[1]), func(users map[uuid.UUID]*entity.User) error {
+		var members [2][]*entity.User
+		var averages [2]PlayerRating
+		for t, ids := range teams {
+			var deviation, volatility float64
+			rate := 0
+			for _, id := range ids {
+				u := users[id]
+				if u == nil {
+					return fmt.Errorf("team %d player %s: %w", t, id, ErrUserNotFound)
+				}
+				members[t] = append(members[t], u)
+				r := withRatingDefaults(toPlayerRating(u))
+				rate += r.Rate
+				deviation += r.Deviation
+				volatility += r.Volatility
+			}
+			n := len(ids)
+			averages[t] = PlayerRating{
+				Rate:       int(math.Round(float64(rate) / float64(n))),
+				Deviation:  deviation / float64(n),
+				Volatility: volatility / float64(n),
+			}
+		}
+
+		// 平均を出し終えてから書き換える。計算中に同じチームの他メンバーの値が変わらないようにするため
+		updated := make(map[*entity.User]PlayerRating, len(users))
+		for t, us := range members {
+			score := 0.5
+			switch winnerTeam {
+			case t:
+				score = 1
+			case 1 - t:
+				score = 0
+			}
+			for _, u := range us {
+				updated[u], _ = uc.calculator.Calculate(toPlayerRating(u), averages[1-t], score)
+			}
+		}
+		changes = make(map[uuid.UUID]RatingChange, len(updated))
+		for u, r := range updated {
+			changes[u.ID] = applyRating(u, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update ratings: %w", err)
+	}
+	return changes, nil
+}
+
+// applyRating は計算結果を u に書き込み、書き込み前後の変動を返す
+func applyRating(u *entity.User, r PlayerRating) RatingChange {
+	r = withRatingDefaults(r)
+	change := newRatingChange(u.Rate, r.Rate)
+	u.Rate = r.Rate
+	u.RatingDeviation = r.Deviation
+	u.RatingVolatility = r.Volatility
+	return change
+}
+
+func toPlayerRating(u *entity.User) PlayerRating {
+	return PlayerRating{
+		Rate:       u.Rate,
+		Deviation:  u.RatingDeviation,
+		Volatility: u.RatingVolatility,
+	}
+}
+
+func newRatingChange(before, after int) RatingChange {
+	return RatingChange{
+		Before: before,
+		After:  after,
+		Delta:  after - before,
+	}
+}
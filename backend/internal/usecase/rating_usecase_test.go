@@ -0,0 +1,169 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/testutil"
+)
+
+func TestEloCalculator_EqualRatingsWin(t *testing.T) {
+	calc := &EloCalculator{K: 32}
+	a, b := calc.Calculate(PlayerRating{Rate: 1500}, PlayerRating{Rate: 1500}, 1)
+
+	assert.Equal(t, 1516, a.Rate)
+	assert.Equal(t, 1484, b.Rate)
+}
+
+func TestEloCalculator_UpsetGainsMore(t *testing.T) {
+	calc := &EloCalculator{K: 32}
+	underdog, favorite := calc.Calculate(PlayerRating{Rate: 1500}, PlayerRating{Rate: 2200}, 1)
+
+	assert.Greater(t, underdog.Rate-1500, 16, "beating a stronger player should gain more than half of K")
+	assert.Equal(t, 2200-(underdog.Rate-1500), favorite.Rate, "Elo should be zero-sum")
+}
+
+func TestEloCalculator_Draw(t *testing.T) {
+	calc := &EloCalculator{K: 32}
+	a, b := calc.Calculate(PlayerRating{Rate: 1500}, PlayerRating{Rate: 1500}, 0.5)
+
+	assert.Equal(t, 1500, a.Rate)
+	assert.Equal(t, 1500, b.Rate)
+}
+
+func TestGlicko2Calculator_Win(t *testing.T) {
+	calc := &Glicko2Calculator{Tau: 0.5}
+	a, b := calc.Calculate(PlayerRating{Rate: 1500}, PlayerRating{Rate: 1500}, 1)
+
+	assert.Greater(t, a.Rate, 1500)
+	assert.Less(t, b.Rate, 1500)
+	assert.Less(t, a.Deviation, defaultRatingDeviation, "deviation should shrink after a game")
+	assert.Less(t, b.Deviation, defaultRatingDeviation)
+	assert.InDelta(t, defaultRatingVolatility, a.Volatility, 0.01)
+}
+
+func TestGlicko2Calculator_ConfidentPlayerMovesLess(t *testing.T) {
+	calc := &Glicko2Calculator{Tau: 0.5}
+	veteran := PlayerRating{Rate: 1500, Deviation: 50, Volatility: 0.06}
+	newcomer := PlayerRating{Rate: 1500, Deviation: 350, Volatility: 0.06}
+
+	v, n := calc.Calculate(veteran, newcomer, 0)
+
+	assert.Less(t, 1500-v.Rate, n.Rate-1500, "low-deviation player should move less")
+}
+
+func TestNewRatingCalculator(t *testing.T) {
+	elo, err := NewRatingCalculator("elo", 32, 0.5)
+	require.NoError(t, err)
+	assert.IsType(t, &EloCalculator{}, elo)
+
+	glicko, err := NewRatingCalculator("glicko2", 32, 0.5)
+	require.NoError(t, err)
+	assert.IsType(t, &Glicko2Calculator{}, glicko)
+
+	_, err = NewRatingCalculator("trueskill", 32, 0.5)
+	require.Error(t, err)
+}
+
+// newRatingUserRepo は users を行ロック済みとして fn に渡し、fn が書き込んだレートを updated に記録するモック
+func newRatingUserRepo(users []*entity.User, updated map[uuid.UUID]int) *testutil.MockUserRepository {
+	return &testutil.MockUserRepository{
+		UpdateRatingsFunc: func(_ context.Context, ids []uuid.UUID, fn func(map[uuid.UUID]*entity.User) error) error {
+			locked := map[uuid.UUID]*entity.User{}
+			for _, u := range users {
+				if slices.Contains(ids, u.ID) {
+					copied := *u
+					locked[u.ID] = &copied
+				}
+			}
+			if err := fn(locked); err != nil {
+				return err
+			}
+			for id, u := range locked {
+				updated[id] = u.Rate
+			}
+			return nil
+		},
+	}
+}
+
+func TestRatingUsecase_ApplyMatchResult(t *testing.T) {
+	p1 := &entity.User{ID: uuid.New(), Rate: 1500}
+	p2 := &entity.User{ID: uuid.New(), Rate: 1500}
+	updated := map[uuid.UUID]int{}
+	userRepo := newRatingUserRepo([]*entity.User{p1, p2}, updated)
+
+	uc := NewRatingUsecase(&EloCalculator{K: 32}, userRepo)
+	c1, c2, err := uc.ApplyMatchResult(context.Background(), p1.ID, p2.ID, uuid.NullUUID{UUID: p2.ID, Valid: true})
+
+	require.NoError(t, err)
+	assert.Equal(t, RatingChange{Before: 1500, After: 1484, Delta: -16}, c1)
+	assert.Equal(t, RatingChange{Before: 1500, After: 1516, Delta: 16}, c2)
+	assert.Equal(t, 1484, updated[p1.ID])
+	assert.Equal(t, 1516, updated[p2.ID])
+}
+
+func TestRatingUsecase_ApplyTeamMatchResult(t *testing.T) {
+	var users []*entity.User
+	var teams [2][]uuid.UUID
+	for i, rate := range []int{1600, 1400, 1500, 1500} {
+		u := &entity.User{ID: uuid.New(), Rate: rate}
+		users = append(users, u)
+		teams[i/2] = append(teams[i/2], u.ID)
+	}
+	updated := map[uuid.UUID]int{}
+	userRepo := newRatingUserRepo(users, updated)
+
+	uc := NewRatingUsecase(&EloCalculator{K: 32}, userRepo)
+	changes, err := uc.ApplyTeamMatchResult(context.Background(), teams, 1)
+
+	require.NoError(t, err)
+	require.Len(t, changes, 4)
+	// 各メンバーは相手チームの平均（どちらも 1500）と対戦したものとして計算する
+	// 負けたチームでは、平均より強いメンバーほど大きく下がる
+	assert.Equal(t, RatingChange{Before: 1600, After: 1580, Delta: -20}, changes[teams[0][0]])
+	assert.Equal(t, RatingChange{Before: 1400, After: 1388, Delta: -12}, changes[teams[0][1]])
+	assert.Equal(t, 1580, updated[teams[0][0]])
+	for _, id := range teams[1] {
+		assert.Equal(t, RatingChange{Before: 1500, After: 1516, Delta: 16}, changes[id])
+	}
+}
+
+func TestRatingUsecase_ApplyMatchResult_UpdateFails(t *testing.T) {
+	userRepo := &testutil.MockUserRepository{
+		UpdateRatingsFunc: func(_ context.Context, _ []uuid.UUID, _ func(map[uuid.UUID]*entity.User) error) error {
+			return errors.New("db error")
+		},
+	}
+
+	uc := NewRatingUsecase(&EloCalculator{K: 32}, userRepo)
+	_, _, err := uc.ApplyMatchResult(context.Background(), uuid.New(), uuid.New(), uuid.NullUUID{})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "update ratings")
+}
+
+func TestRatingUsecase_ApplyMatchResult_LocksBothPlayers(t *testing.T) {
+	p1 := &entity.User{ID: uuid.New(), Rate: 1500}
+	p2 := &entity.User{ID: uuid.New(), Rate: 1500}
+	var locked []uuid.UUID
+	userRepo := newRatingUserRepo([]*entity.User{p1, p2}, map[uuid.UUID]int{})
+	inner := userRepo.UpdateRatingsFunc
+	userRepo.UpdateRatingsFunc = func(ctx context.Context, ids []uuid.UUID, fn func(map[uuid.UUID]*entity.User) error) error {
+		locked = ids
+		return inner(ctx, ids, fn)
+	}
+
+	uc := NewRatingUsecase(&EloCalculator{K: 32}, userRepo)
+	_, _, err := uc.ApplyMatchResult(context.Background(), p1.ID, p2.ID, uuid.NullUUID{})
+
+	require.NoError(t, err)
+	// 両者の読み取りと書き込みを同じロックの中で行う（別々に読み書きすると並行した試合の更新を失う）
+	assert.ElementsMatch(t, []uuid.UUID{p1.ID, p2.ID}, locked)
+}
@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+)
+
+var (
+	// ErrReplayNotFound はルームが存在しない
+	ErrReplayNotFound = errors.New("room not found")
+	// ErrReplayForbidden はリクエストしたユーザーがルームの参加者ではない
+	ErrReplayForbidden = errors.New("not a member of the room")
+	// ErrReplayNotReady は試合がまだ終わっていない
+	ErrReplayNotReady = errors.New("match has not ended")
+)
+
+// ReplayView はリプレイに含めるイベントの範囲
+type ReplayView string
+
+const (
+	ReplayViewOwn  ReplayView = "own"  // リクエストしたプレイヤーに送ったイベントのみ
+	ReplayViewFull ReplayView = "full" // 全プレイヤーに送ったイベント
+)
+
+// Replay は1試合のイベントのタイムライン
+type Replay struct {
+	Status    entity.RoomStatus    `json:"status"`
+	EndReason entity.RoomEndReason `json:"end_reason"`
+	View      ReplayView           `json:"view"`
+	Events    []entity.MatchEvent  `json:"events"` // 送信した順
+	RoomID    uuid.UUID            `json:"room_id"`
+}
+
+type ReplayUsecase struct {
+	roomRepo  repository.RoomRepository
+	matchRepo repository.MatchRepository
+}
+
+func NewReplayUsecase(roomRepo repository.RoomRepository, matchRepo repository.MatchRepository) *ReplayUsecase {
+	return &ReplayUsecase{roomRepo: roomRepo, matchRepo: matchRepo}
+}
+
+// GetReplay は userID が参加した試合のリプレイを返す
+// 試合中のリプレイは相手の問題や回答が見えてしまうため、ルームが終了するまで返さない
+func (uc *ReplayUsecase) GetReplay(ctx context.Context, roomID, userID uuid.UUID, view ReplayView) (*Replay, error) {
+	room, err := uc.roomRepo.GetByID(ctx, roomID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrReplayNotFound
+		}
+		return nil, fmt.Errorf("get room: %w", err)
+	}
+	if !room.HasMember(userID) {
+		return nil, ErrReplayForbidden
+	}
+	if !room.Status.IsEnded() {
+		return nil, ErrReplayNotReady
+	}
+
+	events, err := uc.matchRepo.ListEventsByRoomID(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("list match events: %w", err)
+	}
+	if view != ReplayViewFull {
+		view = ReplayViewOwn
+		own := make([]entity.MatchEvent, 0, len(events))
+		for _, ev := range events {
+			if ev.UserID == userID {
+				own = append(own, ev)
+			}
+		}
+		events = own
+	}
+	return &Replay{
+		RoomID:    room.ID,
+		Status:    room.Status,
+		EndReason: room.EndReason,
+		View:      view,
+		Events:    events,
+	}, nil
+}
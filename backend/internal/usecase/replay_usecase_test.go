@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/testutil"
+)
+
+func newReplayFixture(status entity.RoomStatus) (*ReplayUsecase, *entity.Room) {
+	room := &entity.Room{
+		ID:        uuid.New(),
+		Player1ID: uuid.New(),
+		Player2ID: uuid.New(),
+		Status:    status,
+	}
+	if status.IsEnded() {
+		room.EndReason = entity.RoomEndReasonCompleted
+	}
+	now := time.Now()
+	events := []entity.MatchEvent{
+		{RoomID: room.ID, UserID: room.Player1ID, Type: "ev_room_ready", Payload: json.RawMessage(`{}`), SentAt: now},
+		{RoomID: room.ID, UserID: room.Player2ID, Type: "ev_room_ready", Payload: json.RawMessage(`{}`), SentAt: now},
+		{RoomID: room.ID, UserID: room.Player1ID, Type: "ev_turn_start", Payload: json.RawMessage(`{"turn":1}`), SentAt: now.Add(time.Second)},
+		{RoomID: room.ID, UserID: room.Player2ID, Type: "ev_turn_start", Payload: json.RawMessage(`{"turn":1}`), SentAt: now.Add(time.Second)},
+	}
+	roomRepo := &testutil.MockRoomRepository{
+		GetByIDFunc: func(_ context.Context, id uuid.UUID) (*entity.Room, error) {
+			if id != room.ID {
+				return nil, fmt.Errorf("get room by id: %w", sql.ErrNoRows)
+			}
+			return room, nil
+		},
+	}
+	matchRepo := &testutil.MockMatchRepository{
+		ListEventsByRoomIDFunc: func(_ context.Context, _ uuid.UUID) ([]entity.MatchEvent, error) {
+			return events, nil
+		},
+	}
+	return NewReplayUsecase(roomRepo, matchRepo), room
+}
+
+func TestReplayUsecase_GetReplay_OwnView(t *testing.T) {
+	uc, room := newReplayFixture(entity.RoomStatusFinished)
+
+	replay, err := uc.GetReplay(context.Background(), room.ID, room.Player2ID, ReplayViewOwn)
+	require.NoError(t, err)
+
+	assert.Equal(t, ReplayViewOwn, replay.View)
+	assert.Equal(t, entity.RoomEndReasonCompleted, replay.EndReason)
+	require.Len(t, replay.Events, 2)
+	for _, ev := range replay.Events {
+		assert.Equal(t, room.Player2ID, ev.UserID, "own view should not include the opponent's events")
+	}
+	assert.Equal(t, "ev_room_ready", replay.Events[0].Type)
+	assert.Equal(t, "ev_turn_start", replay.Events[1].Type)
+}
+
+func TestReplayUsecase_GetReplay_FullView(t *testing.T) {
+	uc, room := newReplayFixture(entity.RoomStatusAborted)
+
+	replay, err := uc.GetReplay(context.Background(), room.ID, room.Player1ID, ReplayViewFull)
+	require.NoError(t, err)
+
+	assert.Equal(t, ReplayViewFull, replay.View)
+	assert.Len(t, replay.Events, 4)
+}
+
+func TestReplayUsecase_GetReplay_Errors(t *testing.T) {
+	uc, room := newReplayFixture(entity.RoomStatusFinished)
+	ctx := context.Background()
+
+	_, err := uc.GetReplay(ctx, uuid.New(), room.Player1ID, ReplayViewOwn)
+	assert.ErrorIs(t, err, ErrReplayNotFound)
+
+	_, err = uc.GetReplay(ctx, room.ID, uuid.New(), ReplayViewFull)
+	assert.ErrorIs(t, err, ErrReplayForbidden, "non-participants must not see even the full view")
+
+	inProgress, room := newReplayFixture(entity.RoomStatusInProgress)
+	_, err = inProgress.GetReplay(ctx, room.ID, room.Player1ID, ReplayViewOwn)
+	assert.ErrorIs(t, err, ErrReplayNotReady)
+}
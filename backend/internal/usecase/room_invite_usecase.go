@@ -0,0 +1,207 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+)
+
+const (
+	// inviteCodeAlphabet は招待コードに使う文字（読み間違えやすい 0/O・1/I は使わない）
+	inviteCodeAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+	inviteCodeLength   = 8
+	// inviteCodeAttempts はコードが既存の招待と重なった場合に作り直す回数の上限
+	inviteCodeAttempts = 5
+)
+
+var (
+	// ErrInviteNotFound は招待が存在しない・期限切れ
+	ErrInviteNotFound = errors.New("invite not found")
+	// ErrInviteForbidden はリクエストしたユーザーが招待を操作できない
+	ErrInviteForbidden = errors.New("not allowed to access the invite")
+	// ErrInviteRedeemed は招待が使用済み
+	ErrInviteRedeemed = errors.New("invite already redeemed")
+	// ErrInviteOwn は作成者が自分の招待を使おうとした
+	ErrInviteOwn = errors.New("cannot redeem own invite")
+)
+
+type RoomInviteUsecase struct {
+	inviteRepo repository.RoomInviteRepository
+	roomRepo   repository.RoomRepository
+	ttl        time.Duration
+}
+
+func NewRoomInviteUsecase(inviteRepo repository.RoomInviteRepository, roomRepo repository.RoomRepository, ttl time.Duration) *RoomInviteUsecase {
+	return &RoomInviteUsecase{inviteRepo: inviteRepo, roomRepo: roomRepo, ttl: ttl}
+}
+
+// Create は creatorID の招待を発行する。招待は ttl が過ぎると使えなくなる
+func (uc *RoomInviteUsecase) Create(ctx context.Context, creatorID uuid.UUID) (*entity.RoomInvite, error) {
+	for range inviteCodeAttempts {
+		code, err := generateInviteCode()
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		invite := &entity.RoomInvite{
+			Code:      code,
+			CreatorID: creatorID,
+			CreatedAt: now,
+			ExpiresAt: now.Add(uc.ttl),
+		}
+		created, err := uc.inviteRepo.Create(ctx, invite)
+		if err != nil {
+			return nil, fmt.Errorf("create invite: %w", err)
+		}
+		if created {
+			return invite, nil
+		}
+	}
+	return nil, fmt.Errorf("create invite: no unused code after %d attempts", inviteCodeAttempts)
+}
+
+// Get は招待を返す。作成者と招待を使ったユーザーのみ参照できる
+// 作成者は招待が使われたかをこれで確かめ、RoomID のルームへ接続する
+func (uc *RoomInviteUsecase) Get(ctx context.Context, code string, userID uuid.UUID) (*entity.RoomInvite, error) {
+	invite, err := uc.get(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if userID != invite.CreatorID && userID != invite.GuestID {
+		return nil, ErrInviteForbidden
+	}
+	return invite, nil
+}
+
+// Redeem は招待を使って2人目の席を取り、作成者との対戦ルームを作成する
+// 同じユーザーが使い直した場合は作成済みのルームを返す
+func (uc *RoomInviteUsecase) Redeem(ctx context.Context, code string, guestID uuid.UUID) (*entity.Room, error) {
+	invite, err := uc.get(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if invite.CreatorID == guestID {
+		return nil, ErrInviteOwn
+	}
+	if invite.Redeemed() {
+		return uc.redeemedRoom(ctx, invite, guestID)
+	}
+
+	room := &entity.Room{
+		ID:        uuid.New(),
+		Player1ID: invite.CreatorID,
+		Player2ID: guestID,
+		Status:    entity.RoomStatusWaiting,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	// 先に席を取り、同時に使われた場合にルームが2つできないようにする
+	claimed, err := uc.inviteRepo.Claim(ctx, invite.Code, guestID, room.ID)
+	if err != nil {
+		return nil, fmt.Errorf("claim invite: %w", err)
+	}
+	if !claimed {
+		// 確かめてから席を取るまでに使われたか、期限が切れた
+		latest, err := uc.get(ctx, code)
+		if err != nil {
+			return nil, err
+		}
+		return uc.redeemedRoom(ctx, latest, guestID)
+	}
+
+	if err := uc.roomRepo.Create(ctx, room); err != nil {
+		if releaseErr := uc.inviteRepo.Release(ctx, invite.Code, room.ID); releaseErr != nil {
+			log.Printf("invite %s: release after create room failure: %v", invite.Code, releaseErr)
+		}
+		return nil, fmt.Errorf("create room: %w", err)
+	}
+	return room, nil
+}
+
+// redeemedRoom は使用済みの招待を guestID が使い直した場合にルームを返す
+func (uc *RoomInviteUsecase) redeemedRoom(ctx context.Context, invite *entity.RoomInvite, guestID uuid.UUID) (*entity.Room, error) {
+	if !invite.Redeemed() || invite.GuestID != guestID {
+		return nil, ErrInviteRedeemed
+	}
+	room, err := uc.roomRepo.GetByID(ctx, invite.RoomID)
+	if err != nil {
+		return nil, fmt.Errorf("get room: %w", err)
+	}
+	return room, nil
+}
+
+// Cancel は未使用の招待を取り消す。取り消せるのは作成者のみ
+func (uc *RoomInviteUsecase) Cancel(ctx context.Context, code string, userID uuid.UUID) error {
+	invite, err := uc.get(ctx, code)
+	if err != nil {
+		return err
+	}
+	if invite.CreatorID != userID {
+		return ErrInviteForbidden
+	}
+	if invite.Redeemed() {
+		return ErrInviteRedeemed
+	}
+	deleted, err := uc.inviteRepo.DeleteUnclaimed(ctx, invite.Code)
+	if err != nil {
+		return fmt.Errorf("delete invite: %w", err)
+	}
+	if !deleted {
+		// 確かめてから削除するまでに使われたか、期限が切れた
+		if _, err := uc.get(ctx, code); err != nil {
+			return err
+		}
+		return ErrInviteRedeemed
+	}
+	return nil
+}
+
+// get は入力されたコードを正規化して招待を取得する
+func (uc *RoomInviteUsecase) get(ctx context.Context, code string) (*entity.RoomInvite, error) {
+	code, ok := normalizeInviteCode(code)
+	if !ok {
+		return nil, ErrInviteNotFound
+	}
+	invite, err := uc.inviteRepo.Get(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("get invite: %w", err)
+	}
+	if invite == nil {
+		return nil, ErrInviteNotFound
+	}
+	return invite, nil
+}
+
+func generateInviteCode() (string, error) {
+	buf := make([]byte, inviteCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate invite code: %w", err)
+	}
+	for i, b := range buf {
+		// 256 は文字種の数 (32) で割り切れるため、剰余で選んでも偏らない
+		buf[i] = inviteCodeAlphabet[int(b)%len(inviteCodeAlphabet)]
+	}
+	return string(buf), nil
+}
+
+// normalizeInviteCode は手入力されたコードを大文字にそろえ、招待コードとして正しい形かを返す
+func normalizeInviteCode(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != inviteCodeLength {
+		return "", false
+	}
+	for _, c := range code {
+		if !strings.ContainsRune(inviteCodeAlphabet, c) {
+			return "", false
+		}
+	}
+	return code, true
+}
@@ -0,0 +1,218 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/testutil"
+)
+
+// newMemoryInviteRepo は招待をメモリに持つリポジトリを返す（期限切れは扱わない）
+func newMemoryInviteRepo() (*testutil.MockRoomInviteRepository, map[string]*entity.RoomInvite) {
+	invites := map[string]*entity.RoomInvite{}
+	return &testutil.MockRoomInviteRepository{
+		CreateFunc: func(_ context.Context, invite *entity.RoomInvite) (bool, error) {
+			if _, ok := invites[invite.Code]; ok {
+				return false, nil
+			}
+			stored := *invite
+			invites[invite.Code] = &stored
+			return true, nil
+		},
+		GetFunc: func(_ context.Context, code string) (*entity.RoomInvite, error) {
+			invite, ok := invites[code]
+			if !ok {
+				return nil, nil
+			}
+			got := *invite
+			return &got, nil
+		},
+		ClaimFunc: func(_ context.Context, code string, guestID, roomID uuid.UUID) (bool, error) {
+			invite, ok := invites[code]
+			if !ok || invite.Redeemed() {
+				return false, nil
+			}
+			invite.GuestID, invite.RoomID = guestID, roomID
+			return true, nil
+		},
+		ReleaseFunc: func(_ context.Context, code string, roomID uuid.UUID) error {
+			if invite, ok := invites[code]; ok && invite.RoomID == roomID {
+				invite.GuestID, invite.RoomID = uuid.Nil, uuid.Nil
+			}
+			return nil
+		},
+		DeleteUnclaimedFunc: func(_ context.Context, code string) (bool, error) {
+			invite, ok := invites[code]
+			if !ok || invite.Redeemed() {
+				return false, nil
+			}
+			delete(invites, code)
+			return true, nil
+		},
+	}, invites
+}
+
+// newMemoryRoomRepo は作成したルームをメモリに持つリポジトリを返す
+func newMemoryRoomRepo() *testutil.MockRoomRepository {
+	rooms := map[uuid.UUID]entity.Room{}
+	return &testutil.MockRoomRepository{
+		CreateFunc: func(_ context.Context, room *entity.Room) error {
+			rooms[room.ID] = *room
+			return nil
+		},
+		GetByIDFunc: func(_ context.Context, id uuid.UUID) (*entity.Room, error) {
+			room, ok := rooms[id]
+			if !ok {
+				return nil, sql.ErrNoRows
+			}
+			return &room, nil
+		},
+	}
+}
+
+func TestRoomInviteUsecase_Create(t *testing.T) {
+	inviteRepo, invites := newMemoryInviteRepo()
+	uc := NewRoomInviteUsecase(inviteRepo, newMemoryRoomRepo(), 10*time.Minute)
+	creator := uuid.New()
+
+	invite, err := uc.Create(context.Background(), creator)
+	require.NoError(t, err)
+	assert.Len(t, invite.Code, inviteCodeLength)
+	code, ok := normalizeInviteCode(invite.Code)
+	assert.True(t, ok)
+	assert.Equal(t, invite.Code, code)
+	assert.Equal(t, creator, invite.CreatorID)
+	assert.Equal(t, 10*time.Minute, invite.ExpiresAt.Sub(invite.CreatedAt))
+	assert.Contains(t, invites, invite.Code)
+}
+
+func TestRoomInviteUsecase_Create_RetriesOnCollision(t *testing.T) {
+	attempts := 0
+	inviteRepo := &testutil.MockRoomInviteRepository{
+		CreateFunc: func(context.Context, *entity.RoomInvite) (bool, error) {
+			attempts++
+			return attempts == 3, nil
+		},
+	}
+	uc := NewRoomInviteUsecase(inviteRepo, newMemoryRoomRepo(), time.Minute)
+
+	_, err := uc.Create(context.Background(), uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	attempts = -100
+	_, err = uc.Create(context.Background(), uuid.New())
+	assert.Error(t, err, "gives up after inviteCodeAttempts collisions")
+}
+
+func TestRoomInviteUsecase_Redeem(t *testing.T) {
+	inviteRepo, _ := newMemoryInviteRepo()
+	roomRepo := newMemoryRoomRepo()
+	uc := NewRoomInviteUsecase(inviteRepo, roomRepo, time.Minute)
+	ctx := context.Background()
+	creator, guest := uuid.New(), uuid.New()
+
+	invite, err := uc.Create(ctx, creator)
+	require.NoError(t, err)
+
+	_, err = uc.Redeem(ctx, invite.Code, creator)
+	assert.ErrorIs(t, err, ErrInviteOwn)
+
+	// 手入力の小文字・前後の空白は許す
+	room, err := uc.Redeem(ctx, "  "+strings.ToLower(invite.Code)+" ", guest)
+	require.NoError(t, err)
+	assert.Equal(t, creator, room.Player1ID)
+	assert.Equal(t, guest, room.Player2ID)
+	assert.Equal(t, entity.RoomStatusWaiting, room.Status)
+	stored, err := roomRepo.GetByID(ctx, room.ID)
+	require.NoError(t, err)
+	assert.Equal(t, room.Player2ID, stored.Player2ID)
+
+	// 同じユーザーが使い直すと同じルーム
+	again, err := uc.Redeem(ctx, invite.Code, guest)
+	require.NoError(t, err)
+	assert.Equal(t, room.ID, again.ID)
+
+	_, err = uc.Redeem(ctx, invite.Code, uuid.New())
+	assert.ErrorIs(t, err, ErrInviteRedeemed)
+
+	// 作成者は招待からルームを知る
+	got, err := uc.Get(ctx, invite.Code, creator)
+	require.NoError(t, err)
+	assert.Equal(t, room.ID, got.RoomID)
+	_, err = uc.Get(ctx, invite.Code, uuid.New())
+	assert.ErrorIs(t, err, ErrInviteForbidden)
+}
+
+func TestRoomInviteUsecase_Redeem_LostRace(t *testing.T) {
+	inviteRepo, invites := newMemoryInviteRepo()
+	uc := NewRoomInviteUsecase(inviteRepo, newMemoryRoomRepo(), time.Minute)
+	ctx := context.Background()
+
+	invite, err := uc.Create(ctx, uuid.New())
+	require.NoError(t, err)
+	// 確かめた後に別のユーザーが先に席を取った
+	claim := inviteRepo.ClaimFunc
+	inviteRepo.ClaimFunc = func(ctx context.Context, code string, guestID, roomID uuid.UUID) (bool, error) {
+		invites[code].GuestID, invites[code].RoomID = uuid.New(), uuid.New()
+		return claim(ctx, code, guestID, roomID)
+	}
+
+	_, err = uc.Redeem(ctx, invite.Code, uuid.New())
+	assert.ErrorIs(t, err, ErrInviteRedeemed)
+}
+
+func TestRoomInviteUsecase_Redeem_ReleasesSeatWhenRoomCreateFails(t *testing.T) {
+	inviteRepo, invites := newMemoryInviteRepo()
+	roomRepo := newMemoryRoomRepo()
+	roomRepo.CreateFunc = func(context.Context, *entity.Room) error { return errors.New("db down") }
+	uc := NewRoomInviteUsecase(inviteRepo, roomRepo, time.Minute)
+	ctx := context.Background()
+
+	invite, err := uc.Create(ctx, uuid.New())
+	require.NoError(t, err)
+
+	_, err = uc.Redeem(ctx, invite.Code, uuid.New())
+	require.Error(t, err)
+	assert.False(t, invites[invite.Code].Redeemed(), "the seat is open again")
+}
+
+func TestRoomInviteUsecase_NotFound(t *testing.T) {
+	inviteRepo, _ := newMemoryInviteRepo()
+	uc := NewRoomInviteUsecase(inviteRepo, newMemoryRoomRepo(), time.Minute)
+	ctx := context.Background()
+
+	for _, code := range []string{"", "ABCDEFGH", "ABC", "ABCDEFG0", "../../etc"} {
+		_, err := uc.Redeem(ctx, code, uuid.New())
+		assert.ErrorIs(t, err, ErrInviteNotFound, code)
+	}
+}
+
+func TestRoomInviteUsecase_Cancel(t *testing.T) {
+	inviteRepo, invites := newMemoryInviteRepo()
+	uc := NewRoomInviteUsecase(inviteRepo, newMemoryRoomRepo(), time.Minute)
+	ctx := context.Background()
+	creator := uuid.New()
+
+	invite, err := uc.Create(ctx, creator)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, uc.Cancel(ctx, invite.Code, uuid.New()), ErrInviteForbidden, "only the creator can cancel")
+	require.NoError(t, uc.Cancel(ctx, invite.Code, creator))
+	assert.NotContains(t, invites, invite.Code)
+	assert.ErrorIs(t, uc.Cancel(ctx, invite.Code, creator), ErrInviteNotFound)
+
+	redeemed, err := uc.Create(ctx, creator)
+	require.NoError(t, err)
+	_, err = uc.Redeem(ctx, redeemed.Code, uuid.New())
+	require.NoError(t, err)
+	assert.ErrorIs(t, uc.Cancel(ctx, redeemed.Code, creator), ErrInviteRedeemed)
+}
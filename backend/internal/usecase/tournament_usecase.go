@@ -0,0 +1,325 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+)
+
+const (
+	tournamentNameMaxLength = 100
+	// tournamentMaxAttempts はサーバー側の理由で成立しなかった試合をやり直す回数の上限
+	// 上限に達した試合は上位シードを勝ち上がらせる
+	tournamentMaxAttempts = 3
+)
+
+var (
+	// ErrTournamentNotFound はトーナメントが存在しない
+	ErrTournamentNotFound = errors.New("tournament not found")
+	// ErrTournamentForbidden はリクエストしたユーザーがトーナメントを操作できない
+	ErrTournamentForbidden = errors.New("not allowed to manage the tournament")
+	// ErrTournamentNotOpen はトーナメントが参加登録・開始を受け付けていない
+	ErrTournamentNotOpen = errors.New("tournament is not open for registration")
+	// ErrTournamentFull は参加者が上限に達している
+	ErrTournamentFull = errors.New("tournament is full")
+	// ErrTournamentAlreadyRegistered はユーザーが登録済み
+	ErrTournamentAlreadyRegistered = errors.New("already registered to the tournament")
+	// ErrTournamentTooFew は開始に必要な参加者が揃っていない
+	ErrTournamentTooFew = errors.New("tournament needs at least 2 participants")
+	// ErrTournamentInvalidName はトーナメント名が空または長すぎる
+	ErrTournamentInvalidName = errors.New("invalid tournament name")
+
+	// errTournamentResultIgnored は対戦中でない試合への結果の報告を示す（Update の内容を保存しないために使う）
+	errTournamentResultIgnored = errors.New("tournament match is not playing in the room")
+)
+
+// TournamentRoomResult はトーナメントの試合に使ったルームの終了結果
+type TournamentRoomResult struct {
+	Reason entity.RoomEndReason
+	// Present は終了時にルームに接続していたプレイヤー（不参加・切断の判定に使う）
+	Present  []uuid.UUID
+	WinnerID uuid.NullUUID // 引き分け・勝敗がつかなかった場合は無効値
+	RoomID   uuid.UUID
+}
+
+type TournamentUsecase struct {
+	tournamentRepo repository.TournamentRepository
+	updates        repository.TournamentUpdateRepository // nil の場合は更新を通知しない
+	roomRepo       repository.RoomRepository
+	routingRepo    repository.RoutingRepository // nil の場合は担当インスタンスを確認せずに不参加とする
+	// noShowLimit は作成したルームに誰も接続しない試合を両者不参加とするまでの時間
+	noShowLimit time.Duration
+}
+
+func NewTournamentUsecase(
+	tournamentRepo repository.TournamentRepository,
+	updates repository.TournamentUpdateRepository,
+	roomRepo repository.RoomRepository,
+	routingRepo repository.RoutingRepository,
+	noShowLimit time.Duration,
+) *TournamentUsecase {
+	return &TournamentUsecase{
+		tournamentRepo: tournamentRepo,
+		updates:        updates,
+		roomRepo:       roomRepo,
+		routingRepo:    routingRepo,
+		noShowLimit:    noShowLimit,
+	}
+}
+
+// Create は creatorID が主催するトーナメントを作成する。作成者も参加登録するまでは参加者に含まれない
+func (uc *TournamentUsecase) Create(ctx context.Context, creatorID uuid.UUID, name string) (*entity.Tournament, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > tournamentNameMaxLength {
+		return nil, ErrTournamentInvalidName
+	}
+	tournament := &entity.Tournament{ID: uuid.New(), Name: name, CreatedBy: creatorID}
+	if err := uc.tournamentRepo.Create(ctx, tournament); err != nil {
+		return nil, err
+	}
+	return tournament, nil
+}
+
+// List は新しい順に limit 件のトーナメントを返す
+func (uc *TournamentUsecase) List(ctx context.Context, limit int) ([]*entity.Tournament, error) {
+	return uc.tournamentRepo.List(ctx, limit)
+}
+
+// Get はトーナメント表を返す
+func (uc *TournamentUsecase) Get(ctx context.Context, id uuid.UUID) (*entity.TournamentBracket, error) {
+	bracket, err := uc.tournamentRepo.GetBracket(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTournamentNotFound
+		}
+		return nil, err
+	}
+	return bracket, nil
+}
+
+// Register は user をトーナメントに参加登録する。登録は開始前のみ受け付ける
+func (uc *TournamentUsecase) Register(ctx context.Context, id uuid.UUID, user *entity.User) (*entity.TournamentBracket, error) {
+	return uc.update(ctx, id, func(b *entity.TournamentBracket) error {
+		if b.Tournament.Status != entity.TournamentStatusRegistration {
+			return ErrTournamentNotOpen
+		}
+		if b.Participant(user.ID) != nil {
+			return ErrTournamentAlreadyRegistered
+		}
+		if len(b.Participants) >= entity.MaxTournamentParticipants {
+			return ErrTournamentFull
+		}
+		b.Participants = append(b.Participants, entity.TournamentParticipant{
+			RegisteredAt: time.Now(),
+			GitHubLogin:  user.GitHubLogin,
+			UserID:       user.ID,
+			Rate:         user.Rate,
+		})
+		return nil
+	})
+}
+
+// Start はトーナメントを開始し、1回戦のルームを作成する。開始できるのは作成者のみ
+func (uc *TournamentUsecase) Start(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*entity.TournamentBracket, error) {
+	return uc.update(ctx, id, func(b *entity.TournamentBracket) error {
+		if b.Tournament.CreatedBy != userID {
+			return ErrTournamentForbidden
+		}
+		if b.Tournament.Status != entity.TournamentStatusRegistration {
+			return ErrTournamentNotOpen
+		}
+		if len(b.Participants) < 2 {
+			return ErrTournamentTooFew
+		}
+		now := time.Now()
+		return uc.startMatches(ctx, b, b.Start(now), now)
+	})
+}
+
+// RecordRoomResult はルームの終了結果をトーナメント表に反映し、次のラウンドのルームを作成する
+// トーナメントの試合でないルームや、既に結果を反映した（差し替え前の）ルームの報告は無視する
+//
+//   - completed・tko: 勝者が勝ち上がる。引き分けは上位シードが勝ち上がる
+//   - no_show・disconnected: 接続していたプレイヤーが不戦勝になる。両者がいなければどちらも敗退する
+//   - それ以外（問題生成の失敗・サーバー側の中断）: 新しいルームで試合をやり直す
+func (uc *TournamentUsecase) RecordRoomResult(ctx context.Context, res TournamentRoomResult) error {
+	match, err := uc.tournamentRepo.GetMatchByRoomID(ctx, res.RoomID)
+	if err != nil {
+		return err
+	}
+	if match == nil {
+		return nil
+	}
+	_, err = uc.update(ctx, match.TournamentID, func(b *entity.TournamentBracket) error {
+		m := b.MatchByRoomID(res.RoomID)
+		if m == nil || m.Status != entity.TournamentMatchStatusPlaying {
+			return errTournamentResultIgnored
+		}
+		now := time.Now()
+		switch res.Reason {
+		case entity.RoomEndReasonCompleted, entity.RoomEndReasonTKO:
+			winner := res.WinnerID
+			if !winner.Valid || !m.HasPlayer(winner.UUID) {
+				winner = b.HigherSeed(m)
+			}
+			b.Decide(m, winner, entity.TournamentMatchResultPlayed, now)
+		case entity.RoomEndReasonNoShow, entity.RoomEndReasonDisconnected:
+			var present []uuid.UUID
+			for _, id := range res.Present {
+				if m.HasPlayer(id) {
+					present = append(present, id)
+				}
+			}
+			switch len(present) {
+			case 0:
+				b.Decide(m, uuid.NullUUID{}, entity.TournamentMatchResultVoid, now)
+			case 1:
+				b.Decide(m, uuid.NullUUID{UUID: present[0], Valid: true}, entity.TournamentMatchResultForfeit, now)
+			default:
+				uc.replay(b, m, now)
+			}
+		default:
+			uc.replay(b, m, now)
+		}
+		return uc.startMatches(ctx, b, b.Advance(now), now)
+	})
+	if errors.Is(err, errTournamentResultIgnored) {
+		log.Printf("tournament %s: ignored result of room %s (%s)", match.TournamentID, res.RoomID, res.Reason)
+		return nil
+	}
+	return err
+}
+
+// replay は試合をルームの作成前に戻す。やり直しの上限に達した場合は上位シードを勝ち上がらせる
+func (uc *TournamentUsecase) replay(b *entity.TournamentBracket, m *entity.TournamentMatch, now time.Time) {
+	if m.Attempts >= tournamentMaxAttempts {
+		b.Decide(m, b.HigherSeed(m), entity.TournamentMatchResultSeed, now)
+		return
+	}
+	m.Status = entity.TournamentMatchStatusPending
+	m.RoomID = uuid.NullUUID{}
+	m.UpdatedAt = now
+}
+
+// startMatches は両プレイヤーが決まった試合のルームを作成する。Player1 は上位シード
+func (uc *TournamentUsecase) startMatches(ctx context.Context, b *entity.TournamentBracket, ready []*entity.TournamentMatch, now time.Time) error {
+	for _, m := range ready {
+		player1 := b.HigherSeed(m).UUID
+		player2 := m.Player1ID.UUID
+		if player2 == player1 {
+			player2 = m.Player2ID.UUID
+		}
+		room := &entity.Room{
+			ID:        uuid.New(),
+			Player1ID: player1,
+			Player2ID: player2,
+			Status:    entity.RoomStatusWaiting,
+		}
+		if err := uc.roomRepo.Create(ctx, room); err != nil {
+			return fmt.Errorf("create room for tournament match %d-%d: %w", m.Round, m.Position, err)
+		}
+		m.RoomID = uuid.NullUUID{UUID: room.ID, Valid: true}
+		m.Status = entity.TournamentMatchStatusPlaying
+		m.Attempts++
+		m.UpdatedAt = now
+	}
+	return nil
+}
+
+// SweepNoShows は noShowLimit を過ぎても誰も接続していないルームを中止し、両者不参加として記録する
+// 他のインスタンスがルームを担当している（プレイヤーが接続している）場合は対象外にする
+func (uc *TournamentUsecase) SweepNoShows(ctx context.Context, now time.Time) (int, error) {
+	matches, err := uc.tournamentRepo.ListStaleMatches(ctx, now.Add(-uc.noShowLimit))
+	if err != nil {
+		return 0, err
+	}
+	swept := 0
+	for _, m := range matches {
+		roomID := m.RoomID.UUID
+		if uc.routingRepo != nil {
+			owner, err := uc.routingRepo.GetOwner(ctx, roomID)
+			if err != nil {
+				log.Printf("tournament %s: failed to get owner of room %s: %v", m.TournamentID, roomID, err)
+				continue
+			}
+			if owner != "" {
+				continue
+			}
+		}
+		if err := uc.roomRepo.UpdateStatus(ctx, roomID, entity.RoomStatusAborted, entity.RoomEndReasonNoShow); err != nil {
+			log.Printf("tournament %s: failed to abort room %s: %v", m.TournamentID, roomID, err)
+			continue
+		}
+		if err := uc.RecordRoomResult(ctx, TournamentRoomResult{RoomID: roomID, Reason: entity.RoomEndReasonNoShow}); err != nil {
+			log.Printf("tournament %s: failed to record no-show of room %s: %v", m.TournamentID, roomID, err)
+			continue
+		}
+		swept++
+	}
+	return swept, nil
+}
+
+// RunNoShowSweeper は ctx が終わるまで interval ごとに SweepNoShows を実行する
+func (uc *TournamentUsecase) RunNoShowSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			swept, err := uc.SweepNoShows(ctx, now)
+			if err != nil {
+				log.Printf("tournament: failed to sweep no-shows: %v", err)
+				continue
+			}
+			if swept > 0 {
+				log.Printf("tournament: recorded %d no-show matches", swept)
+			}
+		}
+	}
+}
+
+// Watch はトーナメント表の更新を知らせるチャネルを返す。ctx が終わるとチャネルは閉じられる
+// 通知を受けるたびに Get で最新のトーナメント表を読み直すこと
+func (uc *TournamentUsecase) Watch(ctx context.Context, id uuid.UUID) (<-chan struct{}, error) {
+	if uc.updates == nil {
+		ch := make(chan struct{})
+		go func() {
+			<-ctx.Done()
+			close(ch)
+		}()
+		return ch, nil
+	}
+	return uc.updates.Subscribe(ctx, id)
+}
+
+// update はトーナメント表を更新し、購読者に通知する
+func (uc *TournamentUsecase) update(
+	ctx context.Context,
+	id uuid.UUID,
+	fn func(*entity.TournamentBracket) error,
+) (*entity.TournamentBracket, error) {
+	bracket, err := uc.tournamentRepo.Update(ctx, id, fn)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTournamentNotFound
+		}
+		return nil, err
+	}
+	if uc.updates != nil {
+		if err := uc.updates.Publish(ctx, id); err != nil {
+			log.Printf("tournament %s: failed to publish update: %v", id, err)
+		}
+	}
+	return bracket, nil
+}
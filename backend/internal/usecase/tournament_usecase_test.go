@@ -0,0 +1,296 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/testutil"
+)
+
+// newMemoryTournamentRepo はトーナメントをメモリに持つリポジトリを返す
+// Update は fn がエラーを返した場合に変更を捨てる
+func newMemoryTournamentRepo() (*testutil.MockTournamentRepository, map[uuid.UUID]*entity.TournamentBracket) {
+	brackets := map[uuid.UUID]*entity.TournamentBracket{}
+	clone := func(b *entity.TournamentBracket) *entity.TournamentBracket {
+		c := &entity.TournamentBracket{
+			Tournament:   b.Tournament,
+			Participants: append([]entity.TournamentParticipant(nil), b.Participants...),
+		}
+		for _, m := range b.Matches {
+			copied := *m
+			c.Matches = append(c.Matches, &copied)
+		}
+		return c
+	}
+	return &testutil.MockTournamentRepository{
+		CreateFunc: func(_ context.Context, tournament *entity.Tournament) error {
+			tournament.Status = entity.TournamentStatusRegistration
+			brackets[tournament.ID] = &entity.TournamentBracket{Tournament: *tournament}
+			return nil
+		},
+		GetBracketFunc: func(_ context.Context, id uuid.UUID) (*entity.TournamentBracket, error) {
+			b, ok := brackets[id]
+			if !ok {
+				return nil, fmt.Errorf("get tournament by id: %w", sql.ErrNoRows)
+			}
+			return clone(b), nil
+		},
+		UpdateFunc: func(_ context.Context, id uuid.UUID, fn func(*entity.TournamentBracket) error) (*entity.TournamentBracket, error) {
+			b, ok := brackets[id]
+			if !ok {
+				return nil, fmt.Errorf("lock tournament: %w", sql.ErrNoRows)
+			}
+			updated := clone(b)
+			if err := fn(updated); err != nil {
+				return nil, err
+			}
+			brackets[id] = clone(updated)
+			return updated, nil
+		},
+		GetMatchByRoomIDFunc: func(_ context.Context, roomID uuid.UUID) (*entity.TournamentMatch, error) {
+			for _, b := range brackets {
+				if m := b.MatchByRoomID(roomID); m != nil {
+					copied := *m
+					return &copied, nil
+				}
+			}
+			return nil, nil
+		},
+		ListStaleMatchesFunc: func(_ context.Context, _ time.Time) ([]*entity.TournamentMatch, error) {
+			var stale []*entity.TournamentMatch
+			for _, b := range brackets {
+				for _, m := range b.Matches {
+					if m.Status == entity.TournamentMatchStatusPlaying {
+						copied := *m
+						stale = append(stale, &copied)
+					}
+				}
+			}
+			return stale, nil
+		},
+	}, brackets
+}
+
+// setupTournament は rates のユーザーを登録したトーナメントを作る。users はレートの高い順
+func setupTournament(t *testing.T, uc *TournamentUsecase, rates ...int) (uuid.UUID, []*entity.User) {
+	t.Helper()
+	ctx := context.Background()
+	creator := uuid.New()
+	tournament, err := uc.Create(ctx, creator, "  週末カップ  ")
+	require.NoError(t, err)
+	assert.Equal(t, "週末カップ", tournament.Name)
+	users := make([]*entity.User, 0, len(rates))
+	for i, rate := range rates {
+		user := &entity.User{ID: uuid.New(), GitHubLogin: fmt.Sprintf("user%d", i), Rate: rate}
+		_, err := uc.Register(ctx, tournament.ID, user)
+		require.NoError(t, err)
+		users = append(users, user)
+	}
+	_, err = uc.Start(ctx, tournament.ID, creator)
+	require.NoError(t, err)
+	return tournament.ID, users
+}
+
+func TestTournamentUsecase_RegisterAndStart(t *testing.T) {
+	repo, _ := newMemoryTournamentRepo()
+	roomRepo := newMemoryRoomRepo()
+	published := 0
+	updates := &testutil.MockTournamentUpdateRepository{
+		PublishFunc: func(_ context.Context, _ uuid.UUID) error {
+			published++
+			return nil
+		},
+	}
+	uc := NewTournamentUsecase(repo, updates, roomRepo, nil, time.Minute)
+	ctx := context.Background()
+	creator := uuid.New()
+
+	_, err := uc.Create(ctx, creator, " ")
+	assert.ErrorIs(t, err, ErrTournamentInvalidName)
+
+	tournament, err := uc.Create(ctx, creator, "cup")
+	require.NoError(t, err)
+	alice := &entity.User{ID: uuid.New(), GitHubLogin: "alice", Rate: 1400}
+	bob := &entity.User{ID: uuid.New(), GitHubLogin: "bob", Rate: 1600}
+
+	_, err = uc.Register(ctx, tournament.ID, alice)
+	require.NoError(t, err)
+	_, err = uc.Register(ctx, tournament.ID, alice)
+	assert.ErrorIs(t, err, ErrTournamentAlreadyRegistered)
+	_, err = uc.Start(ctx, tournament.ID, creator)
+	assert.ErrorIs(t, err, ErrTournamentTooFew)
+	_, err = uc.Register(ctx, tournament.ID, bob)
+	require.NoError(t, err)
+	_, err = uc.Start(ctx, tournament.ID, alice.ID)
+	assert.ErrorIs(t, err, ErrTournamentForbidden)
+
+	bracket, err := uc.Start(ctx, tournament.ID, creator)
+	require.NoError(t, err)
+	assert.Equal(t, entity.TournamentStatusInProgress, bracket.Tournament.Status)
+	require.Len(t, bracket.Matches, 1)
+	final := bracket.Matches[0]
+	assert.Equal(t, entity.TournamentMatchStatusPlaying, final.Status)
+	assert.Equal(t, 1, final.Attempts)
+
+	// ルームの Player1 は上位シード
+	room, err := roomRepo.GetByID(ctx, final.RoomID.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, bob.ID, room.Player1ID)
+	assert.Equal(t, alice.ID, room.Player2ID)
+	assert.Equal(t, entity.RoomStatusWaiting, room.Status)
+
+	_, err = uc.Register(ctx, tournament.ID, &entity.User{ID: uuid.New()})
+	assert.ErrorIs(t, err, ErrTournamentNotOpen)
+	_, err = uc.Get(ctx, uuid.New())
+	assert.ErrorIs(t, err, ErrTournamentNotFound)
+	// 失敗した更新は通知しない
+	assert.Equal(t, 3, published)
+}
+
+func TestTournamentUsecase_RecordRoomResult_AdvancesWinners(t *testing.T) {
+	repo, brackets := newMemoryTournamentRepo()
+	uc := NewTournamentUsecase(repo, nil, newMemoryRoomRepo(), nil, time.Minute)
+	ctx := context.Background()
+	id, users := setupTournament(t, uc, 1600, 1500, 1400, 1300)
+
+	semi1 := brackets[id].Match(1, 0) // 1 vs 4
+	semi2 := brackets[id].Match(1, 1) // 2 vs 3
+
+	require.NoError(t, uc.RecordRoomResult(ctx, TournamentRoomResult{
+		RoomID:   semi1.RoomID.UUID,
+		Reason:   entity.RoomEndReasonCompleted,
+		WinnerID: uuid.NullUUID{UUID: users[3].ID, Valid: true},
+	}))
+	// 引き分けは上位シードの勝ち上がり
+	require.NoError(t, uc.RecordRoomResult(ctx, TournamentRoomResult{
+		RoomID: semi2.RoomID.UUID,
+		Reason: entity.RoomEndReasonCompleted,
+	}))
+
+	final := brackets[id].Final()
+	assert.Equal(t, users[3].ID, final.Player1ID.UUID)
+	assert.Equal(t, users[1].ID, final.Player2ID.UUID)
+	assert.Equal(t, entity.TournamentMatchStatusPlaying, final.Status)
+
+	// 同じルームの結果を再び報告しても反映しない
+	require.NoError(t, uc.RecordRoomResult(ctx, TournamentRoomResult{
+		RoomID:   semi1.RoomID.UUID,
+		Reason:   entity.RoomEndReasonCompleted,
+		WinnerID: uuid.NullUUID{UUID: users[0].ID, Valid: true},
+	}))
+	assert.Equal(t, users[3].ID, brackets[id].Match(1, 0).WinnerID.UUID)
+
+	require.NoError(t, uc.RecordRoomResult(ctx, TournamentRoomResult{
+		RoomID:   final.RoomID.UUID,
+		Reason:   entity.RoomEndReasonTKO,
+		WinnerID: uuid.NullUUID{UUID: users[1].ID, Valid: true},
+	}))
+	tournament := brackets[id].Tournament
+	assert.Equal(t, entity.TournamentStatusFinished, tournament.Status)
+	assert.Equal(t, users[1].ID, tournament.WinnerID.UUID)
+
+	// トーナメントの試合でないルームは無視する
+	require.NoError(t, uc.RecordRoomResult(ctx, TournamentRoomResult{RoomID: uuid.New(), Reason: entity.RoomEndReasonCompleted}))
+}
+
+func TestTournamentUsecase_RecordRoomResult_NoShowForfeits(t *testing.T) {
+	repo, brackets := newMemoryTournamentRepo()
+	uc := NewTournamentUsecase(repo, nil, newMemoryRoomRepo(), nil, time.Minute)
+	ctx := context.Background()
+	id, users := setupTournament(t, uc, 1600, 1500, 1400, 1300)
+
+	semi1 := brackets[id].Match(1, 0)
+	semi2 := brackets[id].Match(1, 1)
+	require.NoError(t, uc.RecordRoomResult(ctx, TournamentRoomResult{
+		RoomID:  semi1.RoomID.UUID,
+		Reason:  entity.RoomEndReasonNoShow,
+		Present: []uuid.UUID{users[0].ID},
+	}))
+	require.NoError(t, uc.RecordRoomResult(ctx, TournamentRoomResult{
+		RoomID: semi2.RoomID.UUID,
+		Reason: entity.RoomEndReasonNoShow,
+	}))
+
+	b := brackets[id]
+	assert.Equal(t, entity.TournamentMatchResultForfeit, b.Match(1, 0).Result)
+	assert.Equal(t, entity.TournamentMatchResultVoid, b.Match(1, 1).Result)
+	// 決勝の相手がいないので、シード 1 がそのまま優勝する
+	assert.Equal(t, entity.TournamentMatchResultBye, b.Final().Result)
+	assert.Equal(t, users[0].ID, b.Tournament.WinnerID.UUID)
+	assert.Equal(t, entity.TournamentStatusFinished, b.Tournament.Status)
+}
+
+func TestTournamentUsecase_RecordRoomResult_ReplaysServerFailures(t *testing.T) {
+	repo, brackets := newMemoryTournamentRepo()
+	uc := NewTournamentUsecase(repo, nil, newMemoryRoomRepo(), nil, time.Minute)
+	ctx := context.Background()
+	id, users := setupTournament(t, uc, 1400, 1500)
+
+	for attempt := 1; attempt <= tournamentMaxAttempts; attempt++ {
+		final := brackets[id].Final()
+		require.Equal(t, entity.TournamentMatchStatusPlaying, final.Status)
+		require.Equal(t, attempt, final.Attempts)
+		require.NoError(t, uc.RecordRoomResult(ctx, TournamentRoomResult{
+			RoomID:  final.RoomID.UUID,
+			Reason:  entity.RoomEndReasonQuestionGenerationFailed,
+			Present: []uuid.UUID{users[0].ID, users[1].ID},
+		}))
+	}
+
+	final := brackets[id].Final()
+	assert.Equal(t, entity.TournamentMatchResultSeed, final.Result)
+	assert.Equal(t, users[1].ID, final.WinnerID.UUID)
+}
+
+func TestTournamentUsecase_SweepNoShows(t *testing.T) {
+	repo, brackets := newMemoryTournamentRepo()
+	roomRepo := newMemoryRoomRepo()
+	var aborted []uuid.UUID
+	roomRepo.UpdateStatusFunc = func(_ context.Context, id uuid.UUID, status entity.RoomStatus, reason entity.RoomEndReason) error {
+		assert.Equal(t, entity.RoomStatusAborted, status)
+		assert.Equal(t, entity.RoomEndReasonNoShow, reason)
+		aborted = append(aborted, id)
+		return nil
+	}
+	uc := NewTournamentUsecase(repo, nil, roomRepo, nil, time.Minute)
+	id, _ := setupTournament(t, uc, 1500, 1400)
+	roomID := brackets[id].Final().RoomID.UUID
+
+	swept, err := uc.SweepNoShows(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, swept)
+	assert.Equal(t, []uuid.UUID{roomID}, aborted)
+
+	b := brackets[id]
+	assert.Equal(t, entity.TournamentMatchResultVoid, b.Final().Result)
+	assert.Equal(t, entity.TournamentStatusFinished, b.Tournament.Status)
+	assert.False(t, b.Tournament.WinnerID.Valid)
+}
+
+func TestTournamentUsecase_RoomCreationFailureKeepsBracket(t *testing.T) {
+	repo, brackets := newMemoryTournamentRepo()
+	roomRepo := newMemoryRoomRepo()
+	uc := NewTournamentUsecase(repo, nil, roomRepo, nil, time.Minute)
+	ctx := context.Background()
+	creator := uuid.New()
+	tournament, err := uc.Create(ctx, creator, "cup")
+	require.NoError(t, err)
+	for _, rate := range []int{1500, 1400} {
+		_, err := uc.Register(ctx, tournament.ID, &entity.User{ID: uuid.New(), Rate: rate})
+		require.NoError(t, err)
+	}
+
+	roomRepo.CreateFunc = func(context.Context, *entity.Room) error { return errors.New("db down") }
+	_, err = uc.Start(ctx, tournament.ID, creator)
+	require.Error(t, err)
+	assert.Equal(t, entity.TournamentStatusRegistration, brackets[tournament.ID].Tournament.Status)
+	assert.Empty(t, brackets[tournament.ID].Matches)
+}
@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/repository"
+)
+
+const (
+	// userStatsCacheTTL は成績のキャッシュの有効期限
+	// 試合の終了時に削除するが、削除に失敗しても古い成績を返し続けないよう期限を設ける
+	userStatsCacheTTL = 10 * time.Minute
+	// userStatsHistoryDays は GnuHistory に含める日数
+	userStatsHistoryDays = 30
+)
+
+// ErrUserNotFound は指定した GitHub login のユーザーが存在しない
+var ErrUserNotFound = errors.New("user not found")
+
+type UserStatsUsecase struct {
+	userRepo  repository.UserRepository
+	matchRepo repository.MatchRepository
+	cache     repository.UserStatsCacheRepository
+}
+
+func NewUserStatsUsecase(
+	userRepo repository.UserRepository,
+	matchRepo repository.MatchRepository,
+	cache repository.UserStatsCacheRepository,
+) *UserStatsUsecase {
+	return &UserStatsUsecase{userRepo: userRepo, matchRepo: matchRepo, cache: cache}
+}
+
+// GetByLogin は GitHub login のユーザーの通算成績を返す
+// キャッシュがあればそれを返し、なければ集計してキャッシュする（キャッシュの読み書きの失敗は集計で補う）
+func (uc *UserStatsUsecase) GetByLogin(ctx context.Context, login string) (*entity.UserStats, error) {
+	user, err := uc.userRepo.GetByGitHubLogin(ctx, login)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+
+	cached, err := uc.cache.Get(ctx, user.ID)
+	if err != nil {
+		log.Printf("user stats: failed to read cache for %s: %v", user.ID, err)
+	}
+	if cached != nil {
+		// login は変わりうるため、キャッシュした値ではなく現在の値を返す
+		cached.GitHubLogin = user.GitHubLogin
+		return cached, nil
+	}
+
+	now := time.Now()
+	since := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, -(userStatsHistoryDays - 1))
+	stats, err := uc.matchRepo.GetUserStats(ctx, user.ID, since)
+	if err != nil {
+		return nil, fmt.Errorf("get user stats: %w", err)
+	}
+	stats.UserID = user.ID
+	stats.GitHubLogin = user.GitHubLogin
+	stats.ComputedAt = now
+	if err := uc.cache.Set(ctx, stats, userStatsCacheTTL); err != nil {
+		log.Printf("user stats: failed to write cache for %s: %v", user.ID, err)
+	}
+	return stats, nil
+}
+
+// Invalidate は試合の結果を保存した後に、各プレイヤーの成績のキャッシュを削除する
+func (uc *UserStatsUsecase) Invalidate(ctx context.Context, userIDs ...uuid.UUID) error {
+	if err := uc.cache.Delete(ctx, userIDs...); err != nil {
+		return fmt.Errorf("delete user stats cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/domain/entity"
+	"github.com/tobakuro/hackathon_nulabcup/backend/internal/testutil"
+)
+
+// newMemoryStatsCache は成績をメモリに持つキャッシュを返す
+func newMemoryStatsCache() *testutil.MockUserStatsCacheRepository {
+	cached := map[uuid.UUID]entity.UserStats{}
+	return &testutil.MockUserStatsCacheRepository{
+		GetFunc: func(_ context.Context, userID uuid.UUID) (*entity.UserStats, error) {
+			s, ok := cached[userID]
+			if !ok {
+				return nil, nil
+			}
+			return &s, nil
+		},
+		SetFunc: func(_ context.Context, stats *entity.UserStats, ttl time.Duration) error {
+			cached[stats.UserID] = *stats
+			return nil
+		},
+		DeleteFunc: func(_ context.Context, userIDs ...uuid.UUID) error {
+			for _, id := range userIDs {
+				delete(cached, id)
+			}
+			return nil
+		},
+	}
+}
+
+func TestUserStatsUsecase_GetByLogin_CachesUntilInvalidated(t *testing.T) {
+	alice := &entity.User{ID: uuid.New(), GitHubLogin: "alice"}
+	userRepo := &testutil.MockUserRepository{
+		GetByGitHubLoginFunc: func(_ context.Context, login string) (*entity.User, error) {
+			if login != "alice" {
+				return nil, fmt.Errorf("get user by github login: %w", sql.ErrNoRows)
+			}
+			return alice, nil
+		},
+	}
+	computed := 0
+	matchRepo := &testutil.MockMatchRepository{
+		GetUserStatsFunc: func(_ context.Context, userID uuid.UUID, since time.Time) (*entity.UserStats, error) {
+			computed++
+			assert.Equal(t, alice.ID, userID)
+			assert.WithinDuration(t, time.Now().AddDate(0, 0, -userStatsHistoryDays), since, 48*time.Hour)
+			return &entity.UserStats{MatchesPlayed: computed, Wins: 1}, nil
+		},
+	}
+	uc := NewUserStatsUsecase(userRepo, matchRepo, newMemoryStatsCache())
+	ctx := context.Background()
+
+	stats, err := uc.GetByLogin(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, alice.ID, stats.UserID)
+	assert.Equal(t, "alice", stats.GitHubLogin)
+	assert.Equal(t, 1, stats.MatchesPlayed)
+
+	stats, err = uc.GetByLogin(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 1, computed, "second request is served from the cache")
+	assert.Equal(t, 1, stats.MatchesPlayed)
+
+	require.NoError(t, uc.Invalidate(ctx, alice.ID, uuid.New()))
+	stats, err = uc.GetByLogin(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 2, computed, "invalidated stats are recomputed")
+	assert.Equal(t, 2, stats.MatchesPlayed)
+
+	_, err = uc.GetByLogin(ctx, "nobody")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestUserStatsUsecase_GetByLogin_CacheFailureFallsBack(t *testing.T) {
+	alice := &entity.User{ID: uuid.New(), GitHubLogin: "alice"}
+	userRepo := &testutil.MockUserRepository{
+		GetByGitHubLoginFunc: func(context.Context, string) (*entity.User, error) { return alice, nil },
+	}
+	matchRepo := &testutil.MockMatchRepository{
+		GetUserStatsFunc: func(context.Context, uuid.UUID, time.Time) (*entity.UserStats, error) {
+			return &entity.UserStats{MatchesPlayed: 3}, nil
+		},
+	}
+	cache := &testutil.MockUserStatsCacheRepository{
+		GetFunc: func(context.Context, uuid.UUID) (*entity.UserStats, error) { return nil, errors.New("redis down") },
+		SetFunc: func(context.Context, *entity.UserStats, time.Duration) error { return errors.New("redis down") },
+	}
+	uc := NewUserStatsUsecase(userRepo, matchRepo, cache)
+
+	stats, err := uc.GetByLogin(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.MatchesPlayed)
+}